@@ -17,6 +17,7 @@
 package agreement
 
 import (
+	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/data/basics"
 )
 
@@ -25,3 +26,9 @@ import (
 func ParamsRound(rnd basics.Round) basics.Round {
 	return rnd.SubSaturate(2)
 }
+
+// BalanceRound returns the round whose balances, and so whose registered
+// participation keys, are used for agreement on round rnd.
+func BalanceRound(rnd basics.Round, cparams config.ConsensusParams) basics.Round {
+	return balanceRound(rnd, cparams)
+}
@@ -234,20 +234,67 @@ func (n asyncPseudonode) makePseudonodeVerifier(voteVerifier *AsyncVoteVerifier)
 }
 
 // getParticipations retrieves the participation accounts for a given round.
+//
+// An account may hold several keys valid for the same round while a key is
+// being rolled over. In that case only the key registered on the ledger for
+// that round is used, so the account switches over to the new key as soon as
+// its registration takes effect and never votes with two keys at once.
 func (n asyncPseudonode) getParticipations(procName string, round basics.Round) []account.Participation {
 	keys := n.keys.Keys()
 	participations := make([]account.Participation, 0, len(keys))
+	selected := make(map[basics.Address]int, len(keys))
 	for _, part := range keys {
 		firstValid, lastValid := part.ValidInterval()
 		if round < firstValid || round > lastValid {
 			n.log.Debugf("%v (round=%v): Account %v not participating: %v not in [%v, %v]", procName, round, part.Address(), round, firstValid, lastValid)
 			continue
 		}
-		participations = append(participations, part)
+		i, ok := selected[part.Address()]
+		if !ok {
+			selected[part.Address()] = len(participations)
+			participations = append(participations, part)
+			continue
+		}
+		participations[i] = n.chooseParticipation(round, participations[i], part)
 	}
 	return participations
 }
 
+// chooseParticipation picks which of two keys of the same account to use in
+// the given round. It prefers the key registered on the ledger for the round,
+// and otherwise the key with the later validity interval.
+func (n asyncPseudonode) chooseParticipation(round basics.Round, a, b account.Participation) account.Participation {
+	cparams, err := n.ledger.ConsensusParams(ParamsRound(round))
+	if err == nil {
+		var record basics.BalanceRecord
+		record, err = n.ledger.BalanceRecord(balanceRound(round, cparams), a.Address())
+		if err == nil {
+			if a.Voting != nil && a.Voting.OneTimeSignatureVerifier == record.VoteID {
+				return a
+			}
+			if b.Voting != nil && b.Voting.OneTimeSignatureVerifier == record.VoteID {
+				return b
+			}
+		}
+	}
+	if err != nil {
+		n.log.Warnf("pseudonode.chooseParticipation (round=%v): cannot look up registered key for %v: %v", round, a.Address(), err)
+	}
+
+	aFirst, aLast := a.ValidInterval()
+	bFirst, bLast := b.ValidInterval()
+	if aFirst != bFirst {
+		if aFirst > bFirst {
+			return a
+		}
+		return b
+	}
+	if aLast > bLast {
+		return a
+	}
+	return b
+}
+
 // makeProposals creates a slice of block proposals for the given round and period.
 func (n asyncPseudonode) makeProposals(round basics.Round, period period, accounts []account.Participation) ([]proposal, []unauthenticatedVote) {
 	deadline := time.Now().Add(AssemblyTime)
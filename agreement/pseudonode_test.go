@@ -23,7 +23,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/account"
+	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/protocol"
 )
@@ -366,3 +370,52 @@ func (n serializedPseudonode) MakeVotes(ctx context.Context, r round, p period,
 func (n serializedPseudonode) Quit() {
 	// nothing to do ! this serializedPseudonode is so simplified that no destructor is needed.
 }
+
+func TestPseudonodeKeyRollover(t *testing.T) {
+	t.Parallel()
+
+	var addr basics.Address
+	addr[0] = 1
+	var other basics.Address
+	other[0] = 2
+
+	oldKey := account.Participation{Parent: addr, Voting: crypto.GenerateOneTimeSignatureSecrets(0, 1), FirstValid: 0, LastValid: 1000}
+	newKey := account.Participation{Parent: addr, Voting: crypto.GenerateOneTimeSignatureSecrets(0, 1), FirstValid: 900, LastValid: 2000}
+	otherKey := account.Participation{Parent: other, Voting: crypto.GenerateOneTimeSignatureSecrets(0, 1), FirstValid: 0, LastValid: 2000}
+
+	balances := map[basics.Address]basics.BalanceRecord{
+		addr: {Addr: addr, AccountData: basics.AccountData{VoteID: oldKey.Voting.OneTimeSignatureVerifier}},
+	}
+	ledger := makeTestLedger(balances).(*testLedger)
+	ledger.nextRound = 3000
+
+	n := asyncPseudonode{
+		keys:   simpleKeyManager{newKey, oldKey, otherKey},
+		ledger: ledger,
+		log:    serviceLogger{logging.Base()},
+	}
+
+	keyFor := func(round basics.Round) []account.Participation {
+		var out []account.Participation
+		for _, part := range n.getParticipations("test", round) {
+			if part.Address() == addr {
+				out = append(out, part)
+			}
+		}
+		return out
+	}
+
+	// While the old key is still registered, keep voting with it.
+	require.Equal(t, []account.Participation{oldKey}, keyFor(899))
+	require.Equal(t, []account.Participation{oldKey}, keyFor(950))
+	require.Len(t, n.getParticipations("test", 950), 2)
+
+	// Once the new key is registered, switch over to it.
+	ledger.state[addr] = basics.BalanceRecord{Addr: addr, AccountData: basics.AccountData{VoteID: newKey.Voting.OneTimeSignatureVerifier}}
+	require.Equal(t, []account.Participation{newKey}, keyFor(950))
+	require.Equal(t, []account.Participation{newKey}, keyFor(1001))
+
+	// With neither key registered, prefer the more recent one.
+	delete(ledger.state, addr)
+	require.Equal(t, []account.Participation{newKey}, keyFor(950))
+}
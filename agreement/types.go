@@ -29,6 +29,15 @@ var deadlineTimeout = config.Protocol.BigLambda + config.Protocol.SmallLambda
 var partitionStep = next + 3
 var recoveryExtraTimeout = config.Protocol.SmallLambda
 
+// UpdateTimeouts derives the agreement timeouts from config.Protocol again.
+// A node whose consensus overrides change the lambdas must call it once at
+// startup, before agreement starts.
+func UpdateTimeouts() {
+	filterTimeout = 2 * config.Protocol.SmallLambda
+	deadlineTimeout = config.Protocol.BigLambda + config.Protocol.SmallLambda
+	recoveryExtraTimeout = config.Protocol.SmallLambda
+}
+
 // FilterTimeout is the duration of the first agreement step.
 func FilterTimeout() time.Duration {
 	return filterTimeout
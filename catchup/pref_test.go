@@ -31,6 +31,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/datatest"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/protocol"
 	"github.com/vincentbdb/go-algorand/util/db"
@@ -50,7 +51,7 @@ func BenchmarkServiceFetchBlocks(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		archival := true
 		inMem := true
-		local, err := data.LoadLedger(logging.Base(), b.Name()+"empty"+strconv.Itoa(i), inMem, protocol.ConsensusCurrentVersion, genesisBalances, "", crypto.Digest{}, nil, archival)
+		local, err := data.LoadLedger(logging.Base(), b.Name()+"empty"+strconv.Itoa(i), inMem, protocol.ConsensusCurrentVersion, genesisBalances, "", crypto.Digest{}, nil, archival, false, ledger.StorageSQLite)
 		require.NoError(b, err)
 
 		// Make Service
@@ -66,14 +67,14 @@ func BenchmarkServiceFetchBlocks(b *testing.B) {
 }
 
 // one service
-func benchenv(t testing.TB, numAccounts, numBlocks int) (ledger, emptyLedger *data.Ledger, release func(), genesisBalances data.GenesisBalances) {
+func benchenv(t testing.TB, numAccounts, numBlocks int) (fullLedger, emptyLedger *data.Ledger, release func(), genesisBalances data.GenesisBalances) {
 	P := numAccounts                                  // n accounts
 	maxMoneyAtStart := uint64(10 * defaultRewardUnit) // max money start
 	minMoneyAtStart := uint64(defaultRewardUnit)      // min money start
 
 	accesssors := make([]db.Accessor, 0)
 	release = func() {
-		ledger.Close()
+		fullLedger.Close()
 		emptyLedger.Close()
 		for _, acc := range accesssors {
 			acc.Close()
@@ -130,11 +131,11 @@ func benchenv(t testing.TB, numAccounts, numBlocks int) (ledger, emptyLedger *da
 	genesisBalances = data.MakeGenesisBalances(genesis, sinkAddr, poolAddr)
 	const inMem = true
 	const archival = true
-	emptyLedger, err = data.LoadLedger(logging.Base(), t.Name()+"empty", inMem, protocol.ConsensusCurrentVersion, genesisBalances, "", crypto.Digest{}, nil, archival)
+	emptyLedger, err = data.LoadLedger(logging.Base(), t.Name()+"empty", inMem, protocol.ConsensusCurrentVersion, genesisBalances, "", crypto.Digest{}, nil, archival, false, ledger.StorageSQLite)
 	require.NoError(t, err)
 
-	ledger, err = datatest.FabricateLedger(logging.Base(), t.Name(), parts, genesisBalances, emptyLedger.LastRound()+basics.Round(numBlocks))
+	fullLedger, err = datatest.FabricateLedger(logging.Base(), t.Name(), parts, genesisBalances, emptyLedger.LastRound()+basics.Round(numBlocks))
 	require.NoError(t, err)
-	require.Equal(t, ledger.LastRound(), emptyLedger.LastRound()+basics.Round(numBlocks))
-	return ledger, emptyLedger, release, genesisBalances
+	require.Equal(t, fullLedger.LastRound(), emptyLedger.LastRound()+basics.Round(numBlocks))
+	return fullLedger, emptyLedger, release, genesisBalances
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/algorand/go-deadlock"
 	"github.com/gofrs/flock"
 
+	"github.com/vincentbdb/go-algorand/agreement"
 	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/daemon/algod"
@@ -49,6 +50,7 @@ var versionCheck = flag.Bool("v", false, "Display and write current build versio
 var branchCheck = flag.Bool("b", false, "Display the git branch behind the build")
 var channelCheck = flag.Bool("c", false, "Display and release channel behind the build")
 var initAndExit = flag.Bool("x", false, "Initialize the ledger and exit")
+var importFile = flag.String("import", "", "Import the blocks in a block archive written by goal ledger export, and exit")
 var peerOverride = flag.String("p", "", "Override phonebook with peer ip:port (or semicolon separated list: ip:port;ip:port;ip:port...)")
 var listenIP = flag.String("l", "", "Override config.EndpointAddress (REST listening address) with ip:port")
 var sessionGUID = flag.String("s", "", "Telemetry Session GUID to use")
@@ -137,6 +139,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Private networks may define their own consensus protocols, which
+	// must match those that the network was created with.
+	err = config.LoadConsensusFromDisk(absolutePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load consensus protocols: %v\n", err)
+		os.Exit(1)
+	}
+	agreement.UpdateTimeouts()
+	err = genesis.CheckProtoParams()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot join network %s: %v\n", genesis.ID(), err)
+		os.Exit(1)
+	}
+
 	log := logging.Base()
 	// before doing anything further, attempt to acquire the algod lock
 	// to ensure this is the only node running against this data directory
@@ -246,6 +262,22 @@ func main() {
 		return
 	}
 
+	if *importFile != "" {
+		archive, err := os.Open(*importFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open block archive %s: %v\n", *importFile, err)
+			os.Exit(1)
+		}
+		imported, err := s.ImportBlocks(archive)
+		archive.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot import block archive %s after %d blocks: %v\n", *importFile, imported, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %d blocks from %s\n", imported, *importFile)
+		return
+	}
+
 	if peerOverrideArray != nil {
 		s.OverridePhonebook(peerOverrideArray...)
 	}
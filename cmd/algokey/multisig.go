@@ -70,14 +70,13 @@ var multisigCmd = &cobra.Command{
 				os.Exit(1)
 			}
 
-			ver, thresh, pks := stxn.Msig.Preimage()
-			addr, err := crypto.MultisigAddrGen(ver, thresh, pks)
+			addr, err := crypto.MultisigAddrGenWithSubsigs(stxn.Msig.Version, stxn.Msig.Threshold, stxn.Msig.Subsigs)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Cannot generate multisig addr: %v\n", err)
 				os.Exit(1)
 			}
 
-			stxn.Msig, err = crypto.MultisigSign(stxn.Txn, addr, ver, thresh, pks, *key)
+			stxn.Msig, err = crypto.MultisigSignPreimage(stxn.Txn, addr, stxn.Msig, *key)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Cannot add multisig signature: %v\n", err)
 				os.Exit(1)
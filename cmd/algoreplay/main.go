@@ -0,0 +1,124 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+// algoreplay re-evaluates every block in a node's ledger from genesis, and
+// checks the result against the node's account database.  It reports the
+// first round, account and field where the two disagree.  The node's
+// databases are opened read-only, but algod should not be running.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/ledger"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+var dataDirectory = flag.String("d", "", "Root Algorand daemon data path (default $ALGORAND_DATA)")
+var genesisFile = flag.String("g", "", "Genesis configuration file (default genesis.json in the data path)")
+
+func main() {
+	flag.Parse()
+
+	dataDir := *dataDirectory
+	if dataDir == "" {
+		dataDir = os.Getenv("ALGORAND_DATA")
+	}
+	if dataDir == "" {
+		fmt.Fprintln(os.Stderr, "Data directory not specified.  Please use -d or set $ALGORAND_DATA in your environment.")
+		os.Exit(1)
+	}
+
+	if *genesisFile == "" {
+		*genesisFile = filepath.Join(dataDir, config.GenesisJSONFile)
+	}
+
+	genesis, err := bookkeeping.LoadGenesisFromFile(*genesisFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read genesis file %s: %v\n", *genesisFile, err)
+		os.Exit(1)
+	}
+
+	// Blocks of a private network must be evaluated with the consensus
+	// protocols that the network was created with.
+	err = config.LoadConsensusFromDisk(dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load consensus protocols: %v\n", err)
+		os.Exit(1)
+	}
+	err = genesis.CheckProtoParams()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot replay network %s: %v\n", genesis.ID(), err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfigFromDisk(dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Cannot load config from %s: %v\n", dataDir, err)
+		os.Exit(1)
+	}
+
+	engine, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	genesisInitState, err := makeGenesisInitState(genesis)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot load genesis allocation: %v\n", err)
+		os.Exit(1)
+	}
+
+	log := logging.Base()
+	log.SetLevel(logging.Info)
+
+	executionPool := execpool.MakePool(nil)
+	defer executionPool.Shutdown()
+	verificationPool := execpool.MakeBacklog(executionPool, 2*executionPool.GetParallelism(), execpool.LowPriority, nil)
+	defer verificationPool.Shutdown()
+
+	ledgerPathnamePrefix := filepath.Join(dataDir, genesis.ID(), config.LedgerFilenamePrefix)
+	divergence, err := ledger.Replay(log, ledgerPathnamePrefix, engine, genesisInitState, verificationPool)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if divergence != nil {
+		fmt.Printf("Divergence at %v\n", divergence)
+		os.Exit(2)
+	}
+	fmt.Println("Ledger replay matches the account database")
+}
+
+// makeGenesisInitState builds the initial ledger state from the genesis
+// file, the way algod does when it creates a new ledger.
+func makeGenesisInitState(genesis bookkeeping.Genesis) (ledger.InitState, error) {
+	genesisBal, err := data.LoadGenesisBalances(genesis)
+	if err != nil {
+		return ledger.InitState{}, err
+	}
+	return data.MakeGenesisInitState(genesis.Proto, genesisBal, genesis.ID(), crypto.HashObj(genesis))
+}
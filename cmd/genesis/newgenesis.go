@@ -19,8 +19,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 
+	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/gen"
 	"github.com/vincentbdb/go-algorand/util"
 )
@@ -29,6 +31,7 @@ var outDir = flag.String("d", "", "The directory containing the generated ledger
 var netName = flag.String("n", "", "The name of the network for this ledger (will override config file).")
 var configFile = flag.String("c", "", "The config file containing the genesis ledger and wallets")
 var quiet = flag.Bool("q", false, "Skip verbose informational messages")
+var consensusFile = flag.String("consensus", "", "A consensus.json file defining the consensus protocols of a private network")
 
 func init() {
 	flag.Parse()
@@ -53,6 +56,17 @@ func main() {
 		genesisData.NetworkName = *netName
 	}
 
+	if *consensusFile != "" {
+		data, err := ioutil.ReadFile(*consensusFile)
+		if err != nil {
+			reportErrorf("error loading consensus file: %v\n", err)
+		}
+		err = config.ApplyConsensusOverrides(data)
+		if err != nil {
+			reportErrorf("error applying consensus file: %v\n", err)
+		}
+	}
+
 	err = gen.GenerateGenesisFiles(genesisData, *outDir, !*quiet)
 	if err != nil {
 		reportErrorf("Cannot write genesis files: %s", err)
@@ -33,6 +33,7 @@ import (
 	"github.com/vincentbdb/go-algorand/crypto/passphrase"
 	algodAcct "github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 	"github.com/vincentbdb/go-algorand/data/transactions"
 	"github.com/vincentbdb/go-algorand/libgoal"
 	"github.com/vincentbdb/go-algorand/protocol"
@@ -55,11 +56,14 @@ var (
 	roundLastValid     uint64
 	keyDilution        uint64
 	threshold          uint8
+	multisigWeights    []uint
 	partKeyOutDir      string
 	partKeyFile        string
 	partKeyDeleteInput bool
 	importDefault      bool
 	mnemonic           string
+	newAuthAddress     string
+	partKeyLeadRounds  uint64
 )
 
 func init() {
@@ -78,6 +82,7 @@ func init() {
 	accountCmd.AddCommand(importRootKeysCmd)
 	accountCmd.AddCommand(accountMultisigCmd)
 	accountCmd.AddCommand(markNonparticipatingCmd)
+	accountCmd.AddCommand(rekeyCmd)
 
 	accountMultisigCmd.AddCommand(newMultisigCmd)
 	accountMultisigCmd.AddCommand(deleteMultisigCmd)
@@ -88,6 +93,9 @@ func init() {
 
 	accountCmd.AddCommand(partkeyInfoCmd)
 
+	accountCmd.AddCommand(partkeyCmd)
+	partkeyCmd.AddCommand(schedulePartKeyCmd)
+
 	// Wallet to be used for the account operation
 	accountCmd.PersistentFlags().StringVarP(&walletName, "wallet", "w", "", "Set the wallet to be used for the selected operation")
 
@@ -104,6 +112,7 @@ func init() {
 	// New Multisig account flag
 	newMultisigCmd.Flags().Uint8VarP(&threshold, "threshold", "T", 1, "Number of signatures required to spend from this address")
 	newMultisigCmd.MarkFlagRequired("threshold")
+	newMultisigCmd.Flags().UintSliceVar(&multisigWeights, "weights", nil, "Weight of each address, in order, for a weighted multisig account; the threshold is then the total weight required to spend")
 
 	// Delete multisig account flag
 	deleteMultisigCmd.Flags().StringVarP(&accountAddress, "address", "a", "", "Address of multisig account to delete")
@@ -178,6 +187,16 @@ func init() {
 	renewAllParticipationKeyCmd.Flags().Uint64VarP(&keyDilution, "keyDilution", "", 0, "Key dilution for two-level participation keys")
 	renewAllParticipationKeyCmd.Flags().BoolVarP(&noWaitAfterSend, "no-wait", "N", false, "Don't wait for transaction to commit")
 
+	// schedulePartKeyCmd
+	schedulePartKeyCmd.Flags().StringVarP(&accountAddress, "address", "a", "", "Account address to schedule the next participation key for (required)")
+	schedulePartKeyCmd.MarkFlagRequired("address")
+	schedulePartKeyCmd.Flags().Uint64VarP(&roundLastValid, "roundLastValid", "", 0, "The last round for which the next partkey will be valid")
+	schedulePartKeyCmd.MarkFlagRequired("roundLastValid")
+	schedulePartKeyCmd.Flags().Uint64VarP(&partKeyLeadRounds, "lead", "", 2000, "How many rounds before the current partkey expires to generate and register the next one")
+	schedulePartKeyCmd.Flags().Uint64VarP(&transactionFee, "fee", "f", 0, "The Fee to set on the status change transaction (defaults to suggested fee)")
+	schedulePartKeyCmd.Flags().Uint64VarP(&keyDilution, "keyDilution", "", 0, "Key dilution for two-level participation keys")
+	schedulePartKeyCmd.Flags().BoolVarP(&noWaitAfterSend, "no-wait", "N", false, "Don't wait for transaction to commit")
+
 	// markNonparticipatingCmd flags
 	markNonparticipatingCmd.Flags().StringVarP(&accountAddress, "address", "a", "", "Account address to change")
 	markNonparticipatingCmd.Flags().Uint64VarP(&transactionFee, "fee", "f", 0, "The Fee to set on the status change transaction (defaults to suggested fee)")
@@ -190,6 +209,18 @@ func init() {
 	markNonparticipatingCmd.Flags().BoolVarP(&noWaitAfterSend, "no-wait", "N", false, "Don't wait for transaction to commit")
 	markNonparticipatingCmd.Flags().MarkDeprecated("firstRound", "use --firstvalid instead")
 	markNonparticipatingCmd.Flags().MarkDeprecated("validRounds", "use --validrounds instead")
+
+	// rekeyCmd flags
+	rekeyCmd.Flags().StringVarP(&accountAddress, "address", "a", "", "Account address to rekey (required)")
+	rekeyCmd.MarkFlagRequired("address")
+	rekeyCmd.Flags().StringVarP(&newAuthAddress, "new-auth-addr", "n", "", "Address whose key will authorize the account's transactions from now on (required)")
+	rekeyCmd.MarkFlagRequired("new-auth-addr")
+	rekeyCmd.Flags().Uint64VarP(&transactionFee, "fee", "f", 0, "The Fee to set on the rekey transaction (defaults to suggested fee)")
+	rekeyCmd.Flags().Uint64Var(&firstValid, "firstvalid", 0, "FirstValid for the rekey transaction (0 for current)")
+	rekeyCmd.Flags().Uint64VarP(&numValidRounds, "validrounds", "v", 0, "The validity period for the rekey transaction")
+	rekeyCmd.Flags().Uint64Var(&lastValid, "lastvalid", 0, "The last round where the transaction may be committed to the ledger")
+	rekeyCmd.Flags().StringVarP(&statusChangeTxFile, "txfile", "t", "", "Write the rekey transaction to this file, rather than posting to network")
+	rekeyCmd.Flags().BoolVarP(&noWaitAfterSend, "no-wait", "N", false, "Don't wait for transaction to commit")
 }
 
 func scLeaseBytes(cmd *cobra.Command) (leaseBytes [32]byte) {
@@ -344,7 +375,7 @@ var deleteCmd = &cobra.Command{
 var newMultisigCmd = &cobra.Command{
 	Use:   "new [addr1 addr2 ...]",
 	Short: "Create a new multisig account",
-	Long:  `Create a new multisig account from a list of existing non-multisig addresses`,
+	Long:  `Create a new multisig account from a list of existing non-multisig addresses. With --weights, each address counts for its weight towards the threshold.`,
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		dataDir := ensureSingleDataDir()
@@ -372,7 +403,23 @@ var newMultisigCmd = &cobra.Command{
 			reportWarnln(warnMultisigDuplicatesDetected)
 		}
 		// Generate a new address in the default wallet
-		addr, err := client.CreateMultisigAccount(wh, threshold, args)
+		var addr string
+		var err error
+		if len(multisigWeights) > 0 {
+			if len(multisigWeights) != len(args) {
+				reportErrorf(errorMultisigWeights, len(args))
+			}
+			weights := make([]uint8, len(multisigWeights))
+			for i, w := range multisigWeights {
+				if w == 0 || w > 255 {
+					reportErrorf(errorMultisigWeights, len(args))
+				}
+				weights[i] = uint8(w)
+			}
+			addr, err = client.CreateWeightedMultisigAccount(wh, threshold, args, weights)
+		} else {
+			addr, err = client.CreateMultisigAccount(wh, threshold, args)
+		}
 		if err != nil {
 			reportErrorf(errorRequestFail, err)
 		}
@@ -421,8 +468,12 @@ var infoMultisigCmd = &cobra.Command{
 		fmt.Printf("Version: %d\n", multisigInfo.Version)
 		fmt.Printf("Threshold: %d\n", multisigInfo.Threshold)
 		fmt.Printf("Public keys:\n")
-		for _, pk := range multisigInfo.PKs {
-			fmt.Printf("  %s\n", pk)
+		for i, pk := range multisigInfo.PKs {
+			if i < len(multisigInfo.Weights) {
+				fmt.Printf("  %s (weight %d)\n", pk, multisigInfo.Weights[i])
+			} else {
+				fmt.Printf("  %s\n", pk)
+			}
 		}
 	},
 }
@@ -430,11 +481,12 @@ var infoMultisigCmd = &cobra.Command{
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "Show the list of Algorand accounts on this machine",
-	Long:  `Show the list of Algorand accounts on this machine. Also indicates whether the account is [offline] or [online], and if the account is the default account for goal.`,
+	Long:  `Show the list of Algorand accounts on this machine. Also indicates whether the account is [offline] or [online], and if the account is the default account for goal. Vesting escrows created at genesis are listed under their beneficiary, along with their unlock schedule.`,
 	Args:  validateNoPosArgsFn,
 	Run: func(cmd *cobra.Command, args []string) {
 		dataDir := ensureSingleDataDir()
 		accountList := makeAccountsList(dataDir)
+		vesting := vestingAllocations(dataDir)
 
 		// Get a wallet handle to the specified wallet
 		wh := ensureWalletHandle(dataDir, walletName)
@@ -501,10 +553,38 @@ var listCmd = &cobra.Command{
 
 				fmt.Printf("\t%20d %-8s (creator %s, ID %d%s%s%s%s)\n", bal.Amount, unitName, bal.Creator, aid, assetName, assetURL, assetMetadata, frozen)
 			}
+
+			for _, alloc := range vesting[addr.Addr] {
+				escrowInfo, err := client.AccountInformation(alloc.Address)
+				if err != nil {
+					fmt.Printf(infoVestingEscrowUnknown, alloc.Address)
+				} else {
+					unlocked := alloc.Vesting.Unlocked(basics.Round(escrowInfo.Round), escrowInfo.Amount)
+					fmt.Printf(infoVestingEscrow, alloc.Address, escrowInfo.Amount, unlocked, escrowInfo.Round)
+				}
+				fmt.Printf(infoVestingSchedule, alloc.Vesting.CliffAmount, alloc.Vesting.Cliff, alloc.Vesting.PeriodAmount, alloc.Vesting.Period, alloc.Vesting.Duration, alloc.Vesting.End)
+			}
 		}
 	},
 }
 
+// vestingAllocations returns the vesting escrows in the node's genesis, keyed
+// by beneficiary.  It is okay to list accounts without them, so errors reading
+// the genesis file are ignored.
+func vestingAllocations(dataDir string) map[string][]bookkeeping.GenesisAllocation {
+	vesting := make(map[string][]bookkeeping.GenesisAllocation)
+	genesis, err := readGenesis(dataDir)
+	if err != nil {
+		return vesting
+	}
+	for _, alloc := range genesis.Allocation {
+		if alloc.Vesting != nil {
+			vesting[alloc.Vesting.Beneficiary] = append(vesting[alloc.Vesting.Beneficiary], alloc)
+		}
+	}
+	return vesting
+}
+
 var balanceCmd = &cobra.Command{
 	Use:   "balance",
 	Short: "Retrieve the balance for the specified account, in microAlgos",
@@ -746,6 +826,81 @@ func generateAndRegisterPartKey(address string, currentRound, lastValidRound uin
 	return err
 }
 
+var partkeyCmd = &cobra.Command{
+	Use:   "partkey",
+	Short: "Manage participation keys",
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Return the help text
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var schedulePartKeyCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Register the next participation key before the current one expires",
+	Long:  `Generate and register the next participation key for the specified account once its current key is within the given number of rounds of expiring. The node keeps voting with the current key until the new registration takes effect, so the account does not go offline, and then deletes the old key. Registering needs the account's spending key, so the node cannot do this on its own; running the command earlier is a no-op, so it can be run periodically (e.g. from cron).`,
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, args []string) {
+		dataDir := ensureSingleDataDir()
+
+		client := ensureAlgodClient(dataDir)
+
+		currentRound, err := client.CurrentRound()
+		if err != nil {
+			reportErrorf(errorRequestFail, err)
+		}
+
+		params, err := client.SuggestedParams()
+		if err != nil {
+			reportErrorf(errorRequestFail, err)
+		}
+		proto := config.Consensus[protocol.ConsensusVersion(params.ConsensusVersion)]
+
+		if roundLastValid <= (currentRound + proto.MaxTxnLife) {
+			reportErrorf(errLastRoundInvalid, currentRound)
+		}
+
+		// The new key only takes over once its registration is visible at the
+		// balance lookback, so the current key has to outlive that window.
+		minLead := 2*proto.SeedRefreshInterval*proto.SeedLookback + proto.MaxTxnLife
+		if partKeyLeadRounds < minLead {
+			reportErrorf(errPartKeyLeadTooShort, minLead)
+		}
+
+		// Find the account's latest participation key
+		parts, err := client.ListParticipationKeys()
+		if err != nil {
+			reportErrorf(errorRequestFail, err)
+		}
+		var latest basics.Round
+		found := false
+		for _, part := range parts {
+			if part.Address().String() == accountAddress && (!found || part.LastValid > latest) {
+				latest = part.LastValid
+				found = true
+			}
+		}
+		if !found || latest < basics.Round(currentRound) {
+			reportErrorf(errNoPartKeyToSchedule, accountAddress)
+		}
+		if latest >= basics.Round(roundLastValid) {
+			reportErrorf(errExistingPartKey, roundLastValid, latest)
+		}
+
+		scheduled := latest.SubSaturate(basics.Round(partKeyLeadRounds))
+		if basics.Round(currentRound) < scheduled {
+			fmt.Printf(infoPartKeyNotDue, accountAddress, latest, scheduled)
+			return
+		}
+
+		err = generateAndRegisterPartKey(accountAddress, currentRound, roundLastValid, transactionFee, scLeaseBytes(cmd), keyDilution, walletName, dataDir, client)
+		if err != nil {
+			reportErrorf(err.Error())
+		}
+	},
+}
+
 var renewAllParticipationKeyCmd = &cobra.Command{
 	Use:   "renewallpartkeys",
 	Short: "Renew all existing participation keys",
@@ -1141,3 +1296,62 @@ var markNonparticipatingCmd = &cobra.Command{
 		}
 	},
 }
+
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the key that authorizes an account's transactions",
+	Long:  "Issue a zero-amount payment from the account to itself that rekeys it, so that its transactions must from now on be signed by the key (or multisig) of the new auth address. The account keeps its address, balance and asset holdings. Rekeying an account to its own address restores its original key.",
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkTxValidityPeriodCmdFlags(cmd)
+
+		dataDir := ensureSingleDataDir()
+		accountList := makeAccountsList(dataDir)
+		address := accountList.getAddressByName(accountAddress)
+		authAddress := accountList.getAddressByName(newAuthAddress)
+
+		client := ensureFullClient(dataDir)
+		firstTxRound, lastTxRound, err := client.ComputeValidityRounds(firstValid, lastValid, numValidRounds)
+		if err != nil {
+			reportErrorf(errorConstructingTX, err)
+		}
+		utx, err := client.ConstructPaymentWithRekey(address, address, transactionFee, 0, nil, "", [32]byte{}, basics.Round(firstTxRound), basics.Round(lastTxRound), authAddress)
+		if err != nil {
+			reportErrorf(errorConstructingTX, err)
+		}
+
+		if statusChangeTxFile != "" {
+			err = writeTxnToFile(client, false, dataDir, walletName, utx, statusChangeTxFile)
+			if err != nil {
+				reportErrorf(fileWriteError, statusChangeTxFile, err)
+			}
+			return
+		}
+
+		// The rekey must be signed by whatever currently authorizes the account
+		info, err := client.AccountInformation(address)
+		if err != nil {
+			reportErrorf(errorRequestFail, err)
+		}
+
+		wh, pw := ensureWalletHandleMaybePassword(dataDir, walletName, true)
+		stx, err := client.SignTransactionWithWalletAndSigner(wh, pw, info.AuthAddr, utx)
+		if err != nil {
+			reportErrorf(errorOnlineTX, err)
+		}
+		txid, err := client.BroadcastTransaction(stx)
+		if err != nil {
+			reportErrorf(errorBroadcastingTX, err)
+		}
+		reportInfof(infoRekeyIssued, address, authAddress, txid)
+
+		if noWaitAfterSend {
+			return
+		}
+
+		err = waitForCommit(client, txid)
+		if err != nil {
+			reportErrorf(err.Error())
+		}
+	},
+}
@@ -222,7 +222,15 @@ func (accountList *AccountsList) outputAccount(addr string, acctInfo v1.Account,
 		fmt.Printf("[%s]\t%s\t%s\t%d microAlgos", status, accountList.getNameByAddress(addr), addr, acctInfo.Amount)
 	}
 	if multisigInfo != nil {
-		fmt.Printf("\t[%d/%d multisig]", multisigInfo.Threshold, len(multisigInfo.PKs))
+		if len(multisigInfo.Weights) > 0 {
+			totalWeight := 0
+			for _, w := range multisigInfo.Weights {
+				totalWeight += int(w)
+			}
+			fmt.Printf("\t[%d/%d weighted multisig]", multisigInfo.Threshold, totalWeight)
+		} else {
+			fmt.Printf("\t[%d/%d multisig]", multisigInfo.Threshold, len(multisigInfo.PKs))
+		}
 	}
 	if len(acctInfo.AssetParams) > 0 {
 		fmt.Printf("\t[created assets:")
@@ -19,9 +19,12 @@ package main
 import (
 	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/vincentbdb/go-algorand/data/transactions"
 	"github.com/vincentbdb/go-algorand/libgoal"
 )
 
@@ -29,6 +32,8 @@ var (
 	assetID                 uint64
 	assetCreator            string
 	assetTotal              uint64
+	assetDecimals           uint32
+	assetAmount             string
 	assetFrozen             bool
 	assetUnitName           string
 	assetMetadataHashBase64 string
@@ -37,6 +42,7 @@ var (
 	assetManager            string
 	assetClawback           string
 	assetFreezer            string
+	assetFundOptIn          bool
 
 	assetNewManager  string
 	assetNewReserve  string
@@ -55,7 +61,8 @@ func init() {
 	assetCmd.PersistentFlags().StringVarP(&walletName, "wallet", "w", "", "Set the wallet to be used for the selected operation")
 
 	createAssetCmd.Flags().StringVar(&assetCreator, "creator", "", "Account address for creating an asset")
-	createAssetCmd.Flags().Uint64Var(&assetTotal, "total", 0, "Total amount of tokens for created asset")
+	createAssetCmd.Flags().Uint64Var(&assetTotal, "total", 0, "Total amount of tokens for created asset, in base units")
+	createAssetCmd.Flags().Uint32Var(&assetDecimals, "decimals", 0, "The number of digits to use after the decimal point when displaying this asset")
 	createAssetCmd.Flags().BoolVar(&assetFrozen, "defaultfrozen", false, "Freeze or not freeze holdings by default")
 	createAssetCmd.Flags().StringVar(&assetUnitName, "unitname", "", "Name for the unit of asset")
 	createAssetCmd.Flags().StringVar(&assetName, "name", "", "Name for the entire asset")
@@ -112,8 +119,9 @@ func init() {
 	sendAssetCmd.Flags().StringVar(&assetUnitName, "asset", "", "Unit name of the asset being transferred")
 	sendAssetCmd.Flags().StringVarP(&account, "from", "f", "", "Account address to send the money from (if not specified, uses default account)")
 	sendAssetCmd.Flags().StringVarP(&toAddress, "to", "t", "", "Address to send to money to (required)")
-	sendAssetCmd.Flags().Uint64VarP(&amount, "amount", "a", 0, "The amount to be transferred (required), in microAlgos")
+	sendAssetCmd.Flags().StringVarP(&assetAmount, "amount", "a", "0", "The amount to be transferred (required), in units of the asset with up to as many decimal places as it specifies")
 	sendAssetCmd.Flags().StringVarP(&closeToAddress, "close-to", "c", "", "Close asset account and send remainder to this address")
+	sendAssetCmd.Flags().BoolVar(&assetFundOptIn, "fund-optin", false, "Opt the receiver in to the asset, paying the minimum balance its holding requires, and the base minimum balance if the receiver is a new account (the sender must be the asset manager, the asset must not be frozen by default, and the transaction must be written with --out and grouped with a transaction from the receiver)")
 	sendAssetCmd.Flags().Uint64Var(&fee, "fee", 0, "The transaction fee (automatically determined by default), in microAlgos")
 	sendAssetCmd.Flags().Uint64Var(&firstValid, "firstvalid", 0, "The first round where the transaction may be committed to the ledger")
 	sendAssetCmd.Flags().Uint64Var(&numValidRounds, "validrounds", 0, "The number of rounds for which the transaction will be valid")
@@ -201,6 +209,37 @@ func lookupAssetID(cmd *cobra.Command, creator string, client libgoal.Client) {
 	}
 }
 
+// parseAssetAmount converts an amount of an asset with the given number of
+// decimal places, such as "1.5", into base units.
+func parseAssetAmount(amount string, decimals uint32) (uint64, error) {
+	whole, frac := amount, ""
+	if i := strings.IndexByte(amount, '.'); i >= 0 {
+		whole, frac = amount[:i], amount[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("malformed amount %q", amount)
+	}
+	if uint32(len(frac)) > decimals {
+		return 0, fmt.Errorf("amount %s has more than %d decimal places", amount, decimals)
+	}
+
+	units, err := strconv.ParseUint(whole+frac+strings.Repeat("0", int(decimals)-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed amount %q", amount)
+	}
+	return units, nil
+}
+
+// formatAssetAmount formats an amount in base units of an asset with the
+// given number of decimal places.
+func formatAssetAmount(units uint64, decimals uint32) string {
+	if decimals == 0 {
+		return strconv.FormatUint(units, 10)
+	}
+	s := fmt.Sprintf("%0*d", decimals+1, units)
+	return s[:len(s)-int(decimals)] + "." + s[len(s)-int(decimals):]
+}
+
 var createAssetCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create an asset",
@@ -222,7 +261,7 @@ var createAssetCmd = &cobra.Command{
 			}
 		}
 
-		tx, err := client.MakeUnsignedAssetCreateTx(assetTotal, assetFrozen, creator, creator, creator, creator, assetUnitName, assetName, assetURL, assetMetadataHash)
+		tx, err := client.MakeUnsignedAssetCreateTx(assetTotal, assetFrozen, creator, creator, creator, creator, assetUnitName, assetName, assetURL, assetMetadataHash, assetDecimals)
 		if err != nil {
 			reportErrorf("Cannot construct transaction: %s", err)
 		}
@@ -432,7 +471,7 @@ var configAssetCmd = &cobra.Command{
 var sendAssetCmd = &cobra.Command{
 	Use:   "send",
 	Short: "Transfer assets",
-	Long:  "Transfer asset holdings.  Use a zero self-transfer to add an asset to an account in the first place.",
+	Long:  "Transfer asset holdings.  Use a zero self-transfer to add an asset to an account in the first place, or have the asset manager send with --fund-optin to add it on the receiver's behalf.",
 	Args:  validateNoPosArgsFn,
 	Run: func(cmd *cobra.Command, _ []string) {
 		checkTxValidityPeriodCmdFlags(cmd)
@@ -463,7 +502,27 @@ var sendAssetCmd = &cobra.Command{
 			closeToAddressResolved = accountList.getAddressByName(closeToAddress)
 		}
 
-		tx, err := client.MakeUnsignedAssetSendTx(assetID, amount, toAddressResolved, closeToAddressResolved, senderForClawback)
+		params, err := client.AssetInformation(assetID)
+		if err != nil {
+			reportErrorf(errorRequestFail, err)
+		}
+		amount, err := parseAssetAmount(assetAmount, params.Decimals)
+		if err != nil {
+			reportErrorf(errorAssetAmount, err)
+		}
+
+		var tx transactions.Transaction
+		if assetFundOptIn {
+			if senderForClawback != "" || closeToAddressResolved != "" {
+				reportErrorf(errorAssetFundOptInFlags)
+			}
+			if txFilename == "" {
+				reportErrorf(errorAssetFundOptInOut)
+			}
+			tx, err = client.MakeUnsignedAssetFundedOptInTx(assetID, amount, toAddressResolved)
+		} else {
+			tx, err = client.MakeUnsignedAssetSendTx(assetID, amount, toAddressResolved, closeToAddressResolved, senderForClawback)
+		}
 		if err != nil {
 			reportErrorf("Cannot construct transaction: %s", err)
 		}
@@ -602,9 +661,10 @@ var infoAssetCmd = &cobra.Command{
 		fmt.Printf("Creator:          %s\n", params.Creator)
 		fmt.Printf("Asset name:       %s\n", params.AssetName)
 		fmt.Printf("Unit name:        %s\n", params.UnitName)
-		fmt.Printf("Maximum issue:    %d %s\n", params.Total, params.UnitName)
-		fmt.Printf("Reserve amount:   %d %s\n", reserve.Assets[assetID].Amount, params.UnitName)
-		fmt.Printf("Issued:           %d %s\n", params.Total-reserve.Assets[assetID].Amount, params.UnitName)
+		fmt.Printf("Maximum issue:    %s %s\n", formatAssetAmount(params.Total, params.Decimals), params.UnitName)
+		fmt.Printf("Reserve amount:   %s %s\n", formatAssetAmount(reserve.Assets[assetID].Amount, params.Decimals), params.UnitName)
+		fmt.Printf("Issued:           %s %s\n", formatAssetAmount(params.Total-reserve.Assets[assetID].Amount, params.Decimals), params.UnitName)
+		fmt.Printf("Decimals:         %d\n", params.Decimals)
 		fmt.Printf("Default frozen:   %v\n", params.DefaultFrozen)
 		fmt.Printf("Manager address:  %s\n", params.ManagerAddr)
 		fmt.Printf("Reserve address:  %s\n", params.ReserveAddr)
@@ -0,0 +1,72 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAssetAmount(t *testing.T) {
+	for _, c := range []struct {
+		amount   string
+		decimals uint32
+		units    uint64
+	}{
+		{"0", 0, 0},
+		{"42", 0, 42},
+		{"42", 2, 4200},
+		{"1.5", 2, 150},
+		{"1.05", 2, 105},
+		{".5", 1, 5},
+		{"1.", 3, 1000},
+		{"18446744073709551615", 0, 18446744073709551615},
+		{"1.8446744073709551615", 19, 18446744073709551615},
+	} {
+		units, err := parseAssetAmount(c.amount, c.decimals)
+		require.NoError(t, err, c.amount)
+		require.Equal(t, c.units, units, c.amount)
+	}
+
+	for _, c := range []struct {
+		amount   string
+		decimals uint32
+	}{
+		{"", 2},
+		{".", 2},
+		{"1.5", 0},
+		{"1.555", 2},
+		{"-1", 2},
+		{"1.-5", 2},
+		{"1e5", 2},
+		{"18446744073709551616", 0},
+		{"1.8446744073709551616", 19},
+	} {
+		_, err := parseAssetAmount(c.amount, c.decimals)
+		require.Error(t, err, c.amount)
+	}
+}
+
+func TestFormatAssetAmount(t *testing.T) {
+	require.Equal(t, "0", formatAssetAmount(0, 0))
+	require.Equal(t, "42", formatAssetAmount(42, 0))
+	require.Equal(t, "0.00", formatAssetAmount(0, 2))
+	require.Equal(t, "0.05", formatAssetAmount(5, 2))
+	require.Equal(t, "1.50", formatAssetAmount(150, 2))
+	require.Equal(t, "1.8446744073709551615", formatAssetAmount(18446744073709551615, 19))
+}
@@ -61,7 +61,11 @@ var (
 	progByteFile    string
 	logicSigFile    string
 	timeStamp       int64
+	dryrunRound     uint64
 	protoVersion    string
+	rekeyToAddress  string
+	signerAddress   string
+	groupFeePayer   int
 )
 
 func init() {
@@ -96,6 +100,8 @@ func init() {
 	sendCmd.Flags().StringVarP(&progByteFile, "from-program-bytes", "P", "", "Program binary to use as account logic")
 	sendCmd.Flags().StringSliceVar(&argB64Strings, "argb64", nil, "base64 encoded args to pass to transaction logic")
 	sendCmd.Flags().StringVarP(&logicSigFile, "logic-sig", "L", "", "LogicSig to apply to transaction")
+	sendCmd.Flags().StringVar(&rekeyToAddress, "rekey-to", "", "Rekey the sender so that its future transactions must be signed by this address")
+	sendCmd.Flags().StringVar(&signerAddress, "signer", "", "Address of the key to sign with, if the sender has been rekeyed")
 
 	sendCmd.MarkFlagRequired("to")
 	sendCmd.MarkFlagRequired("amount")
@@ -112,11 +118,13 @@ func init() {
 	signCmd.Flags().StringVarP(&logicSigFile, "logic-sig", "L", "", "LogicSig to apply to transaction")
 	signCmd.Flags().StringSliceVar(&argB64Strings, "argb64", nil, "base64 encoded args to pass to transaction logic")
 	signCmd.Flags().StringVarP(&protoVersion, "proto", "P", "", "consensus protocol version id string")
+	signCmd.Flags().StringVar(&signerAddress, "signer", "", "Address of the key to sign with, if the sender has been rekeyed")
 	signCmd.MarkFlagRequired("infile")
 	signCmd.MarkFlagRequired("outfile")
 
 	groupCmd.Flags().StringVarP(&txFilename, "infile", "i", "", "File storing transactions to be grouped")
 	groupCmd.Flags().StringVarP(&outFilename, "outfile", "o", "", "Filename for writing the grouped transactions")
+	groupCmd.Flags().IntVar(&groupFeePayer, "fee-payer", -1, "Index of a transaction in the group that pays the fees of all of them, for protocols with fee pooling")
 	groupCmd.MarkFlagRequired("infile")
 	groupCmd.MarkFlagRequired("outfile")
 
@@ -132,7 +140,8 @@ func init() {
 	compileCmd.Flags().StringVarP(&account, "account", "a", "", "Account address to sign the program (If not specified, uses default account)")
 
 	dryrunCmd.Flags().StringVarP(&txFilename, "txfile", "t", "", "transaction or transaction-group to test")
-	dryrunCmd.Flags().Int64VarP(&timeStamp, "time-stamp", "S", 0, "unix time value for txn FirstValidTime (default now)")
+	dryrunCmd.Flags().Int64VarP(&timeStamp, "time-stamp", "S", 0, "unix time value for txn FirstValidTime and global LatestTimestamp (default now)")
+	dryrunCmd.Flags().Uint64VarP(&dryrunRound, "round", "r", 0, "round value for global Round (default FirstValid of each transaction)")
 	dryrunCmd.Flags().StringVarP(&protoVersion, "proto", "P", "", "consensus protocol version id string")
 	dryrunCmd.MarkFlagRequired("txfile")
 }
@@ -324,14 +333,20 @@ var sendCmd = &cobra.Command{
 			closeToAddressResolved = accountList.getAddressByName(closeToAddress)
 		}
 
+		// Likewise if rekeying it
+		var rekeyToAddressResolved string
+		if rekeyToAddress != "" {
+			rekeyToAddressResolved = accountList.getAddressByName(rekeyToAddress)
+		}
+
 		client := ensureFullClient(dataDir)
 		firstValid, lastValid, err = client.ComputeValidityRounds(firstValid, lastValid, numValidRounds)
 		if err != nil {
 			reportErrorf(err.Error())
 		}
-		payment, err := client.ConstructPayment(
+		payment, err := client.ConstructPaymentWithRekey(
 			fromAddressResolved, toAddressResolved, fee, amount, noteBytes, closeToAddressResolved,
-			leaseBytes, basics.Round(firstValid), basics.Round(lastValid),
+			leaseBytes, basics.Round(firstValid), basics.Round(lastValid), rekeyToAddressResolved,
 		)
 		if err != nil {
 			reportErrorf(errorConstructingTX, err)
@@ -361,6 +376,12 @@ var sendCmd = &cobra.Command{
 					Args:  programArgs,
 				},
 			}
+		} else if signerAddress != "" && (sign || txFilename == "") {
+			wh, pw := ensureWalletHandleMaybePassword(dataDir, walletName, true)
+			stx, err = client.SignTransactionWithWalletAndSigner(wh, pw, accountList.getAddressByName(signerAddress), payment)
+			if err != nil {
+				reportErrorf(errorSigningTX, err)
+			}
 		} else {
 			signTx := sign || (txFilename == "")
 			stx, err = createSignedTransaction(client, signTx, dataDir, walletName, payment)
@@ -618,6 +639,7 @@ var signCmd = &cobra.Command{
 		var client libgoal.Client
 		var wh []byte
 		var pw []byte
+		var signerAddressResolved string
 
 		if programSource != "" {
 			if logicSigFile != "" {
@@ -633,6 +655,9 @@ var signCmd = &cobra.Command{
 			dataDir := ensureSingleDataDir()
 			client = ensureKmdClient(dataDir)
 			wh, pw = ensureWalletHandleMaybePassword(dataDir, walletName, true)
+			if signerAddress != "" {
+				signerAddressResolved = makeAccountsList(dataDir).getAddressByName(signerAddress)
+			}
 		}
 
 		var outData []byte
@@ -660,7 +685,7 @@ var signCmd = &cobra.Command{
 				signedTxn.Lsig = lsig
 			} else {
 				// sign the usual way
-				signedTxn, err = client.SignTransactionWithWallet(wh, pw, unsignedTxn.Txn)
+				signedTxn, err = client.SignTransactionWithWalletAndSigner(wh, pw, signerAddressResolved, unsignedTxn.Txn)
 				if err != nil {
 					reportErrorf(errorSigningTX, err)
 				}
@@ -679,7 +704,7 @@ var signCmd = &cobra.Command{
 var groupCmd = &cobra.Command{
 	Use:   "group",
 	Short: "Group transactions together",
-	Long:  `Form a transaction group.  The input file must contain one or more transactions that will form a group.  The output file will contain the same transactions, in order, with a group flag added to each transaction, which requires that the transactions must be committed together.`,
+	Long:  `Form a transaction group.  The input file must contain one or more transactions that will form a group.  The output file will contain the same transactions, in order, with a group flag added to each transaction, which requires that the transactions must be committed together.  With --fee-payer, the fees of all the transactions are moved to the given one, so that it pays for the whole group.`,
 	Args:  validateNoPosArgsFn,
 	Run: func(cmd *cobra.Command, args []string) {
 		data, err := readFile(txFilename)
@@ -706,6 +731,16 @@ var groupCmd = &cobra.Command{
 			}
 
 			txns = append(txns, txn)
+		}
+
+		if cmd.Flags().Changed("fee-payer") {
+			if groupFeePayer < 0 || groupFeePayer >= len(txns) {
+				reportErrorf(txFeePayerError, groupFeePayer, len(txns))
+			}
+			poolGroupFees(txns, groupFeePayer)
+		}
+
+		for _, txn := range txns {
 			group.TxGroupHashes = append(group.TxGroupHashes, crypto.HashObj(txn.Txn))
 		}
 
@@ -722,6 +757,17 @@ var groupCmd = &cobra.Command{
 	},
 }
 
+// poolGroupFees moves the fees of all the transactions in txns to the one at
+// index payer, which relies on fee pooling to pay for the whole group.
+func poolGroupFees(txns []transactions.SignedTxn, payer int) {
+	var total uint64
+	for i := range txns {
+		total = basics.AddSaturate(total, txns[i].Txn.Fee.Raw)
+		txns[i].Txn.Fee = basics.MicroAlgos{}
+	}
+	txns[payer].Txn.Fee = basics.MicroAlgos{Raw: total}
+}
+
 var splitCmd = &cobra.Command{
 	Use:   "split",
 	Short: "Split a file containing many transactions into one transaction per file",
@@ -911,6 +957,10 @@ var dryrunCmd = &cobra.Command{
 			if err != nil {
 				reportErrorf("program failed Check: %s", err)
 			}
+			round := dryrunRound
+			if round == 0 {
+				round = uint64(txn.Txn.FirstValid)
+			}
 			sb := strings.Builder{}
 			ep = logic.EvalParams{
 				Txn:                 &txn.SignedTxn,
@@ -919,6 +969,8 @@ var dryrunCmd = &cobra.Command{
 				TxnGroup:            txgroup,
 				GroupIndex:          i,
 				FirstValidTimeStamp: uint64(timeStamp),
+				Round:               round,
+				LatestTimestamp:     timeStamp,
 			}
 			pass, err := logic.Eval(txn.Lsig.Logic, ep)
 			// TODO: optionally include `inspect` output here?
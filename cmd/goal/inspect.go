@@ -54,8 +54,9 @@ type inspectMultisigSig struct {
 type inspectMultisigSubsig struct {
 	_struct struct{} `codec:",omitempty,omitemptyarray"`
 
-	Key basics.Address   `codec:"pk"`
-	Sig crypto.Signature `codec:"s"`
+	Key    basics.Address   `codec:"pk"`
+	Sig    crypto.Signature `codec:"s"`
+	Weight uint8            `codec:"w"`
 }
 
 // similar to data/transactions/logicsig.go LogicSig but uses types
@@ -139,8 +140,9 @@ func msigToInspect(msig crypto.MultisigSig) inspectMultisigSig {
 
 	for _, subsig := range msig.Subsigs {
 		res.Subsigs = append(res.Subsigs, inspectMultisigSubsig{
-			Sig: subsig.Sig,
-			Key: basics.Address(subsig.Key),
+			Sig:    subsig.Sig,
+			Key:    basics.Address(subsig.Key),
+			Weight: subsig.Weight,
 		})
 	}
 
@@ -155,8 +157,9 @@ func msigFromInspect(msi inspectMultisigSig) crypto.MultisigSig {
 
 	for _, subsig := range msi.Subsigs {
 		res.Subsigs = append(res.Subsigs, crypto.MultisigSubsig{
-			Sig: subsig.Sig,
-			Key: crypto.PublicKey(subsig.Key),
+			Sig:    subsig.Sig,
+			Key:    crypto.PublicKey(subsig.Key),
+			Weight: subsig.Weight,
 		})
 	}
 
@@ -18,12 +18,45 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/ledger"
+	"github.com/vincentbdb/go-algorand/libgoal"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/rpcs"
 )
 
+var verifyRound uint64
+var rollbackRound uint64
+var exportFrom uint64
+var exportTo uint64
+var exportOut string
+
 func init() {
 	ledgerCmd.AddCommand(supplyCmd)
+	ledgerCmd.AddCommand(verifyCmd)
+	ledgerCmd.AddCommand(rollbackCmd)
+	ledgerCmd.AddCommand(exportBlocksCmd)
+
+	verifyCmd.Flags().Uint64VarP(&verifyRound, "round", "r", 0, "Round to verify, which must be recent (defaults to the latest round)")
+
+	rollbackCmd.Flags().Uint64Var(&rollbackRound, "round", 0, "Round to roll the ledger back to")
+	rollbackCmd.MarkFlagRequired("round")
+
+	exportBlocksCmd.Flags().Uint64Var(&exportFrom, "from", 0, "First round to export")
+	exportBlocksCmd.Flags().Uint64Var(&exportTo, "to", 0, "Last round to export")
+	exportBlocksCmd.Flags().StringVarP(&exportOut, "out", "o", "", "Filename of the block archive to write")
+	exportBlocksCmd.MarkFlagRequired("from")
+	exportBlocksCmd.MarkFlagRequired("to")
+	exportBlocksCmd.MarkFlagRequired("out")
 }
 
 var ledgerCmd = &cobra.Command{
@@ -52,3 +85,200 @@ var supplyCmd = &cobra.Command{
 		fmt.Printf("Round: %v\nTotal Money: %v microAlgos\nOnline Money: %v microAlgos\n", response.Round, response.TotalMoney, response.OnlineMoney)
 	},
 }
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the ledger of a stopped node",
+	Long:  "Cross-check the account totals of a stopped node against its account table, and the fee sink and rewards pool against the block headers. The node's ledger is opened directly, so the node must not be running. This reads every account, so it is slow on large ledgers.",
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, _ []string) {
+		dataDir := ensureSingleDataDir()
+		if nodeRunning(dataDir) {
+			reportErrorln(errorLedgerVerifyRunning)
+		}
+
+		genesis, err := readLedgerGenesis(dataDir)
+		if err != nil {
+			reportErrorf(errorLedgerVerify, err)
+		}
+
+		cfg, err := config.LoadConfigFromDisk(dataDir)
+		if err != nil && !os.IsNotExist(err) {
+			reportErrorf(errLoadingConfig, dataDir, err)
+		}
+		storage, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+		if err != nil {
+			reportErrorf(errorLedgerVerify, err)
+		}
+
+		genalloc, err := data.LoadGenesisBalances(genesis)
+		if err != nil {
+			reportErrorf(errorLedgerVerify, err)
+		}
+
+		ledgerPathnamePrefix := filepath.Join(dataDir, genesis.ID(), config.LedgerFilenamePrefix)
+		l, err := data.LoadLedger(logging.Base(), ledgerPathnamePrefix, false, genesis.Proto, genalloc, genesis.ID(), crypto.HashObj(genesis), nil, cfg.Archival, cfg.Archival && cfg.EnableAccountHistory, storage)
+		if err != nil {
+			reportErrorf(errorLedgerVerify, err)
+		}
+
+		rnd := basics.Round(verifyRound)
+		if rnd == 0 {
+			rnd = l.Latest()
+		}
+		err = l.VerifyTotals(rnd)
+		l.Close()
+		if err == nil {
+			reportInfof(infoLedgerVerified, rnd)
+			return
+		}
+		mismatch, ok := err.(*ledger.TotalsMismatch)
+		if !ok {
+			reportErrorf(errorLedgerVerify, err)
+		}
+
+		fmt.Fprintf(os.Stderr, errorLedgerNotVerified+"\n", rnd)
+		for _, problem := range mismatch.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+		os.Exit(1)
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll the ledger of a stopped development node back to an earlier round",
+	Long:  `Delete every block after the given round from the ledger of a stopped node, and revert its account database to the nearest catchpoint (or genesis) so that it is replayed up to that round. Agreement state is discarded, so the node resumes agreement at the next round once started. This is only allowed on development and private networks, and never on a hosted node.`,
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, _ []string) {
+		dataDir := ensureSingleDataDir()
+		if nodeRunning(dataDir) {
+			reportErrorln(errorLedgerRollbackRunning)
+		}
+
+		genesis, err := readLedgerGenesis(dataDir)
+		if err != nil {
+			reportErrorf(errorLedgerRollback, err)
+		}
+		if config.IsPublicNetwork(genesis.Network) {
+			reportErrorf(errorLedgerRollbackPublic, genesis.ID())
+		}
+
+		cfg, err := config.LoadConfigFromDisk(dataDir)
+		if err != nil && !os.IsNotExist(err) {
+			reportErrorf(errLoadingConfig, dataDir, err)
+		}
+		if cfg.RunHosted {
+			reportErrorln(errorLedgerRollbackHosted)
+		}
+		storage, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+		if err != nil {
+			reportErrorf(errorLedgerRollback, err)
+		}
+
+		rnd := basics.Round(rollbackRound)
+		genesisDir := filepath.Join(dataDir, genesis.ID())
+		ledgerPathnamePrefix := filepath.Join(genesisDir, config.LedgerFilenamePrefix)
+		snapshot, err := ledger.RollbackLedger(logging.Base(), ledgerPathnamePrefix, storage, filepath.Join(genesisDir, config.CatchpointDirName), rnd)
+		if err != nil {
+			reportErrorf(errorLedgerRollback, err)
+		}
+
+		// Without its crash database, agreement starts over at the
+		// round after the ledger's latest.
+		crashPathname := filepath.Join(genesisDir, config.CrashFilename)
+		for _, fn := range []string{crashPathname, crashPathname + "-shm", crashPathname + "-wal"} {
+			err = os.Remove(fn)
+			if err != nil && !os.IsNotExist(err) {
+				reportErrorf(errorLedgerRollback, err)
+			}
+		}
+
+		reportInfof(infoLedgerRollbackDone, rnd, snapshot, rnd+1)
+	},
+}
+
+var exportBlocksCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a range of blocks to a block archive",
+	Long:  "Write the blocks and certificates for rounds --from through --to of the node's ledger into a block archive, which another node can ingest with algod -import instead of fetching the blocks over the network. The node's ledger is read directly, so the node need not be running.",
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, _ []string) {
+		dataDir := ensureSingleDataDir()
+		genesis, err := readLedgerGenesis(dataDir)
+		if err != nil {
+			reportErrorf(errorLedgerExport, err)
+		}
+
+		cfg, err := config.LoadConfigFromDisk(dataDir)
+		if err != nil && !os.IsNotExist(err) {
+			reportErrorf(errLoadingConfig, dataDir, err)
+		}
+		storage, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+		if err != nil {
+			reportErrorf(errorLedgerExport, err)
+		}
+
+		out, err := os.Create(exportOut)
+		if err != nil {
+			reportErrorf(errorLedgerExport, err)
+		}
+		err = exportBlocks(out, dataDir, genesis, storage)
+		closeErr := out.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(exportOut)
+			reportErrorf(errorLedgerExport, err)
+		}
+		reportInfof(infoLedgerExported, exportFrom, exportTo, exportOut)
+	},
+}
+
+// readLedgerGenesis reads the genesis of the node in dataDir and, as algod
+// does, applies its network's consensus.json before the ledger is opened
+func readLedgerGenesis(dataDir string) (genesis bookkeeping.Genesis, err error) {
+	genesis, err = readGenesis(dataDir)
+	if err != nil {
+		return
+	}
+	err = config.LoadConsensusFromDisk(dataDir)
+	if err != nil {
+		return
+	}
+	err = genesis.CheckProtoParams()
+	return
+}
+
+// nodeRunning reports whether the node in dataDir answers its health check
+func nodeRunning(dataDir string) bool {
+	clientConfig := libgoal.ClientConfig{
+		AlgodDataDir: dataDir,
+		KMDDataDir:   resolveKmdDataDir(dataDir),
+		CacheDir:     ensureCacheDir(dataDir),
+	}
+	client, err := libgoal.MakeClientFromConfig(clientConfig, libgoal.AlgodClient)
+	return err == nil && client.HealthCheck() == nil
+}
+
+func exportBlocks(out *os.File, dataDir string, genesis bookkeeping.Genesis, storage ledger.StorageEngine) error {
+	aw, err := rpcs.MakeBlockArchiveWriter(out, rpcs.BlockArchiveHeader{
+		GenesisID:   genesis.ID(),
+		GenesisHash: crypto.HashObj(genesis),
+		FirstRound:  basics.Round(exportFrom),
+		LastRound:   basics.Round(exportTo),
+	})
+	if err != nil {
+		return err
+	}
+
+	ledgerPathnamePrefix := filepath.Join(dataDir, genesis.ID(), config.LedgerFilenamePrefix)
+	err = ledger.ReadEncodedBlockCerts(ledgerPathnamePrefix, storage, basics.Round(exportFrom), basics.Round(exportTo), func(rnd basics.Round, blk []byte, cert []byte) error {
+		return aw.Write(blk, cert)
+	})
+	if err != nil {
+		return err
+	}
+	return aw.Close()
+}
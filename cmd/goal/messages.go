@@ -26,6 +26,9 @@ const (
 
 	// Account
 	infoNoAccounts                 = "Did not find any account. Please import or create a new one."
+	infoVestingEscrow              = "\tvesting escrow %s: %d microAlgos held, %d withdrawable in round %d\n"
+	infoVestingEscrowUnknown       = "\tvesting escrow %s: [n/a]\n"
+	infoVestingSchedule            = "\t\t%d microAlgos at round %d, then %d every %d rounds, each withdrawable for %d more rounds; the rest after round %d\n"
 	infoRenamedAccount             = "Renamed account '%s' to '%s'"
 	infoImportedKey                = "Imported %s"
 	infoExportedKey                = "Exported key for account %s: \"%s\""
@@ -41,8 +44,13 @@ const (
 	warnMultisigDuplicatesDetected = "Warning: one or more duplicate addresses detected in multisig account creation. This will effectively give the duplicated address(es) extra signature weight. Continuing multisig account creation."
 	errLastRoundInvalid            = "roundLastValid needs to be well after the current round (%d)"
 	errExistingPartKey             = "Account already has a participation key valid at least until roundLastValid (%d) - current is %d"
+	errNoPartKeyToSchedule         = "Account %s has no unexpired participation key to roll over - use 'goal account renewpartkey' instead"
+	errPartKeyLeadTooShort         = "lead must be at least %d rounds so the next key is registered before the current one expires"
+	infoPartKeyNotDue              = "Current participation key for %s expires at round %d; the next key will be registered from round %d\n"
 	errorSeedConversion            = "Got private key for account %s, but was unable to convert to seed: %s"
 	errorMnemonicConversion        = "Got seed for account %s, but was unable to convert to mnemonic: %s"
+	infoRekeyIssued                = "Rekeying account %s to be authorized by %s, transaction ID: %s"
+	errorMultisigWeights           = "--weights must give one weight between 1 and 255 for each of the %d addresses"
 
 	// KMD
 	infoKMDStopped        = "Stopped kmd"
@@ -58,6 +66,9 @@ const (
 	infoTryingToStopNode             = "Trying to stop the node..."
 	infoNodeSuccessfullyStopped      = "The node was successfully stopped."
 	infoNodeStatus                   = "Last committed block: %d\nTime since last block: %s\nSync Time: %s\nLast consensus protocol: %s\nNext consensus protocol: %s\nRound for next consensus protocol: %d\nNext consensus protocol supported: %v\nHas Synced Since Startup: %t"
+	infoNodeUpgradeNone              = "Current consensus protocol: %s\nNo upgrade proposal in progress"
+	infoNodeUpgradeVoting            = "Current consensus protocol: %s\nProposed upgrade: %s\nApprovals: %d of %d needed (%d voting rounds)\nVoting ends at round: %d\nSwitch round if approved: %d"
+	infoNodeUpgradeApproved          = "Current consensus protocol: %s\nApproved upgrade: %s\nSwitch round: %d"
 	errorNodeCreationIPFailure       = "Parsing passed IP %v failed: need a valid IPv4 or IPv6 address with a specified port number"
 	errorNodeNotDetected             = "Algorand node does not appear to be running: %s"
 	errorNodeStatus                  = "Cannot contact Algorand node: %s."
@@ -71,11 +82,30 @@ const (
 	infoNodeWroteToken               = "Successfully wrote new API token: %s"
 	infoNodePendingTxnsDescription   = "Pending Transactions (Truncated max=%d, Total in pool=%d): "
 	infoNodeNoPendingTxnsDescription = "None"
+	errorNodeRunningCatchup          = "Node must be stopped before catching up from a catchpoint"
+	errorNodeCatchup                 = "Cannot catch up from catchpoint: %s"
+	infoNodeCatchupDone              = "Ledger restored from catchpoint %s; start the node to resume from it"
 	infoDataDir                      = "[Data Directory: %s]"
 	errLoadingConfig                 = "Error loading Config file from '%s': %v"
 
+	// Ledger
+	infoLedgerVerified         = "Round %d: ledger totals verified"
+	errorLedgerNotVerified     = "Round %d: ledger totals do not verify:"
+	errorLedgerVerify          = "Cannot verify ledger: %s"
+	errorLedgerVerifyRunning   = "Node must be stopped before verifying its ledger"
+	errorLedgerRollback        = "Cannot roll back ledger: %s"
+	errorLedgerRollbackRunning = "Node must be stopped before rolling back its ledger"
+	errorLedgerRollbackHosted  = "Cannot roll back the ledger of a hosted node"
+	errorLedgerRollbackPublic  = "Cannot roll back the ledger of public network %s"
+	infoLedgerRollbackDone     = "Ledger rolled back to round %d (accounts replayed from round %d); agreement will resume at round %d"
+	errorLedgerExport          = "Cannot export blocks: %s"
+	infoLedgerExported         = "Exported rounds %d-%d to %s"
+
 	// Asset
-	malformedMetadataHash = "Cannot base64-decode metadata hash %s: %s"
+	malformedMetadataHash    = "Cannot base64-decode metadata hash %s: %s"
+	errorAssetAmount         = "Invalid asset amount: %s"
+	errorAssetFundOptInFlags = "--fund-optin cannot be combined with --clawback or --close-to"
+	errorAssetFundOptInOut   = "--fund-optin needs --out: the transaction is only valid in a group with a transaction from the receiver (see goal clerk group)"
 
 	// Clerk
 	infoTxIssued    = "Sent %d MicroAlgos from account %s to address %s, transaction ID: %s. Fee set to %d"
@@ -94,6 +124,7 @@ const (
 	soFlagError     = "-s is not meaningful without -o"
 	infoRawTxIssued = "Raw transaction ID %s issued"
 	txPoolError     = "Transaction %s kicked out of local node pool: %s"
+	txFeePayerError = "Fee payer index %d is not in a group of %d transactions"
 
 	infoAutoFeeSet = "Automatically set fee to %d MicroAlgos"
 
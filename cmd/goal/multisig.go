@@ -284,6 +284,10 @@ func msigInfoToMsig(multisigInfo libgoal.MultisigInfo) (msig crypto.MultisigSig,
 		}
 		pks = append(pks, crypto.PublicKey(addr))
 	}
+	if multisigInfo.Version == crypto.WeightedMultisigVersion {
+		msig = crypto.MultisigPreimageFromWeightedPKs(multisigInfo.Threshold, pks, multisigInfo.Weights)
+		return
+	}
 	msig = crypto.MultisigPreimageFromPKs(multisigInfo.Version, multisigInfo.Threshold, pks)
 	return
 }
@@ -17,10 +17,12 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,8 +32,10 @@ import (
 
 	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/daemon/algod/api/spec/v1"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/libgoal"
 	"github.com/vincentbdb/go-algorand/nodecontrol"
+	"github.com/vincentbdb/go-algorand/rpcs"
 	"github.com/vincentbdb/go-algorand/util"
 	"github.com/vincentbdb/go-algorand/util/tokens"
 )
@@ -49,11 +53,13 @@ var newNodeDestination string
 var newNodeArchival bool
 var newNodeIndexer bool
 var newNodeRelay string
+var catchpointPeer string
 
 func init() {
 	nodeCmd.AddCommand(startCmd)
 	nodeCmd.AddCommand(stopCmd)
 	nodeCmd.AddCommand(statusCmd)
+	nodeCmd.AddCommand(upgradeStatusCmd)
 	nodeCmd.AddCommand(lastroundCmd)
 	nodeCmd.AddCommand(restartCmd)
 	nodeCmd.AddCommand(cloneCmd)
@@ -61,6 +67,7 @@ func init() {
 	nodeCmd.AddCommand(pendingTxnsCmd)
 	nodeCmd.AddCommand(waitCmd)
 	nodeCmd.AddCommand(createCmd)
+	nodeCmd.AddCommand(catchupCmd)
 
 	startCmd.Flags().StringVarP(&peerDial, "peer", "p", "", "Peer address to dial for initial connection")
 	startCmd.Flags().StringVarP(&listenIP, "listen", "l", "", "Endpoint / REST address to listen on")
@@ -84,6 +91,8 @@ func init() {
 	createCmd.Flags().StringVar(&listenIP, "api", "", "REST API Endpoint")
 	createCmd.MarkFlagRequired("destination")
 	createCmd.MarkFlagRequired("network")
+	catchupCmd.Flags().StringVarP(&catchpointPeer, "peer", "p", "", "Address of the peer to fetch the catchpoint file from")
+	catchupCmd.MarkFlagRequired("peer")
 }
 
 var nodeCmd = &cobra.Command{
@@ -293,6 +302,33 @@ func makeStatusString(stat v1.NodeStatus) string {
 	return fmt.Sprintf(infoNodeStatus, stat.LastRound, lastRoundTime, catchupTime, stat.LastVersion, stat.NextVersion, stat.NextVersionRound, stat.NextVersionSupported, stat.HasSyncedSinceStartup)
 }
 
+var upgradeStatusCmd = &cobra.Command{
+	Use:   "upgrade-status",
+	Short: "Show the state of the consensus upgrade vote",
+	Long:  `Show the consensus upgrade currently being voted on by the network, the approvals collected so far and the round at which an approved upgrade takes effect`,
+	Args:  validateNoPosArgsFn,
+	Run: func(cmd *cobra.Command, _ []string) {
+		onDataDirs(func(dataDir string) {
+			client := ensureAlgodClient(dataDir)
+			stat, err := client.Status()
+			if err != nil {
+				reportErrorf(errorNodeStatus, err)
+			}
+			fmt.Println(makeUpgradeStatusString(stat))
+		})
+	},
+}
+
+func makeUpgradeStatusString(stat v1.NodeStatus) string {
+	if stat.UpgradeProposal == "" {
+		return fmt.Sprintf(infoNodeUpgradeNone, stat.LastVersion)
+	}
+	if stat.LastRound >= stat.UpgradeVoteBefore {
+		return fmt.Sprintf(infoNodeUpgradeApproved, stat.LastVersion, stat.UpgradeProposal, stat.UpgradeSwitchRound)
+	}
+	return fmt.Sprintf(infoNodeUpgradeVoting, stat.LastVersion, stat.UpgradeProposal, stat.UpgradeApprovals, stat.UpgradeThreshold, stat.UpgradeVoteRounds, stat.UpgradeVoteBefore, stat.UpgradeSwitchRound)
+}
+
 var lastroundCmd = &cobra.Command{
 	Use:   "lastround",
 	Short: "Print the last round number",
@@ -329,6 +365,58 @@ var cloneCmd = &cobra.Command{
 	},
 }
 
+var catchupCmd = &cobra.Command{
+	Use:   "catchup [catchpoint]",
+	Short: "Initialize the ledger of a stopped node from a catchpoint",
+	Long:  `Fetch the catchpoint file named by the given catchpoint label (<round>#<digest>) from a peer, check it against the label, and replace the node's ledger with it. The node must be stopped; once started, it resumes from the catchpoint instead of replaying every block from genesis.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		label := args[0]
+		rnd, _, err := ledger.ParseCatchpointLabel(label)
+		if err != nil {
+			reportErrorf(errorNodeCatchup, err)
+		}
+
+		dataDir := ensureSingleDataDir()
+		clientConfig := libgoal.ClientConfig{
+			AlgodDataDir: dataDir,
+			KMDDataDir:   resolveKmdDataDir(dataDir),
+			CacheDir:     ensureCacheDir(dataDir),
+		}
+		client, err := libgoal.MakeClientFromConfig(clientConfig, libgoal.AlgodClient)
+		if err == nil && client.HealthCheck() == nil {
+			reportErrorln(errorNodeRunningCatchup)
+		}
+
+		genesis, err := readGenesis(dataDir)
+		if err != nil {
+			reportErrorf(errorNodeCatchup, err)
+		}
+
+		catchpoint, err := rpcs.FetchCatchpoint(context.Background(), http.DefaultClient, catchpointPeer, genesis.ID(), rnd)
+		if err != nil {
+			reportErrorf(errorNodeCatchup, err)
+		}
+		defer catchpoint.Close()
+
+		cfg, err := config.LoadConfigFromDisk(dataDir)
+		if err != nil && !os.IsNotExist(err) {
+			reportErrorf(errLoadingConfig, dataDir, err)
+		}
+		storage, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+		if err != nil {
+			reportErrorf(errorNodeCatchup, err)
+		}
+
+		ledgerPathnamePrefix := filepath.Join(dataDir, genesis.ID(), config.LedgerFilenamePrefix)
+		err = ledger.RestoreCatchpoint(ledgerPathnamePrefix, catchpoint, label, storage)
+		if err != nil {
+			reportErrorf(errorNodeCatchup, err)
+		}
+		reportInfof(infoNodeCatchupDone, label)
+	},
+}
+
 // Simple command to dump a snapshot of current pending transactions in the node's transaction pool
 var pendingTxnsCmd = &cobra.Command{
 	Use:   "pendingtxns",
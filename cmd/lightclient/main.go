@@ -0,0 +1,293 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/crypto/merkletrie"
+	"github.com/vincentbdb/go-algorand/daemon/algod/api/client"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/ledger"
+	"github.com/vincentbdb/go-algorand/lightclient"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// The lightclient follows the head of the chain served by an algod REST
+// endpoint, authenticating every block header against its certificate
+// instead of trusting the node.  It starts from a block that the user
+// trusts, named by its round and hash.
+//
+// Sortition weighs votes against the online-account balances of earlier
+// rounds, which the lightclient also fetches from the node.  When the
+// protocol commits to account state in block headers, each account record
+// is checked against the AccountStateRoot of an authenticated header;
+// under protocols without account state commitments, account records are
+// trusted to be reported correctly by the node.
+//
+// The total online stake is not committed to by block headers, so it is
+// fetched from the algod endpoint given by -supply, which the user must
+// trust.  With -trust-node-supply, it is taken from the node being
+// followed instead.  One of the two must be given.
+
+var algodURL = flag.String("algod", "http://127.0.0.1:8080", "algod REST endpoint")
+var algodToken = flag.String("token", "", "algod API token")
+var trustedRound = flag.Uint64("round", 0, "Round of the trusted block")
+var trustedHash = flag.String("hash", "", "Hash of the trusted block")
+var supplyURL = flag.String("supply", "", "Trusted algod REST endpoint to fetch the total online stake from")
+var supplyToken = flag.String("supply-token", "", "API token of the -supply endpoint")
+var trustNodeSupply = flag.Bool("trust-node-supply", false, "Trust the -algod endpoint to report the total online stake")
+
+// nodeBalances implements lightclient.Balances by fetching balances from
+// the node and checking them against the client's authenticated headers.
+// The total online stake, which cannot be checked, comes from supply.
+type nodeBalances struct {
+	rest   client.RestClient
+	supply client.RestClient
+	lc     *lightclient.Client
+
+	mu          sync.Mutex
+	records     map[basics.Round]map[basics.Address]basics.AccountData
+	circulation map[basics.Round]basics.MicroAlgos
+}
+
+// forget drops cached balances of rounds before rnd.  It must be called
+// with nb.mu held.
+func (nb *nodeBalances) forget(rnd basics.Round) {
+	for r := range nb.records {
+		if r < rnd {
+			delete(nb.records, r)
+		}
+	}
+	for r := range nb.circulation {
+		if r < rnd {
+			delete(nb.circulation, r)
+		}
+	}
+}
+
+func (nb *nodeBalances) BalanceRecord(rnd basics.Round, addr basics.Address) (basics.BalanceRecord, error) {
+	nb.mu.Lock()
+	data, ok := nb.records[rnd][addr]
+	nb.mu.Unlock()
+	if ok {
+		return basics.BalanceRecord{Addr: addr, AccountData: data}, nil
+	}
+
+	hdr, ok := nb.lc.Header(rnd)
+	if !ok {
+		return basics.BalanceRecord{}, fmt.Errorf("no authenticated header for round %d", rnd)
+	}
+	proto, ok := config.Consensus[hdr.CurrentProtocol]
+	if !ok {
+		return basics.BalanceRecord{}, fmt.Errorf("round %d: unsupported protocol %v", rnd, hdr.CurrentProtocol)
+	}
+
+	resp, err := nb.rest.AccountProof(addr.String(), uint64(rnd))
+	if err != nil {
+		return basics.BalanceRecord{}, err
+	}
+	err = protocol.Decode(resp.Account, &data)
+	if err != nil {
+		return basics.BalanceRecord{}, err
+	}
+	if proto.AccountStateRoot {
+		var proof merkletrie.Proof
+		err = protocol.Decode(resp.Proof, &proof)
+		if err != nil {
+			return basics.BalanceRecord{}, err
+		}
+		if !proof.Verify(hdr.AccountStateRoot, ledger.AccountStateKey(addr), ledger.AccountValueHash(data)) {
+			return basics.BalanceRecord{}, fmt.Errorf("round %d: account %v does not match the account state root", rnd, addr)
+		}
+	}
+	data = data.WithUpdatedRewards(proto, hdr.RewardsLevel)
+
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+	nb.forget(rnd)
+	if nb.records[rnd] == nil {
+		nb.records[rnd] = make(map[basics.Address]basics.AccountData)
+	}
+	nb.records[rnd][addr] = data
+	return basics.BalanceRecord{Addr: addr, AccountData: data}, nil
+}
+
+func (nb *nodeBalances) Circulation(rnd basics.Round) (basics.MicroAlgos, error) {
+	nb.mu.Lock()
+	total, ok := nb.circulation[rnd]
+	nb.mu.Unlock()
+	if ok {
+		return total, nil
+	}
+
+	resp, err := nb.supply.LedgerSupplyAtRound(uint64(rnd))
+	if err != nil {
+		return basics.MicroAlgos{}, err
+	}
+	if resp.Round != uint64(rnd) {
+		return basics.MicroAlgos{}, fmt.Errorf("node reported the supply of round %d instead of %d", resp.Round, rnd)
+	}
+	total = basics.MicroAlgos{Raw: resp.OnlineMoney}
+
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+	nb.forget(rnd)
+	nb.circulation[rnd] = total
+	return total, nil
+}
+
+func fetchCertifiedHeader(rest client.RestClient, rnd basics.Round) (hdr bookkeeping.BlockHeader, cert agreement.Certificate, err error) {
+	resp, err := rest.CertifiedBlockHeader(uint64(rnd))
+	if err != nil {
+		return
+	}
+	err = protocol.Decode(resp.Header, &hdr)
+	if err != nil {
+		return
+	}
+	err = protocol.Decode(resp.Certificate, &cert)
+	if err != nil {
+		return
+	}
+	if hdr.Round != rnd {
+		err = fmt.Errorf("node returned the header of round %d instead of %d", hdr.Round, rnd)
+	}
+	return
+}
+
+func main() {
+	flag.Parse()
+
+	u, err := url.Parse(*algodURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot parse algod URL %s: %v\n", *algodURL, err)
+		os.Exit(1)
+	}
+
+	expected, err := crypto.DigestFromString(*trustedHash)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot parse trusted block hash %s: %v\n", *trustedHash, err)
+		os.Exit(1)
+	}
+
+	rest := client.MakeRestClient(*u, *algodToken)
+
+	var supply client.RestClient
+	switch {
+	case *supplyURL != "":
+		su, err := url.Parse(*supplyURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot parse supply URL %s: %v\n", *supplyURL, err)
+			os.Exit(1)
+		}
+		supply = client.MakeRestClient(*su, *supplyToken)
+	case *trustNodeSupply:
+		supply = rest
+	default:
+		fmt.Fprintf(os.Stderr, "The total online stake cannot be authenticated; give a trusted endpoint with -supply, or -trust-node-supply to trust %s\n", *algodURL)
+		os.Exit(1)
+	}
+
+	// Fetch the trusted header, along with the earlier headers that the
+	// following rounds draw their seeds, parameters and balances from.
+	// These are authenticated by the hash chain ending at the trusted one.
+	trusted, _, err := fetchCertifiedHeader(rest, basics.Round(*trustedRound))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot fetch header of round %d: %v\n", *trustedRound, err)
+		os.Exit(1)
+	}
+	if crypto.Digest(trusted.Hash()) != expected {
+		fmt.Fprintf(os.Stderr, "Block of round %d has hash %v, not the trusted %v\n", trusted.Round, crypto.Digest(trusted.Hash()), expected)
+		os.Exit(1)
+	}
+	proto, ok := config.Consensus[trusted.CurrentProtocol]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Block of round %d has unsupported protocol %v\n", trusted.Round, trusted.CurrentProtocol)
+		os.Exit(1)
+	}
+	first := agreement.BalanceRound(trusted.Round+1, proto)
+	if params := agreement.ParamsRound(trusted.Round + 1); params < first {
+		first = params
+	}
+
+	var hdrs []bookkeeping.BlockHeader
+	for rnd := first; rnd < trusted.Round; rnd++ {
+		hdr, _, err := fetchCertifiedHeader(rest, rnd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot fetch header of round %d: %v\n", rnd, err)
+			os.Exit(1)
+		}
+		hdrs = append(hdrs, hdr)
+	}
+	hdrs = append(hdrs, trusted)
+
+	balances := &nodeBalances{
+		rest:        rest,
+		supply:      supply,
+		records:     make(map[basics.Round]map[basics.Address]basics.AccountData),
+		circulation: make(map[basics.Round]basics.MicroAlgos),
+	}
+	lc, err := lightclient.MakeClient(hdrs, balances)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot start from round %d: %v\n", trusted.Round, err)
+		os.Exit(1)
+	}
+	defer lc.Close()
+	balances.lc = lc
+
+	if !proto.AccountStateRoot {
+		fmt.Printf("Protocol %v does not commit to account state; trusting the node's account records\n", trusted.CurrentProtocol)
+	}
+	if *supplyURL == "" {
+		fmt.Printf("Trusting the node's total online stake\n")
+	}
+	fmt.Printf("Trusting round %d, hash %v\n", trusted.Round, expected)
+
+	for {
+		latest := lc.Latest().Round
+		status, err := rest.StatusAfterBlock(uint64(latest))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot wait for round %d: %v\n", latest+1, err)
+			os.Exit(1)
+		}
+		if status.LastRound <= uint64(latest) {
+			continue
+		}
+
+		hdr, cert, err := fetchCertifiedHeader(rest, latest+1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot fetch header of round %d: %v\n", latest+1, err)
+			os.Exit(1)
+		}
+		err = lc.Verify(hdr, cert)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot authenticate round %d: %v\n", latest+1, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Round %d, hash %v, protocol %v\n", hdr.Round, crypto.Digest(hdr.Hash()), hdr.CurrentProtocol)
+	}
+}
@@ -43,6 +43,13 @@ const Testnet protocol.NetworkID = "testnet"
 // Mainnet identifies the publicly-available real-money network
 const Mainnet protocol.NetworkID = "mainnet"
 
+// IsPublicNetwork returns true for the publicly-available networks, whose
+// nodes must never deviate from the built-in protocol rules (for instance by
+// rolling back their ledger or voting for unapproved upgrades).
+func IsPublicNetwork(network protocol.NetworkID) bool {
+	return network == Mainnet || network == Testnet || network == "betanet"
+}
+
 // GenesisJSONFile is the name of the genesis.json file
 const GenesisJSONFile = "genesis.json"
 
@@ -62,6 +69,10 @@ var Protocol = Global{
 // ConsensusParams specifies settings that might vary based on the
 // particular version of the consensus protocol.
 type ConsensusParams struct {
+	// Omitting empty fields keeps the encoding, and so the hash that a
+	// genesis records, of existing versions unchanged when fields are added.
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
 	// Consensus protocol upgrades.  Votes for upgrades are collected for
 	// UpgradeVoteRounds.  If the number of positive votes is over
 	// UpgradeThreshold, the proposal is accepted.
@@ -201,6 +212,9 @@ type ConsensusParams struct {
 	// max length of asset url
 	MaxAssetURLBytes int
 
+	// max number of digits to display after the decimal point in asset amounts
+	MaxAssetDecimals uint32
+
 	// support sequential transaction counter TxnCounter
 	TxnCounter bool
 
@@ -216,6 +230,23 @@ type ConsensusParams struct {
 	// 0 for no support, otherwise highest version supported
 	LogicSigVersion uint64
 
+	// commit to the state of all accounts in the block header
+	AccountStateRoot bool
+
+	// support for rekeying an account to a different spending key
+	SupportRekeying bool
+
+	// allow the transactions in a group to pool their fees, so that the
+	// group as a whole pays MinTxnFee for each of its transactions
+	EnableFeePooling bool
+
+	// support for weighted (version 2) multisig addresses
+	SupportWeightedMultisig bool
+
+	// allow an asset's manager to opt another account in to the asset,
+	// paying the MinBalance that the new holding requires
+	SupportFundedAssetOptIn bool
+
 	// len(LogicSig.Logic) + len(LogicSig.Args[*]) must be less than this
 	LogicSigMaxSize uint64
 
@@ -449,6 +480,27 @@ func initConsensusProtocols() {
 	// but not yet released in a production protocol version.
 	vFuture := v19
 	vFuture.ApprovedUpgrades = map[protocol.ConsensusVersion]bool{}
+
+	// TEAL version 2 adds subroutines
+	vFuture.LogicSigVersion = 2
+
+	// Block headers commit to the account state trie
+	vFuture.AccountStateRoot = true
+
+	// Accounts can be rekeyed to a different spending key
+	vFuture.SupportRekeying = true
+
+	// Assets carry the number of decimal places to display amounts with
+	vFuture.MaxAssetDecimals = 19
+
+	// A transaction group may pay its members' fees from any of them
+	vFuture.EnableFeePooling = true
+
+	// Multisig addresses may give their keys different weights
+	vFuture.SupportWeightedMultisig = true
+
+	// An asset's manager may opt accounts in to the asset on their behalf
+	vFuture.SupportFundedAssetOptIn = true
 	Consensus[protocol.ConsensusFuture] = vFuture
 }
 
@@ -716,6 +768,36 @@ type Local struct {
 
 	// EnableRequestLogger enabled the logging of the incoming requests to the telemetry server.
 	EnableRequestLogger bool
+
+	// CatchpointInterval is the number of rounds between catchpoint files written by the ledger, which
+	// other nodes can fetch to bootstrap without replaying every block. Zero disables catchpoints.
+	CatchpointInterval uint64
+
+	// LedgerStorageEngine selects where the ledger keeps blocks and accounts: "sqlite" (the default)
+	// or "kv" for the embedded key-value store. Switching engines starts a new, empty ledger.
+	LedgerStorageEngine string
+
+	// EnableAccountHistory makes an archival node keep the state of every account at every round in its
+	// account DB, so that accounts can be looked up as of any past round. It grows the DB with every
+	// account change; turning it off drops the history.
+	EnableAccountHistory bool
+
+	// EnableStateDeltas makes the ledger keep the state deltas of recent rounds in memory, so that
+	// /v1/deltas/subscribe can serve them. ArchiveStateDeltas implies it.
+	EnableStateDeltas bool
+
+	// ArchiveStateDeltas makes the ledger keep the state delta of every round on disk, so that
+	// /v1/deltas/subscribe can resume from rounds older than the in-memory window.
+	ArchiveStateDeltas bool
+
+	// VerifyLedgerTotalsOnStartup makes the node cross-check the ledger's account totals against its
+	// account table when it starts, and refuse to start if they disagree. It reads every account.
+	VerifyLedgerTotalsOnStartup bool
+
+	// VoteForUpgrade makes the blocks this node proposes propose and approve an upgrade to the given
+	// consensus protocol version, even if the current protocol does not approve it. It is meant for
+	// rehearsing upgrades on private networks, and the node refuses to start with it on public networks.
+	VoteForUpgrade string
 }
 
 // Filenames of config files within the configdir (e.g. ~/.algorand)
@@ -729,6 +811,9 @@ const PhonebookFilename = "phonebook.json" // No longer used in product - still
 // LedgerFilenamePrefix is the prefix of the name of the ledger database files
 const LedgerFilenamePrefix = "ledger"
 
+// CatchpointDirName is the name of the directory, next to the ledger database files, holding catchpoint files
+const CatchpointDirName = "catchpoints"
+
 // CrashFilename is the name of the agreement database file.
 // It is used to recover from node crashes.
 const CrashFilename = "crash.sqlite"
@@ -0,0 +1,139 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// ConsensusFilename is the name of the file in a data directory that
+// defines additional consensus versions, or overrides the parameters of
+// existing ones, for private networks.
+const ConsensusFilename = "consensus.json"
+
+// consensusFile is the content of a ConsensusFilename file.
+type consensusFile struct {
+	// Protocols maps consensus versions to their parameters.  The
+	// parameters of a known version are overridden field by field; a new
+	// version starts from the parameters of protocol.ConsensusCurrentVersion.
+	Protocols map[protocol.ConsensusVersion]json.RawMessage
+
+	// SmallLambdaMsec and BigLambdaMsec, if nonzero, override the
+	// agreement timing in Protocol.
+	SmallLambdaMsec int64
+	BigLambdaMsec   int64
+}
+
+// consensusOverridden holds the versions that ApplyConsensusOverrides has
+// defined or overridden, and smallLambdaOverride and bigLambdaOverride the
+// agreement timing it has set, if any.
+var consensusOverridden = make(map[protocol.ConsensusVersion]bool)
+var smallLambdaOverride, bigLambdaOverride time.Duration
+
+// LoadConsensusFromDisk applies the consensus overrides in the
+// ConsensusFilename file of dataDir, if there is one.
+func LoadConsensusFromDisk(dataDir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, ConsensusFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ApplyConsensusOverrides(data)
+}
+
+// ApplyConsensusOverrides applies the content of a ConsensusFilename file
+// to Consensus and Protocol.  Nodes of a network must all apply the same
+// overrides, or they will not agree with one another.
+func ApplyConsensusOverrides(data []byte) error {
+	var file consensusFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %v", ConsensusFilename, err)
+	}
+
+	base := Consensus[protocol.ConsensusCurrentVersion]
+	overridden := make(map[protocol.ConsensusVersion]ConsensusParams)
+	for version, raw := range file.Protocols {
+		params, ok := Consensus[version]
+		if !ok {
+			params = base
+		}
+
+		// Decoding into params would otherwise update the
+		// ApprovedUpgrades map that it shares with Consensus.
+		approved := params.ApprovedUpgrades
+		params.ApprovedUpgrades = make(map[protocol.ConsensusVersion]bool)
+		for v, ok := range approved {
+			params.ApprovedUpgrades[v] = ok
+		}
+
+		err = json.Unmarshal(raw, &params)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s parameters of %s: %v", ConsensusFilename, version, err)
+		}
+		overridden[version] = params
+	}
+
+	for version, params := range overridden {
+		Consensus[version] = params
+		consensusOverridden[version] = true
+	}
+	if file.SmallLambdaMsec != 0 {
+		smallLambdaOverride = time.Duration(file.SmallLambdaMsec) * time.Millisecond
+		Protocol.SmallLambda = smallLambdaOverride
+	}
+	if file.BigLambdaMsec != 0 {
+		bigLambdaOverride = time.Duration(file.BigLambdaMsec) * time.Millisecond
+		Protocol.BigLambda = bigLambdaOverride
+	}
+	return nil
+}
+
+// ConsensusOverridden reports whether ApplyConsensusOverrides has changed
+// any consensus version or the agreement timing.
+func ConsensusOverridden() bool {
+	return len(consensusOverridden) > 0 || smallLambdaOverride != 0 || bigLambdaOverride != 0
+}
+
+// ConsensusTimingOverrides returns the agreement timing that
+// ApplyConsensusOverrides has set, or zero where it has set none.
+func ConsensusTimingOverrides() (smallLambda time.Duration, bigLambda time.Duration) {
+	return smallLambdaOverride, bigLambdaOverride
+}
+
+// OverriddenConsensusVersions returns, in order, the consensus versions
+// that ApplyConsensusOverrides has defined or overridden.
+func OverriddenConsensusVersions() []protocol.ConsensusVersion {
+	versions := make([]protocol.ConsensusVersion, 0, len(consensusOverridden))
+	for version := range consensusOverridden {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+	return versions
+}
@@ -18,6 +18,11 @@ package config
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/protocol"
 )
 
 func TestConsensusParams(t *testing.T) {
@@ -34,3 +39,56 @@ func TestConsensusParams(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyConsensusOverrides(t *testing.T) {
+	current := Consensus[protocol.ConsensusCurrentVersion]
+	smallLambda, bigLambda := Protocol.SmallLambda, Protocol.BigLambda
+	custom := protocol.ConsensusVersion("test-consensus-overrides")
+	defer func() {
+		Consensus[protocol.ConsensusCurrentVersion] = current
+		delete(Consensus, custom)
+		Protocol.SmallLambda, Protocol.BigLambda = smallLambda, bigLambda
+		consensusOverridden = make(map[protocol.ConsensusVersion]bool)
+		smallLambdaOverride, bigLambdaOverride = 0, 0
+	}()
+
+	require.False(t, ConsensusOverridden())
+
+	err := ApplyConsensusOverrides([]byte(`{
+		"Protocols": {
+			"` + string(protocol.ConsensusCurrentVersion) + `": {"MaxTxnLife": 100},
+			"test-consensus-overrides": {"NumProposers": 5, "ApprovedUpgrades": {"other": true}}
+		},
+		"SmallLambdaMsec": 500
+	}`))
+	require.NoError(t, err)
+
+	// Overrides only change the given fields.
+	overridden := Consensus[protocol.ConsensusCurrentVersion]
+	require.Equal(t, uint64(100), overridden.MaxTxnLife)
+	require.Equal(t, current.MinTxnFee, overridden.MinTxnFee)
+
+	// New versions start from the current version as built in.
+	params, ok := Consensus[custom]
+	require.True(t, ok)
+	require.Equal(t, uint64(5), params.NumProposers)
+	require.Equal(t, current.MaxTxnLife, params.MaxTxnLife)
+	require.True(t, params.ApprovedUpgrades["other"])
+	require.False(t, current.ApprovedUpgrades["other"])
+
+	require.Equal(t, 500*time.Millisecond, Protocol.SmallLambda)
+	require.Equal(t, bigLambda, Protocol.BigLambda)
+
+	require.Equal(t, []protocol.ConsensusVersion{protocol.ConsensusCurrentVersion, custom}, OverriddenConsensusVersions())
+	require.True(t, ConsensusOverridden())
+	small, big := ConsensusTimingOverrides()
+	require.Equal(t, 500*time.Millisecond, small)
+	require.Equal(t, time.Duration(0), big)
+
+	// Nothing is applied from a file that cannot be parsed.
+	err = ApplyConsensusOverrides([]byte(`{"Protocols": {"other": {"MaxTxnLife": "long"}}}`))
+	require.Error(t, err)
+	_, ok = Consensus["other"]
+	require.False(t, ok)
+	require.Len(t, OverriddenConsensusVersions(), 2)
+}
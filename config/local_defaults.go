@@ -43,29 +43,34 @@ var defaultLocalV5 = Local{
 	// DO NOT MODIFY VALUES - New values may be added carefully - See WARNING at top of file
 	Version:                               5,
 	Archival:                              false,
+	ArchiveStateDeltas:                    false,
 	BaseLoggerDebugLevel:                  4, // Was 1
 	BroadcastConnectionsLimit:             -1,
 	AnnounceParticipationKey:              true,
 	PriorityPeers:                         map[string]bool{},
 	CadaverSizeTarget:                     1073741824,
+	CatchpointInterval:                    0,
 	CatchupFailurePeerRefreshRate:         10,
 	CatchupParallelBlocks:                 50,
 	ConnectionsRateLimitingCount:          60,
 	ConnectionsRateLimitingWindowSeconds:  1,
 	DeadlockDetection:                     0,
 	DNSBootstrapID:                        "<network>.algorand.network",
+	EnableAccountHistory:                  false,
 	EnableAgreementReporting:              false,
 	EnableAgreementTimeMetrics:            false,
 	EnableIncomingMessageFilter:           false,
 	EnableMetricReporting:                 false,
 	EnableOutgoingNetworkMessageFiltering: true,
 	EnableRequestLogger:                   false,
+	EnableStateDeltas:                     false,
 	EnableTopAccountsReporting:            false,
 	EndpointAddress:                       "127.0.0.1:0",
 	GossipFanout:                          4,
 	IncomingConnectionsLimit:              10000, // Was -1
 	IncomingMessageFilterBucketCount:      5,
 	IncomingMessageFilterBucketSize:       512,
+	LedgerStorageEngine:                   "sqlite",
 	LogArchiveName:                        "node.archive.log",
 	LogArchiveMaxAge:                      "",
 	LogSizeLimit:                          1073741824,
@@ -87,6 +92,8 @@ var defaultLocalV5 = Local{
 	TxSyncIntervalSeconds:                 60,
 	TxSyncTimeoutSeconds:                  30,
 	TxSyncServeResponseSize:               1000000,
+	VerifyLedgerTotalsOnStartup:           false,
+	VoteForUpgrade:                        "",
 	// DO NOT MODIFY VALUES - New values may be added carefully - See WARNING at top of file
 }
 
@@ -26,5 +26,6 @@ const errorkeynotexist = "Key does not exist"
 const errorsubsigverification = "Verification failure: subsignature"
 const errorkeysnotmatch = "Public key lists do not match"
 const errorinvalidduplicates = "Invalid duplicates"
+const errorinvalidweight = "Invalid weight"
 
 var errUnknownVersion = errors.New("unknown version")
@@ -0,0 +1,266 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package merkletrie implements an authenticated map from 256-bit keys to
+// 256-bit value hashes, as a binary Merkle-Patricia trie.
+//
+// The shape of the trie, and therefore its root, depends only on the set of
+// keys and values it holds, not on the order in which they were inserted.
+// Tries are immutable: Put and Delete return a new Trie that shares all
+// unmodified subtrees with the original, so keeping the trie of every recent
+// round costs memory proportional to the number of changes, and an update
+// costs one hash per level of the trie.
+package merkletrie
+
+import (
+	"encoding/binary"
+
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// KeyBits is the number of bits in a trie key.
+const KeyBits = 8 * crypto.DigestSize
+
+// node is either a leaf, holding a key and its value hash, or a branch
+// whose two subtrees hold keys that agree on every bit before bit, and
+// differ at bit.  A branch's key is one of the keys below it, which is
+// enough to tell whether another key shares the branch's prefix.
+type node struct {
+	hash     crypto.Digest
+	key      crypto.Digest
+	value    crypto.Digest
+	bit      int
+	children [2]*node
+}
+
+type leafRep struct {
+	key   crypto.Digest
+	value crypto.Digest
+}
+
+func (l leafRep) ToBeHashed() (protocol.HashID, []byte) {
+	return protocol.MerkleTrieLeaf, append(append([]byte{}, l.key[:]...), l.value[:]...)
+}
+
+type branchRep struct {
+	bit   int
+	left  crypto.Digest
+	right crypto.Digest
+}
+
+func (b branchRep) ToBeHashed() (protocol.HashID, []byte) {
+	buf := make([]byte, 2, 2+2*crypto.DigestSize)
+	binary.BigEndian.PutUint16(buf, uint16(b.bit))
+	buf = append(buf, b.left[:]...)
+	return protocol.MerkleTrieNode, append(buf, b.right[:]...)
+}
+
+func (n *node) isLeaf() bool {
+	return n.children[0] == nil
+}
+
+func makeLeaf(key crypto.Digest, value crypto.Digest) *node {
+	return &node{
+		hash:  crypto.HashObj(leafRep{key, value}),
+		key:   key,
+		value: value,
+	}
+}
+
+func makeBranch(bit int, left *node, right *node) *node {
+	return &node{
+		hash:     crypto.HashObj(branchRep{bit, left.hash, right.hash}),
+		key:      left.key,
+		bit:      bit,
+		children: [2]*node{left, right},
+	}
+}
+
+// keyBit returns bit i of key, counting from the most significant bit of key[0].
+func keyBit(key crypto.Digest, i int) int {
+	return int(key[i/8]>>(7-uint(i%8))) & 1
+}
+
+// firstDiff returns the first bit at which a and b differ, or KeyBits if they are equal.
+func firstDiff(a crypto.Digest, b crypto.Digest) int {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			bit := 8 * i
+			for x&0x80 == 0 {
+				x <<= 1
+				bit++
+			}
+			return bit
+		}
+	}
+	return KeyBits
+}
+
+// join returns a branch holding n and a new leaf for key, which must
+// differ from n's keys at bit, before n's own branch bit if n is a branch.
+func join(n *node, bit int, key crypto.Digest, value crypto.Digest) *node {
+	leaf := makeLeaf(key, value)
+	if keyBit(key, bit) == 0 {
+		return makeBranch(bit, leaf, n)
+	}
+	return makeBranch(bit, n, leaf)
+}
+
+func put(n *node, key crypto.Digest, value crypto.Digest) *node {
+	if n == nil {
+		return makeLeaf(key, value)
+	}
+
+	d := firstDiff(n.key, key)
+	if n.isLeaf() {
+		if d == KeyBits {
+			if n.value == value {
+				return n
+			}
+			return makeLeaf(key, value)
+		}
+		return join(n, d, key, value)
+	}
+
+	if d < n.bit {
+		return join(n, d, key, value)
+	}
+
+	side := keyBit(key, n.bit)
+	child := put(n.children[side], key, value)
+	if child == n.children[side] {
+		return n
+	}
+	children := n.children
+	children[side] = child
+	return makeBranch(n.bit, children[0], children[1])
+}
+
+func del(n *node, key crypto.Digest) *node {
+	if n == nil {
+		return nil
+	}
+
+	d := firstDiff(n.key, key)
+	if n.isLeaf() {
+		if d == KeyBits {
+			return nil
+		}
+		return n
+	}
+
+	if d < n.bit {
+		return n
+	}
+
+	side := keyBit(key, n.bit)
+	child := del(n.children[side], key)
+	if child == n.children[side] {
+		return n
+	}
+	if child == nil {
+		return n.children[1-side]
+	}
+	children := n.children
+	children[side] = child
+	return makeBranch(n.bit, children[0], children[1])
+}
+
+// Trie is an immutable Merkle-Patricia trie.  The zero value is an empty trie.
+type Trie struct {
+	root *node
+}
+
+// Root returns the root hash of the trie, which is the zero digest for an empty trie.
+func (t Trie) Root() crypto.Digest {
+	if t.root == nil {
+		return crypto.Digest{}
+	}
+	return t.root.hash
+}
+
+// Put returns a trie in which key maps to value.
+func (t Trie) Put(key crypto.Digest, value crypto.Digest) Trie {
+	return Trie{put(t.root, key, value)}
+}
+
+// Delete returns a trie without key.
+func (t Trie) Delete(key crypto.Digest) Trie {
+	return Trie{del(t.root, key)}
+}
+
+// Get returns the value hash stored for key, if any.
+func (t Trie) Get(key crypto.Digest) (value crypto.Digest, ok bool) {
+	n := t.root
+	for n != nil && !n.isLeaf() {
+		n = n.children[keyBit(key, n.bit)]
+	}
+	if n == nil || n.key != key {
+		return
+	}
+	return n.value, true
+}
+
+// Proof authenticates a key and its value against a trie root.  It lists
+// the branches from the root down to the key's leaf: the bit at which each
+// branch splits, and the hash of the subtree on the side away from the key.
+type Proof struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Bits     []uint16        `codec:"bits"`
+	Siblings []crypto.Digest `codec:"sibs"`
+}
+
+// Prove returns a proof that key is in the trie with its current value,
+// or false if key is not in the trie.
+func (t Trie) Prove(key crypto.Digest) (proof Proof, ok bool) {
+	n := t.root
+	for n != nil && !n.isLeaf() {
+		side := keyBit(key, n.bit)
+		proof.Bits = append(proof.Bits, uint16(n.bit))
+		proof.Siblings = append(proof.Siblings, n.children[1-side].hash)
+		n = n.children[side]
+	}
+	if n == nil || n.key != key {
+		return Proof{}, false
+	}
+	return proof, true
+}
+
+// Verify checks that the proof shows key mapping to value in the trie with the given root.
+func (p Proof) Verify(root crypto.Digest, key crypto.Digest, value crypto.Digest) bool {
+	if len(p.Bits) != len(p.Siblings) {
+		return false
+	}
+
+	h := crypto.HashObj(leafRep{key, value})
+	next := KeyBits
+	for i := len(p.Bits) - 1; i >= 0; i-- {
+		bit := int(p.Bits[i])
+		if bit >= next {
+			return false
+		}
+		next = bit
+
+		if keyBit(key, bit) == 0 {
+			h = crypto.HashObj(branchRep{bit, h, p.Siblings[i]})
+		} else {
+			h = crypto.HashObj(branchRep{bit, p.Siblings[i], h})
+		}
+	}
+	return h == root
+}
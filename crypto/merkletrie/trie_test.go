@@ -0,0 +1,152 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package merkletrie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/crypto"
+)
+
+func randDigest(r *rand.Rand) (d crypto.Digest) {
+	r.Read(d[:])
+	return
+}
+
+func TestTrieOrderIndependent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	kv := make(map[crypto.Digest]crypto.Digest)
+	var keys []crypto.Digest
+	for i := 0; i < 200; i++ {
+		k := randDigest(r)
+		kv[k] = randDigest(r)
+		keys = append(keys, k)
+	}
+	// a pair of keys sharing a long prefix
+	near := keys[0]
+	near[crypto.DigestSize-1] ^= 1
+	kv[near] = randDigest(r)
+	keys = append(keys, near)
+
+	var a Trie
+	for _, k := range keys {
+		a = a.Put(k, kv[k])
+	}
+
+	var b Trie
+	for _, i := range r.Perm(len(keys)) {
+		b = b.Put(keys[i], kv[keys[i]])
+	}
+	require.Equal(t, a.Root(), b.Root())
+
+	// adding and removing extra keys leaves the root unchanged
+	c := b
+	var extra []crypto.Digest
+	for i := 0; i < 50; i++ {
+		k := randDigest(r)
+		extra = append(extra, k)
+		c = c.Put(k, randDigest(r))
+	}
+	require.NotEqual(t, a.Root(), c.Root())
+	for _, k := range extra {
+		c = c.Delete(k)
+	}
+	require.Equal(t, a.Root(), c.Root())
+
+	// deleting everything gives back the empty trie
+	for _, k := range keys {
+		c = c.Delete(k)
+	}
+	require.Equal(t, crypto.Digest{}, c.Root())
+}
+
+func TestTrieImmutable(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	var trie Trie
+	for i := 0; i < 50; i++ {
+		trie = trie.Put(randDigest(r), randDigest(r))
+	}
+	root := trie.Root()
+
+	k := randDigest(r)
+	updated := trie.Put(k, randDigest(r))
+	require.NotEqual(t, root, updated.Root())
+	require.Equal(t, root, trie.Root())
+	_, ok := trie.Get(k)
+	require.False(t, ok)
+	_, ok = updated.Get(k)
+	require.True(t, ok)
+
+	// putting the same value again is a no-op
+	v, _ := updated.Get(k)
+	require.Equal(t, updated.root, updated.Put(k, v).root)
+	// deleting a missing key is a no-op
+	require.Equal(t, trie.root, trie.Delete(k).root)
+}
+
+func TestTrieProof(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	kv := make(map[crypto.Digest]crypto.Digest)
+	var trie Trie
+	for i := 0; i < 300; i++ {
+		k, v := randDigest(r), randDigest(r)
+		kv[k] = v
+		trie = trie.Put(k, v)
+	}
+	root := trie.Root()
+
+	for k, v := range kv {
+		got, ok := trie.Get(k)
+		require.True(t, ok)
+		require.Equal(t, v, got)
+
+		proof, ok := trie.Prove(k)
+		require.True(t, ok)
+		require.True(t, proof.Verify(root, k, v))
+
+		require.False(t, proof.Verify(root, k, randDigest(r)))
+		other := k
+		other[0] ^= 0x80
+		require.False(t, proof.Verify(root, other, v))
+		if len(proof.Siblings) > 0 {
+			proof.Siblings[0][0] ^= 1
+			require.False(t, proof.Verify(root, k, v))
+		}
+	}
+
+	_, ok := trie.Prove(randDigest(r))
+	require.False(t, ok)
+
+	var empty Trie
+	_, ok = empty.Prove(randDigest(r))
+	require.False(t, ok)
+}
+
+func TestFirstDiff(t *testing.T) {
+	var a, b crypto.Digest
+	require.Equal(t, KeyBits, firstDiff(a, b))
+	b[0] = 0x80
+	require.Equal(t, 0, firstDiff(a, b))
+	b[0] = 0
+	b[3] = 0x10
+	require.Equal(t, 27, firstDiff(a, b))
+	require.Equal(t, 1, keyBit(b, 27))
+	require.Equal(t, 0, keyBit(b, 26))
+}
@@ -28,8 +28,9 @@ type SecretKey = SignatureSecrets
 type MultisigSubsig struct {
 	_struct struct{} `codec:",omitempty,omitemptyarray"`
 
-	Key PublicKey `codec:"pk"` // all public keys that are possible signers for this address
-	Sig Signature `codec:"s"`  // may be either empty or a signature
+	Key    PublicKey `codec:"pk"` // all public keys that are possible signers for this address
+	Sig    Signature `codec:"s"`  // may be either empty or a signature
+	Weight uint8     `codec:"w"`  // weight of this key; must be zero unless the multisig is weighted
 }
 
 // MultisigSig is the structure that holds multiple Subsigs
@@ -51,6 +52,18 @@ func MultisigPreimageFromPKs(version, threshold uint8, pks []PublicKey) Multisig
 	return out
 }
 
+// MultisigPreimageFromWeightedPKs makes an empty weighted MultisigSig for a
+// given preimage, where weights[i] is the weight of pks[i].
+func MultisigPreimageFromWeightedPKs(threshold uint8, pks []PublicKey, weights []uint8) MultisigSig {
+	out := MultisigPreimageFromPKs(WeightedMultisigVersion, threshold, pks)
+	for i := range out.Subsigs {
+		if i < len(weights) {
+			out.Subsigs[i].Weight = weights[i]
+		}
+	}
+	return out
+}
+
 // Blank returns true iff the msig is empty. We need this instead of just
 // comparing with == MultisigSig{}, because Subsigs is a slice.
 func (msig MultisigSig) Blank() bool {
@@ -75,9 +88,36 @@ func (msig MultisigSig) Preimage() (version, threshold uint8, pks []PublicKey) {
 	return msig.Version, msig.Threshold, pks
 }
 
+// Weights returns the weight of every public key in a (partial) weighted
+// multisig address, or nil if the multisig is not weighted
+func (msig MultisigSig) Weights() []uint8 {
+	if msig.Version != WeightedMultisigVersion {
+		return nil
+	}
+	weights := make([]uint8, len(msig.Subsigs))
+	for i, subsig := range msig.Subsigs {
+		weights[i] = subsig.Weight
+	}
+	return weights
+}
+
+// keyWeight returns the weight a signature from the given subsig counts for
+// towards the threshold of a multisig of the given version
+func (subsig MultisigSubsig) keyWeight(version uint8) int {
+	if version == WeightedMultisigVersion {
+		return int(subsig.Weight)
+	}
+	return 1
+}
+
 const multiSigString = "MultisigAddr"
 const maxMultisig = 255
 
+// WeightedMultisigVersion is the multisig version in which every public key
+// carries a weight, and the threshold is a total weight rather than a number
+// of signatures
+const WeightedMultisigVersion = 2
+
 // MultisigAddrGen identifes the exact group, version,
 // and devices (Public keys) that it requires to sign
 // Hash("MultisigAddr" || version uint8 || threshold uint8 || PK1 || PK2 || ...)
@@ -87,36 +127,65 @@ func MultisigAddrGen(version, threshold uint8, pk []PublicKey) (addr Digest, err
 		return
 	}
 
-	if threshold == 0 || len(pk) == 0 || int(threshold) > len(pk) {
-		err = errors.New(errorinvalidthreshold)
+	return MultisigAddrGenWithSubsigs(version, threshold, MultisigPreimageFromPKs(version, threshold, pk).Subsigs)
+}
+
+// MultisigAddrGenWeighted identifies a weighted multisig address
+// Hash("MultisigAddr" || 2 || threshold uint8 || PK1 || W1 || PK2 || W2 || ...)
+func MultisigAddrGenWeighted(threshold uint8, pk []PublicKey, weights []uint8) (addr Digest, err error) {
+	if len(pk) != len(weights) {
+		err = errors.New(errorinvalidweight)
 		return
 	}
 
-	buffer := append([]byte(multiSigString), byte(version), byte(threshold))
-	for _, pki := range pk {
-		buffer = append(buffer, pki[:]...)
-	}
-	return Hash(buffer), nil
+	return MultisigAddrGenWithSubsigs(WeightedMultisigVersion, threshold, MultisigPreimageFromWeightedPKs(threshold, pk, weights).Subsigs)
 }
 
 // MultisigAddrGenWithSubsigs is similiar to MultisigAddrGen
-// except the input is []Subsig rather than []PublicKey
+// except the input is []Subsig rather than []PublicKey.
+// It also supports weighted (version 2) multisig addresses.
 func MultisigAddrGenWithSubsigs(version uint8, threshold uint8,
 	subsigs []MultisigSubsig) (addr Digest, err error) {
 
-	if version != 1 {
-		err = errUnknownVersion
-		return
-	}
-
-	if threshold == 0 || len(subsigs) == 0 || int(threshold) > len(subsigs) {
+	if threshold == 0 || len(subsigs) == 0 {
 		err = errors.New(errorinvalidthreshold)
 		return
 	}
 
 	buffer := append([]byte(multiSigString), byte(version), byte(threshold))
-	for _, subsigsi := range subsigs {
-		buffer = append(buffer, subsigsi.Key[:]...)
+	switch version {
+	case 1:
+		if int(threshold) > len(subsigs) {
+			err = errors.New(errorinvalidthreshold)
+			return
+		}
+		for _, subsigsi := range subsigs {
+			// weights are not part of a version 1 address, so they
+			// must be absent to keep signatures non-malleable
+			if subsigsi.Weight != 0 {
+				err = errors.New(errorinvalidweight)
+				return
+			}
+			buffer = append(buffer, subsigsi.Key[:]...)
+		}
+	case WeightedMultisigVersion:
+		totalWeight := 0
+		for _, subsigsi := range subsigs {
+			if subsigsi.Weight == 0 {
+				err = errors.New(errorinvalidweight)
+				return
+			}
+			totalWeight += int(subsigsi.Weight)
+			buffer = append(buffer, subsigsi.Key[:]...)
+			buffer = append(buffer, subsigsi.Weight)
+		}
+		if int(threshold) > totalWeight {
+			err = errors.New(errorinvalidthreshold)
+			return
+		}
+	default:
+		err = errUnknownVersion
+		return
 	}
 	return Hash(buffer), nil
 }
@@ -128,8 +197,15 @@ func MultisigSign(msg Hashable, addr Digest, version, threshold uint8, pk []Publ
 		return
 	}
 
+	return MultisigSignPreimage(msg, addr, MultisigPreimageFromPKs(version, threshold, pk), sk)
+}
+
+// MultisigSignPreimage is like MultisigSign, but takes the preimage of the
+// address as an (unsigned) MultisigSig, so that it works with weighted
+// multisig addresses as well
+func MultisigSignPreimage(msg Hashable, addr Digest, preimage MultisigSig, sk SecretKey) (sig MultisigSig, err error) {
 	// check the address matches the keys
-	addrnew, err := MultisigAddrGen(version, threshold, pk)
+	addrnew, err := MultisigAddrGenWithSubsigs(preimage.Version, preimage.Threshold, preimage.Subsigs)
 	if err != nil {
 		return
 	}
@@ -140,26 +216,27 @@ func MultisigSign(msg Hashable, addr Digest, version, threshold uint8, pk []Publ
 	}
 
 	// setup parameters
-	sig.Version = version
-	sig.Threshold = threshold
-	sig.Subsigs = make([]MultisigSubsig, len(pk))
+	sig.Version = preimage.Version
+	sig.Threshold = preimage.Threshold
+	sig.Subsigs = make([]MultisigSubsig, len(preimage.Subsigs))
 
 	// check if sk.pk exist in the pk list
-	keyexist := len(pk)
-	for i := 0; i < len(pk); i++ {
-		if sk.SignatureVerifier == pk[i] {
-			keyexist = i
+	keyexist := false
+	for _, subsig := range preimage.Subsigs {
+		if sk.SignatureVerifier == subsig.Key {
+			keyexist = true
 		}
 	}
-	if keyexist == len(pk) {
+	if !keyexist {
 		err = errors.New(errorkeynotexist)
 		return
 	}
 
 	// form the multisig
-	for i := 0; i < len(pk); i++ {
-		sig.Subsigs[i].Key = pk[i]
-		if sk.SignatureVerifier == pk[i] {
+	for i, subsig := range preimage.Subsigs {
+		sig.Subsigs[i].Key = subsig.Key
+		sig.Subsigs[i].Weight = subsig.Weight
+		if sk.SignatureVerifier == subsig.Key {
 			sig.Subsigs[i].Sig = sk.Sign(msg)
 		}
 	}
@@ -189,7 +266,8 @@ func MultisigAssemble(unisig []MultisigSig) (msig MultisigSig, err error) {
 			return
 		}
 		for j := 0; j < len(unisig[0].Subsigs); j++ {
-			if unisig[0].Subsigs[j].Key != unisig[i].Subsigs[j].Key {
+			if unisig[0].Subsigs[j].Key != unisig[i].Subsigs[j].Key ||
+				unisig[0].Subsigs[j].Weight != unisig[i].Subsigs[j].Weight {
 				err = errors.New(errorkeysnotmatch)
 				return
 			}
@@ -204,6 +282,7 @@ func MultisigAssemble(unisig []MultisigSig) (msig MultisigSig, err error) {
 
 	for i := 0; i < len(unisig[0].Subsigs); i++ {
 		msig.Subsigs[i].Key = unisig[0].Subsigs[i].Key
+		msig.Subsigs[i].Weight = unisig[0].Subsigs[i].Weight
 	}
 	for i := 0; i < len(unisig); i++ {
 		for j := 0; j < len(unisig[0].Subsigs); j++ {
@@ -242,19 +321,19 @@ func MultisigVerify(msg Hashable, addr Digest, sig MultisigSig) (verified bool,
 	}
 
 	// check that we don't have too few multisig subsigs
-	if len(sig.Subsigs) < int(sig.Threshold) {
+	if sig.Version == 1 && len(sig.Subsigs) < int(sig.Threshold) {
 		err = errors.New(errorinvalidnumberofsignature)
 		return
 	}
 
-	// checks the number of non-blank signatures is no less than threshold
-	var counter uint8
+	// checks the (weighted) number of non-blank signatures is no less than threshold
+	var counter int
 	for _, subsigi := range sig.Subsigs {
 		if (subsigi.Sig != Signature{}) {
-			counter++
+			counter += subsigi.keyWeight(sig.Version)
 		}
 	}
-	if counter < sig.Threshold {
+	if counter < int(sig.Threshold) {
 		err = errors.New(errorinvalidnumberofsignature)
 		return
 	}
@@ -267,7 +346,7 @@ func MultisigVerify(msg Hashable, addr Digest, sig MultisigSig) (verified bool,
 				err = errors.New(errorsubsigverification)
 				return
 			}
-			verifiedCount++
+			verifiedCount += subsigi.keyWeight(sig.Version)
 		}
 	}
 
@@ -306,7 +385,8 @@ func MultisigAdd(unisig []MultisigSig, msig *MultisigSig) (err error) {
 			return
 		}
 		for j := 0; j < len(unisig[0].Subsigs); j++ {
-			if msig.Subsigs[j].Key != unisig[i].Subsigs[j].Key {
+			if msig.Subsigs[j].Key != unisig[i].Subsigs[j].Key ||
+				msig.Subsigs[j].Weight != unisig[i].Subsigs[j].Weight {
 				err = errors.New(errorkeysnotmatch)
 				return
 			}
@@ -343,7 +423,8 @@ func MultisigMerge(msig1 MultisigSig, msig2 MultisigSig) (msigt MultisigSig, err
 		return
 	}
 	for i := 0; i < len(msig1.Subsigs); i++ {
-		if msig1.Subsigs[i].Key != msig2.Subsigs[i].Key {
+		if msig1.Subsigs[i].Key != msig2.Subsigs[i].Key ||
+			msig1.Subsigs[i].Weight != msig2.Subsigs[i].Weight {
 			err = errors.New(errorkeysnotmatch)
 			return
 		}
@@ -354,6 +435,7 @@ func MultisigMerge(msig1 MultisigSig, msig2 MultisigSig) (msigt MultisigSig, err
 	msigt.Subsigs = make([]MultisigSubsig, len(msig1.Subsigs))
 	for i := 0; i < len(msigt.Subsigs); i++ {
 		msigt.Subsigs[i].Key = msig1.Subsigs[i].Key
+		msigt.Subsigs[i].Weight = msig1.Subsigs[i].Weight
 		if (msig1.Subsigs[i].Sig == Signature{}) {
 			if (msig2.Subsigs[i].Sig != Signature{}) {
 				// update signature with msig2's signature
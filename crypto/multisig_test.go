@@ -223,3 +223,91 @@ func TestMultisigAddAndMerge(t *testing.T) {
 
 	return
 }
+
+// test weighted multisig: pk0 has weight 2, pk1..pk3 have weight 1,
+// and the threshold is 3
+func TestWeightedMultisig(t *testing.T) {
+	var s Seed
+	txid := TestingHashable{[]byte("test: txid 1000")}
+	threshold := uint8(3)
+	weights := []uint8{2, 1, 1, 1}
+
+	userkeypair := make([]*SecretKey, 4)
+	pk := make([]PublicKey, 4)
+	for i := 0; i < 4; i++ {
+		RandBytes(s[:])
+		userkeypair[i] = GenerateSignatureSecrets(s)
+		pk[i] = userkeypair[i].SignatureVerifier
+	}
+
+	// invalid weights and thresholds are detected
+	_, err := MultisigAddrGenWeighted(threshold, pk, []uint8{2, 1, 0, 1})
+	require.Error(t, err, "zero weight should be rejected")
+	_, err = MultisigAddrGenWeighted(threshold, pk, weights[:3])
+	require.Error(t, err, "mismatched number of weights should be rejected")
+	_, err = MultisigAddrGenWeighted(6, pk, weights)
+	require.Error(t, err, "threshold above the total weight should be rejected")
+
+	addr, err := MultisigAddrGenWeighted(threshold, pk, weights)
+	require.NoError(t, err)
+
+	// the weights are part of the address
+	addr2, err := MultisigAddrGenWeighted(threshold, pk, []uint8{1, 2, 1, 1})
+	require.NoError(t, err)
+	require.NotEqual(t, addr, addr2)
+	addr1, err := MultisigAddrGen(1, threshold, pk)
+	require.NoError(t, err)
+	require.NotEqual(t, addr, addr1)
+
+	preimage := MultisigPreimageFromWeightedPKs(threshold, pk, weights)
+	require.Equal(t, weights, preimage.Weights())
+	require.Nil(t, MultisigPreimageFromPKs(1, threshold, pk).Weights())
+
+	sign := func(i int) MultisigSig {
+		sig, err := MultisigSignPreimage(txid, addr, preimage, *userkeypair[i])
+		require.NoError(t, err)
+		return sig
+	}
+
+	// pk1 and pk2 together only have weight 2
+	msig, err := MultisigAssemble([]MultisigSig{sign(1), sign(2)})
+	require.NoError(t, err)
+	verify, err := MultisigVerify(txid, addr, msig)
+	require.Error(t, err)
+	require.False(t, verify)
+
+	// pk0 and pk1 have weight 3
+	msig, err = MultisigMerge(sign(0), sign(1))
+	require.NoError(t, err)
+	verify, err = MultisigVerify(txid, addr, msig)
+	require.NoError(t, err)
+	require.True(t, verify)
+
+	// three directors also reach the threshold
+	msig, err = MultisigAssemble([]MultisigSig{sign(1), sign(2)})
+	require.NoError(t, err)
+	err = MultisigAdd([]MultisigSig{sign(3)}, &msig)
+	require.NoError(t, err)
+	verify, err = MultisigVerify(txid, addr, msig)
+	require.NoError(t, err)
+	require.True(t, verify)
+
+	// tampering with a weight changes the address
+	msig.Subsigs[1].Weight = 2
+	verify, err = MultisigVerify(txid, addr, msig)
+	require.Error(t, err)
+	require.False(t, verify)
+
+	// signatures with different weights cannot be merged
+	other := sign(0)
+	other.Subsigs[2].Weight = 2
+	_, err = MultisigMerge(sign(1), other)
+	require.Error(t, err)
+
+	// version 1 signatures must not carry weights
+	v1, err := MultisigSign(txid, addr1, 1, threshold, pk, *userkeypair[0])
+	require.NoError(t, err)
+	v1.Subsigs[0].Weight = 1
+	_, err = MultisigAddrGenWithSubsigs(v1.Version, v1.Threshold, v1.Subsigs)
+	require.Error(t, err)
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/vincentbdb/go-algorand/daemon/algod/api/spec/v1"
+)
+
+// DeltaStream reads the block headers and state deltas of consecutive rounds
+// from /v1/deltas/subscribe.  The server ends each stream when its write
+// timeout expires, so DeltaStream subscribes again from the next round
+// whenever its connection ends.
+type DeltaStream struct {
+	client RestClient
+	ctx    context.Context
+	next   uint64
+
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// SubscribeDeltas returns a DeltaStream starting at round from.  The stream
+// stops, and Next returns an error, once ctx is cancelled.
+func (client RestClient) SubscribeDeltas(ctx context.Context, from uint64) *DeltaStream {
+	return &DeltaStream{
+		client: client,
+		ctx:    ctx,
+		next:   from,
+	}
+}
+
+func (s *DeltaStream) connect() error {
+	queryURL := s.client.serverURL
+	queryURL.Path = strings.Join([]string{apiVersionPathPrefix, "/deltas/subscribe"}, "")
+	queryURL.RawQuery = fmt.Sprintf("from=%d", s.next)
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(authHeader, s.client.apiToken)
+
+	httpClient := http.Client{}
+	resp, err := httpClient.Do(req.WithContext(s.ctx))
+	if err != nil {
+		return err
+	}
+
+	err = extractError(resp)
+	if err != nil {
+		resp.Body.Close()
+		return err
+	}
+
+	s.body = resp.Body
+	s.dec = json.NewDecoder(resp.Body)
+	return nil
+}
+
+// Next blocks until the next round is available and returns it.
+func (s *DeltaStream) Next() (response v1.RoundDelta, err error) {
+	for {
+		if s.body == nil {
+			err = s.connect()
+			if err != nil {
+				return
+			}
+		}
+
+		err = s.dec.Decode(&response)
+		if err == nil {
+			if response.Round != s.next {
+				err = fmt.Errorf("DeltaStream: received round %d, expected %d", response.Round, s.next)
+				return
+			}
+			s.next++
+			return
+		}
+
+		s.Close()
+		if s.ctx.Err() != nil {
+			err = s.ctx.Err()
+			return
+		}
+	}
+}
+
+// Close ends the current connection, if any.  A later call to Next
+// subscribes again from the next round.
+func (s *DeltaStream) Close() {
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+		s.dec = nil
+	}
+}
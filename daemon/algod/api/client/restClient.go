@@ -208,6 +208,17 @@ func (client RestClient) LedgerSupply() (response v1.Supply, err error) {
 	return
 }
 
+type ledgerSupplyParams struct {
+	Round uint64 `url:"round"`
+}
+
+// LedgerSupplyAtRound gets the supply details for the specified node's Ledger
+// as of the end of the given round, which must be recent
+func (client RestClient) LedgerSupplyAtRound(round uint64) (response v1.Supply, err error) {
+	err = client.get(&response, "/ledger/supply", ledgerSupplyParams{round})
+	return
+}
+
 type transactionsByAddrParams struct {
 	FirstRound uint64 `url:"firstRound"`
 	LastRound  uint64 `url:"lastRound"`
@@ -232,6 +243,18 @@ func (client RestClient) AssetInformation(index uint64) (response v1.AssetParams
 	return
 }
 
+type assetHoldersParams struct {
+	After string `url:"after,omitempty"`
+	Max   uint64 `url:"max,omitempty"`
+}
+
+// AssetHolders gets up to max holders of the asset with the passed index, starting
+// after the address after; pass an empty after for the first page
+func (client RestClient) AssetHolders(index uint64, after string, max uint64) (response v1.AssetHolderList, err error) {
+	err = client.get(&response, fmt.Sprintf("/asset/%d/holders", index), assetHoldersParams{after, max})
+	return
+}
+
 // Assets gets up to max assets with maximum asset index assetIdx
 func (client RestClient) Assets(assetIdx, max uint64) (response v1.AssetList, err error) {
 	err = client.get(&response, "/assets", assetsParams{assetIdx, max})
@@ -244,6 +267,24 @@ func (client RestClient) AccountInformation(address string) (response v1.Account
 	return
 }
 
+// AccountProof gets the record of the passed address at the given round, along with
+// a Merkle proof of it against that round's account state root
+func (client RestClient) AccountProof(address string, round uint64) (response v1.AccountProof, err error) {
+	err = client.get(&response, fmt.Sprintf("/account/%s/proof/%d", address, round), nil)
+	return
+}
+
+type accountInformationParams struct {
+	Round uint64 `url:"round"`
+}
+
+// AccountInformationAtRound gets the AccountInformationResponse associated with the passed address
+// as of the end of the given round, which archival nodes that keep account history can serve for any past round
+func (client RestClient) AccountInformationAtRound(address string, round uint64) (response v1.Account, err error) {
+	err = client.get(&response, fmt.Sprintf("/account/%s", address), accountInformationParams{round})
+	return
+}
+
 // TransactionInformation gets information about a specific transaction involving a specific account
 func (client RestClient) TransactionInformation(accountAddress, transactionID string) (response v1.Transaction, err error) {
 	transactionID = stripTransaction(transactionID)
@@ -307,6 +348,12 @@ func (client RestClient) Block(round uint64) (response v1.Block, err error) {
 	return
 }
 
+// CertifiedBlockHeader gets the encoded header and certificate of the block for the given round
+func (client RestClient) CertifiedBlockHeader(round uint64) (response v1.CertifiedBlockHeader, err error) {
+	err = client.get(&response, fmt.Sprintf("/block/%d/certified-header", round), nil)
+	return
+}
+
 // GetGoRoutines gets a dump of the goroutines from pprof
 // Not supported
 func (client RestClient) GetGoRoutines(ctx context.Context) (goRoutines string, err error) {
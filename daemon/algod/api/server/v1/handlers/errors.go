@@ -23,8 +23,10 @@ var (
 	errFailedLookingUpTransactionPool      = "failed to retrieve information from the transaction pool"
 	errFailedRetrievingNodeStatus          = "failed retrieving node status"
 	errFailedRetrievingAsset               = "failed to retrieve asset information"
+	errFailedRetrievingStateDelta          = "failed to retrieve the state delta"
 	errFailedParsingRoundNumber            = "failed to parse the round number"
 	errFailedParsingMaxAssetsToList        = "failed to parse max assets, must be between %d and %d"
+	errFailedParsingMaxHoldersToList       = "failed to parse max holders, must be between %d and %d"
 	errFailedParsingAssetIdx               = "failed to parse asset index"
 	errFailedToGetAssetCreator             = "failed to retrieve asset creator from the ledger"
 	errFailedToParseAddress                = "failed to parse the address"
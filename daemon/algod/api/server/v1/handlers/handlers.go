@@ -58,6 +58,12 @@ func nodeStatus(node *node.AlgorandFullNode) (res v1.NodeStatus, err error) {
 		NextVersionSupported: stat.NextVersionSupported,
 		TimeSinceLastRound:   stat.TimeSinceLastRound().Nanoseconds(),
 		CatchupTime:          stat.CatchupTime.Nanoseconds(),
+		UpgradeProposal:      string(stat.UpgradeProposal),
+		UpgradeApprovals:     stat.UpgradeApprovals,
+		UpgradeThreshold:     stat.UpgradeThreshold,
+		UpgradeVoteRounds:    stat.UpgradeVoteRounds,
+		UpgradeVoteBefore:    uint64(stat.UpgradeVoteBefore),
+		UpgradeSwitchRound:   uint64(stat.UpgradeSwitchRound),
 	}, nil
 }
 
@@ -104,6 +110,9 @@ func txEncode(tx transactions.Transaction, ad transactions.ApplyData) (v1.Transa
 	res.GenesisID = tx.GenesisID
 	res.GenesisHash = tx.GenesisHash[:]
 	res.Group = tx.Group[:]
+	if !tx.RekeyTo.IsZero() {
+		res.RekeyTo = tx.RekeyTo.String()
+	}
 	return res, nil
 }
 
@@ -142,6 +151,7 @@ func keyregTxEncode(tx transactions.Transaction, ad transactions.ApplyData) v1.T
 func assetParams(creator basics.Address, params basics.AssetParams) v1.AssetParams {
 	paramsModel := v1.AssetParams{
 		Total:         params.Total,
+		Decimals:      params.Decimals,
 		DefaultFrozen: params.DefaultFrozen,
 	}
 
@@ -190,9 +200,10 @@ func assetConfigTxEncode(tx transactions.Transaction, ad transactions.ApplyData)
 
 func assetTransferTxEncode(tx transactions.Transaction, ad transactions.ApplyData) v1.Transaction {
 	xfer := v1.AssetTransferTransactionType{
-		AssetID:  uint64(tx.AssetTransferTxnFields.XferAsset),
-		Amount:   tx.AssetTransferTxnFields.AssetAmount,
-		Receiver: tx.AssetTransferTxnFields.AssetReceiver.String(),
+		AssetID:   uint64(tx.AssetTransferTxnFields.XferAsset),
+		Amount:    tx.AssetTransferTxnFields.AssetAmount,
+		Receiver:  tx.AssetTransferTxnFields.AssetReceiver.String(),
+		FundOptIn: tx.AssetTransferTxnFields.AssetFundOptIn,
 	}
 
 	if !tx.AssetTransferTxnFields.AssetSender.IsZero() {
@@ -531,7 +542,10 @@ func AccountInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Reque
 	// swagger:operation GET /v1/account/{address} AccountInformation
 	// ---
 	//     Summary: Get account information.
-	//     Description: Given a specific account public key, this call returns the accounts status, balance and spendable amounts
+	//     Description: >
+	//       Given a specific account public key, this call returns the accounts status, balance and spendable amounts.
+	//       Archival nodes that keep account history (EnableAccountHistory) can also return this
+	//       information as of any past round.
 	//     Produces:
 	//     - application/json
 	//     Schemes:
@@ -543,6 +557,13 @@ func AccountInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Reque
 	//         pattern: "[A-Z0-9]{58}"
 	//         required: true
 	//         description: An account public key
+	//       - name: round
+	//         in: query
+	//         type: integer
+	//         format: int64
+	//         minimum: 0
+	//         required: false
+	//         description: Return the account information as of the end of this round (defaults to the latest round).
 	//     Responses:
 	//       200:
 	//         "$ref": '#/responses/AccountInformationResponse'
@@ -569,6 +590,15 @@ func AccountInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Reque
 
 	myLedger := ctx.Node.Ledger()
 	lastRound := myLedger.Latest()
+	if queryRound := r.FormValue("round"); queryRound != "" {
+		rnd, err := strconv.ParseUint(queryRound, 10, 64)
+		if err != nil || basics.Round(rnd) > lastRound {
+			lib.ErrorResponse(w, http.StatusBadRequest, fmt.Errorf(errFailedParsingRoundNumber), errFailedParsingRoundNumber, ctx.Log)
+			return
+		}
+		lastRound = basics.Round(rnd)
+	}
+
 	record, err := myLedger.Lookup(lastRound, basics.Address(addr))
 	if err != nil {
 		lib.ErrorResponse(w, http.StatusInternalServerError, err, errFailedLookingUpLedger, ctx.Log)
@@ -637,10 +667,171 @@ func AccountInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Reque
 		AssetParams:                 thisAssetParams,
 		Assets:                      assets,
 	}
+	if !record.AuthAddr.IsZero() {
+		accountInfo.AuthAddr = record.AuthAddr.String()
+	}
 
 	SendJSON(AccountInformationResponse{&accountInfo}, w, ctx.Log)
 }
 
+// AccountProof is an httpHandler for route GET /v1/account/{addr:[A-Z0-9]{KeyLength}}/proof/{round:[0-9]+}
+func AccountProof(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v1/account/{address}/proof/{round} AccountProof
+	// ---
+	//     Summary: Get an account record with a proof of its state.
+	//     Description: >
+	//       Given a specific account public key and a recent round, this call returns the account record
+	//       at the end of that round, without pending rewards, along with a Merkle proof of the record
+	//       against the round's account state root.
+	//     Produces:
+	//     - application/json
+	//     Schemes:
+	//     - http
+	//     Parameters:
+	//       - name: address
+	//         in: path
+	//         type: string
+	//         pattern: "[A-Z0-9]{58}"
+	//         required: true
+	//         description: An account public key
+	//       - name: round
+	//         in: path
+	//         type: integer
+	//         format: int64
+	//         minimum: 0
+	//         required: true
+	//         description: The round for which to prove the account record.
+	//     Responses:
+	//       200:
+	//         "$ref": '#/responses/AccountProofResponse'
+	//       400:
+	//         description: Bad Request
+	//         schema: {type: string}
+	//       500:
+	//         description: Internal Error
+	//         schema: {type: string}
+	//       401: { description: Invalid API Token }
+	//       default: { description: Unknown Error }
+	queryAddr := mux.Vars(r)["addr"]
+	if queryAddr == "" {
+		lib.ErrorResponse(w, http.StatusBadRequest, fmt.Errorf(errNoAccountSpecified), errNoAccountSpecified, ctx.Log)
+		return
+	}
+
+	addr, err := basics.UnmarshalChecksumAddress(queryAddr)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedToParseAddress, ctx.Log)
+		return
+	}
+
+	queryRound, err := strconv.ParseUint(mux.Vars(r)["round"], 10, 64)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedParsingRoundNumber, ctx.Log)
+		return
+	}
+
+	record, proof, root, err := ctx.Node.Ledger().AccountProof(basics.Round(queryRound), addr)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedLookingUpLedger, ctx.Log)
+		return
+	}
+
+	accountProof := v1.AccountProof{
+		Round:   queryRound,
+		Address: addr.String(),
+		Account: protocol.Encode(record),
+		Root:    root[:],
+		Proof:   protocol.Encode(proof),
+	}
+
+	SendJSON(AccountProofResponse{&accountProof}, w, ctx.Log)
+}
+
+// SubscribeDeltas is an httpHandler for route GET /v1/deltas/subscribe
+func SubscribeDeltas(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v1/deltas/subscribe SubscribeDeltas
+	// ---
+	//     Summary: Stream the block header and state delta of every round.
+	//     Description: >
+	//       Streams one RoundDelta object per line, in round order, starting at round `from` and
+	//       following the ledger as new rounds are added. Recent rounds are available when the node
+	//       keeps state deltas (EnableStateDeltas); older rounds are only available when the node
+	//       archives them (ArchiveStateDeltas). The
+	//       stream ends when the server's write timeout expires or a round's delta is unavailable;
+	//       clients resume by subscribing again from the round after the last one they received.
+	//     Produces:
+	//     - application/json
+	//     Schemes:
+	//     - http
+	//     Parameters:
+	//       - name: from
+	//         in: query
+	//         type: integer
+	//         format: int64
+	//         minimum: 0
+	//         required: true
+	//         description: The first round to stream.
+	//     Responses:
+	//       200:
+	//         "$ref": '#/responses/RoundDeltaResponse'
+	//       400:
+	//         description: Bad Request
+	//         schema: {type: string}
+	//       404:
+	//         description: The delta of round `from` is no longer available
+	//         schema: {type: string}
+	//       401: { description: Invalid API Token }
+	//       default: { description: Unknown Error }
+	from, err := strconv.ParseUint(r.FormValue("from"), 10, 64)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedParsingRoundNumber, ctx.Log)
+		return
+	}
+
+	l := ctx.Node.Ledger()
+	flusher, _ := w.(http.Flusher)
+	started := false
+	for rnd := basics.Round(from); ; rnd++ {
+		select {
+		case <-l.Wait(rnd):
+		case <-r.Context().Done():
+			return
+		}
+
+		hdr, err := l.BlockHdr(rnd)
+		var delta ledger.RoundDelta
+		if err == nil {
+			delta, err = l.RoundDelta(rnd)
+		}
+		if err != nil {
+			if !started {
+				lib.ErrorResponse(w, http.StatusNotFound, err, errFailedRetrievingStateDelta, ctx.Log)
+			} else {
+				ctx.Log.Infof("SubscribeDeltas: ending stream at round %d: %v", rnd, err)
+			}
+			return
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", "application/json")
+			started = true
+		}
+
+		roundDelta := v1.RoundDelta{
+			Round:  uint64(rnd),
+			Header: protocol.Encode(hdr),
+			Delta:  protocol.Encode(delta),
+		}
+		err = writeJSON(RoundDeltaResponse{&roundDelta}.getBody(), w)
+		if err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
 // TransactionInformation is an httpHandler for route GET /v1/account/{addr:[A-Z0-9]{KeyLength}}/transaction/{txid:[A-Z0-9]+}
 func TransactionInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
 	// swagger:operation GET /v1/account/{address}/transaction/{txid} TransactionInformation
@@ -1042,6 +1233,101 @@ func AssetInformation(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request
 	}
 }
 
+// AssetHolders is an httpHandler for route GET /v1/asset/{index:[0-9]+}/holders
+func AssetHolders(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v1/asset/{index}/holders AssetHolders
+	// ---
+	//     Summary: List the holders of an asset
+	//     Description: >
+	//       Returns up to `max` accounts holding the asset as of the latest round, in order of address,
+	//       starting after the address `after`. To fetch every holder, pass the `next` address of each
+	//       page as `after` until a page has no `next`. Pages are read at the latest round when they are
+	//       requested, so holdings may change between pages.
+	//     Produces:
+	//     - application/json
+	//     Schemes:
+	//     - http
+	//     Parameters:
+	//       - name: index
+	//         in: path
+	//         type: integer
+	//         format: int64
+	//         required: true
+	//         description: Asset index
+	//       - name: after
+	//         in: query
+	//         type: string
+	//         pattern: "[A-Z0-9]{58}"
+	//         required: false
+	//         description: Only list holders whose address sorts after this one. If omitted, start at the first holder.
+	//       - name: max
+	//         in: query
+	//         type: integer
+	//         format: int64
+	//         minimum: 1
+	//         maximum: 1000
+	//         required: false
+	//         description: Fetch no more than this many holders. Defaults to 1000.
+	//     Responses:
+	//       200:
+	//         "$ref": '#/responses/AssetHoldersResponse'
+	//       400:
+	//         description: Bad Request
+	//         schema: {type: string}
+	//       500:
+	//         description: Internal Error
+	//         schema: {type: string}
+	//       401: { description: Invalid API Token }
+	//       default: { description: Unknown Error }
+
+	const maxHoldersToList = 1000
+
+	queryIndex, err := strconv.ParseUint(mux.Vars(r)["index"], 10, 64)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedToParseAssetIndex, ctx.Log)
+		return
+	}
+
+	max := uint64(maxHoldersToList)
+	if queryMax := r.FormValue("max"); queryMax != "" {
+		max, err = strconv.ParseUint(queryMax, 10, 64)
+		if err != nil || max < 1 || max > maxHoldersToList {
+			err := fmt.Errorf(errFailedParsingMaxHoldersToList, 1, maxHoldersToList)
+			lib.ErrorResponse(w, http.StatusBadRequest, err, err.Error(), ctx.Log)
+			return
+		}
+	}
+
+	var after basics.Address
+	if queryAfter := r.FormValue("after"); queryAfter != "" {
+		after, err = basics.UnmarshalChecksumAddress(queryAfter)
+		if err != nil {
+			lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedToParseAddress, ctx.Log)
+			return
+		}
+	}
+
+	holders, err := ctx.Node.Ledger().ListAssetHolders(basics.AssetIndex(queryIndex), after, max)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusInternalServerError, err, errFailedLookingUpLedger, ctx.Log)
+		return
+	}
+
+	var result v1.AssetHolderList
+	for _, holder := range holders {
+		result.Holders = append(result.Holders, v1.AssetHolder{
+			Address: holder.Address.String(),
+			Amount:  holder.Holding.Amount,
+			Frozen:  holder.Holding.Frozen,
+		})
+	}
+	if uint64(len(holders)) == max {
+		result.Next = holders[len(holders)-1].Address.String()
+	}
+
+	SendJSON(AssetHoldersResponse{&result}, w, ctx.Log)
+}
+
 // Assets is an httpHandler for route GET /v1/assets
 func Assets(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
 	// swagger:operation GET /v1/assets Assets
@@ -1247,6 +1533,56 @@ func GetBlock(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
 	SendJSON(BlockResponse{&block}, w, ctx.Log)
 }
 
+// GetCertifiedBlockHeader is an httpHandler for route GET /v1/block/{round}/certified-header
+func GetCertifiedBlockHeader(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
+	// swagger:operation GET /v1/block/{round}/certified-header GetCertifiedBlockHeader
+	// ---
+	//     Summary: Get the header and certificate of the block for the given round.
+	//     Description: Returns the msgpack-encoded block header and agreement certificate, which light clients use to authenticate the block without the rest of the ledger.
+	//     Produces:
+	//     - application/json
+	//     Schemes:
+	//     - http
+	//     Parameters:
+	//       - name: round
+	//         in: path
+	//         type: integer
+	//         format: int64
+	//         minimum: 0
+	//         required: true
+	//         description: The round from which to fetch the block header.
+	//     Responses:
+	//       200:
+	//         "$ref": '#/responses/CertifiedBlockHeaderResponse'
+	//       400:
+	//         description: Bad Request
+	//         schema: {type: string}
+	//       500:
+	//         description: Internal Error
+	//         schema: {type: string}
+	//       401: { description: Invalid API Token }
+	//       default: { description: Unknown Error }
+	queryRound, err := strconv.ParseUint(mux.Vars(r)["round"], 10, 64)
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedParsingRoundNumber, ctx.Log)
+		return
+	}
+
+	b, c, err := ctx.Node.Ledger().BlockCert(basics.Round(queryRound))
+	if err != nil {
+		lib.ErrorResponse(w, http.StatusInternalServerError, err, errFailedLookingUpLedger, ctx.Log)
+		return
+	}
+
+	header := v1.CertifiedBlockHeader{
+		Round:       queryRound,
+		Header:      protocol.Encode(b.BlockHeader),
+		Certificate: protocol.Encode(c),
+	}
+
+	SendJSON(CertifiedBlockHeaderResponse{&header}, w, ctx.Log)
+}
+
 // GetSupply is an httpHandler for route GET /v1/ledger/supply
 func GetSupply(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
 	// swagger:operation GET /v1/ledger/supply GetSupply
@@ -1256,20 +1592,40 @@ func GetSupply(ctx lib.ReqContext, w http.ResponseWriter, r *http.Request) {
 	//     - application/json
 	//     Schemes:
 	//     - http
+	//     Parameters:
+	//       - name: round
+	//         in: query
+	//         type: integer
+	//         format: int64
+	//         minimum: 0
+	//         required: false
+	//         description: The round whose supply to report, which must be recent. Defaults to the latest round.
 	//     Responses:
 	//       200:
 	//         "$ref": '#/responses/SupplyResponse'
+	//       400:
+	//         description: Bad Request
+	//         schema: {type: string}
 	//       401: { description: Invalid API Token }
 	//       default: { description: Unknown Error }
-	latest := ctx.Node.Ledger().Latest()
-	totals, err := ctx.Node.Ledger().Totals(latest)
+	rnd := ctx.Node.Ledger().Latest()
+	if queryRound := r.FormValue("round"); queryRound != "" {
+		parsed, err := strconv.ParseUint(queryRound, 10, 64)
+		if err != nil {
+			lib.ErrorResponse(w, http.StatusBadRequest, err, errFailedParsingRoundNumber, ctx.Log)
+			return
+		}
+		rnd = basics.Round(parsed)
+	}
+
+	totals, err := ctx.Node.Ledger().Totals(rnd)
 	if err != nil {
-		err = fmt.Errorf("GetSupply(): round %d failed: %v", latest, err)
+		err = fmt.Errorf("GetSupply(): round %d failed: %v", rnd, err)
 		lib.ErrorResponse(w, http.StatusInternalServerError, err, errInternalFailure, ctx.Log)
 		return
 	}
 	supply := v1.Supply{
-		Round:       uint64(latest),
+		Round:       uint64(rnd),
 		TotalMoney:  totals.Participating().Raw,
 		OnlineMoney: totals.Online.Money.Raw,
 	}
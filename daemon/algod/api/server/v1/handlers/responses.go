@@ -102,6 +102,30 @@ func (r AccountInformationResponse) getBody() interface{} {
 	return r.Body
 }
 
+// AccountProofResponse contains an account record and its Merkle proof
+//
+// swagger:response AccountProofResponse
+type AccountProofResponse struct {
+	// in: body
+	Body *v1.AccountProof
+}
+
+func (r AccountProofResponse) getBody() interface{} {
+	return r.Body
+}
+
+// RoundDeltaResponse contains a block header and the state delta of its round
+//
+// swagger:response RoundDeltaResponse
+type RoundDeltaResponse struct {
+	// in: body
+	Body *v1.RoundDelta
+}
+
+func (r RoundDeltaResponse) getBody() interface{} {
+	return r.Body
+}
+
 // TransactionResponse contains a transaction information
 //
 // swagger:response TransactionResponse
@@ -138,6 +162,18 @@ func (r AssetsResponse) getBody() interface{} {
 	return r.Body
 }
 
+// AssetHoldersResponse contains a page of the holders of an asset
+//
+// swagger:response AssetHoldersResponse
+type AssetHoldersResponse struct {
+	// in: body
+	Body *v1.AssetHolderList
+}
+
+func (r AssetHoldersResponse) getBody() interface{} {
+	return r.Body
+}
+
 // AssetInformationResponse contains asset information
 //
 // swagger:response AssetInformationResponse
@@ -187,6 +223,18 @@ func (r BlockResponse) getBody() interface{} {
 	return r.Body
 }
 
+// CertifiedBlockHeaderResponse contains a block header and its certificate
+//
+// swagger:response CertifiedBlockHeaderResponse
+type CertifiedBlockHeaderResponse struct {
+	// in: body
+	Body *v1.CertifiedBlockHeader
+}
+
+func (r CertifiedBlockHeaderResponse) getBody() interface{} {
+	return r.Body
+}
+
 // SupplyResponse contains the ledger supply information
 //
 // swagger:response SupplyResponse
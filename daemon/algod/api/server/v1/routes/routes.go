@@ -56,6 +56,20 @@ var Routes = lib.Routes{
 		HandlerFunc: handlers.AccountInformation,
 	},
 
+	lib.Route{
+		Name:        "account-proof",
+		Method:      "GET",
+		Path:        fmt.Sprintf("/account/{addr:[A-Z0-9]{%d}}/proof/{round:[0-9]+}", KeyLength),
+		HandlerFunc: handlers.AccountProof,
+	},
+
+	lib.Route{
+		Name:        "deltas-subscribe",
+		Method:      "GET",
+		Path:        "/deltas/subscribe",
+		HandlerFunc: handlers.SubscribeDeltas,
+	},
+
 	lib.Route{
 		Name:        "transaction-information",
 		Method:      "GET",
@@ -91,6 +105,13 @@ var Routes = lib.Routes{
 		HandlerFunc: handlers.GetBlock,
 	},
 
+	lib.Route{
+		Name:        "block-certified-header",
+		Method:      "GET",
+		Path:        "/block/{round:[0-9]+}/certified-header",
+		HandlerFunc: handlers.GetCertifiedBlockHeader,
+	},
+
 	lib.Route{
 		Name:        "ledger-supply",
 		Method:      "GET",
@@ -126,6 +147,13 @@ var Routes = lib.Routes{
 		HandlerFunc: handlers.AssetInformation,
 	},
 
+	lib.Route{
+		Name:        "asset-holders",
+		Method:      "GET",
+		Path:        "/asset/{index:[0-9]+}/holders",
+		HandlerFunc: handlers.AssetHolders,
+	},
+
 	lib.Route{
 		Name:        "list-assets",
 		Method:      "GET",
@@ -64,6 +64,36 @@ type NodeStatus struct {
 	// HasSyncedSinceStartup indicates whether a round has completed since startup
 	// Required: true
 	HasSyncedSinceStartup bool `json:"hasSyncedSinceStartup"`
+
+	// UpgradeProposal is the consensus version currently being voted on, if any
+	//
+	// required: false
+	UpgradeProposal string `json:"upgradeProposal,omitempty"`
+
+	// UpgradeApprovals is the number of blocks that have approved the proposal so far
+	//
+	// required: false
+	UpgradeApprovals uint64 `json:"upgradeApprovals,omitempty"`
+
+	// UpgradeThreshold is the number of approvals needed for the proposal to pass
+	//
+	// required: false
+	UpgradeThreshold uint64 `json:"upgradeThreshold,omitempty"`
+
+	// UpgradeVoteRounds is the number of rounds over which votes are collected
+	//
+	// required: false
+	UpgradeVoteRounds uint64 `json:"upgradeVoteRounds,omitempty"`
+
+	// UpgradeVoteBefore is the round by which the proposal must collect its approvals
+	//
+	// required: false
+	UpgradeVoteBefore uint64 `json:"upgradeVoteBefore,omitempty"`
+
+	// UpgradeSwitchRound is the round at which an approved proposal takes effect
+	//
+	// required: false
+	UpgradeSwitchRound uint64 `json:"upgradeSwitchRound,omitempty"`
 }
 
 // TransactionID Description
@@ -166,6 +196,68 @@ type Account struct {
 	//
 	// required: false
 	Assets map[uint64]AssetHolding `json:"assets,omitempty"`
+
+	// AuthAddr is the address whose key authorizes this account's
+	// transactions, if the account has been rekeyed.
+	//
+	// required: false
+	AuthAddr string `json:"auth-addr,omitempty"`
+}
+
+// AccountProof contains an account record and a Merkle proof of it against
+// the account state root of a round
+// swagger:model AccountProof
+type AccountProof struct {
+	// Round indicates the round for which this information is relevant
+	//
+	// required: true
+	Round uint64 `json:"round"`
+
+	// Address indicates the account public key
+	//
+	// required: true
+	Address string `json:"address"`
+
+	// Account is the msgpack-encoded account record, without pending
+	// rewards, exactly as committed to by the account state root.
+	//
+	// required: true
+	Account []byte `json:"account"`
+
+	// Root is the account state root of the round.  It matches the
+	// AccountStateRoot field of the round's block header when the
+	// round's protocol supports account state commitments.
+	//
+	// required: true
+	Root []byte `json:"root"`
+
+	// Proof is the msgpack-encoded Merkle proof of the account record
+	// against Root.
+	//
+	// required: true
+	Proof []byte `json:"proof"`
+}
+
+// RoundDelta contains a block header and the changes its round made to the
+// ledger state
+// swagger:model RoundDelta
+type RoundDelta struct {
+	// Round indicates the round of the block and delta
+	//
+	// required: true
+	Round uint64 `json:"round"`
+
+	// Header is the msgpack-encoded block header of the round.
+	//
+	// required: true
+	Header []byte `json:"header"`
+
+	// Delta is the msgpack-encoded ledger.RoundDelta of the round: the
+	// account records it modified, before and after, the assets it
+	// created or destroyed, and the transaction leases it took out.
+	//
+	// required: true
+	Delta []byte `json:"delta"`
 }
 
 // Asset specifies both the unique identifier and the parameters for an asset
@@ -198,6 +290,12 @@ type AssetParams struct {
 	// required: true
 	Total uint64 `json:"total"`
 
+	// Decimals specifies the number of digits to display after the decimal
+	// place when displaying amounts of this asset.
+	//
+	// required: false
+	Decimals uint32 `json:"decimals"`
+
 	// DefaultFrozen specifies whether holdings in this asset
 	// are frozen by default.
 	//
@@ -385,6 +483,12 @@ type Transaction struct {
 	// required: false
 	// swagger:strfmt byte
 	Group []byte `json:"group"`
+
+	// RekeyTo is the address the sender is rekeyed to by this transaction,
+	// if any.
+	//
+	// required: false
+	RekeyTo string `json:"rekey-to,omitempty"`
 }
 
 // PaymentTransactionType contains the additional fields for a payment Transaction
@@ -504,6 +608,13 @@ type AssetTransferTransactionType struct {
 	//
 	// required: false
 	CloseTo string `json:"closeto"`
+
+	// FundOptIn is set if the sender (the asset manager) opted the
+	// receiver in to the asset and paid for its holding, with the
+	// receiver's consent in the same group.
+	//
+	// required: false
+	FundOptIn bool `json:"fundoptin,omitempty"`
 }
 
 // AssetFreezeTransactionType contains the additional fields for an asset freeze transaction
@@ -543,6 +654,39 @@ type AssetList struct {
 	Assets []Asset `json:"assets,omitempty"`
 }
 
+// AssetHolder contains an account's holding of an asset
+// swagger:model AssetHolder
+type AssetHolder struct {
+	// Address is the account holding the asset
+	//
+	// required: true
+	Address string `json:"address"`
+
+	// Amount is the number of units of the asset held
+	//
+	// required: true
+	Amount uint64 `json:"amount"`
+
+	// Frozen specifies whether the holding is frozen
+	//
+	// required: true
+	Frozen bool `json:"frozen"`
+}
+
+// AssetHolderList contains a page of the holders of an asset, in order of
+// address
+// swagger:model AssetHolderList
+type AssetHolderList struct {
+	// Holders is a list of asset holders
+	//
+	// required: true
+	Holders []AssetHolder `json:"holders,omitempty"`
+
+	// Next is the address to pass as `after` to fetch the next page.  It
+	// is empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
 // TransactionFee contains the suggested fee
 // swagger:model TransactionFee
 type TransactionFee struct {
@@ -662,6 +806,27 @@ type Block struct {
 	UpgradeVote
 }
 
+// CertifiedBlockHeader contains the header of a block and the certificate
+// that agreement produced for it, so that the block can be authenticated
+// without fetching its transactions
+// swagger:model CertifiedBlockHeader
+type CertifiedBlockHeader struct {
+	// Round is the round of the block
+	//
+	// required: true
+	Round uint64 `json:"round"`
+
+	// Header is the msgpack-encoded block header
+	//
+	// required: true
+	Header []byte `json:"header"`
+
+	// Certificate is the msgpack-encoded agreement certificate for the block
+	//
+	// required: true
+	Certificate []byte `json:"certificate"`
+}
+
 // UpgradeState contains the information about a current state of an upgrade
 // swagger:model UpgradeState
 type UpgradeState struct {
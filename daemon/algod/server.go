@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/vincentbdb/go-algorand/node/appinterface"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -280,6 +281,12 @@ func (s *Server) Stop() {
 	s.stopped = true
 }
 
+// ImportBlocks adds the blocks in a block archive to the ledger of the
+// server's node, which must not have been started.
+func (s *Server) ImportBlocks(archive io.Reader) (uint64, error) {
+	return s.node.ImportBlocks(archive)
+}
+
 // OverridePhonebook is used to replace the phonebook associated with
 // the server's node.
 func (s *Server) OverridePhonebook(dialOverride ...string) {
@@ -803,7 +803,8 @@ func postTransactionSignHandler(ctx reqContext, w http.ResponseWriter, r *http.R
 	//    Summary: Sign a transaction
 	//    Description: >
 	//      Signs the passed transaction with a key from the wallet, determined
-	//      by the sender encoded in the transaction.
+	//      by the sender encoded in the transaction, or by the public key in
+	//      the request if the sender has been rekeyed.
 	//    Produces:
 	//    - application/json
 	//    Parameters:
@@ -843,7 +844,7 @@ func postTransactionSignHandler(ctx reqContext, w http.ResponseWriter, r *http.R
 	}
 
 	// Sign the transaction
-	stx, err := wallet.SignTransaction(tx, []byte(req.WalletPassword))
+	stx, err := wallet.SignTransaction(tx, req.PublicKey, []byte(req.WalletPassword))
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err)
 		return
@@ -973,7 +974,8 @@ func postMultisigImportHandler(ctx reqContext, w http.ResponseWriter, r *http.Re
 	//    Summary: Import a multisig account
 	//    Description: >
 	//      Generates a multisig account from the passed public keys array and multisig
-	//      metadata, and stores all of this in the wallet.
+	//      metadata, and stores all of this in the wallet. Weighted (version 2)
+	//      multisig accounts also take the weight of each public key.
 	//    Produces:
 	//    - application/json
 	//    Parameters:
@@ -1003,7 +1005,7 @@ func postMultisigImportHandler(ctx reqContext, w http.ResponseWriter, r *http.Re
 	}
 
 	// Import the key
-	addr, err := wallet.ImportMultisigAddr(req.Version, req.Threshold, req.PKs)
+	addr, err := wallet.ImportMultisigAddr(req.Version, req.Threshold, req.PKs, req.Weights)
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err)
 		return
@@ -1063,7 +1065,7 @@ func postMultisigExportHandler(ctx reqContext, w http.ResponseWriter, r *http.Re
 	}
 
 	// Export the key
-	version, threshold, pks, err := wallet.LookupMultisigPreimage(crypto.Digest(reqAddr))
+	version, threshold, pks, weights, err := wallet.LookupMultisigPreimage(crypto.Digest(reqAddr))
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err)
 		return
@@ -1074,6 +1076,7 @@ func postMultisigExportHandler(ctx reqContext, w http.ResponseWriter, r *http.Re
 		Version:   version,
 		Threshold: threshold,
 		PKs:       pks,
+		Weights:   weights,
 	}
 
 	// Return and encode the response
@@ -1127,7 +1130,7 @@ func postMultisigTransactionSignHandler(ctx reqContext, w http.ResponseWriter, r
 	}
 
 	// Sign the transaction
-	msig, err := wallet.MultisigSignTransaction(tx, req.PublicKey, req.PartialMsig, []byte(req.WalletPassword))
+	msig, err := wallet.MultisigSignTransaction(tx, req.PublicKey, req.PartialMsig, []byte(req.WalletPassword), req.Signer)
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err)
 		return
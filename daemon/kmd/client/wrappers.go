@@ -108,12 +108,13 @@ func (kcl KMDClient) ListMultisigAddrs(walletHandle []byte) (resp kmdapi.APIV1PO
 }
 
 // ImportMultisigAddr wraps kmdapi.APIV1POSTMultisigImportRequest
-func (kcl KMDClient) ImportMultisigAddr(walletHandle []byte, version, threshold uint8, pks []crypto.PublicKey) (resp kmdapi.APIV1POSTMultisigImportResponse, err error) {
+func (kcl KMDClient) ImportMultisigAddr(walletHandle []byte, version, threshold uint8, pks []crypto.PublicKey, weights []uint8) (resp kmdapi.APIV1POSTMultisigImportResponse, err error) {
 	req := kmdapi.APIV1POSTMultisigImportRequest{
 		WalletHandleToken: string(walletHandle),
 		Version:           version,
 		Threshold:         threshold,
 		PKs:               pks,
+		Weights:           weights,
 	}
 	err = kcl.DoV1Request(req, &resp)
 	return
@@ -141,13 +142,14 @@ func (kcl KMDClient) DeleteMultisigAddr(walletHandle []byte, pw []byte, addr str
 }
 
 // MultisigSignTransaction wraps kmdapi.APIV1POSTMultisigTransactionSignRequest
-func (kcl KMDClient) MultisigSignTransaction(walletHandle, pw []byte, tx []byte, pk crypto.PublicKey, partial crypto.MultisigSig) (resp kmdapi.APIV1POSTMultisigTransactionSignResponse, err error) {
+func (kcl KMDClient) MultisigSignTransaction(walletHandle, pw []byte, tx []byte, pk crypto.PublicKey, partial crypto.MultisigSig, signer crypto.Digest) (resp kmdapi.APIV1POSTMultisigTransactionSignResponse, err error) {
 	req := kmdapi.APIV1POSTMultisigTransactionSignRequest{
 		WalletHandleToken: string(walletHandle),
 		WalletPassword:    string(pw),
 		Transaction:       tx,
 		PublicKey:         pk,
 		PartialMsig:       partial,
+		Signer:            signer,
 	}
 	err = kcl.DoV1Request(req, &resp)
 	return
@@ -197,12 +199,13 @@ func (kcl KMDClient) ExportMasterDerivationKey(walletHandle []byte, walletPasswo
 }
 
 // SignTransaction wraps kmdapi.APIV1POSTTransactionSignRequest
-func (kcl KMDClient) SignTransaction(walletHandle, pw []byte, tx transactions.Transaction) (resp kmdapi.APIV1POSTTransactionSignResponse, err error) {
+func (kcl KMDClient) SignTransaction(walletHandle, pw []byte, pk crypto.PublicKey, tx transactions.Transaction) (resp kmdapi.APIV1POSTTransactionSignResponse, err error) {
 	txBytes := protocol.Encode(tx)
 	req := kmdapi.APIV1POSTTransactionSignRequest{
 		WalletHandleToken: string(walletHandle),
 		WalletPassword:    string(pw),
 		Transaction:       txBytes,
+		PublicKey:         pk,
 	}
 	err = kcl.DoV1Request(req, &resp)
 	return
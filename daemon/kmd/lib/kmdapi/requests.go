@@ -162,8 +162,10 @@ type APIV1POSTTransactionSignRequest struct {
 	APIV1RequestEnvelope
 	WalletHandleToken string `json:"wallet_handle_token"`
 	// swagger:strfmt byte
-	Transaction    []byte `json:"transaction"`
-	WalletPassword string `json:"wallet_password"`
+	Transaction []byte `json:"transaction"`
+	// PublicKey is the key to sign with, if the sender has been rekeyed
+	PublicKey      crypto.PublicKey `json:"public_key"`
+	WalletPassword string           `json:"wallet_password"`
 }
 
 // APIV1POSTProgramSignRequest is the request for `POST /v1/program/sign`
@@ -195,6 +197,7 @@ type APIV1POSTMultisigImportRequest struct {
 	Version           uint8              `json:"multisig_version"`
 	Threshold         uint8              `json:"threshold"`
 	PKs               []crypto.PublicKey `json:"pks"`
+	Weights           []uint8            `json:"weights,omitempty"`
 }
 
 // APIV1POSTMultisigExportRequest is the request for `POST /v1/multisig/export`
@@ -227,6 +230,9 @@ type APIV1POSTMultisigTransactionSignRequest struct {
 	PublicKey      crypto.PublicKey   `json:"public_key"`
 	PartialMsig    crypto.MultisigSig `json:"partial_multisig"`
 	WalletPassword string             `json:"wallet_password"`
+	// Signer is the multisig address to sign for, if the sender has been
+	// rekeyed to one
+	Signer crypto.Digest `json:"signer"`
 }
 
 // APIV1POSTMultisigProgramSignRequest is the request for `POST /v1/multisig/signprogram`
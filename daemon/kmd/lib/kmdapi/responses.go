@@ -182,6 +182,7 @@ type APIV1POSTMultisigExportResponse struct {
 	Version   uint8            `json:"multisig_version"`
 	Threshold uint8            `json:"threshold"`
 	PKs       []APIV1PublicKey `json:"pks"`
+	Weights   []uint8          `json:"weights,omitempty"`
 }
 
 // APIV1DELETEMultisigResponse is the response to POST /v1/multisig/delete`
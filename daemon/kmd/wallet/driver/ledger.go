@@ -26,6 +26,7 @@ import (
 	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/daemon/kmd/config"
 	"github.com/vincentbdb/go-algorand/daemon/kmd/wallet"
+	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/transactions"
 	"github.com/vincentbdb/go-algorand/protocol"
 )
@@ -152,17 +153,37 @@ func (lw *LedgerWallet) Metadata() (wallet.Metadata, error) {
 
 // ListKeys implements the Wallet interface.
 func (lw *LedgerWallet) ListKeys() ([]crypto.Digest, error) {
+	pk, err := lw.devicePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return []crypto.Digest{crypto.Digest(pk)}, nil
+}
+
+// devicePublicKey returns the only key the device signs with.
+func (lw *LedgerWallet) devicePublicKey() (pk crypto.PublicKey, err error) {
 	lw.mu.Lock()
 	defer lw.mu.Unlock()
 
 	reply, err := lw.dev.Exchange([]byte{ledgerClass, ledgerInsGetPublicKey, 0x00, 0x00, 0x00})
 	if err != nil {
-		return nil, err
+		return
 	}
+	copy(pk[:], reply)
+	return
+}
 
-	var addr crypto.Digest
-	copy(addr[:], reply)
-	return []crypto.Digest{addr}, nil
+// checkDeviceKey returns an error if pk is set and is not the device key,
+// since the device would sign with its own key regardless.
+func (lw *LedgerWallet) checkDeviceKey(pk crypto.PublicKey) (crypto.PublicKey, error) {
+	devicePK, err := lw.devicePublicKey()
+	if err != nil {
+		return crypto.PublicKey{}, err
+	}
+	if (pk != crypto.PublicKey{}) && pk != devicePK {
+		return crypto.PublicKey{}, errKeyNotFound
+	}
+	return devicePK, nil
 }
 
 // ImportKey implements the Wallet interface.
@@ -186,13 +207,13 @@ func (lw *LedgerWallet) DeleteKey(pk crypto.Digest, pw []byte) error {
 }
 
 // ImportMultisigAddr implements the Wallet interface.
-func (lw *LedgerWallet) ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey) (crypto.Digest, error) {
+func (lw *LedgerWallet) ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey, weights []uint8) (crypto.Digest, error) {
 	return crypto.Digest{}, errNotSupported
 }
 
 // LookupMultisigPreimage implements the Wallet interface.
-func (lw *LedgerWallet) LookupMultisigPreimage(crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, err error) {
-	return 0, 0, nil, errNotSupported
+func (lw *LedgerWallet) LookupMultisigPreimage(crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, weights []uint8, err error) {
+	return 0, 0, nil, nil, errNotSupported
 }
 
 // ListMultisigAddrs implements the Wallet interface.
@@ -206,16 +227,25 @@ func (lw *LedgerWallet) DeleteMultisigAddr(addr crypto.Digest, pw []byte) error
 }
 
 // SignTransaction implements the Wallet interface.
-func (lw *LedgerWallet) SignTransaction(tx transactions.Transaction, pw []byte) ([]byte, error) {
+func (lw *LedgerWallet) SignTransaction(tx transactions.Transaction, pk crypto.PublicKey, pw []byte) ([]byte, error) {
+	devicePK, err := lw.checkDeviceKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
 	sig, err := lw.signTransactionHelper(tx)
 	if err != nil {
 		return nil, err
 	}
 
-	return protocol.Encode(transactions.SignedTxn{
+	stx := transactions.SignedTxn{
 		Txn: tx,
 		Sig: sig,
-	}), nil
+	}
+	if basics.Address(devicePK) != tx.Sender {
+		stx.AuthAddr = basics.Address(devicePK)
+	}
+	return protocol.Encode(stx), nil
 }
 
 // SignProgram implements the Wallet interface.
@@ -229,7 +259,7 @@ func (lw *LedgerWallet) SignProgram(data []byte, src crypto.Digest, pw []byte) (
 }
 
 // MultisigSignTransaction implements the Wallet interface.
-func (lw *LedgerWallet) MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte) (crypto.MultisigSig, error) {
+func (lw *LedgerWallet) MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte, signer crypto.Digest) (crypto.MultisigSig, error) {
 	isValidKey := false
 	for i := 0; i < len(partial.Subsigs); i++ {
 		subsig := &partial.Subsigs[i]
@@ -243,6 +273,10 @@ func (lw *LedgerWallet) MultisigSignTransaction(tx transactions.Transaction, pk
 		return partial, errMsigWrongKey
 	}
 
+	if _, err := lw.checkDeviceKey(pk); err != nil {
+		return partial, err
+	}
+
 	sig, err := lw.signTransactionHelper(tx)
 	if err != nil {
 		return partial, err
@@ -273,6 +307,10 @@ func (lw *LedgerWallet) MultisigSignProgram(data []byte, src crypto.Digest, pk c
 		return partial, errMsigWrongKey
 	}
 
+	if _, err := lw.checkDeviceKey(pk); err != nil {
+		return partial, err
+	}
+
 	sig, err := lw.signProgramHelper(data)
 	if err != nil {
 		return partial, err
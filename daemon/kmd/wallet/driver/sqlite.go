@@ -32,6 +32,7 @@ import (
 	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/daemon/kmd/config"
 	"github.com/vincentbdb/go-algorand/daemon/kmd/wallet"
+	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/transactions"
 	"github.com/vincentbdb/go-algorand/data/transactions/logic"
 	"github.com/vincentbdb/go-algorand/protocol"
@@ -80,6 +81,10 @@ CREATE TABLE IF NOT EXISTS msig_addrs (
 );
 `
 
+// For weighted multisig addresses, the pks column of msig_addrs holds the
+// msgpack-encoded []crypto.MultisigSubsig (public keys with their weights)
+// rather than a []crypto.PublicKey, so that existing wallets need no migration.
+
 // SQLiteWalletDriver is the default wallet driver used by kmd. Keys are stored
 // as authenticated-encrypted blobs in a sqlite 3 database.
 type SQLiteWalletDriver struct {
@@ -976,9 +981,20 @@ func (sw *SQLiteWallet) DeleteKey(addr crypto.Digest, pw []byte) (err error) {
 }
 
 // ImportMultisigAddr imports a multisig address, taking in version, threshold,
-// and public keys
-func (sw *SQLiteWallet) ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey) (addr crypto.Digest, err error) {
-	addr, err = crypto.MultisigAddrGen(version, threshold, pks)
+// public keys, and, for weighted multisig addresses, the weight of each key
+func (sw *SQLiteWallet) ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey, weights []uint8) (addr crypto.Digest, err error) {
+	var pksBlob []byte
+	if version == crypto.WeightedMultisigVersion {
+		addr, err = crypto.MultisigAddrGenWeighted(threshold, pks, weights)
+		pksBlob = msgpackEncode(crypto.MultisigPreimageFromWeightedPKs(threshold, pks, weights).Subsigs)
+	} else {
+		if len(weights) != 0 {
+			err = errMsigWeights
+			return
+		}
+		addr, err = crypto.MultisigAddrGen(version, threshold, pks)
+		pksBlob = msgpackEncode(pks)
+	}
 	if err != nil {
 		return
 	}
@@ -991,7 +1007,7 @@ func (sw *SQLiteWallet) ImportMultisigAddr(version, threshold uint8, pks []crypt
 	}
 	defer db.Close()
 
-	_, err = db.Exec("INSERT INTO msig_addrs (address, version, threshold, pks) VALUES (?, ?, ?, ?)", addr[:], version, threshold, msgpackEncode(pks))
+	_, err = db.Exec("INSERT INTO msig_addrs (address, version, threshold, pks) VALUES (?, ?, ?, ?)", addr[:], version, threshold, pksBlob)
 	err = checkDBError(err)
 	if err != nil {
 		return
@@ -1000,8 +1016,8 @@ func (sw *SQLiteWallet) ImportMultisigAddr(version, threshold uint8, pks []crypt
 }
 
 // LookupMultisigPreimage exports the preimage of a multisig address: version,
-// threshold, public keys
-func (sw *SQLiteWallet) LookupMultisigPreimage(addr crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, err error) {
+// threshold, public keys, and weights (nil unless the address is weighted)
+func (sw *SQLiteWallet) LookupMultisigPreimage(addr crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, weights []uint8, err error) {
 	// Connect to the database
 	db, err := sqlx.Connect("sqlite3", dbConnectionURL(sw.dbPath))
 	if err != nil {
@@ -1010,7 +1026,7 @@ func (sw *SQLiteWallet) LookupMultisigPreimage(addr crypto.Digest) (version, thr
 	}
 	defer db.Close()
 
-	var pksCandidate []crypto.PublicKey
+	var subsigsCandidate []crypto.MultisigSubsig
 	var versionCandidate, thresholdCandidate int
 	var pksBlob []byte
 
@@ -1022,21 +1038,30 @@ func (sw *SQLiteWallet) LookupMultisigPreimage(addr crypto.Digest) (version, thr
 	}
 
 	// Decode the candidate
-	err = msgpackDecode(pksBlob, &pksCandidate)
-	if err != nil {
-		return
+	if uint8(versionCandidate) == crypto.WeightedMultisigVersion {
+		err = msgpackDecode(pksBlob, &subsigsCandidate)
+		if err != nil {
+			return
+		}
+	} else {
+		var pksCandidate []crypto.PublicKey
+		err = msgpackDecode(pksBlob, &pksCandidate)
+		if err != nil {
+			return
+		}
+		subsigsCandidate = crypto.MultisigPreimageFromPKs(uint8(versionCandidate), uint8(thresholdCandidate), pksCandidate).Subsigs
 	}
 
 	// Sanity check: make sure the preimage is correct
-	addr2, err := crypto.MultisigAddrGen(uint8(versionCandidate), uint8(thresholdCandidate), pksCandidate)
+	addr2, err := crypto.MultisigAddrGenWithSubsigs(uint8(versionCandidate), uint8(thresholdCandidate), subsigsCandidate)
 	if addr2 != addr {
 		err = errTampering
 		return
 	}
 
-	version = uint8(versionCandidate)
-	threshold = uint8(thresholdCandidate)
-	pks = pksCandidate
+	preimage := crypto.MultisigSig{Version: uint8(versionCandidate), Threshold: uint8(thresholdCandidate), Subsigs: subsigsCandidate}
+	version, threshold, pks = preimage.Preimage()
+	weights = preimage.Weights()
 	return
 }
 
@@ -1086,9 +1111,9 @@ func (sw *SQLiteWallet) ListMultisigAddrs() (addrs []crypto.Digest, err error) {
 	return
 }
 
-// SignTransaction signs the passed transaction, inferring the required private
-// key from the transaction itself
-func (sw *SQLiteWallet) SignTransaction(tx transactions.Transaction, pw []byte) (stx []byte, err error) {
+// SignTransaction signs the passed transaction with the key for pk. If pk is
+// zero, the required private key is inferred from the transaction itself
+func (sw *SQLiteWallet) SignTransaction(tx transactions.Transaction, pk crypto.PublicKey, pw []byte) (stx []byte, err error) {
 	// Check the password
 	err = sw.CheckPassword(pw)
 	if err != nil {
@@ -1096,7 +1121,11 @@ func (sw *SQLiteWallet) SignTransaction(tx transactions.Transaction, pw []byte)
 	}
 
 	// Fetch the required key
-	sk, err := sw.fetchSecretKey(crypto.Digest(tx.Src()))
+	signer := crypto.Digest(tx.Src())
+	if (pk != crypto.PublicKey{}) {
+		signer = publicKeyToAddress(pk)
+	}
+	sk, err := sw.fetchSecretKey(signer)
 	if err != nil {
 		return
 	}
@@ -1108,8 +1137,12 @@ func (sw *SQLiteWallet) SignTransaction(tx transactions.Transaction, pw []byte)
 		return
 	}
 
-	// Sign the transaction
-	stx = protocol.Encode(tx.Sign(secrets))
+	// Sign the transaction, noting the signer if the sender has been rekeyed
+	signedTx := tx.Sign(secrets)
+	if signer != crypto.Digest(tx.Src()) {
+		signedTx.AuthAddr = basics.Address(signer)
+	}
+	stx = protocol.Encode(signedTx)
 	return
 }
 
@@ -1143,24 +1176,35 @@ func (sw *SQLiteWallet) SignProgram(data []byte, src crypto.Digest, pw []byte) (
 
 // MultisigSignTransaction starts a multisig signature or adds a signature to a
 // partially signed multisig transaction signature of the passed transaction
-// using the key
-func (sw *SQLiteWallet) MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte) (sig crypto.MultisigSig, err error) {
+// using the key. If signer is nonzero, it is the multisig address that the
+// sender has been rekeyed to
+func (sw *SQLiteWallet) MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte, signer crypto.Digest) (sig crypto.MultisigSig, err error) {
 	// Check the password
 	err = sw.CheckPassword(pw)
 	if err != nil {
 		return
 	}
 
+	from := crypto.Digest(tx.Src())
+	if (signer != crypto.Digest{}) {
+		from = signer
+	}
+
 	if partial.Version == 0 && partial.Threshold == 0 && len(partial.Subsigs) == 0 {
 		// We weren't given a partial multisig, so create a new one
 
 		// Look up the preimage in the database
 		var pks []crypto.PublicKey
+		var weights []uint8
 		var version, threshold uint8
-		version, threshold, pks, err = sw.LookupMultisigPreimage(crypto.Digest(tx.Src()))
+		version, threshold, pks, weights, err = sw.LookupMultisigPreimage(from)
 		if err != nil {
 			return
 		}
+		preimage := crypto.MultisigPreimageFromPKs(version, threshold, pks)
+		if version == crypto.WeightedMultisigVersion {
+			preimage = crypto.MultisigPreimageFromWeightedPKs(threshold, pks, weights)
+		}
 
 		// Fetch the required secret key
 		var sk crypto.PrivateKey
@@ -1178,8 +1222,7 @@ func (sw *SQLiteWallet) MultisigSignTransaction(tx transactions.Transaction, pk
 		}
 
 		// Sign the transaction
-		from := crypto.Digest(tx.Src())
-		sig, err = crypto.MultisigSign(tx, from, version, threshold, pks, *secrets)
+		sig, err = crypto.MultisigSignPreimage(tx, from, preimage, *secrets)
 		return
 	}
 
@@ -1191,7 +1234,7 @@ func (sw *SQLiteWallet) MultisigSignTransaction(tx transactions.Transaction, pk
 	if err != nil {
 		return
 	}
-	if addr != crypto.Digest(tx.Src()) {
+	if addr != from {
 		err = errMsigWrongAddr
 		return
 	}
@@ -1221,8 +1264,7 @@ func (sw *SQLiteWallet) MultisigSignTransaction(tx transactions.Transaction, pk
 	}
 
 	// Sign the transaction, and merge the multisig into the partial
-	version, threshold, pks := partial.Preimage()
-	msig2, err := crypto.MultisigSign(tx, addr, version, threshold, pks, *secrets)
+	msig2, err := crypto.MultisigSignPreimage(tx, addr, partial, *secrets)
 	if err != nil {
 		return
 	}
@@ -1245,11 +1287,16 @@ func (sw *SQLiteWallet) MultisigSignProgram(data []byte, src crypto.Digest, pk c
 
 		// Look up the preimage in the database
 		var pks []crypto.PublicKey
+		var weights []uint8
 		var version, threshold uint8
-		version, threshold, pks, err = sw.LookupMultisigPreimage(src)
+		version, threshold, pks, weights, err = sw.LookupMultisigPreimage(src)
 		if err != nil {
 			return
 		}
+		preimage := crypto.MultisigPreimageFromPKs(version, threshold, pks)
+		if version == crypto.WeightedMultisigVersion {
+			preimage = crypto.MultisigPreimageFromWeightedPKs(threshold, pks, weights)
+		}
 
 		// Fetch the required secret key
 		var sk crypto.PrivateKey
@@ -1269,7 +1316,7 @@ func (sw *SQLiteWallet) MultisigSignProgram(data []byte, src crypto.Digest, pk c
 		// Sign the transaction
 		from := src
 		progb := logic.Program(data)
-		sig, err = crypto.MultisigSign(&progb, from, version, threshold, pks, *secrets)
+		sig, err = crypto.MultisigSignPreimage(&progb, from, preimage, *secrets)
 		return
 	}
 
@@ -1311,9 +1358,8 @@ func (sw *SQLiteWallet) MultisigSignProgram(data []byte, src crypto.Digest, pk c
 	}
 
 	// Sign the transaction, and merge the multisig into the partial
-	version, threshold, pks := partial.Preimage()
 	progb := logic.Program(data)
-	msig2, err := crypto.MultisigSign(&progb, addr, version, threshold, pks, *secrets)
+	msig2, err := crypto.MultisigSignPreimage(&progb, addr, partial, *secrets)
 	if err != nil {
 		return
 	}
@@ -44,3 +44,4 @@ var errNameTooLong = fmt.Errorf("wallet name too long, must be <= %d bytes", sql
 var errIDTooLong = fmt.Errorf("wallet id too long, must be <= %d bytes", sqliteMaxWalletIDLen)
 var errMsigWrongAddr = fmt.Errorf("given multisig preimage hashes to wrong address")
 var errMsigWrongKey = fmt.Errorf("given key is not a possible signer for this multisig")
+var errMsigWeights = fmt.Errorf("only weighted multisig addresses may have key weights")
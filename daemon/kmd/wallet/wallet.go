@@ -46,14 +46,14 @@ type Wallet interface {
 	GenerateKey(displayMnemonic bool) (crypto.Digest, error)
 	DeleteKey(pk crypto.Digest, pw []byte) error
 
-	ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey) (crypto.Digest, error)
-	LookupMultisigPreimage(crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, err error)
+	ImportMultisigAddr(version, threshold uint8, pks []crypto.PublicKey, weights []uint8) (crypto.Digest, error)
+	LookupMultisigPreimage(crypto.Digest) (version, threshold uint8, pks []crypto.PublicKey, weights []uint8, err error)
 	ListMultisigAddrs() (addrs []crypto.Digest, err error)
 	DeleteMultisigAddr(addr crypto.Digest, pw []byte) error
 
-	SignTransaction(tx transactions.Transaction, pw []byte) ([]byte, error)
+	SignTransaction(tx transactions.Transaction, pk crypto.PublicKey, pw []byte) ([]byte, error)
 
-	MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte) (crypto.MultisigSig, error)
+	MultisigSignTransaction(tx transactions.Transaction, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte, signer crypto.Digest) (crypto.MultisigSig, error)
 
 	SignProgram(program []byte, src crypto.Digest, pw []byte) ([]byte, error)
 	MultisigSignProgram(program []byte, src crypto.Digest, pk crypto.PublicKey, partial crypto.MultisigSig, pw []byte) (crypto.MultisigSig, error)
@@ -22,6 +22,7 @@ import (
 	"github.com/algorand/go-deadlock"
 
 	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/logging"
@@ -120,13 +121,15 @@ func (manager *AccountManager) AddParticipation(participation account.Participat
 }
 
 // DeleteOldKeys deletes all accounts' ephemeral keys strictly older than the
-// current round.
-func (manager *AccountManager) DeleteOldKeys(current basics.Round, proto config.ConsensusParams) {
+// current round.  Keys that have expired by the current round are no longer
+// managed.  It returns the intervals of those keys.
+func (manager *AccountManager) DeleteOldKeys(current basics.Round, proto config.ConsensusParams) (expired []account.ParticipationInterval) {
 	manager.mu.Lock()
 	pendingItems := make(map[string]<-chan error, len(manager.partIntervals))
+	var expiredParts []account.Participation
 	func() {
 		defer manager.mu.Unlock()
-		for _, part := range manager.partIntervals {
+		for interval, part := range manager.partIntervals {
 			// we pre-create the reported error string here, so that we won't need to have the participation key object if error is detected.
 			first, last := part.ValidInterval()
 			errString := fmt.Sprintf("AccountManager.DeleteOldKeys(%d): key for %s (%d-%d)",
@@ -134,6 +137,12 @@ func (manager *AccountManager) DeleteOldKeys(current basics.Round, proto config.
 			errCh := part.DeleteOldKeys(current, proto)
 
 			pendingItems[errString] = errCh
+
+			if interval.LastValid < current {
+				expiredParts = append(expiredParts, part)
+				delete(manager.partIntervals, interval)
+				expired = append(expired, interval)
+			}
 		}
 	}()
 
@@ -144,4 +153,69 @@ func (manager *AccountManager) DeleteOldKeys(current basics.Round, proto config.
 			logging.Base().Warnf("%s: %v", errString, err)
 		}
 	}
+
+	for _, part := range expiredParts {
+		part.Close()
+	}
+	return expired
+}
+
+// DeleteSupersededKeys deletes the keys that an account has rolled over
+// from.  registered returns the voting key that the ledger registers for an
+// account in the next round that agreement votes in.  Once that is a key
+// of the account that became valid after some of its other keys, agreement
+// no longer votes with those older keys, so their secrets are erased and
+// they are no longer managed.  It returns the intervals of the deleted keys.
+func (manager *AccountManager) DeleteSupersededKeys(proto config.ConsensusParams, registered func(addr basics.Address) (crypto.OneTimeSignatureVerifier, error)) (deleted []account.ParticipationInterval) {
+	manager.mu.Lock()
+	byAddress := make(map[basics.Address][]account.ParticipationInterval)
+	for interval := range manager.partIntervals {
+		byAddress[interval.Address] = append(byAddress[interval.Address], interval)
+	}
+
+	var superseded []account.Participation
+	func() {
+		defer manager.mu.Unlock()
+		for addr, intervals := range byAddress {
+			if len(intervals) < 2 {
+				continue
+			}
+
+			voteID, err := registered(addr)
+			if err != nil {
+				manager.log.Warnf("AccountManager.DeleteSupersededKeys: cannot look up registered key for %v: %v", addr, err)
+				continue
+			}
+
+			var current *account.ParticipationInterval
+			for i, interval := range intervals {
+				part := manager.partIntervals[interval]
+				if part.Voting != nil && part.Voting.OneTimeSignatureVerifier == voteID {
+					current = &intervals[i]
+					break
+				}
+			}
+			if current == nil {
+				continue
+			}
+
+			for _, interval := range intervals {
+				if interval.FirstValid < current.FirstValid {
+					superseded = append(superseded, manager.partIntervals[interval])
+					delete(manager.partIntervals, interval)
+					deleted = append(deleted, interval)
+				}
+			}
+		}
+	}()
+
+	for _, part := range superseded {
+		first, last := part.ValidInterval()
+		err := <-part.DeleteOldKeys(last+1, proto)
+		if err != nil {
+			manager.log.Warnf("AccountManager.DeleteSupersededKeys: key for %s (%d-%d): %v", part.Address().String(), first, last, err)
+		}
+		part.Close()
+	}
+	return deleted
 }
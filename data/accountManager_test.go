@@ -0,0 +1,121 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package data
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/account"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/db"
+)
+
+func TestDeleteSupersededKeys(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	manager := MakeAccountManager(logging.Base())
+
+	makeKey := func(addr basics.Address, first, last basics.Round) account.Participation {
+		store, err := db.MakeAccessor(fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64()), false, true)
+		require.NoError(t, err)
+		part, err := account.FillDBWithParticipationKeys(store, addr, first, last, proto.DefaultKeyDilution)
+		require.NoError(t, err)
+		require.True(t, manager.AddParticipation(part))
+		return part
+	}
+
+	var addr, other basics.Address
+	addr[0] = 1
+	other[0] = 2
+	oldKey := makeKey(addr, 0, 1000)
+	newKey := makeKey(addr, 900, 2000)
+	otherKey := makeKey(other, 0, 2000)
+	defer newKey.Close()
+	defer otherKey.Close()
+
+	registered := map[basics.Address]crypto.OneTimeSignatureVerifier{
+		addr:  oldKey.Voting.OneTimeSignatureVerifier,
+		other: otherKey.Voting.OneTimeSignatureVerifier,
+	}
+	lookup := func(addr basics.Address) (crypto.OneTimeSignatureVerifier, error) {
+		return registered[addr], nil
+	}
+
+	// While the old key is registered, both keys are kept.
+	require.Empty(t, manager.DeleteSupersededKeys(proto, lookup))
+	require.Len(t, manager.Keys(), 3)
+
+	// Keys that cannot be checked against the ledger are kept too.
+	registered[addr] = newKey.Voting.OneTimeSignatureVerifier
+	deleted := manager.DeleteSupersededKeys(proto, func(basics.Address) (crypto.OneTimeSignatureVerifier, error) {
+		return crypto.OneTimeSignatureVerifier{}, fmt.Errorf("no ledger")
+	})
+	require.Empty(t, deleted)
+
+	// Once the new key is registered, the old one is deleted.
+	deleted = manager.DeleteSupersededKeys(proto, lookup)
+	require.Equal(t, []account.ParticipationInterval{{Address: addr, FirstValid: 0, LastValid: 1000}}, deleted)
+	require.ElementsMatch(t, []account.ParticipationInterval{
+		{Address: addr, FirstValid: 900, LastValid: 2000},
+		{Address: other, FirstValid: 0, LastValid: 2000},
+	}, intervalsOf(manager.Keys()))
+
+	require.Empty(t, manager.DeleteSupersededKeys(proto, lookup))
+}
+
+func TestDeleteOldKeysExpired(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	manager := MakeAccountManager(logging.Base())
+
+	var addr basics.Address
+	addr[0] = 1
+	for _, interval := range [][2]basics.Round{{0, 100}, {50, 2000}} {
+		store, err := db.MakeAccessor(fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64()), false, true)
+		require.NoError(t, err)
+		part, err := account.FillDBWithParticipationKeys(store, addr, interval[0], interval[1], proto.DefaultKeyDilution)
+		require.NoError(t, err)
+		require.True(t, manager.AddParticipation(part))
+	}
+
+	// A key is kept through its last valid round.
+	require.Empty(t, manager.DeleteOldKeys(100, proto))
+	require.Len(t, manager.Keys(), 2)
+
+	// After that, it is no longer managed.
+	expired := manager.DeleteOldKeys(101, proto)
+	require.Equal(t, []account.ParticipationInterval{{Address: addr, FirstValid: 0, LastValid: 100}}, expired)
+	require.Equal(t, []account.ParticipationInterval{{Address: addr, FirstValid: 50, LastValid: 2000}}, intervalsOf(manager.Keys()))
+	require.Empty(t, manager.DeleteOldKeys(101, proto))
+
+	for _, part := range manager.Keys() {
+		part.Close()
+	}
+}
+
+func intervalsOf(parts []account.Participation) (out []account.ParticipationInterval) {
+	for _, part := range parts {
+		first, last := part.ValidInterval()
+		out = append(out, account.ParticipationInterval{Address: part.Address(), FirstValid: first, LastValid: last})
+	}
+	return
+}
@@ -59,6 +59,15 @@ func OMul(a uint64, b uint64) (res uint64, overflowed bool) {
 	return c, false
 }
 
+// AddSaturate adds 2 values with saturation on overflow
+func AddSaturate(a uint64, b uint64) uint64 {
+	res, overflowed := OAdd(a, b)
+	if overflowed {
+		return math.MaxUint64
+	}
+	return res
+}
+
 // MulSaturate multiplies 2 values with saturation on overflow
 func MulSaturate(a uint64, b uint64) uint64 {
 	res, overflowed := OMul(a, b)
@@ -136,6 +136,11 @@ type AccountData struct {
 	// structs; allocate a copy and modify that instead.  AccountData
 	// is expected to have copy-by-value semantics.
 	Assets map[AssetIndex]AssetHolding `codec:"asset"`
+
+	// AuthAddr is the address whose key authorizes transactions sent
+	// from this account, if it has been rekeyed.  If it is zero, the
+	// account's own key (or multisig preimage, or program) does.
+	AuthAddr Address `codec:"spend"`
 }
 
 // AccountDetail encapsulates meaningful details about a given account, for external consumption
@@ -172,6 +177,13 @@ type AssetLocator struct {
 	Index   AssetIndex
 }
 
+// AssetHolder stores an account that has opted in to an asset, along with
+// its holding of that asset
+type AssetHolder struct {
+	Address Address
+	Holding AssetHolding
+}
+
 // AssetHolding describes an asset held by an account.
 type AssetHolding struct {
 	Amount uint64 `codec:"a"`
@@ -186,6 +198,12 @@ type AssetParams struct {
 	// created.
 	Total uint64 `codec:"t"`
 
+	// Decimals specifies the number of digits to display after the decimal
+	// place when displaying this asset. A value of 0 represents an asset
+	// that is not divisible, a value of 1 represents an asset divisible
+	// into tenths, and so on.
+	Decimals uint32 `codec:"dc"`
+
 	// DefaultFrozen specifies whether slots for this asset
 	// in user accounts are frozen by default or not.
 	DefaultFrozen bool `codec:"df"`
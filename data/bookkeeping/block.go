@@ -121,6 +121,13 @@ type (
 		// transactions have ever been committed (since TxnCounter
 		// started being supported).
 		TxnCounter uint64 `codec:"tc"`
+
+		// AccountStateRoot is the root of a Merkle trie mapping every
+		// account's address to the hash of its AccountData (without
+		// pending rewards) at the end of this block.  It lets clients
+		// verify a single account's state against a block header.  It is
+		// zero when the consensus protocol does not support this feature.
+		AccountStateRoot crypto.Digest `codec:"asr"`
 	}
 
 	// RewardsState represents the global parameters controlling the rate
@@ -352,6 +359,14 @@ func (s UpgradeState) applyUpgradeVote(r basics.Round, vote UpgradeVote) (res Up
 
 // MakeBlock constructs a new valid block with an empty payset and an unset Seed.
 func MakeBlock(prev BlockHeader) Block {
+	return MakeBlockWithUpgrade(prev, "")
+}
+
+// MakeBlockWithUpgrade is like MakeBlock, but if upgrade is not empty, the
+// block also proposes and approves an upgrade to that protocol version, as
+// though the current protocol listed it in its ApprovedUpgrades.  This lets
+// private networks rehearse upgrades that are not yet approved.
+func MakeBlockWithUpgrade(prev BlockHeader, upgrade protocol.ConsensusVersion) Block {
 	round := prev.Round + 1
 
 	// Find parameters for current protocol; panic if not supported
@@ -362,21 +377,29 @@ func MakeBlock(prev BlockHeader) Block {
 
 	// Decide on the votes for protocol upgrades
 	upgradeVote := UpgradeVote{}
+	if upgrade == prev.CurrentProtocol {
+		upgrade = ""
+	}
 
 	// If there is no upgrade proposal, see if we can make one
 	if prev.NextProtocol == "" {
-		for k, v := range prevParams.ApprovedUpgrades {
-			if v {
-				upgradeVote.UpgradePropose = k
-				upgradeVote.UpgradeApprove = true
-				break
+		if upgrade != "" {
+			upgradeVote.UpgradePropose = upgrade
+			upgradeVote.UpgradeApprove = true
+		} else {
+			for k, v := range prevParams.ApprovedUpgrades {
+				if v {
+					upgradeVote.UpgradePropose = k
+					upgradeVote.UpgradeApprove = true
+					break
+				}
 			}
 		}
 	}
 
 	// If there is a proposal being voted on, see if we approve it
 	if round < prev.NextProtocolVoteBefore {
-		if prevParams.ApprovedUpgrades[prev.NextProtocol] {
+		if prevParams.ApprovedUpgrades[prev.NextProtocol] || (upgrade != "" && prev.NextProtocol == upgrade) {
 			upgradeVote.UpgradeApprove = true
 		}
 	}
@@ -135,6 +135,38 @@ func TestMakeBlockUpgrades(t *testing.T) {
 	require.Equal(t, b3.UpgradeApprove, false)
 }
 
+func TestMakeBlockWithUpgrade(t *testing.T) {
+	var b Block
+	b.CurrentProtocol = proto2
+	b.BlockHeader.GenesisID = "test"
+	crypto.RandBytes(b.BlockHeader.GenesisHash[:])
+
+	// proto2 approves no upgrades, so MakeBlock does not propose one.
+	b1 := MakeBlock(b.BlockHeader)
+	require.NoError(t, b1.PreCheck(b.BlockHeader))
+	require.Equal(t, protocol.ConsensusVersion(""), b1.UpgradePropose)
+
+	// Voting for the current protocol is a no-op.
+	b1 = MakeBlockWithUpgrade(b.BlockHeader, proto2)
+	require.Equal(t, protocol.ConsensusVersion(""), b1.UpgradePropose)
+
+	b1 = MakeBlockWithUpgrade(b.BlockHeader, proto1)
+	require.NoError(t, b1.PreCheck(b.BlockHeader))
+	require.Equal(t, proto1, b1.UpgradePropose)
+	require.True(t, b1.UpgradeApprove)
+	require.Equal(t, proto1, b1.NextProtocol)
+
+	// The node keeps approving the proposal it votes for.
+	b2 := MakeBlockWithUpgrade(b1.BlockHeader, proto1)
+	require.NoError(t, b2.PreCheck(b1.BlockHeader))
+	require.Equal(t, protocol.ConsensusVersion(""), b2.UpgradePropose)
+	require.True(t, b2.UpgradeApprove)
+
+	// But not other proposals, or this one without the override.
+	require.False(t, MakeBlockWithUpgrade(b1.BlockHeader, proto3).UpgradeApprove)
+	require.False(t, MakeBlock(b1.BlockHeader).UpgradeApprove)
+}
+
 func TestBlockUnsupported(t *testing.T) {
 	var b Block
 	b.CurrentProtocol = protoUnsupported
@@ -17,8 +17,12 @@
 package bookkeeping
 
 import (
+	"fmt"
 	"io/ioutil"
+	"time"
 
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/protocol"
 )
@@ -60,6 +64,13 @@ type Genesis struct {
 
 	// Arbitrary genesis comment string - will be excluded from file if empty
 	Comment string `codec:"comment"`
+
+	// ProtoParamsHash is the hash of the consensus parameters of Proto,
+	// and of every version defined or overridden in consensus.json, that
+	// the genesis was created with.  It is only recorded for private
+	// networks that override consensus parameters, whose nodes check
+	// that their parameters match before joining.
+	ProtoParamsHash crypto.Digest `codec:"protohash"`
 }
 
 // LoadGenesisFromFile attempts to load a Genesis structure from a (presumably) genesis.json file.
@@ -84,14 +95,135 @@ func (genesis Genesis) ID() string {
 // an address in the genesis block.  Address is the checksummed
 // short address.  Comment is a note about what this address is
 // representing, and is purely informational.  State is the initial
-// account state.
+// account state.  Vesting, if set, describes the escrow program
+// that locks the allocation.
 type GenesisAllocation struct {
 	Address string             `codec:"addr"`
 	Comment string             `codec:"comment"`
 	State   basics.AccountData `codec:"state"`
+	Vesting *VestingSchedule   `codec:"vesting,omitempty"`
+}
+
+// A VestingSchedule describes a genesis allocation held by a logic-sig
+// escrow compiled from the vesting template.  The escrow releases
+// CliffAmount to Beneficiary at round Cliff, then PeriodAmount at every
+// later multiple of Period, and lets Beneficiary close it out after round
+// End.  Each release can only be withdrawn within Duration rounds of the
+// round it is due, so a release that is missed stays locked until End.
+type VestingSchedule struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Beneficiary  string       `codec:"rcv"`
+	Cliff        basics.Round `codec:"cliff"`
+	CliffAmount  uint64       `codec:"cliffamt"`
+	Period       uint64       `codec:"period"`
+	PeriodAmount uint64       `codec:"amt"`
+	Duration     uint64       `codec:"dur"`
+	End          basics.Round `codec:"end"`
+
+	// Program is the escrow's logic, which withdrawals must be signed with.
+	Program []byte `codec:"prog"`
+}
+
+// Unlocked returns how much the escrow lets Beneficiary withdraw in round
+// rnd, when it holds held: the releases whose withdrawal windows are open
+// in round rnd, or all of held after End.
+func (v VestingSchedule) Unlocked(rnd basics.Round, held uint64) uint64 {
+	if rnd > v.End {
+		return held
+	}
+
+	var unlocked uint64
+	if rnd >= v.Cliff && rnd <= v.Cliff+basics.Round(v.Duration) {
+		unlocked += v.CliffAmount
+	}
+	if v.Period != 0 {
+		due := rnd - rnd%basics.Round(v.Period)
+		if due > v.Cliff && rnd <= due+basics.Round(v.Duration) {
+			unlocked += v.PeriodAmount
+		}
+	}
+	if unlocked > held {
+		return held
+	}
+	return unlocked
 }
 
 // ToBeHashed impements the crypto.Hashable interface.
 func (genesis Genesis) ToBeHashed() (protocol.HashID, []byte) {
 	return protocol.Genesis, protocol.Encode(genesis)
 }
+
+// protoParams holds the consensus parameters that a network depends on,
+// so that they can be hashed.
+type protoParams struct {
+	Versions map[protocol.ConsensusVersion]config.ConsensusParams
+
+	// SmallLambda and BigLambda are the agreement timing set in
+	// consensus.json, or zero.
+	SmallLambda time.Duration
+	BigLambda   time.Duration
+}
+
+// ToBeHashed implements the crypto.Hashable interface.
+func (p protoParams) ToBeHashed() (protocol.HashID, []byte) {
+	return protocol.ConsensusParams, protocol.Encode(p)
+}
+
+// ProtoParamsHash returns the hash of the consensus parameters that a
+// network with genesis protocol proto depends on, as recorded in
+// Genesis.ProtoParamsHash: those of proto, and of every version defined or
+// overridden in consensus.json, including the upgrades between them, and
+// the agreement timing set there.  Upgrades to other versions are left out,
+// since new releases approve upgrades from existing versions.
+func ProtoParamsHash(proto protocol.ConsensusVersion) crypto.Digest {
+	set := make(map[protocol.ConsensusVersion]config.ConsensusParams)
+	for _, version := range append(config.OverriddenConsensusVersions(), proto) {
+		if params, ok := config.Consensus[version]; ok {
+			set[version] = params
+		}
+	}
+
+	for version, params := range set {
+		var approved map[protocol.ConsensusVersion]bool
+		for to, ok := range params.ApprovedUpgrades {
+			if _, in := set[to]; in {
+				if approved == nil {
+					approved = make(map[protocol.ConsensusVersion]bool)
+				}
+				approved[to] = ok
+			}
+		}
+		params.ApprovedUpgrades = approved
+		set[version] = params
+	}
+
+	smallLambda, bigLambda := config.ConsensusTimingOverrides()
+	return crypto.HashObj(protoParams{Versions: set, SmallLambda: smallLambda, BigLambda: bigLambda})
+}
+
+// CheckProtoParams returns an error if this node does not support the
+// genesis protocol, or if its consensus parameters differ from those the
+// genesis records.  Consensus parameters may only be overridden on private
+// networks whose genesis records them; a genesis that records none runs on
+// the stock protocols of each release.
+func (genesis Genesis) CheckProtoParams() error {
+	if _, ok := config.Consensus[genesis.Proto]; !ok {
+		return fmt.Errorf("genesis protocol %s is not supported", genesis.Proto)
+	}
+	if config.ConsensusOverridden() {
+		if config.IsPublicNetwork(genesis.Network) {
+			return fmt.Errorf("%s may not override consensus parameters on public network %s", config.ConsensusFilename, genesis.Network)
+		}
+		if genesis.ProtoParamsHash.IsZero() {
+			return fmt.Errorf("the genesis does not record consensus parameters, so %s may not override them", config.ConsensusFilename)
+		}
+	}
+	if genesis.ProtoParamsHash.IsZero() {
+		return nil
+	}
+	if hash := ProtoParamsHash(genesis.Proto); hash != genesis.ProtoParamsHash {
+		return fmt.Errorf("consensus parameters have hash %v, but the network was created with %v; check that %s matches the network's", hash, genesis.ProtoParamsHash, config.ConsensusFilename)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package bookkeeping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+func TestCheckProtoParams(t *testing.T) {
+	current := config.Consensus[protocol.ConsensusCurrentVersion]
+	custom := protocol.ConsensusVersion("test-check-proto-params")
+	smallLambda := config.Protocol.SmallLambda
+	defer func() {
+		config.Consensus[protocol.ConsensusCurrentVersion] = current
+		delete(config.Consensus, custom)
+		config.Protocol.SmallLambda = smallLambda
+	}()
+
+	genesis := Genesis{
+		SchemaID: "test",
+		Network:  "privnet",
+		Proto:    protocol.ConsensusCurrentVersion,
+	}
+
+	// Recording no hash keeps the genesis hash of existing networks.
+	hash := crypto.HashObj(genesis)
+	require.NoError(t, genesis.CheckProtoParams())
+
+	genesis.ProtoParamsHash = ProtoParamsHash(genesis.Proto)
+	require.NotEqual(t, hash, crypto.HashObj(genesis))
+	require.NoError(t, genesis.CheckProtoParams())
+
+	// Approving upgrades to other versions does not change the hash.
+	params := current
+	params.ApprovedUpgrades = map[protocol.ConsensusVersion]bool{"other": true}
+	config.Consensus[protocol.ConsensusCurrentVersion] = params
+	require.Equal(t, genesis.ProtoParamsHash, ProtoParamsHash(genesis.Proto))
+
+	// A recorded hash must match even without overrides: a node missing
+	// the network's consensus.json would evaluate with stock parameters.
+	params.MaxTxnLife++
+	config.Consensus[protocol.ConsensusCurrentVersion] = params
+	require.NotEqual(t, genesis.ProtoParamsHash, ProtoParamsHash(genesis.Proto))
+	require.False(t, config.ConsensusOverridden())
+	require.Error(t, genesis.CheckProtoParams())
+
+	// Networks that record no hash run whatever stock protocol the
+	// release defines.
+	stock := genesis
+	stock.ProtoParamsHash = crypto.Digest{}
+	require.NoError(t, stock.CheckProtoParams())
+	config.Consensus[protocol.ConsensusCurrentVersion] = current
+
+	// Versions defined in consensus.json, such as upgrade targets, are
+	// covered too.
+	require.NoError(t, config.ApplyConsensusOverrides([]byte(`{
+		"Protocols": {
+			"`+string(protocol.ConsensusCurrentVersion)+`": {"ApprovedUpgrades": {"test-check-proto-params": true}},
+			"test-check-proto-params": {"MaxTxnLife": 100}
+		}
+	}`)))
+	require.Error(t, genesis.CheckProtoParams())
+	genesis.ProtoParamsHash = ProtoParamsHash(genesis.Proto)
+	require.NoError(t, genesis.CheckProtoParams())
+
+	upgraded := config.Consensus[custom]
+	upgraded.MaxTxnLife++
+	config.Consensus[custom] = upgraded
+	require.Error(t, genesis.CheckProtoParams())
+	upgraded.MaxTxnLife--
+	config.Consensus[custom] = upgraded
+	require.NoError(t, genesis.CheckProtoParams())
+
+	// So is the agreement timing.
+	require.NoError(t, config.ApplyConsensusOverrides([]byte(`{"SmallLambdaMsec": 500}`)))
+	require.Error(t, genesis.CheckProtoParams())
+	genesis.ProtoParamsHash = ProtoParamsHash(genesis.Proto)
+	require.NoError(t, genesis.CheckProtoParams())
+
+	// Overrides are refused on public networks, and on networks whose
+	// genesis does not record the parameters.
+	public := genesis
+	public.Network = config.Testnet
+	require.Error(t, public.CheckProtoParams())
+	genesis.ProtoParamsHash = crypto.Digest{}
+	require.Error(t, genesis.CheckProtoParams())
+
+	genesis.Proto = "unknown"
+	require.Error(t, genesis.CheckProtoParams())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/protocol"
 	"github.com/vincentbdb/go-algorand/util/db"
@@ -107,7 +108,7 @@ func testingenv(t testing.TB, numAccounts, numTxs int, offlineAccounts bool) (*L
 	// generate test transactions
 	const inMem = true
 	const archival = true
-	ledger, err := LoadLedger(logging.Base(), t.Name(), inMem, protocol.ConsensusCurrentVersion, bootstrap, genesisID, genesisHash, nil, archival)
+	ledger, err := LoadLedger(logging.Base(), t.Name(), inMem, protocol.ConsensusCurrentVersion, bootstrap, genesisID, genesisHash, nil, archival, false, ledger.StorageSQLite)
 	if err != nil {
 		panic(err)
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data"
 	"github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/protocol"
 )
@@ -35,7 +36,7 @@ var roundDeadline = 0 * time.Second
 func FabricateLedger(log logging.Logger, ledgerName string, accounts []account.Participation, genesis data.GenesisBalances, lastRound basics.Round) (*data.Ledger, error) {
 	const inMem = true
 	const archival = true
-	ledger, err := data.LoadLedger(log, ledgerName, inMem, protocol.ConsensusCurrentVersion, genesis, "", crypto.Digest{}, nil, archival)
+	ledger, err := data.LoadLedger(log, ledgerName, inMem, protocol.ConsensusCurrentVersion, genesis, "", crypto.Digest{}, nil, archival, false, ledger.StorageSQLite)
 	if err != nil {
 		return nil, err
 	}
@@ -17,9 +17,11 @@
 package data
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 )
 
 // GenesisBalances contains the information needed to generate a new ledger
@@ -39,3 +41,33 @@ func MakeGenesisBalances(balances map[basics.Address]basics.AccountData, feeSink
 func MakeTimestampedGenesisBalances(balances map[basics.Address]basics.AccountData, feeSink, rewardsPool basics.Address, timestamp int64) GenesisBalances {
 	return GenesisBalances{balances: balances, feeSink: feeSink, rewardsPool: rewardsPool, timestamp: timestamp}
 }
+
+// LoadGenesisBalances returns the allocation, fee sink and rewards pool recorded in a genesis file
+func LoadGenesisBalances(genesis bookkeeping.Genesis) (GenesisBalances, error) {
+	balances := make(map[basics.Address]basics.AccountData)
+	for _, entry := range genesis.Allocation {
+		addr, err := basics.UnmarshalChecksumAddress(entry.Address)
+		if err != nil {
+			return GenesisBalances{}, fmt.Errorf("cannot parse genesis addr %s: %v", entry.Address, err)
+		}
+
+		_, present := balances[addr]
+		if present {
+			return GenesisBalances{}, fmt.Errorf("repeated allocation to %s", entry.Address)
+		}
+
+		balances[addr] = entry.State
+	}
+
+	feeSink, err := basics.UnmarshalChecksumAddress(genesis.FeeSink)
+	if err != nil {
+		return GenesisBalances{}, fmt.Errorf("cannot parse fee sink addr %s: %v", genesis.FeeSink, err)
+	}
+
+	rewardsPool, err := basics.UnmarshalChecksumAddress(genesis.RewardsPool)
+	if err != nil {
+		return GenesisBalances{}, fmt.Errorf("cannot parse rewards pool addr %s: %v", genesis.RewardsPool, err)
+	}
+
+	return MakeTimestampedGenesisBalances(balances, feeSink, rewardsPool, genesis.Timestamp), nil
+}
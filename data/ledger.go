@@ -90,19 +90,17 @@ func makeGenesisBlock(proto protocol.ConsensusVersion, genesisBal GenesisBalance
 	return blk, nil
 }
 
-// LoadLedger creates a Ledger object to represent the ledger with the
-// specified database file prefix, initializing it if necessary.
-func LoadLedger(
-	log logging.Logger, dbFilenamePrefix string, memory bool,
+// MakeGenesisInitState returns the genesis block and accounts that
+// LoadLedger initializes a new ledger with.
+func MakeGenesisInitState(
 	genesisProto protocol.ConsensusVersion, genesisBal GenesisBalances, genesisID string, genesisHash crypto.Digest,
-	blockListeners []ledger.BlockListener, isArchival bool,
-) (*Ledger, error) {
+) (ledger.InitState, error) {
 	if genesisBal.balances == nil {
 		genesisBal.balances = make(map[basics.Address]basics.AccountData)
 	}
 	genBlock, err := makeGenesisBlock(genesisProto, genesisBal, genesisID, genesisHash)
 	if err != nil {
-		return nil, err
+		return ledger.InitState{}, err
 	}
 
 	params := config.Consensus[genesisProto]
@@ -113,17 +111,33 @@ func LoadLedger(
 		genesisBal.balances[sinkAddr] = sinkData
 	}
 
-	l := &Ledger{
-		log: log,
-	}
-	genesisInitState := ledger.InitState{
+	return ledger.InitState{
 		Block:       genBlock,
 		Accounts:    genesisBal.balances,
 		GenesisHash: genesisHash,
+	}, nil
+}
+
+// LoadLedger creates a Ledger object to represent the ledger with the
+// specified database file prefix, initializing it if necessary.  The
+// ledger is kept in the given storage engine, and keeps account history
+// if accountHistory is set.
+func LoadLedger(
+	log logging.Logger, dbFilenamePrefix string, memory bool,
+	genesisProto protocol.ConsensusVersion, genesisBal GenesisBalances, genesisID string, genesisHash crypto.Digest,
+	blockListeners []ledger.BlockListener, isArchival bool, accountHistory bool, storage ledger.StorageEngine,
+) (*Ledger, error) {
+	genesisInitState, err := MakeGenesisInitState(genesisProto, genesisBal, genesisID, genesisHash)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Ledger{
+		log: log,
 	}
 	l.log.Debugf("Initializing Ledger(%s)", dbFilenamePrefix)
 
-	ll, err := ledger.OpenLedger(log, dbFilenamePrefix, memory, genesisInitState, isArchival)
+	ll, err := ledger.OpenLedgerWithStorage(log, dbFilenamePrefix, memory, genesisInitState, isArchival, accountHistory, storage)
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +30,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 	"github.com/vincentbdb/go-algorand/data/pools"
 	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/logging/telemetryspec"
 	"github.com/vincentbdb/go-algorand/protocol"
@@ -95,7 +96,7 @@ func BenchmarkAssemblePayset(b *testing.B) {
 	ledgerName := fmt.Sprintf("%s-mem-%d", b.Name(), b.N)
 	const inMem = true
 	const archival = true
-	ledger, err := LoadLedger(log, ledgerName, inMem, protocol.ConsensusCurrentVersion, genBal, genesisID, genesisHash, nil, archival)
+	ledger, err := LoadLedger(log, ledgerName, inMem, protocol.ConsensusCurrentVersion, genBal, genesisID, genesisHash, nil, archival, false, ledger.StorageSQLite)
 	require.NoError(b, err)
 
 	l := ledger
@@ -245,6 +245,24 @@ func (pool *TransactionPool) checkSufficientFee(txgroup []transactions.SignedTxn
 		feePerByte *= pool.expFeeFactor
 	}
 
+	// With fee pooling, the group only needs to pay the sum of its
+	// transactions' thresholds, in whatever way it splits its fees.
+	proto := config.Consensus[pool.pendingBlockEvaluator.ConsensusVersion()]
+	if proto.EnableFeePooling {
+		var totalFee, totalThreshold uint64
+		var totalLength int
+		for _, t := range txgroup {
+			totalFee = basics.AddSaturate(totalFee, t.Txn.Fee.Raw)
+			totalThreshold = basics.AddSaturate(totalThreshold, feePerByte*uint64(t.GetEncodedLength()))
+			totalLength += t.GetEncodedLength()
+		}
+		if totalFee < totalThreshold {
+			return fmt.Errorf("group fee %d below threshold %d (%d per byte * %d bytes)",
+				totalFee, totalThreshold, feePerByte, totalLength)
+		}
+		return nil
+	}
+
 	for _, t := range txgroup {
 		feeThreshold := feePerByte * uint64(t.GetEncodedLength())
 		if t.Txn.Fee.Raw < feeThreshold {
@@ -473,7 +491,7 @@ func (pool *TransactionPool) Verified(txn transactions.SignedTxn) bool {
 		return false
 	}
 
-	return pendingSigTxn.Sig == txn.Sig && pendingSigTxn.Msig.Equal(txn.Msig) && pendingSigTxn.Lsig.Equal(&txn.Lsig)
+	return pendingSigTxn.Sig == txn.Sig && pendingSigTxn.Msig.Equal(txn.Msig) && pendingSigTxn.Lsig.Equal(&txn.Lsig) && pendingSigTxn.AuthAddr == txn.AuthAddr
 }
 
 // EvalOk for LogicSig Eval of a txn by txid, returns the SignedTxn, error string, and found.
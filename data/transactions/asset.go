@@ -61,6 +61,14 @@ type AssetTransferTxnFields struct {
 	// asset holdings should be transferred.  It's always valid to transfer
 	// remaining asset holdings to the creator account.
 	AssetCloseTo basics.Address `codec:"aclose"`
+
+	// AssetFundOptIn indicates that the sender, who must be the
+	// asset's Manager, opts AssetReceiver in to the asset, and moves
+	// MinBalance microAlgos to AssetReceiver to pay for the new slot
+	// in its Assets map, plus another MinBalance if AssetReceiver does
+	// not exist yet.  AssetAmount is then transferred as usual.  The
+	// receiver must consent by sending a transaction of the same group.
+	AssetFundOptIn bool `codec:"afund"`
 }
 
 // AssetFreezeTxnFields captures the fields used for freezing asset slots.
@@ -253,6 +261,61 @@ func putIn(balances Balances, addr basics.Address, asset basics.AssetIndex, amou
 	return balances.Put(rcv)
 }
 
+// fundOptIn allocates a slot for ct.XferAsset in the receiver's account on
+// behalf of the asset manager, who pays the MinBalance the slot requires,
+// and the receiver's base MinBalance if the receiver is a new account.
+// Assets that are frozen by default cannot be funded this way.  The
+// evaluator checks that the receiver consented, since that depends on the
+// rest of the transaction group.
+func (ct AssetTransferTxnFields) fundOptIn(header Header, balances Balances, ad *ApplyData) error {
+	params, _, err := getParams(balances, ct.XferAsset)
+	if err != nil {
+		return err
+	}
+
+	if params.Manager.IsZero() || (header.Sender != params.Manager) {
+		return fmt.Errorf("funded opt-in not allowed: sender %v, manager %v", header.Sender, params.Manager)
+	}
+
+	// The receiver did not ask for the slot, so it must be able to close
+	// it out, which a frozen holding may not allow.
+	if params.DefaultFrozen {
+		return fmt.Errorf("funded opt-in not allowed: asset %v is frozen by default", ct.XferAsset)
+	}
+
+	rcv, err := balances.Get(ct.AssetReceiver, false)
+	if err != nil {
+		return err
+	}
+
+	proto := balances.ConsensusParams()
+	funding := basics.MicroAlgos{Raw: proto.MinBalance}
+	if rcv.MicroAlgos.IsZero() && len(rcv.Assets) == 0 {
+		funding.Raw += proto.MinBalance
+	}
+	err = balances.Move(header.Sender, ct.AssetReceiver, funding, &ad.SenderRewards, &ad.ReceiverRewards)
+	if err != nil {
+		return err
+	}
+
+	rcv, err = balances.Get(ct.AssetReceiver, false)
+	if err != nil {
+		return err
+	}
+
+	rcv.Assets = clone(rcv.Assets)
+	if _, ok := rcv.Assets[ct.XferAsset]; ok {
+		return fmt.Errorf("asset %v already present in account %v", ct.XferAsset, ct.AssetReceiver)
+	}
+
+	rcv.Assets[ct.XferAsset] = basics.AssetHolding{}
+	if len(rcv.Assets) > proto.MaxAssetsPerAccount {
+		return fmt.Errorf("too many assets in account: %d > %d", len(rcv.Assets), proto.MaxAssetsPerAccount)
+	}
+
+	return balances.Put(rcv)
+}
+
 func (ct AssetTransferTxnFields) apply(header Header, balances Balances, spec SpecialAddresses, ad *ApplyData) error {
 	// Default to sending from the transaction sender's account.
 	source := header.Sender
@@ -276,6 +339,15 @@ func (ct AssetTransferTxnFields) apply(header Header, balances Balances, spec Sp
 		clawback = true
 	}
 
+	// Allocate a slot for asset in the receiver's account, paid for
+	// by the asset manager.
+	if ct.AssetFundOptIn {
+		err := ct.fundOptIn(header, balances, ad)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Allocate a slot for asset (self-transfer of zero amount).
 	if ct.AssetAmount == 0 && ct.AssetReceiver == source && !clawback {
 		snd, err := balances.Get(source, false)
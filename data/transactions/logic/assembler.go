@@ -209,11 +209,23 @@ func (ops *OpStream) Arg(val uint64) error {
 	return nil
 }
 
-// Txn writes opcodes for loading a field from the current transaction
-func (ops *OpStream) Txn(val uint64) error {
+// checkTxnField ensures that a txn field is available in the program version being assembled
+func (ops *OpStream) checkTxnField(val uint64) error {
 	if val >= uint64(len(TxnFieldNames)) {
 		return errors.New("invalid txn field")
 	}
+	if txnFieldVersions[val] > ops.version() {
+		return fmt.Errorf("txn %s was introduced in version %d but program is version %d", TxnFieldNames[val], txnFieldVersions[val], ops.version())
+	}
+	return nil
+}
+
+// Txn writes opcodes for loading a field from the current transaction
+func (ops *OpStream) Txn(val uint64) error {
+	err := ops.checkTxnField(val)
+	if err != nil {
+		return err
+	}
 	ops.Out.WriteByte(0x31)
 	ops.Out.WriteByte(uint8(val))
 	ops.tpush(TxnFieldTypes[val])
@@ -222,8 +234,9 @@ func (ops *OpStream) Txn(val uint64) error {
 
 // Gtxn writes opcodes for loading a field from the current transaction
 func (ops *OpStream) Gtxn(gid, val uint64) error {
-	if val >= uint64(len(TxnFieldNames)) {
-		return errors.New("invalid txn field")
+	err := ops.checkTxnField(val)
+	if err != nil {
+		return err
 	}
 	if gid > 255 {
 		return errors.New("gtxn cannot look up beyond group index 255")
@@ -235,11 +248,61 @@ func (ops *OpStream) Gtxn(gid, val uint64) error {
 	return nil
 }
 
+// Gtxns writes opcodes for loading a field from the transaction in the current group whose index is on the stack
+func (ops *OpStream) Gtxns(val uint64) error {
+	err := ops.checkTxnField(val)
+	if err != nil {
+		return err
+	}
+	spec := opsByOpcode[0x38]
+	err = ops.checkVersion(spec)
+	if err != nil {
+		return err
+	}
+	err = ops.checkArgs(spec)
+	if err != nil {
+		return err
+	}
+	ops.Out.WriteByte(0x38)
+	ops.Out.WriteByte(uint8(val))
+	ops.tpush(TxnFieldTypes[val])
+	return nil
+}
+
+// Op writes the opcode for an op that takes no immediate arguments,
+// typechecking it against what is on the stack
+func (ops *OpStream) Op(name string) error {
+	opcode, ok := opcodesByName[name]
+	if !ok {
+		return fmt.Errorf("unknown opcode %v", name)
+	}
+	if _, hasArgs := argOps[name]; hasArgs {
+		return fmt.Errorf("%s needs immediate arguments", name)
+	}
+	spec := opsByOpcode[opcode]
+	err := ops.checkVersion(spec)
+	if err != nil {
+		return err
+	}
+	err = ops.checkArgs(spec)
+	if err != nil {
+		return err
+	}
+	if spec.Returns != nil {
+		ops.tpusha(spec.Returns)
+		ops.trace("pushes%#v", spec.Returns)
+	}
+	return ops.Out.WriteByte(opcode)
+}
+
 // Global writes opcodes for loading an evaluator-global field
 func (ops *OpStream) Global(val uint64) error {
 	if val >= uint64(len(GlobalFieldNames)) {
 		return errors.New("invalid txn field")
 	}
+	if globalFieldVersions[val] > ops.version() {
+		return fmt.Errorf("global %s was introduced in version %d but program is version %d", GlobalFieldNames[val], globalFieldVersions[val], ops.version())
+	}
 	ops.Out.WriteByte(0x32)
 	ops.Out.WriteByte(uint8(val))
 	ops.tpush(GlobalFieldTypes[val])
@@ -446,6 +509,23 @@ func assembleBnz(ops *OpStream, args []string) error {
 	return nil
 }
 
+func assembleCallsub(ops *OpStream, args []string) error {
+	if len(args) != 1 {
+		return errors.New("callsub operation needs label argument")
+	}
+	opcode := opcodesByName["callsub"]
+	err := ops.checkVersion(opsByOpcode[opcode])
+	if err != nil {
+		return err
+	}
+	ops.ReferToLabel(ops.sourceLine, ops.Out.Len(), args[0])
+	ops.Out.WriteByte(opcode)
+	// zero bytes will get replaced with actual offset in resolveLabels()
+	ops.Out.WriteByte(0)
+	ops.Out.WriteByte(0)
+	return nil
+}
+
 func assembleLoad(ops *OpStream, args []string) error {
 	val, err := strconv.ParseUint(args[0], 0, 64)
 	if err != nil {
@@ -533,6 +613,8 @@ const (
 	GroupIndex
 	// TxID Transaction.ID()
 	TxID
+	// RekeyTo Transaction.RekeyTo
+	RekeyTo
 
 	invalidTxnField // fence for some setup that loops from Sender..invalidTxnField
 )
@@ -542,40 +624,45 @@ var TxnFieldNames []string
 var txnFields map[string]uint
 
 type txnFieldType struct {
-	field TxnField
-	ftype StackType
+	field   TxnField
+	ftype   StackType
+	version uint64 // first program version in which the field is available
 }
 
 var txnFieldTypePairs = []txnFieldType{
-	{Sender, StackBytes},
-	{Fee, StackUint64},
-	{FirstValid, StackUint64},
-	{FirstValidTime, StackUint64},
-	{LastValid, StackUint64},
-	{Note, StackBytes},
-	{Lease, StackBytes},
-	{Receiver, StackBytes},
-	{Amount, StackUint64},
-	{CloseRemainderTo, StackBytes},
-	{VotePK, StackBytes},
-	{SelectionPK, StackBytes},
-	{VoteFirst, StackUint64},
-	{VoteLast, StackUint64},
-	{VoteKeyDilution, StackUint64},
-	{Type, StackBytes},
-	{TypeEnum, StackUint64},
-	{XferAsset, StackUint64},
-	{AssetAmount, StackUint64},
-	{AssetSender, StackBytes},
-	{AssetReceiver, StackBytes},
-	{AssetCloseTo, StackBytes},
-	{GroupIndex, StackUint64},
-	{TxID, StackBytes},
+	{Sender, StackBytes, 1},
+	{Fee, StackUint64, 1},
+	{FirstValid, StackUint64, 1},
+	{FirstValidTime, StackUint64, 1},
+	{LastValid, StackUint64, 1},
+	{Note, StackBytes, 1},
+	{Lease, StackBytes, 1},
+	{Receiver, StackBytes, 1},
+	{Amount, StackUint64, 1},
+	{CloseRemainderTo, StackBytes, 1},
+	{VotePK, StackBytes, 1},
+	{SelectionPK, StackBytes, 1},
+	{VoteFirst, StackUint64, 1},
+	{VoteLast, StackUint64, 1},
+	{VoteKeyDilution, StackUint64, 1},
+	{Type, StackBytes, 1},
+	{TypeEnum, StackUint64, 1},
+	{XferAsset, StackUint64, 1},
+	{AssetAmount, StackUint64, 1},
+	{AssetSender, StackBytes, 1},
+	{AssetReceiver, StackBytes, 1},
+	{AssetCloseTo, StackBytes, 1},
+	{GroupIndex, StackUint64, 1},
+	{TxID, StackBytes, 1},
+	{RekeyTo, StackBytes, 2},
 }
 
 // TxnFieldTypes is StackBytes or StackUint64 parallel to TxnFieldNames
 var TxnFieldTypes []StackType
 
+// txnFieldVersions is the first program version of each field, in parallel with TxnFieldNames
+var txnFieldVersions []uint64
+
 // TxnTypeNames is the values of Txn.Type in enum order
 var TxnTypeNames = []string{
 	string(protocol.UnknownTx),
@@ -618,6 +705,17 @@ func assembleGtxn(ops *OpStream, args []string) error {
 	return ops.Gtxn(gtid, uint64(val))
 }
 
+func assembleGtxns(ops *OpStream, args []string) error {
+	if len(args) != 1 {
+		return errors.New("gtxns expects one argument")
+	}
+	val, ok := txnFields[args[0]]
+	if !ok {
+		return fmt.Errorf("gtxns unknown arg %s", args[0])
+	}
+	return ops.Gtxns(uint64(val))
+}
+
 //go:generate stringer -type=GlobalField
 
 // GlobalField is an enum for `global` opcode
@@ -634,6 +732,12 @@ const (
 	ZeroAddress
 	// GroupSize len(txn group)
 	GroupSize
+	// GroupID Transaction.Group
+	GroupID
+	// Round the round being evaluated
+	Round
+	// LatestTimestamp Block[Round-1].TimeStamp
+	LatestTimestamp
 	invalidGlobalField
 )
 
@@ -641,21 +745,28 @@ const (
 var GlobalFieldNames []string
 
 type globalFieldType struct {
-	gfield GlobalField
-	ftype  StackType
+	gfield  GlobalField
+	ftype   StackType
+	version uint64 // first program version in which the field is available
 }
 
 var globalFieldTypeList = []globalFieldType{
-	{MinTxnFee, StackUint64},
-	{MinBalance, StackUint64},
-	{MaxTxnLife, StackUint64},
-	{ZeroAddress, StackBytes},
-	{GroupSize, StackUint64},
+	{MinTxnFee, StackUint64, 1},
+	{MinBalance, StackUint64, 1},
+	{MaxTxnLife, StackUint64, 1},
+	{ZeroAddress, StackBytes, 1},
+	{GroupSize, StackUint64, 1},
+	{GroupID, StackBytes, 2},
+	{Round, StackUint64, 2},
+	{LatestTimestamp, StackUint64, 2},
 }
 
 // GlobalFieldTypes is StackUint64 StackBytes in parallel with GlobalFieldNames
 var GlobalFieldTypes []StackType
 
+// globalFieldVersions is the first program version of each field, in parallel with GlobalFieldNames
+var globalFieldVersions []uint64
+
 var globalFields map[string]uint
 
 func assembleGlobal(ops *OpStream, args []string) error {
@@ -699,8 +810,10 @@ func init() {
 	argOps["arg"] = assembleArg
 	argOps["txn"] = assembleTxn
 	argOps["gtxn"] = assembleGtxn
+	argOps["gtxns"] = assembleGtxns
 	argOps["global"] = assembleGlobal
 	argOps["bnz"] = assembleBnz
+	argOps["callsub"] = assembleCallsub
 	argOps["load"] = assembleLoad
 	argOps["store"] = assembleStore
 	// WARNING: special case op assembly by argOps functions must do their own type stack maintenance via ops.tpop() ops.tpush()/ops.tpusha()
@@ -715,11 +828,13 @@ func init() {
 	}
 
 	TxnFieldTypes = make([]StackType, int(invalidTxnField))
+	txnFieldVersions = make([]uint64, int(invalidTxnField))
 	for i, ft := range txnFieldTypePairs {
 		if int(ft.field) != i {
 			panic("txnFieldTypePairs disjoint with TxnField enum")
 		}
 		TxnFieldTypes[i] = ft.ftype
+		txnFieldVersions[i] = ft.version
 	}
 
 	GlobalFieldNames = make([]string, int(invalidGlobalField))
@@ -727,8 +842,10 @@ func init() {
 		GlobalFieldNames[int(i)] = i.String()
 	}
 	GlobalFieldTypes = make([]StackType, len(GlobalFieldNames))
+	globalFieldVersions = make([]uint64, len(GlobalFieldNames))
 	for _, ft := range globalFieldTypeList {
 		GlobalFieldTypes[int(ft.gfield)] = ft.ftype
+		globalFieldVersions[int(ft.gfield)] = ft.version
 	}
 	globalFields = make(map[string]uint)
 	for i, gfn := range GlobalFieldNames {
@@ -762,6 +879,10 @@ func (lew *lineErrorWrapper) Error() string {
 }
 
 func lineErr(line int, err error) error {
+	if _, ok := err.(*lineErrorWrapper); ok {
+		// already knows its line, e.g. from checkArgs
+		return err
+	}
 	return &lineErrorWrapper{Line: line, Err: err}
 }
 
@@ -790,6 +911,41 @@ func (ops *OpStream) trace(format string, args ...interface{}) {
 	fmt.Fprintf(ops.Trace, format, args...)
 }
 
+// version is the program version being assembled
+func (ops *OpStream) version() uint64 {
+	if ops.Version == 0 {
+		return AssemblerDefaultVersion
+	}
+	return ops.Version
+}
+
+// checkVersion ensures that an op is available in the program version being assembled
+func (ops *OpStream) checkVersion(spec OpSpec) error {
+	if spec.Version > ops.version() {
+		return fmt.Errorf("%s opcode was introduced in version %d but program is version %d", spec.Name, spec.Version, ops.version())
+	}
+	return nil
+}
+
+// pragma handles a `#pragma` directive
+func (ops *OpStream) pragma(fields []string) error {
+	if len(fields) != 3 || fields[1] != "version" {
+		return fmt.Errorf("unknown pragma %s", strings.Join(fields[1:], " "))
+	}
+	if ops.Out.Len() != 0 {
+		return errors.New("#pragma version must precede all instructions")
+	}
+	version, err := strconv.ParseUint(fields[2], 0, 64)
+	if err != nil {
+		return err
+	}
+	if version < 1 || version > EvalMaxVersion {
+		return fmt.Errorf("unsupported version %d", version)
+	}
+	ops.Version = version
+	return nil
+}
+
 // checks (and pops) arg types from arg type stack
 func (ops *OpStream) checkArgs(spec OpSpec) error {
 	firstPop := true
@@ -838,6 +994,14 @@ func (ops *OpStream) Assemble(fin io.Reader) error {
 			continue
 		}
 		opstring := fields[0]
+		if opstring == "#pragma" {
+			ops.trace("%3d: #pragma line\n", ops.sourceLine)
+			err := ops.pragma(fields)
+			if err != nil {
+				return lineErr(ops.sourceLine, err)
+			}
+			continue
+		}
 		argf, ok := argOps[opstring]
 		if ok {
 			ops.trace("%3d: %s\t", ops.sourceLine, opstring)
@@ -848,19 +1012,10 @@ func (ops *OpStream) Assemble(fin io.Reader) error {
 			ops.trace("\n")
 			continue
 		}
-		opcode, ok := opcodesByName[opstring]
+		_, ok = opcodesByName[opstring]
 		if ok {
 			ops.trace("%3d: %s\t", ops.sourceLine, opstring)
-			spec := opsByOpcode[opcode]
-			err := ops.checkArgs(spec)
-			if err != nil {
-				return err
-			}
-			if spec.Returns != nil {
-				ops.tpusha(spec.Returns)
-				ops.trace("pushes%#v", spec.Returns)
-			}
-			err = ops.Out.WriteByte(opcode)
+			err := ops.Op(opstring)
 			if err != nil {
 				return lineErr(ops.sourceLine, err)
 			}
@@ -917,11 +1072,7 @@ func (ops *OpStream) Bytes() (program []byte, err error) {
 	var scratch [binary.MaxVarintLen64]byte
 	prebytes := bytes.Buffer{}
 	// TODO: configurable what version to compile for in case we're near a version boundary?
-	version := ops.Version
-	if version == 0 {
-		//version = config.Consensus[protocol.ConsensusCurrentVersion].LogicSigVersion
-		version = AssemblerDefaultVersion
-	}
+	version := ops.version()
 	vlen := binary.PutUvarint(scratch[:], version)
 	prebytes.Write(scratch[:vlen])
 	if len(ops.intc) > 0 {
@@ -1008,8 +1159,10 @@ var disassemblers = []disassembler{
 	{"arg", disArg},
 	{"txn", disTxn},
 	{"gtxn", disGtxn},
+	{"gtxns", disGtxns},
 	{"global", disGlobal},
 	{"bnz", disBnz},
+	{"callsub", disCallsub},
 	{"load", disLoad},
 	{"store", disStore},
 }
@@ -1240,6 +1393,16 @@ func disGtxn(dis *disassembleState) {
 	_, dis.err = fmt.Fprintf(dis.out, "gtxn %d %s\n", gi, TxnFieldNames[txarg])
 }
 
+func disGtxns(dis *disassembleState) {
+	dis.nextpc = dis.pc + 2
+	txarg := dis.program[dis.pc+1]
+	if int(txarg) >= len(TxnFieldNames) {
+		dis.err = fmt.Errorf("invalid txn arg index %d at pc=%d", txarg, dis.pc)
+		return
+	}
+	_, dis.err = fmt.Fprintf(dis.out, "gtxns %s\n", TxnFieldNames[txarg])
+}
+
 func disGlobal(dis *disassembleState) {
 	dis.nextpc = dis.pc + 2
 	garg := dis.program[dis.pc+1]
@@ -1260,6 +1423,16 @@ func disBnz(dis *disassembleState) {
 	_, dis.err = fmt.Fprintf(dis.out, "bnz %s\n", label)
 }
 
+func disCallsub(dis *disassembleState) {
+	dis.nextpc = dis.pc + 3
+	offset := (uint(dis.program[dis.pc+1]) << 8) | uint(dis.program[dis.pc+2])
+	target := int(offset) + dis.pc + 3
+	dis.labelCount++
+	label := fmt.Sprintf("label%d", dis.labelCount)
+	dis.putLabel(label, target)
+	_, dis.err = fmt.Fprintf(dis.out, "callsub %s\n", label)
+}
+
 func disLoad(dis *disassembleState) {
 	n := uint(dis.program[dis.pc+1])
 	dis.nextpc = dis.pc + 2
@@ -1282,7 +1455,11 @@ func Disassemble(program []byte) (text string, err error) {
 		fmt.Fprintf(dis.out, "// invalid version\n")
 		return out.String(), nil
 	}
-	fmt.Fprintf(dis.out, "// version %d\n", version)
+	if version > AssemblerDefaultVersion {
+		fmt.Fprintf(dis.out, "#pragma version %d\n", version)
+	} else {
+		fmt.Fprintf(dis.out, "// version %d\n", version)
+	}
 	dis.pc = vlen
 	for dis.pc < len(program) {
 		label, hasLabel := dis.pendingLabels[dis.pc]
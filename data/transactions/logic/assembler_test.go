@@ -19,6 +19,7 @@ package logic
 import (
 	"bytes"
 	"encoding/hex"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -145,6 +146,10 @@ func TestAssemble(t *testing.T) {
 		// Ensure that we have some basic check of all the ops, except
 		// we don't test every combination of
 		// intcblock,bytecblock,intc*,bytec*,arg* here.
+		// Later versions are covered by their own programs below.
+		if spec.Version > 1 {
+			continue
+		}
 		if !strings.Contains(bigTestAssembleNonsenseProgram, spec.Name) && !strings.HasPrefix(spec.Name, "int") && !strings.HasPrefix(spec.Name, "byte") && !strings.HasPrefix(spec.Name, "arg") {
 			t.Errorf("test should contain op %v", spec.Name)
 		}
@@ -308,3 +313,69 @@ func TestAssembleDisassembleCycle(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, program, p2)
 }
+
+const v2TestAssembleProgram = `#pragma version 2
+int 1
+callsub double
+int 2
+==
+bnz done
+err
+double:
+dup
++
+retsub
+done:
+int 0
+gtxns Amount
+global Round
+global LatestTimestamp
+global GroupID
+pop
+pop
+pop
+`
+
+func TestAssembleV2(t *testing.T) {
+	for _, spec := range OpSpecs {
+		if spec.Version == 2 && !strings.Contains(v2TestAssembleProgram, spec.Name) {
+			t.Errorf("v2 test should contain op %v", spec.Name)
+		}
+	}
+	program, err := AssembleString(v2TestAssembleProgram)
+	require.NoError(t, err)
+	expectedBytes, _ := hex.DecodeString("02200301020022880006231240000400490889243808320632073205484848")
+	if bytes.Compare(expectedBytes, program) != 0 {
+		t.Log(hex.EncodeToString(program))
+	}
+	require.Equal(t, expectedBytes, program)
+
+	t2, err := Disassemble(program)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(t2, "#pragma version 2\n"))
+	p2, err := AssembleString(t2)
+	require.NoError(t, err)
+	require.Equal(t, program, p2)
+}
+
+func TestAssembleVersionPragma(t *testing.T) {
+	_, err := AssembleString("int 1\ncallsub sub\nsub:\nretsub\n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "introduced in version 2")
+
+	_, err = AssembleString("#pragma version 1\nint 1\nretsub\n")
+	require.Error(t, err)
+
+	_, err = AssembleString("int 1\n#pragma version 2\n")
+	require.Error(t, err)
+
+	_, err = AssembleString(fmt.Sprintf("#pragma version %d\nint 1\n", EvalMaxVersion+1))
+	require.Error(t, err)
+
+	_, err = AssembleString("#pragma frobnicate\nint 1\n")
+	require.Error(t, err)
+
+	program, err := AssembleString("#pragma version 2\nint 1\n")
+	require.NoError(t, err)
+	require.Equal(t, byte(2), program[0])
+}
@@ -81,12 +81,15 @@ var opDocList = []stringString{
 	{"arg_3", "push Args[3] to stack"},
 	{"txn", "push field from current transaction to stack"},
 	{"gtxn", "push field to the stack from a transaction in the current transaction group"},
+	{"gtxns", "push field to the stack from the transaction in the current transaction group at index X"},
 	{"global", "push value from globals to stack"},
 	{"load", "copy a value from scratch space to the stack"},
 	{"store", "pop a value from the stack and store to scratch space"},
 	{"bnz", "branch if value X is not zero"},
 	{"pop", "discard value X from stack"},
 	{"dup", "duplicate last value on stack"},
+	{"callsub", "branch unconditionally to subroutine label, saving the address of the next instruction on the call stack"},
+	{"retsub", "pop the top of the call stack and branch to it"},
 }
 
 var opDocByName map[string]string
@@ -108,8 +111,10 @@ var opcodeImmediateNoteList = []stringString{
 	{"arg", "{uint8 arg index N}"},
 	{"txn", "{uint8 transaction field index}"},
 	{"gtxn", "{uint8 transaction group index}{uint8 transaction field index}"},
+	{"gtxns", "{uint8 transaction field index}"},
 	{"global", "{uint8 global field index}"},
 	{"bnz", "{0..0x7fff forward branch offset, big endian}"},
+	{"callsub", "{0..0x7fff forward branch offset, big endian}"},
 	{"load", "{uint8 position in scratch space to load from}"},
 	{"store", "{uint8 position in scratch space to store to}"},
 }
@@ -132,7 +137,10 @@ var opDocExtraList = []stringString{
 	{"*", "Overflow is an error condition which halts execution and fails the transaction. Full precision is available from `mulw`."},
 	{"txn", "FirstValidTime is actually the time of the round at FirstValid-1. Subtle implementation details make it much faster to serve details of an already completed round. `int` accepts the user friendly names for comparison to `txn TypeEnum`"},
 	{"gtxn", "for notes on transaction fields available, see `txn`. If this transaction is _i_ in the group, `gtxn i field` is equivalent to `txn field`"},
+	{"gtxns", "for notes on transaction fields available, see `txn`. If top of stack is _i_, `gtxns field` is equivalent to `gtxn _i_ field`, so a program can loop over the group with subroutines or compute its own position with `txn GroupIndex`. `gtxns` panics if _i_ is not less than `global GroupSize`."},
 	{"btoi", "`btoi` panics if the input is longer than 8 bytes"},
+	{"callsub", "The call stack is separate from the data stack. Only `callsub` and `retsub` manipulate it, so a subroutine may consume values from the data stack and leave results on it. Like `bnz`, the offset is a forward branch. Calls may nest at most 8 deep; Check rejects a program whose calls could nest deeper, and counts the cost of a subroutine once for every `callsub` which may reach it."},
+	{"retsub", "`retsub` panics if the call stack is empty."},
 }
 
 var opDocExtras map[string]string
@@ -155,8 +163,8 @@ type OpGroup struct {
 // OpGroupList is groupings of ops for documentation purposes.
 var OpGroupList = []OpGroup{
 	{"Arithmetic", []string{"sha256", "keccak256", "sha512_256", "ed25519verify", "+", "-", "/", "*", "<", ">", "<=", ">=", "&&", "||", "==", "!=", "!", "len", "itob", "btoi", "%", "|", "&", "^", "~", "mulw"}},
-	{"Loading Values", []string{"intcblock", "intc", "intc_0", "intc_1", "intc_2", "intc_3", "bytecblock", "bytec", "bytec_0", "bytec_1", "bytec_2", "bytec_3", "arg", "arg_0", "arg_1", "arg_2", "arg_3", "txn", "gtxn", "global", "load", "store"}},
-	{"Flow Control", []string{"err", "bnz", "pop", "dup"}},
+	{"Loading Values", []string{"intcblock", "intc", "intc_0", "intc_1", "intc_2", "intc_3", "bytecblock", "bytec", "bytec_0", "bytec_1", "bytec_2", "bytec_3", "arg", "arg_0", "arg_1", "arg_2", "arg_3", "txn", "gtxn", "gtxns", "global", "load", "store"}},
+	{"Flow Control", []string{"err", "bnz", "pop", "dup", "callsub", "retsub"}},
 }
 
 var opCostByName map[string]int
@@ -241,6 +249,7 @@ var txnFieldDocList = []stringString{
 	{"AssetCloseTo", "32 byte address"},
 	{"GroupIndex", "Position of this transaction within an atomic transaction group. A stand-alone transaction is implicitly element 0 in a group of 1."},
 	{"TxID", "The computed ID for this transaction. 32 bytes."},
+	{"RekeyTo", "32 byte address the sender is rekeyed to, or all zero bytes. Version 2."},
 }
 
 // TxnFieldDocs are notes on fields available by `txn` and `gtxn`
@@ -252,6 +261,9 @@ var globalFieldDocList = []stringString{
 	{"MaxTxnLife", "rounds"},
 	{"ZeroAddress", "32 byte address of all zero bytes"},
 	{"GroupSize", "Number of transactions in this atomic transaction group. At least 1."},
+	{"GroupID", "32 byte ID of this transaction's atomic transaction group, or all zero bytes for a stand-alone transaction. Version 2."},
+	{"Round", "Round number of the block this transaction is being evaluated in. Version 2."},
+	{"LatestTimestamp", "Seconds since 1970-01-01 00:00:00 UTC of the block before Round. Version 2."},
 }
 
 // GlobalFieldDocs are notes on fields available in `global`
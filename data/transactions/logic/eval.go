@@ -42,7 +42,14 @@ import (
 )
 
 // EvalMaxVersion is the max version we can interpret and run
-const EvalMaxVersion = 1
+const EvalMaxVersion = 2
+
+// subroutineVersion is the first version with callsub and retsub
+const subroutineVersion = 2
+
+// RekeyToVersion is the first version that can read txn RekeyTo. Older
+// programs cannot see a rekey, so they must not approve one.
+const RekeyToVersion = 2
 
 // EvalMaxArgs is the maximum number of arguments to an LSig
 const EvalMaxArgs = 255
@@ -95,6 +102,12 @@ type EvalParams struct {
 
 	FirstValidTimeStamp uint64
 
+	// Round is the round of the block the transaction is being evaluated in
+	Round uint64
+
+	// LatestTimestamp is the timestamp of the block before Round
+	LatestTimestamp int64
+
 	Logger logging.Logger
 }
 
@@ -121,10 +134,16 @@ type evalContext struct {
 	stepCount int
 	cost      int
 
+	// return addresses of the subroutines currently executing
+	callstack []int
+
 	// Ordered set of pc values that a branch could go to.
 	// If Check pc skips a target, the source branch was invalid!
 	branchTargets []int
 
+	// callsub pc -> subroutine pc, collected by Check
+	subroutineTargets map[int]int
+
 	programHash crypto.Digest
 }
 
@@ -196,10 +215,6 @@ func Eval(program []byte, params EvalParams) (pass bool, err error) {
 		err = errLogicSignNotSupported
 		return
 	}
-	if params.Txn.Lsig.Args != nil && len(params.Txn.Lsig.Args) > EvalMaxArgs {
-		err = errTooManyArgs
-		return
-	}
 	var cx evalContext
 	version, vlen := binary.Uvarint(program)
 	if vlen <= 0 {
@@ -214,10 +229,18 @@ func Eval(program []byte, params EvalParams) (pass bool, err error) {
 		cx.err = fmt.Errorf("program version %d greater than protocol supported version %d", version, params.Proto.LogicSigVersion)
 		return false, cx.err
 	}
+	if params.Txn.Lsig.Args != nil && len(params.Txn.Lsig.Args) > EvalMaxArgs {
+		err = errTooManyArgs
+		return
+	}
 	// TODO: if EvalMaxVersion > version, ensure that inaccessible
 	// fields as of the program's version are zero or other
 	// default value so that no one is hiding unexpected
 	// operations from an old program.
+	if version < RekeyToVersion && !params.Txn.Txn.RekeyTo.IsZero() {
+		cx.err = fmt.Errorf("program version %d cannot approve a rekey", version)
+		return false, cx.err
+	}
 	cx.version = version
 	cx.pc = vlen
 	cx.EvalParams = params
@@ -227,7 +250,9 @@ func Eval(program []byte, params EvalParams) (pass bool, err error) {
 	for (cx.err == nil) && (cx.pc < len(cx.program)) {
 		cx.step()
 		cx.stepCount++
-		if cx.stepCount > len(cx.program) {
+		// subroutines may run the same code more than once, so for
+		// those versions the cost limit bounds execution instead
+		if cx.version < subroutineVersion && cx.stepCount > len(cx.program) {
 			return false, errLoopDetected
 		}
 		if uint64(cx.cost) > params.Proto.LogicSigMaxCost {
@@ -295,16 +320,71 @@ func Check(program []byte, params EvalParams) (cost int, err error) {
 	cx.pc = vlen
 	cx.EvalParams = params
 	cx.program = program
+	var steps []checkedStep
 	for (cx.err == nil) && (cx.pc < len(cx.program)) {
-		cost += cx.checkStep()
+		pc := cx.pc
+		stepCost := cx.checkStep()
+		steps = append(steps, checkedStep{pc, stepCost})
+		cost += stepCost
 	}
 	if cx.err != nil {
 		err = fmt.Errorf("%3d %s", cx.pc, cx.err)
 		return
 	}
+	if len(cx.subroutineTargets) > 0 {
+		cost, err = cx.checkCallPaths(steps)
+	}
 	return
 }
 
+// checkedStep is the cost of one instruction as seen by Check
+type checkedStep struct {
+	pc   int
+	cost int
+}
+
+// maxCheckCost caps the cost computed along call paths so that it
+// cannot overflow; any program anywhere near it is rejected anyway.
+const maxCheckCost = math.MaxInt32
+
+// checkCallPaths computes the cost of the most expensive execution of
+// a program that uses subroutines, and verifies that no sequence of
+// calls can exceed MaxCallStackDepth.
+//
+// Branches and calls only go forward, so the instructions from any pc to
+// the end of the program bound what can run from there: their own cost
+// plus, for each callsub among them, what can run from its subroutine.
+// One backward pass computes that bound for every instruction.
+func (cx *evalContext) checkCallPaths(steps []checkedStep) (cost int, err error) {
+	type bound struct {
+		cost  int
+		depth int
+	}
+	bounds := make(map[int]bound, len(steps))
+	var tail bound
+	for i := len(steps) - 1; i >= 0; i-- {
+		st := steps[i]
+		stepCost := st.cost
+		if target, isCall := cx.subroutineTargets[st.pc]; isCall {
+			sub := bounds[target]
+			stepCost += sub.cost
+			if sub.depth+1 > tail.depth {
+				tail.depth = sub.depth + 1
+			}
+		}
+		tail.cost += stepCost
+		if tail.cost > maxCheckCost || tail.cost < 0 {
+			tail.cost = maxCheckCost
+		}
+		bounds[st.pc] = tail
+	}
+	if tail.depth > MaxCallStackDepth {
+		err = fmt.Errorf("subroutine calls may nest %d deep, more than %d", tail.depth, MaxCallStackDepth)
+		return
+	}
+	return tail.cost, nil
+}
+
 // OpSpec defines one byte opcode
 type OpSpec struct {
 	Opcode  byte
@@ -312,6 +392,7 @@ type OpSpec struct {
 	op      opFunc      // evaluate the op
 	Args    []StackType // what gets popped from the stack
 	Returns []StackType // what gets pushed to the stack
+	Version uint64      // first program version in which the op is available
 }
 
 var oneBytes = []StackType{StackBytes}
@@ -325,60 +406,64 @@ var twoAny = []StackType{StackAny, StackAny}
 //
 // Any changes should be reflected in README.md which serves as the language spec.
 var OpSpecs = []OpSpec{
-	{0x00, "err", opErr, nil, nil},
-	{0x01, "sha256", opSHA256, oneBytes, oneBytes},
-	{0x02, "keccak256", opKeccak256, oneBytes, oneBytes},
-	{0x03, "sha512_256", opSHA512_256, oneBytes, oneBytes},
-	{0x04, "ed25519verify", opEd25519verify, threeBytes, oneInt},
-	{0x08, "+", opPlus, twoInts, oneInt},
-	{0x09, "-", opMinus, twoInts, oneInt},
-	{0x0a, "/", opDiv, twoInts, oneInt},
-	{0x0b, "*", opMul, twoInts, oneInt},
-	{0x0c, "<", opLt, twoInts, oneInt},
-	{0x0d, ">", opGt, twoInts, oneInt},
-	{0x0e, "<=", opLe, twoInts, oneInt},
-	{0x0f, ">=", opGe, twoInts, oneInt},
-	{0x10, "&&", opAnd, twoInts, oneInt},
-	{0x11, "||", opOr, twoInts, oneInt},
-	{0x12, "==", opEq, twoAny, oneInt},
-	{0x13, "!=", opNeq, twoAny, oneInt},
-	{0x14, "!", opNot, oneInt, oneInt},
-	{0x15, "len", opLen, oneBytes, oneInt},
-	{0x16, "itob", opItob, oneInt, oneBytes},
-	{0x17, "btoi", opBtoi, oneBytes, oneInt},
-	{0x18, "%", opModulo, twoInts, oneInt},
-	{0x19, "|", opBitOr, twoInts, oneInt},
-	{0x1a, "&", opBitAnd, twoInts, oneInt},
-	{0x1b, "^", opBitXor, twoInts, oneInt},
-	{0x1c, "~", opBitNot, oneInt, oneInt},
-	{0x1d, "mulw", opMulw, twoInts, twoInts},
-
-	{0x20, "intcblock", opIntConstBlock, nil, nil},
-	{0x21, "intc", opIntConstLoad, nil, oneInt},
-	{0x22, "intc_0", opIntConst0, nil, oneInt},
-	{0x23, "intc_1", opIntConst1, nil, oneInt},
-	{0x24, "intc_2", opIntConst2, nil, oneInt},
-	{0x25, "intc_3", opIntConst3, nil, oneInt},
-	{0x26, "bytecblock", opByteConstBlock, nil, nil},
-	{0x27, "bytec", opByteConstLoad, nil, oneBytes},
-	{0x28, "bytec_0", opByteConst0, nil, oneBytes},
-	{0x29, "bytec_1", opByteConst1, nil, oneBytes},
-	{0x2a, "bytec_2", opByteConst2, nil, oneBytes},
-	{0x2b, "bytec_3", opByteConst3, nil, oneBytes},
-	{0x2c, "arg", opArg, nil, oneBytes},
-	{0x2d, "arg_0", opArg0, nil, oneBytes},
-	{0x2e, "arg_1", opArg1, nil, oneBytes},
-	{0x2f, "arg_2", opArg2, nil, oneBytes},
-	{0x30, "arg_3", opArg3, nil, oneBytes},
-	{0x31, "txn", opTxn, nil, oneAny},       // TODO: check output type by subfield retrieved in txn,global,account,txid
-	{0x32, "global", opGlobal, nil, oneAny}, // TODO: check output type against specific field
-	{0x33, "gtxn", opGtxn, nil, oneAny},     // TODO: check output type by subfield retrieved in txn,global,account,txid
-	{0x34, "load", opLoad, nil, oneAny},
-	{0x35, "store", opStore, oneAny, nil},
-
-	{0x40, "bnz", opBnz, oneInt, nil},
-	{0x48, "pop", opPop, oneAny, nil},
-	{0x49, "dup", opDup, oneAny, twoAny},
+	{0x00, "err", opErr, nil, nil, 1},
+	{0x01, "sha256", opSHA256, oneBytes, oneBytes, 1},
+	{0x02, "keccak256", opKeccak256, oneBytes, oneBytes, 1},
+	{0x03, "sha512_256", opSHA512_256, oneBytes, oneBytes, 1},
+	{0x04, "ed25519verify", opEd25519verify, threeBytes, oneInt, 1},
+	{0x08, "+", opPlus, twoInts, oneInt, 1},
+	{0x09, "-", opMinus, twoInts, oneInt, 1},
+	{0x0a, "/", opDiv, twoInts, oneInt, 1},
+	{0x0b, "*", opMul, twoInts, oneInt, 1},
+	{0x0c, "<", opLt, twoInts, oneInt, 1},
+	{0x0d, ">", opGt, twoInts, oneInt, 1},
+	{0x0e, "<=", opLe, twoInts, oneInt, 1},
+	{0x0f, ">=", opGe, twoInts, oneInt, 1},
+	{0x10, "&&", opAnd, twoInts, oneInt, 1},
+	{0x11, "||", opOr, twoInts, oneInt, 1},
+	{0x12, "==", opEq, twoAny, oneInt, 1},
+	{0x13, "!=", opNeq, twoAny, oneInt, 1},
+	{0x14, "!", opNot, oneInt, oneInt, 1},
+	{0x15, "len", opLen, oneBytes, oneInt, 1},
+	{0x16, "itob", opItob, oneInt, oneBytes, 1},
+	{0x17, "btoi", opBtoi, oneBytes, oneInt, 1},
+	{0x18, "%", opModulo, twoInts, oneInt, 1},
+	{0x19, "|", opBitOr, twoInts, oneInt, 1},
+	{0x1a, "&", opBitAnd, twoInts, oneInt, 1},
+	{0x1b, "^", opBitXor, twoInts, oneInt, 1},
+	{0x1c, "~", opBitNot, oneInt, oneInt, 1},
+	{0x1d, "mulw", opMulw, twoInts, twoInts, 1},
+
+	{0x20, "intcblock", opIntConstBlock, nil, nil, 1},
+	{0x21, "intc", opIntConstLoad, nil, oneInt, 1},
+	{0x22, "intc_0", opIntConst0, nil, oneInt, 1},
+	{0x23, "intc_1", opIntConst1, nil, oneInt, 1},
+	{0x24, "intc_2", opIntConst2, nil, oneInt, 1},
+	{0x25, "intc_3", opIntConst3, nil, oneInt, 1},
+	{0x26, "bytecblock", opByteConstBlock, nil, nil, 1},
+	{0x27, "bytec", opByteConstLoad, nil, oneBytes, 1},
+	{0x28, "bytec_0", opByteConst0, nil, oneBytes, 1},
+	{0x29, "bytec_1", opByteConst1, nil, oneBytes, 1},
+	{0x2a, "bytec_2", opByteConst2, nil, oneBytes, 1},
+	{0x2b, "bytec_3", opByteConst3, nil, oneBytes, 1},
+	{0x2c, "arg", opArg, nil, oneBytes, 1},
+	{0x2d, "arg_0", opArg0, nil, oneBytes, 1},
+	{0x2e, "arg_1", opArg1, nil, oneBytes, 1},
+	{0x2f, "arg_2", opArg2, nil, oneBytes, 1},
+	{0x30, "arg_3", opArg3, nil, oneBytes, 1},
+	{0x31, "txn", opTxn, nil, oneAny, 1},       // TODO: check output type by subfield retrieved in txn,global,account,txid
+	{0x32, "global", opGlobal, nil, oneAny, 1}, // TODO: check output type against specific field
+	{0x33, "gtxn", opGtxn, nil, oneAny, 1},     // TODO: check output type by subfield retrieved in txn,global,account,txid
+	{0x34, "load", opLoad, nil, oneAny, 1},
+	{0x35, "store", opStore, oneAny, nil, 1},
+	{0x38, "gtxns", opGtxns, oneInt, oneAny, 2},
+
+	{0x40, "bnz", opBnz, oneInt, nil, 1},
+	{0x48, "pop", opPop, oneAny, nil, 1},
+	{0x49, "dup", opDup, oneAny, twoAny, 1},
+
+	{0x88, "callsub", opCallsub, nil, nil, 2},
+	{0x89, "retsub", opRetsub, nil, nil, 2},
 }
 
 // direct opcode bytes
@@ -402,11 +487,13 @@ var opSizes = []opSize{
 	{"sha512_256", 9, 1, nil},
 	{"ed25519verify", 1900, 1, nil},
 	{"bnz", 1, 3, checkBnz},
+	{"callsub", 1, 3, checkCallsub},
 	{"intc", 1, 2, nil},
 	{"bytec", 1, 2, nil},
 	{"arg", 1, 2, nil},
 	{"txn", 1, 2, nil},
 	{"gtxn", 1, 3, nil},
+	{"gtxns", 1, 2, nil},
 	{"global", 1, 2, nil},
 	{"intcblock", 1, 0, checkIntConstBlock},
 	{"bytecblock", 1, 0, checkByteConstBlock},
@@ -447,9 +534,12 @@ func opCompat(expected, got StackType) bool {
 // MaxStackDepth should move to consensus params
 const MaxStackDepth = 1000
 
+// MaxCallStackDepth is the maximum number of nested subroutine calls
+const MaxCallStackDepth = 8
+
 func (cx *evalContext) step() {
 	opcode := cx.program[cx.pc]
-	if opsByOpcode[opcode].op == nil {
+	if opsByOpcode[opcode].op == nil || opsByOpcode[opcode].Version > cx.version {
 		cx.err = fmt.Errorf("%3d illegal opcode %02x", cx.pc, opcode)
 		return
 	}
@@ -476,10 +566,14 @@ func (cx *evalContext) step() {
 	cx.cost += oz.cost
 	opsByOpcode[opcode].op(cx)
 	if cx.Trace != nil {
-		if len(cx.stack) == 0 {
-			fmt.Fprintf(cx.Trace, "%3d %s => %s\n", cx.pc, opsByOpcode[opcode].Name, "<empty stack>")
+		top := "<empty stack>"
+		if len(cx.stack) != 0 {
+			top = cx.stack[len(cx.stack)-1].String()
+		}
+		if len(cx.callstack) == 0 {
+			fmt.Fprintf(cx.Trace, "%3d %s => %s\n", cx.pc, opsByOpcode[opcode].Name, top)
 		} else {
-			fmt.Fprintf(cx.Trace, "%3d %s => %s\n", cx.pc, opsByOpcode[opcode].Name, cx.stack[len(cx.stack)-1].String())
+			fmt.Fprintf(cx.Trace, "%3d [depth %d] %s => %s\n", cx.pc, len(cx.callstack), opsByOpcode[opcode].Name, top)
 		}
 	}
 	if cx.err != nil {
@@ -499,7 +593,7 @@ func (cx *evalContext) step() {
 
 func (cx *evalContext) checkStep() (cost int) {
 	opcode := cx.program[cx.pc]
-	if opsByOpcode[opcode].op == nil {
+	if opsByOpcode[opcode].op == nil || opsByOpcode[opcode].Version > cx.version {
 		cx.err = fmt.Errorf("%3d illegal opcode %02x", cx.pc, opcode)
 		return 1
 	}
@@ -926,20 +1020,27 @@ func opArg3(cx *evalContext) {
 	opArgN(cx, 3)
 }
 
-func checkBnz(cx *evalContext) int {
+// checkBranch validates the forward offset of a 3 byte branching op and
+// records its target, which Check must later find aligned to an instruction.
+func checkBranch(cx *evalContext, name string) (target int) {
 	offset := (uint(cx.program[cx.pc+1]) << 8) | uint(cx.program[cx.pc+2])
 	if offset > 0x7fff {
-		cx.err = fmt.Errorf("bnz offset %x too large", offset)
-		return 1
+		cx.err = fmt.Errorf("%s offset %x too large", name, offset)
+		return
 	}
 	cx.nextpc = cx.pc + 3
-	target := cx.nextpc + int(offset)
+	target = cx.nextpc + int(offset)
 	if target >= len(cx.program) {
-		cx.err = errors.New("bnz target beyond end of program")
-		return 1
+		cx.err = fmt.Errorf("%s target beyond end of program", name)
+		return
 	}
 	cx.branchTargets = append(cx.branchTargets, target)
 	sort.Ints(cx.branchTargets)
+	return
+}
+
+func checkBnz(cx *evalContext) int {
+	checkBranch(cx, "bnz")
 	return 1
 }
 func opBnz(cx *evalContext) {
@@ -957,6 +1058,42 @@ func opBnz(cx *evalContext) {
 	}
 }
 
+func checkCallsub(cx *evalContext) int {
+	target := checkBranch(cx, "callsub")
+	if cx.err != nil {
+		return 1
+	}
+	if cx.subroutineTargets == nil {
+		cx.subroutineTargets = make(map[int]int)
+	}
+	cx.subroutineTargets[cx.pc] = target
+	return 1
+}
+
+func opCallsub(cx *evalContext) {
+	if len(cx.callstack) >= MaxCallStackDepth {
+		cx.err = fmt.Errorf("callsub exceeds max call stack depth %d", MaxCallStackDepth)
+		return
+	}
+	offset := (uint(cx.program[cx.pc+1]) << 8) | uint(cx.program[cx.pc+2])
+	if offset > 0x7fff {
+		cx.err = fmt.Errorf("callsub offset %x too large", offset)
+		return
+	}
+	cx.callstack = append(cx.callstack, cx.pc+3)
+	cx.nextpc = cx.pc + 3 + int(offset)
+}
+
+func opRetsub(cx *evalContext) {
+	last := len(cx.callstack) - 1
+	if last < 0 {
+		cx.err = errors.New("retsub with empty call stack")
+		return
+	}
+	cx.nextpc = cx.callstack[last]
+	cx.callstack = cx.callstack[:last]
+}
+
 func opPop(cx *evalContext) {
 	last := len(cx.stack) - 1
 	cx.stack = cx.stack[:last]
@@ -970,6 +1107,10 @@ func opDup(cx *evalContext) {
 
 func (cx *evalContext) txnFieldToStack(txn *transactions.Transaction, field uint64) (sv stackValue, err error) {
 	err = nil
+	if field < uint64(len(txnFieldVersions)) && txnFieldVersions[field] > cx.version {
+		err = fmt.Errorf("txn %s is not available in version %d", TxnField(field).String(), cx.version)
+		return
+	}
 	switch TxnField(field) {
 	case Sender:
 		sv.Bytes = txn.Sender[:]
@@ -1020,6 +1161,8 @@ func (cx *evalContext) txnFieldToStack(txn *transactions.Transaction, field uint
 		sv.Bytes = txid[:]
 	case Lease:
 		sv.Bytes = txn.Lease[:]
+	case RekeyTo:
+		sv.Bytes = txn.RekeyTo[:]
 	default:
 		err = fmt.Errorf("invalid txn field %d", field)
 	}
@@ -1039,34 +1182,56 @@ func opTxn(cx *evalContext) {
 	cx.nextpc = cx.pc + 2
 }
 
+func (cx *evalContext) groupTxnFieldToStack(gtxid int, field uint64) (sv stackValue, err error) {
+	if TxnField(field) == GroupIndex {
+		// GroupIndex; asking this when we just specified it is _dumb_, but oh well
+		sv.Uint = uint64(gtxid)
+		return
+	}
+	return cx.txnFieldToStack(&cx.TxnGroup[gtxid].Txn, field)
+}
+
 func opGtxn(cx *evalContext) {
 	gtxid := int(uint(cx.program[cx.pc+1]))
 	if gtxid >= len(cx.TxnGroup) {
 		cx.err = fmt.Errorf("gtxn lookup TxnGroup[%d] but it only has %d", gtxid, len(cx.TxnGroup))
 		return
 	}
-	tx := &cx.TxnGroup[gtxid].Txn
 	field := uint64(cx.program[cx.pc+2])
-	var sv stackValue
-	var err error
-	if TxnField(field) == GroupIndex {
-		// GroupIndex; asking this when we just specified it is _dumb_, but oh well
-		sv.Uint = uint64(gtxid)
-	} else {
-		sv, err = cx.txnFieldToStack(tx, field)
-		if err != nil {
-			cx.err = err
-			return
-		}
+	sv, err := cx.groupTxnFieldToStack(gtxid, field)
+	if err != nil {
+		cx.err = err
+		return
 	}
 	cx.stack = append(cx.stack, sv)
 	cx.nextpc = cx.pc + 3
 }
 
+func opGtxns(cx *evalContext) {
+	last := len(cx.stack) - 1
+	gtxid := cx.stack[last].Uint
+	if gtxid >= uint64(len(cx.TxnGroup)) {
+		cx.err = fmt.Errorf("gtxns lookup TxnGroup[%d] but it only has %d", gtxid, len(cx.TxnGroup))
+		return
+	}
+	field := uint64(cx.program[cx.pc+1])
+	sv, err := cx.groupTxnFieldToStack(int(gtxid), field)
+	if err != nil {
+		cx.err = err
+		return
+	}
+	cx.stack[last] = sv
+	cx.nextpc = cx.pc + 2
+}
+
 var zeroAddress basics.Address
 
 func opGlobal(cx *evalContext) {
 	gindex := uint64(cx.program[cx.pc+1])
+	if gindex < uint64(len(globalFieldVersions)) && globalFieldVersions[gindex] > cx.version {
+		cx.err = fmt.Errorf("global %s is not available in version %d", GlobalField(gindex).String(), cx.version)
+		return
+	}
 	var sv stackValue
 	switch GlobalField(gindex) {
 	case MinTxnFee:
@@ -1079,6 +1244,16 @@ func opGlobal(cx *evalContext) {
 		sv.Bytes = zeroAddress[:]
 	case GroupSize:
 		sv.Uint = uint64(len(cx.TxnGroup))
+	case GroupID:
+		sv.Bytes = cx.Txn.Txn.Group[:]
+	case Round:
+		sv.Uint = cx.Round
+	case LatestTimestamp:
+		if cx.LatestTimestamp < 0 {
+			cx.err = fmt.Errorf("latest timestamp %d before 1970", cx.LatestTimestamp)
+			return
+		}
+		sv.Uint = uint64(cx.LatestTimestamp)
 	default:
 		cx.err = fmt.Errorf("invalid global[%d]", gindex)
 		return
@@ -1087,6 +1262,29 @@ func opGlobal(cx *evalContext) {
 	cx.nextpc = cx.pc + 2
 }
 
+// ReadsRound reports whether a program reads `global Round` or `global
+// LatestTimestamp`. Its result then depends on the block it is evaluated
+// in, so it must not be cached across blocks. A program that cannot be
+// walked is reported as reading the round, to be safe.
+func ReadsRound(program []byte) bool {
+	version, vlen := binary.Uvarint(program)
+	if vlen <= 0 {
+		return true
+	}
+	cx := evalContext{version: version, pc: vlen, program: program}
+	globalOpcode := opcodesByName["global"]
+	for cx.err == nil && cx.pc < len(cx.program) {
+		if cx.program[cx.pc] == globalOpcode && cx.pc+1 < len(cx.program) {
+			field := GlobalField(cx.program[cx.pc+1])
+			if field == Round || field == LatestTimestamp {
+				return true
+			}
+		}
+		cx.checkStep()
+	}
+	return cx.err != nil
+}
+
 // Msg is data meant to be signed and then verified with the
 // ed25519verify opcode.
 type Msg struct {
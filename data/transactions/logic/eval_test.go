@@ -879,7 +879,10 @@ int 1
 
 func TestGlobal(t *testing.T) {
 	t.Parallel()
-	for _, globalField := range GlobalFieldNames {
+	for i, globalField := range GlobalFieldNames {
+		if globalFieldVersions[i] > 1 {
+			continue
+		}
 		if !strings.Contains(globalTestProgram, globalField) {
 			t.Errorf("TestGlobal missing field %v", globalField)
 		}
@@ -919,6 +922,159 @@ func TestGlobal(t *testing.T) {
 	require.True(t, pass)
 }
 
+const globalV2TestProgram = `#pragma version 2
+global GroupID
+txn CloseRemainderTo
+!=
+global GroupID
+gtxn 1 CloseRemainderTo
+==
+&&
+global LatestTimestamp
+int 2069
+==
+&&
+global Round
+int 999999
+==
+&&
+`
+
+func TestGlobalV2(t *testing.T) {
+	t.Parallel()
+	for i, globalField := range GlobalFieldNames {
+		if globalFieldVersions[i] == 2 && !strings.Contains(globalV2TestProgram, globalField) {
+			t.Errorf("TestGlobalV2 missing field %v", globalField)
+		}
+	}
+	program, err := AssembleString(globalV2TestProgram)
+	require.NoError(t, err)
+	sb := strings.Builder{}
+	ep := subroutineEvalParams(&sb, nil)
+	cost, err := Check(program, ep)
+	require.NoError(t, err)
+	require.True(t, cost < 1000)
+	var txn transactions.SignedTxn
+	txn.Lsig.Logic = program
+	txn.Txn.Group = crypto.Hash([]byte("group"))
+	txgroup := make([]transactions.SignedTxnWithAD, 2)
+	txgroup[0].SignedTxn = txn
+	// the group ID is the same 32 bytes as an address, for comparison
+	copy(txgroup[1].Txn.CloseRemainderTo[:], txn.Txn.Group[:])
+	ep.Txn = &txn
+	ep.TxnGroup = txgroup
+	ep.Round = 999999
+	ep.LatestTimestamp = 2069
+	pass, err := Eval(program, ep)
+	if !pass {
+		t.Log(hex.EncodeToString(program))
+		t.Log(sb.String())
+	}
+	require.NoError(t, err)
+	require.True(t, pass)
+
+	ep.LatestTimestamp = -1
+	pass, err = Eval(program, ep)
+	require.Error(t, err)
+	require.False(t, pass)
+}
+
+func TestReadsRound(t *testing.T) {
+	t.Parallel()
+	for source, reads := range map[string]bool{
+		"int 1":                               false,
+		"#pragma version 2\nglobal GroupSize": false,
+		"#pragma version 2\nglobal Round":     true,
+		"#pragma version 2\nint 1\nglobal LatestTimestamp\n<": true,
+		// 0x32 0x06 is an immediate of intcblock here, not `global Round`
+		"#pragma version 2\nint 50\nint 6": false,
+	} {
+		program, err := AssembleString(source)
+		require.NoError(t, err)
+		require.Equal(t, reads, ReadsRound(program), source)
+	}
+	require.True(t, ReadsRound([]byte{0x02, 0xff}))
+}
+
+func TestGlobalV2FieldsNotInVersion1(t *testing.T) {
+	t.Parallel()
+	_, err := AssembleString("global Round\nint 1\n==")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "introduced in version 2")
+
+	var txn transactions.SignedTxn
+	txgroup := make([]transactions.SignedTxnWithAD, 1)
+	for _, field := range []GlobalField{GroupID, Round, LatestTimestamp} {
+		program := []byte{0x01, 0x32, byte(field)}
+		ep := subroutineEvalParams(nil, &txn)
+		ep.TxnGroup = txgroup
+		pass, err := Eval(program, ep)
+		require.Error(t, err)
+		require.False(t, pass)
+		isNotPanic(t, err)
+	}
+}
+
+func TestTxnRekeyTo(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+txn RekeyTo
+global ZeroAddress
+==
+gtxn 1 RekeyTo
+arg 0
+==
+&&
+int 1
+gtxns RekeyTo
+arg 0
+==
+&&`)
+	require.NoError(t, err)
+
+	var txn transactions.SignedTxn
+	txgroup := make([]transactions.SignedTxnWithAD, 2)
+	copy(txgroup[1].Txn.RekeyTo[:], []byte("aoeuiaoeuiaoeuiaoeuiaoeuiaoeui00"))
+	txn.Lsig.Args = [][]byte{txgroup[1].Txn.RekeyTo[:]}
+	txgroup[0].SignedTxn = txn
+	ep := subroutineEvalParams(nil, &txn)
+	ep.TxnGroup = txgroup
+	pass, err := Eval(program, ep)
+	require.NoError(t, err)
+	require.True(t, pass)
+
+	txn.Txn.RekeyTo = txgroup[1].Txn.RekeyTo
+	pass, err = Eval(program, ep)
+	require.NoError(t, err)
+	require.False(t, pass)
+}
+
+func TestTxnRekeyToNotInVersion1(t *testing.T) {
+	t.Parallel()
+	_, err := AssembleString("txn RekeyTo\nglobal ZeroAddress\n==")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "introduced in version 2")
+
+	var txn transactions.SignedTxn
+	program := []byte{0x01, 0x31, byte(RekeyTo)}
+	ep := subroutineEvalParams(nil, &txn)
+	pass, err := Eval(program, ep)
+	require.Error(t, err)
+	require.False(t, pass)
+	isNotPanic(t, err)
+
+	// a version 1 program cannot see a rekey, so it must not approve one
+	program, err = AssembleString("int 1")
+	require.NoError(t, err)
+	pass, err = Eval(program, ep)
+	require.NoError(t, err)
+	require.True(t, pass)
+	txn.Txn.RekeyTo = basics.Address{0x01}
+	pass, err = Eval(program, ep)
+	require.Error(t, err)
+	require.False(t, pass)
+}
+
 func TestTypeEnum(t *testing.T) {
 	t.Parallel()
 	ttypes := []protocol.TxType{
@@ -1066,8 +1222,8 @@ arg 8
 
 func TestTxn(t *testing.T) {
 	t.Parallel()
-	for _, txnField := range TxnFieldNames {
-		if !strings.Contains(testTxnProgramText, txnField) {
+	for i, txnField := range TxnFieldNames {
+		if txnFieldVersions[i] == 1 && !strings.Contains(testTxnProgramText, txnField) {
 			t.Errorf("TestTxn missing field %v", txnField)
 		}
 	}
@@ -1825,10 +1981,11 @@ int 1`)
 import random
 
 def foo():
-    for i in range(64):
-        print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
-    for i in range(63):
-        print('+')
+
+	for i in range(64):
+	    print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
+	for i in range(63):
+	    print('+')
 */
 const addBenchmarkSource = `int 20472989571761113
 int 80135167795737348
@@ -1963,10 +2120,11 @@ int 28939890412103745
 import random
 
 def foo():
-    print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
-    for i in range(63):
-        print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
-        print('+')
+
+	print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
+	for i in range(63):
+	    print('int {}'.format(random.randint(0,0x01ffffffffffffff)))
+	    print('+')
 */
 const addBenchmark2Source = `int 8371863094338737
 int 29595196041051360
@@ -2330,3 +2488,217 @@ func BenchmarkCheckx5(b *testing.B) {
 		}
 	}
 }
+
+func subroutineEvalParams(sb *strings.Builder, txn *transactions.SignedTxn) EvalParams {
+	ep := defaultEvalParams(sb, txn)
+	ep.Proto.LogicSigVersion = 2
+	return ep
+}
+
+func TestSubroutine(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+int 3
+callsub square
+int 9
+==
+bnz ok
+err
+square:
+dup
+*
+retsub
+ok:
+int 1`)
+	require.NoError(t, err)
+	sb := strings.Builder{}
+	ep := subroutineEvalParams(&sb, nil)
+	_, err = Check(program, ep)
+	require.NoError(t, err)
+	pass, err := Eval(program, ep)
+	require.NoError(t, err)
+	require.True(t, pass)
+	require.Contains(t, sb.String(), "[depth 1] dup")
+	require.NotContains(t, sb.String(), "[depth 1] ==")
+}
+
+func TestSubroutineNotInVersion1(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+int 1
+callsub sub
+sub:
+retsub`)
+	require.NoError(t, err)
+	program[0] = 1
+	_, err = Check(program, defaultEvalParams(nil, nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "illegal opcode")
+	pass, err := Eval(program, defaultEvalParams(nil, nil))
+	require.Error(t, err)
+	require.False(t, pass)
+	isNotPanic(t, err)
+
+	program[0] = 2
+	_, err = Check(program, defaultEvalParams(nil, nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "protocol supported version")
+}
+
+func TestRetsubEmptyCallStack(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+int 1
+retsub`)
+	require.NoError(t, err)
+	_, err = Check(program, subroutineEvalParams(nil, nil))
+	require.NoError(t, err)
+	pass, err := Eval(program, subroutineEvalParams(nil, nil))
+	require.Error(t, err)
+	require.False(t, pass)
+	isNotPanic(t, err)
+}
+
+func TestSubroutineCallPathCost(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+byte 0x01
+callsub hash
+callsub hash
+len
+int 32
+==
+bnz done
+err
+hash:
+sha256
+retsub
+done:
+int 1`)
+	require.NoError(t, err)
+	cost, err := Check(program, subroutineEvalParams(nil, nil))
+	require.NoError(t, err)
+	// every instruction is counted once in the program text, and
+	// everything from the subroutine to the end of the program (sha256,
+	// retsub, int 1) is counted once more for each call
+	sha256Cost := OpCost("sha256")
+	require.Equal(t, 12+sha256Cost+2*(sha256Cost+2), cost)
+
+	sb := strings.Builder{}
+	pass, err := Eval(program, subroutineEvalParams(&sb, nil))
+	require.NoError(t, err)
+	require.True(t, pass)
+}
+
+// nestedSubroutines builds a program whose subroutines each call the next, n deep
+func nestedSubroutines(n int) string {
+	text := "#pragma version 2\nint 1\ncallsub sub1\nbnz done\nerr\n"
+	for i := 1; i <= n; i++ {
+		text += fmt.Sprintf("sub%d:\n", i)
+		if i < n {
+			text += fmt.Sprintf("callsub sub%d\n", i+1)
+		}
+		text += "retsub\n"
+	}
+	return text + "done:\nint 1\n"
+}
+
+func TestSubroutineCallStackDepth(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(nestedSubroutines(MaxCallStackDepth))
+	require.NoError(t, err)
+	_, err = Check(program, subroutineEvalParams(nil, nil))
+	require.NoError(t, err)
+	pass, err := Eval(program, subroutineEvalParams(nil, nil))
+	require.NoError(t, err)
+	require.True(t, pass)
+
+	program, err = AssembleString(nestedSubroutines(MaxCallStackDepth + 1))
+	require.NoError(t, err)
+	_, err = Check(program, subroutineEvalParams(nil, nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nest")
+	pass, err = Eval(program, subroutineEvalParams(nil, nil))
+	require.Error(t, err)
+	require.False(t, pass)
+	isNotPanic(t, err)
+}
+
+func TestSubroutineCostSaturates(t *testing.T) {
+	t.Parallel()
+	// each subroutine calls the next one twice, doubling the cost at every level
+	text := "#pragma version 2\nint 1\ncallsub sub1\nbnz done\nerr\n"
+	for i := 1; i <= 40; i++ {
+		text += fmt.Sprintf("sub%d:\n", i)
+		if i < 40 {
+			text += fmt.Sprintf("callsub sub%d\ncallsub sub%d\n", i+1, i+1)
+		}
+		text += "retsub\n"
+	}
+	text += "done:\nint 1\n"
+	program, err := AssembleString(text)
+	require.NoError(t, err)
+	_, err = Check(program, subroutineEvalParams(nil, nil))
+	require.Error(t, err)
+}
+
+func TestGtxns(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+int 0
+gtxns Amount
+int 42
+==
+int 1
+gtxns Amount
+int 1000000
+==
+&&
+txn GroupIndex
+gtxns Sender
+txn Sender
+==
+&&
+txn GroupIndex
+gtxns GroupIndex
+txn GroupIndex
+==
+&&`)
+	require.NoError(t, err)
+	sb := strings.Builder{}
+	ep := subroutineEvalParams(&sb, nil)
+	_, err = Check(program, ep)
+	require.NoError(t, err)
+	txgroup := make([]transactions.SignedTxnWithAD, 2)
+	txgroup[0].Txn.Amount.Raw = 42
+	txgroup[1].Txn.Amount.Raw = 1000000
+	copy(txgroup[1].Txn.Sender[:], []byte("aoeuiaoeuiaoeuiaoeuiaoeuiaoeui00"))
+	ep.Txn = &txgroup[1].SignedTxn
+	ep.TxnGroup = txgroup
+	ep.GroupIndex = 1
+	pass, err := Eval(program, ep)
+	if !pass {
+		t.Log(hex.EncodeToString(program))
+		t.Log(sb.String())
+	}
+	require.NoError(t, err)
+	require.True(t, pass)
+}
+
+func TestGtxnsBadIndex(t *testing.T) {
+	t.Parallel()
+	program, err := AssembleString(`#pragma version 2
+int 2
+gtxns Amount`)
+	require.NoError(t, err)
+	var txn transactions.SignedTxn
+	ep := subroutineEvalParams(nil, &txn)
+	ep.TxnGroup = make([]transactions.SignedTxnWithAD, 2)
+	pass, err := Eval(program, ep)
+	require.Error(t, err)
+	require.False(t, pass)
+	isNotPanic(t, err)
+
+	_, err = AssembleString("int 0\ngtxns Amount")
+	require.Error(t, err)
+}
@@ -0,0 +1,515 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package logic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+// The fuzz harness generates random well-typed programs, builds them
+// with the OpStream API and checks that:
+//  - the assembled program passes Check
+//  - Eval never panics
+//  - Eval never costs more than the bound computed by Check
+//  - Eval agrees with refEval, a deliberately naive reference interpreter
+//
+// Failing cases are reported as program text, which can be added to
+// fuzzCorpus to keep them covered.
+
+// fuzzCorpus is run before any generated program. It pins down edge
+// cases that have caused trouble before.
+var fuzzCorpus = []string{
+	// mulw at the limits
+	"int 0xffffffffffffffff\nint 0xffffffffffffffff\nmulw\n+",
+	"int 0xffffffffffffffff\nint 0xffffffffffffffff\nmulw\npop",
+	"int 0x100000000\nint 0x100000000\nmulw\npop",
+	"int 0\nint 0xffffffffffffffff\nmulw\n==",
+	// btoi on short, 8 and 9 byte values
+	"byte 0x\nbtoi\n!",
+	"byte 0x0102030405060708\nbtoi",
+	"byte 0x010203040506070809\nbtoi",
+	"int 1\nitob\nbtoi",
+	// division and modulo by zero
+	"int 1\nint 0\n%",
+	"int 0\nint 0\n/",
+	"int 7\nint 0xffffffffffffffff\n%",
+	// overflow and underflow
+	"int 0xffffffffffffffff\nint 1\n+",
+	"int 0\nint 1\n-",
+	"int 0x100000000\nint 0x100000000\n*",
+	// bytes equality and bitwise ops
+	"byte 0x\nbyte 0x\n==",
+	"byte 0x00\nbyte 0x\n!=",
+	"int 0\n~\nint 0xffffffffffffffff\n==",
+	"int 6\nint 3\n^\nint 5\n==",
+	// scratch space
+	"load 1",
+	"byte 0x01\nstore 2\nload 2\nlen",
+	"byte 0x01\nsha256\nkeccak256\nsha512_256\nlen",
+}
+
+// fuzzOp is one instruction of a generated program
+type fuzzOp struct {
+	name string
+	u    uint64 // the constant of an int, or the scratch slot of load/store
+	b    []byte // the constant of a byte
+}
+
+func (op fuzzOp) String() string {
+	switch op.name {
+	case "int":
+		return fmt.Sprintf("int 0x%x", op.u)
+	case "byte":
+		return "byte 0x" + hex.EncodeToString(op.b)
+	case "load", "store":
+		return fmt.Sprintf("%s %d", op.name, op.u)
+	}
+	return op.name
+}
+
+func fuzzText(prog []fuzzOp) string {
+	lines := make([]string, len(prog))
+	for i, op := range prog {
+		lines[i] = op.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseFuzzOps reads the restricted program text used in fuzzCorpus
+func parseFuzzOps(text string) (prog []fuzzOp, err error) {
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		op := fuzzOp{name: fields[0]}
+		switch op.name {
+		case "int", "load", "store":
+			op.u, err = strconv.ParseUint(fields[1], 0, 64)
+		case "byte":
+			op.b, err = hex.DecodeString(strings.TrimPrefix(fields[1], "0x"))
+		}
+		if err != nil {
+			return
+		}
+		prog = append(prog, op)
+	}
+	return
+}
+
+// buildFuzzProgram assembles prog through the OpStream builder API
+func buildFuzzProgram(prog []fuzzOp) ([]byte, error) {
+	ops := OpStream{}
+	for _, op := range prog {
+		var err error
+		switch op.name {
+		case "int":
+			err = ops.Uint(op.u)
+		case "byte":
+			err = ops.ByteLiteral(op.b)
+		case "load":
+			err = assembleLoad(&ops, []string{strconv.FormatUint(op.u, 10)})
+		case "store":
+			err = assembleStore(&ops, []string{strconv.FormatUint(op.u, 10)})
+		default:
+			err = ops.Op(op.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ops.Bytes()
+}
+
+type refStack []stackValue
+
+func (st *refStack) pop() stackValue {
+	last := len(*st) - 1
+	sv := (*st)[last]
+	*st = (*st)[:last]
+	return sv
+}
+
+func (st *refStack) push(sv stackValue) {
+	*st = append(*st, sv)
+}
+
+func (st *refStack) pushUint(x uint64) {
+	st.push(stackValue{Uint: x})
+}
+
+func (st *refStack) pushBool(cond bool) {
+	if cond {
+		st.pushUint(1)
+	} else {
+		st.pushUint(0)
+	}
+}
+
+func (st *refStack) pushBytes(b []byte) {
+	if b == nil {
+		b = []byte{}
+	}
+	st.push(stackValue{Bytes: b})
+}
+
+// refBinop pops B then A and applies f to them as arbitrary precision integers
+func (st *refStack) refBinop(f func(a, b *big.Int) (*big.Int, error)) error {
+	b := new(big.Int).SetUint64(st.pop().Uint)
+	a := new(big.Int).SetUint64(st.pop().Uint)
+	v, err := f(a, b)
+	if err != nil {
+		return err
+	}
+	if v.Sign() < 0 || !v.IsUint64() {
+		return errors.New("out of range")
+	}
+	st.pushUint(v.Uint64())
+	return nil
+}
+
+var errRefDivZero = errors.New("divide by zero")
+
+// refOps is the reference semantics of every op the generator emits
+var refOps = map[string]func(st *refStack) error{
+	"+": func(st *refStack) error {
+		return st.refBinop(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Add(a, b), nil })
+	},
+	"-": func(st *refStack) error {
+		return st.refBinop(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Sub(a, b), nil })
+	},
+	"*": func(st *refStack) error {
+		return st.refBinop(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Mul(a, b), nil })
+	},
+	"/": func(st *refStack) error {
+		return st.refBinop(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errRefDivZero
+			}
+			return new(big.Int).Quo(a, b), nil
+		})
+	},
+	"%": func(st *refStack) error {
+		return st.refBinop(func(a, b *big.Int) (*big.Int, error) {
+			if b.Sign() == 0 {
+				return nil, errRefDivZero
+			}
+			return new(big.Int).Rem(a, b), nil
+		})
+	},
+	"<":  func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushBool(a < b); return nil },
+	">":  func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushBool(a > b); return nil },
+	"<=": func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushBool(a <= b); return nil },
+	">=": func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushBool(a >= b); return nil },
+	"&&": func(st *refStack) error {
+		b, a := st.pop().Uint, st.pop().Uint
+		st.pushBool(a != 0 && b != 0)
+		return nil
+	},
+	"||": func(st *refStack) error {
+		b, a := st.pop().Uint, st.pop().Uint
+		st.pushBool(a != 0 || b != 0)
+		return nil
+	},
+	"==": func(st *refStack) error { b, a := st.pop(), st.pop(); st.pushBool(refEqual(a, b)); return nil },
+	"!=": func(st *refStack) error { b, a := st.pop(), st.pop(); st.pushBool(!refEqual(a, b)); return nil },
+	"!":  func(st *refStack) error { st.pushBool(st.pop().Uint == 0); return nil },
+	"|":  func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushUint(a | b); return nil },
+	"&":  func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushUint(a & b); return nil },
+	"^":  func(st *refStack) error { b, a := st.pop().Uint, st.pop().Uint; st.pushUint(a ^ b); return nil },
+	"~":  func(st *refStack) error { st.pushUint(math.MaxUint64 - st.pop().Uint); return nil },
+	"len": func(st *refStack) error {
+		st.pushUint(uint64(len(st.pop().Bytes)))
+		return nil
+	},
+	"itob": func(st *refStack) error {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, st.pop().Uint)
+		st.pushBytes(b)
+		return nil
+	},
+	"btoi": func(st *refStack) error {
+		b := st.pop().Bytes
+		v := new(big.Int).SetBytes(b)
+		if len(b) > 8 {
+			return errors.New("btoi too long")
+		}
+		st.pushUint(v.Uint64())
+		return nil
+	},
+	"mulw": func(st *refStack) error {
+		b := new(big.Int).SetUint64(st.pop().Uint)
+		a := new(big.Int).SetUint64(st.pop().Uint)
+		v := new(big.Int).Mul(a, b)
+		st.pushUint(new(big.Int).Rsh(v, 64).Uint64())
+		st.pushUint(new(big.Int).And(v, new(big.Int).SetUint64(math.MaxUint64)).Uint64())
+		return nil
+	},
+	"sha256": func(st *refStack) error {
+		sum := sha256.Sum256(st.pop().Bytes)
+		st.pushBytes(sum[:])
+		return nil
+	},
+	"sha512_256": func(st *refStack) error {
+		sum := sha512.Sum512_256(st.pop().Bytes)
+		st.pushBytes(sum[:])
+		return nil
+	},
+	"keccak256": func(st *refStack) error {
+		h := sha3.NewLegacyKeccak256()
+		h.Write(st.pop().Bytes)
+		st.pushBytes(h.Sum(nil))
+		return nil
+	},
+	"pop": func(st *refStack) error { st.pop(); return nil },
+	"dup": func(st *refStack) error {
+		sv := st.pop()
+		st.push(sv)
+		st.push(sv)
+		return nil
+	},
+}
+
+func refEqual(a, b stackValue) bool {
+	if (a.Bytes == nil) != (b.Bytes == nil) {
+		return false
+	}
+	if a.Bytes != nil {
+		return bytes.Equal(a.Bytes, b.Bytes)
+	}
+	return a.Uint == b.Uint
+}
+
+// refEval runs prog with the reference semantics
+func refEval(prog []fuzzOp) (pass bool, err error) {
+	var st refStack
+	var scratch [EvalMaxScratchSize + 1]stackValue
+	for _, op := range prog {
+		switch op.name {
+		case "int":
+			st.pushUint(op.u)
+		case "byte":
+			st.pushBytes(op.b)
+		case "load":
+			st.push(scratch[op.u])
+		case "store":
+			scratch[op.u] = st.pop()
+		default:
+			f, ok := refOps[op.name]
+			if !ok {
+				return false, fmt.Errorf("no reference for %s", op.name)
+			}
+			if err = f(&st); err != nil {
+				return false, err
+			}
+		}
+	}
+	if len(st) != 1 || st[0].Bytes != nil {
+		return false, errors.New("bad final stack")
+	}
+	return st[0].Uint != 0, nil
+}
+
+var fuzzInts = []uint64{0, 1, 2, 3, 7, 8, 255, 256, 1<<32 - 1, 1 << 32, 1 << 63, math.MaxUint64 - 1, math.MaxUint64}
+
+// fuzzScratchSlots are the scratch slots the generator uses, few enough that loads find stores
+const fuzzScratchSlots = 4
+
+// fuzzGen generates a random well-typed program, tracking the types of
+// the stack and scratch slots as it goes
+type fuzzGen struct {
+	rng     *rand.Rand
+	stack   []StackType
+	scratch [fuzzScratchSlots]StackType
+	prog    []fuzzOp
+}
+
+func (g *fuzzGen) emit(op fuzzOp, pops int, pushes ...StackType) {
+	g.prog = append(g.prog, op)
+	g.stack = append(g.stack[:len(g.stack)-pops], pushes...)
+}
+
+func (g *fuzzGen) pushConstant() {
+	if g.rng.Intn(2) == 0 {
+		var x uint64
+		if g.rng.Intn(3) == 0 {
+			x = g.rng.Uint64() >> uint(g.rng.Intn(64))
+		} else {
+			x = fuzzInts[g.rng.Intn(len(fuzzInts))]
+		}
+		g.emit(fuzzOp{name: "int", u: x}, 0, StackUint64)
+		return
+	}
+	// lengths around 8 exercise btoi
+	b := make([]byte, g.rng.Intn(12))
+	g.rng.Read(b)
+	g.emit(fuzzOp{name: "byte", b: b}, 0, StackBytes)
+}
+
+// fits reports whether the top of the stack can be passed to spec
+func (g *fuzzGen) fits(spec OpSpec) bool {
+	n := len(spec.Args)
+	if n > len(g.stack) {
+		return false
+	}
+	top := g.stack[len(g.stack)-n:]
+	for i, argType := range spec.Args {
+		if argType != StackAny && argType != top[i] {
+			return false
+		}
+	}
+	// comparing different types is not well-typed
+	if n == 2 && spec.Args[0] == StackAny && top[0] != top[1] {
+		return false
+	}
+	return true
+}
+
+func (g *fuzzGen) step() {
+	switch r := g.rng.Intn(10); {
+	case r < 3 || len(g.stack) == 0:
+		g.pushConstant()
+		return
+	case r == 3:
+		slot := g.rng.Intn(fuzzScratchSlots)
+		g.scratch[slot] = g.stack[len(g.stack)-1]
+		g.emit(fuzzOp{name: "store", u: uint64(slot)}, 1)
+		return
+	case r == 4:
+		slot := g.rng.Intn(fuzzScratchSlots)
+		g.emit(fuzzOp{name: "load", u: uint64(slot)}, 0, g.scratch[slot])
+		return
+	}
+	var candidates []OpSpec
+	for _, spec := range OpSpecs {
+		if _, ok := refOps[spec.Name]; ok && g.fits(spec) {
+			candidates = append(candidates, spec)
+		}
+	}
+	if len(candidates) == 0 {
+		g.pushConstant()
+		return
+	}
+	spec := candidates[g.rng.Intn(len(candidates))]
+	var pushes []StackType
+	switch spec.Name {
+	case "dup":
+		pushes = []StackType{g.stack[len(g.stack)-1], g.stack[len(g.stack)-1]}
+	default:
+		pushes = spec.Returns
+	}
+	g.emit(fuzzOp{name: spec.Name}, len(spec.Args), pushes...)
+}
+
+// finish leaves a single uint64 on the stack
+func (g *fuzzGen) finish() {
+	for len(g.stack) > 1 {
+		g.emit(fuzzOp{name: "pop"}, 1)
+	}
+	if len(g.stack) == 0 {
+		g.pushConstant()
+	}
+	if g.stack[0] == StackBytes {
+		g.emit(fuzzOp{name: "len"}, 1, StackUint64)
+	}
+}
+
+func generateFuzzProgram(rng *rand.Rand) []fuzzOp {
+	g := fuzzGen{rng: rng}
+	for i := range g.scratch {
+		// unwritten scratch space loads as uint64 zero
+		g.scratch[i] = StackUint64
+	}
+	n := 1 + rng.Intn(40)
+	for i := 0; i < n; i++ {
+		g.step()
+	}
+	g.finish()
+	return g.prog
+}
+
+// checkFuzzProgram runs one program through every invariant
+func checkFuzzProgram(t *testing.T, prog []fuzzOp) {
+	text := fuzzText(prog)
+	program, err := buildFuzzProgram(prog)
+	require.NoError(t, err, text)
+
+	ep := defaultEvalParams(nil, nil)
+	cost, err := Check(program, ep)
+	require.NoError(t, err, text)
+
+	// with the cost limit set to the static bound, Eval must never run out
+	proto := *ep.Proto
+	proto.LogicSigMaxCost = uint64(cost)
+	ep.Proto = &proto
+	pass, err := Eval(program, ep)
+	isNotPanic(t, err)
+	require.NotEqual(t, errCostTooHigh, err, text)
+
+	refPass, refErr := refEval(prog)
+	if (err == nil) != (refErr == nil) || pass != refPass {
+		t.Fatalf("Eval returned (%v, %v) but reference returned (%v, %v) for program:\n%s", pass, err, refPass, refErr, text)
+	}
+}
+
+func TestFuzzCorpus(t *testing.T) {
+	t.Parallel()
+	for _, text := range fuzzCorpus {
+		prog, err := parseFuzzOps(text)
+		require.NoError(t, err, text)
+		checkFuzzProgram(t, prog)
+	}
+}
+
+func TestFuzzEval(t *testing.T) {
+	t.Parallel()
+	iterations := 2000
+	if testing.Short() {
+		iterations = 200
+	}
+	seed := rand.Int63()
+	t.Logf("fuzz seed %d", seed)
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < iterations; i++ {
+		checkFuzzProgram(t, generateFuzzProgram(rng))
+	}
+}
+
+func TestFuzzReference(t *testing.T) {
+	t.Parallel()
+	// every op the reference knows must be a real op that needs no immediates
+	for name := range refOps {
+		opcode, ok := opcodesByName[name]
+		require.True(t, ok, name)
+		require.Equal(t, 1, opSizeByOpcode[opcode].size, name)
+	}
+}
@@ -13,12 +13,15 @@ func _() {
 	_ = x[MaxTxnLife-2]
 	_ = x[ZeroAddress-3]
 	_ = x[GroupSize-4]
-	_ = x[invalidGlobalField-5]
+	_ = x[GroupID-5]
+	_ = x[Round-6]
+	_ = x[LatestTimestamp-7]
+	_ = x[invalidGlobalField-8]
 }
 
-const _GlobalField_name = "MinTxnFeeMinBalanceMaxTxnLifeZeroAddressGroupSizeinvalidGlobalField"
+const _GlobalField_name = "MinTxnFeeMinBalanceMaxTxnLifeZeroAddressGroupSizeGroupIDRoundLatestTimestampinvalidGlobalField"
 
-var _GlobalField_index = [...]uint8{0, 9, 19, 29, 40, 49, 67}
+var _GlobalField_index = [...]uint8{0, 9, 19, 29, 40, 49, 56, 61, 76, 94}
 
 func (i GlobalField) String() string {
 	if i < 0 || i >= GlobalField(len(_GlobalField_index)-1) {
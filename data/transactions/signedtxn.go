@@ -49,6 +49,10 @@ type SignedTxn struct {
 	Lsig LogicSig           `codec:"lsig"`
 	Txn  Transaction        `codec:"txn"`
 
+	// AuthAddr is the address that signed the transaction, if it is
+	// not the sender, because the sender has been rekeyed.
+	AuthAddr basics.Address `codec:"sgnr"`
+
 	// The length of the encoded SignedTxn, used for computing the
 	// transaction's priority in the transaction pool.
 	cachedEncodingLen int
@@ -121,6 +125,16 @@ func (s SignedTxn) ID() Txid {
 	return s.Txn.ID()
 }
 
+// Authorizer returns the address whose signature, multisig or logic
+// signature must authorize this transaction: AuthAddr if it is set,
+// and the sender otherwise.
+func (s SignedTxn) Authorizer() basics.Address {
+	if (s.AuthAddr == basics.Address{}) {
+		return s.Txn.Sender
+	}
+	return s.AuthAddr
+}
+
 // ID on SignedTxnInBlock should never be called, because the ID depends
 // on the block from which this transaction will be decoded.  By having
 // a different return value from SignedTxn.ID(), we will catch errors at
@@ -93,6 +93,11 @@ type Header struct {
 	// the LastValid round passes.  While this transaction possesses the
 	// lease, no other transaction specifying this lease can be confirmed.
 	Lease [32]byte `codec:"lx"`
+
+	// RekeyTo, if nonzero, sets the sender's AuthAddr to the given
+	// address once the transaction is applied.  Rekeying an account
+	// to its own address clears its AuthAddr.
+	RekeyTo basics.Address `codec:"rekey"`
 }
 
 // Transaction describes a transaction that can appear in a block.
@@ -289,6 +294,18 @@ func (tx Transaction) WellFormed(spec SpecialAddresses, proto config.ConsensusPa
 			return fmt.Errorf("asset transaction not supported")
 		}
 
+		if tx.AssetFundOptIn {
+			if !proto.SupportFundedAssetOptIn {
+				return fmt.Errorf("funded asset opt-in not supported")
+			}
+			if !tx.AssetSender.IsZero() || !tx.AssetCloseTo.IsZero() {
+				return fmt.Errorf("funded asset opt-in cannot be combined with clawback or close-to")
+			}
+			if tx.AssetReceiver.IsZero() || tx.AssetReceiver == tx.Sender {
+				return fmt.Errorf("funded asset opt-in needs a receiver other than the sender")
+			}
+		}
+
 	case protocol.AssetFreezeTx:
 		if !proto.Asset {
 			return fmt.Errorf("asset transaction not supported")
@@ -325,7 +342,8 @@ func (tx Transaction) WellFormed(spec SpecialAddresses, proto config.ConsensusPa
 		}
 	}
 
-	if tx.Fee.LessThan(basics.MicroAlgos{Raw: proto.MinTxnFee}) {
+	if !proto.EnableFeePooling && tx.Fee.LessThan(basics.MicroAlgos{Raw: proto.MinTxnFee}) {
+		// With fee pooling, the minimum fee is checked for the whole group by CheckGroupFee.
 		return makeMinFeeErrorf("transaction had fee %v, which is less than the minimum %v", tx.Fee, proto.MinTxnFee)
 	}
 	if tx.LastValid < tx.FirstValid {
@@ -346,6 +364,9 @@ func (tx Transaction) WellFormed(spec SpecialAddresses, proto config.ConsensusPa
 	if len(tx.AssetConfigTxnFields.AssetParams.URL) > proto.MaxAssetURLBytes {
 		return fmt.Errorf("transaction asset url too big: %d > %d", len(tx.AssetConfigTxnFields.AssetParams.URL), proto.MaxAssetURLBytes)
 	}
+	if tx.AssetConfigTxnFields.AssetParams.Decimals > proto.MaxAssetDecimals {
+		return fmt.Errorf("transaction asset decimals too big: %d > %d", tx.AssetConfigTxnFields.AssetParams.Decimals, proto.MaxAssetDecimals)
+	}
 	if tx.Sender == spec.RewardsPool {
 		// this check is just to be safe, but reaching here seems impossible, since it requires computing a preimage of rwpool
 		return fmt.Errorf("transaction from incentive pool is invalid")
@@ -359,6 +380,30 @@ func (tx Transaction) WellFormed(spec SpecialAddresses, proto config.ConsensusPa
 	if !proto.SupportTxGroups && (tx.Group != crypto.Digest{}) {
 		return fmt.Errorf("transaction has group but groups not yet enabled")
 	}
+	if !proto.SupportRekeying && (tx.RekeyTo != basics.Address{}) {
+		return fmt.Errorf("transaction tried to rekey to %v but protocol does not support rekeying", tx.RekeyTo)
+	}
+	return nil
+}
+
+// CheckGroupFee checks that the fees of the transactions in a group add up
+// to at least MinTxnFee for each of them.  With fee pooling, WellFormed
+// accepts a transaction that pays less than MinTxnFee, or nothing at all,
+// as long as another transaction in its group makes up the difference.
+// Without fee pooling, every transaction already pays MinTxnFee on its own.
+func CheckGroupFee(fees []basics.MicroAlgos, proto config.ConsensusParams) error {
+	if !proto.EnableFeePooling {
+		return nil
+	}
+
+	var total basics.MicroAlgos
+	for _, fee := range fees {
+		total.Raw = basics.AddSaturate(total.Raw, fee.Raw)
+	}
+	required := basics.MulSaturate(proto.MinTxnFee, uint64(len(fees)))
+	if total.Raw < required {
+		return makeMinFeeErrorf("transaction group had fees %v, which is less than the minimum %v for %d transactions", total, required, len(fees))
+	}
 	return nil
 }
 
@@ -429,6 +474,25 @@ func (tx Transaction) Apply(balances Balances, spec SpecialAddresses, ctr uint64
 		return
 	}
 
+	// rekey the sender before the type-specific effects, so that
+	// closing the account also clears its AuthAddr
+	if tx.RekeyTo != (basics.Address{}) {
+		var record basics.BalanceRecord
+		record, err = balances.Get(tx.Sender, false)
+		if err != nil {
+			return
+		}
+		if tx.RekeyTo == tx.Sender {
+			record.AuthAddr = basics.Address{}
+		} else {
+			record.AuthAddr = tx.RekeyTo
+		}
+		err = balances.Put(record)
+		if err != nil {
+			return
+		}
+	}
+
 	switch tx.Type {
 	case protocol.PaymentTx:
 		err = tx.PaymentTxnFields.apply(tx.Header, balances, spec, &ad)
@@ -17,6 +17,7 @@
 package transactions
 
 import (
+	"math"
 	"testing"
 
 	"github.com/vincentbdb/go-algorand/config"
@@ -118,3 +119,108 @@ func TestGoNonparticipatingWellFormed(t *testing.T) {
 	err = tx.WellFormed(SpecialAddresses{FeeSink: feeSink}, curProto)
 	require.Error(t, err)
 }
+
+func TestRekeyWellFormed(t *testing.T) {
+	addr, err := basics.UnmarshalChecksumAddress("NDQCJNNY5WWWFLP4GFZ7MEF2QJSMZYK6OWIV2AQ7OMAVLEFCGGRHFPKJJA")
+	require.NoError(t, err)
+
+	proto := config.Consensus[protocol.ConsensusFuture]
+	tx := Transaction{
+		Type: protocol.PaymentTx,
+		Header: Header{
+			Sender:     addr,
+			Fee:        basics.MicroAlgos{Raw: proto.MinTxnFee},
+			FirstValid: basics.Round(1000),
+			LastValid:  basics.Round(1000 + proto.MaxTxnLife),
+			RekeyTo:    basics.Address{0x01},
+		},
+		PaymentTxnFields: PaymentTxnFields{
+			Receiver: addr,
+		},
+	}
+	require.NoError(t, tx.WellFormed(spec, proto))
+
+	// Rekeying is not supported by the current protocol.
+	require.Error(t, tx.WellFormed(spec, config.Consensus[protocol.ConsensusCurrentVersion]))
+}
+
+func TestAssetDecimalsWellFormed(t *testing.T) {
+	addr, err := basics.UnmarshalChecksumAddress("NDQCJNNY5WWWFLP4GFZ7MEF2QJSMZYK6OWIV2AQ7OMAVLEFCGGRHFPKJJA")
+	require.NoError(t, err)
+
+	proto := config.Consensus[protocol.ConsensusFuture]
+	tx := Transaction{
+		Type: protocol.AssetConfigTx,
+		Header: Header{
+			Sender:     addr,
+			Fee:        basics.MicroAlgos{Raw: proto.MinTxnFee},
+			FirstValid: basics.Round(1000),
+			LastValid:  basics.Round(1000 + proto.MaxTxnLife),
+		},
+		AssetConfigTxnFields: AssetConfigTxnFields{
+			AssetParams: basics.AssetParams{
+				Total:    1000,
+				Decimals: proto.MaxAssetDecimals,
+			},
+		},
+	}
+	require.NoError(t, tx.WellFormed(spec, proto))
+
+	tx.AssetParams.Decimals++
+	require.Error(t, tx.WellFormed(spec, proto))
+
+	// Decimals are not supported by the current protocol.
+	tx.AssetParams.Decimals = 2
+	require.Error(t, tx.WellFormed(spec, config.Consensus[protocol.ConsensusCurrentVersion]))
+}
+
+func TestFundedAssetOptInWellFormed(t *testing.T) {
+	addr, err := basics.UnmarshalChecksumAddress("NDQCJNNY5WWWFLP4GFZ7MEF2QJSMZYK6OWIV2AQ7OMAVLEFCGGRHFPKJJA")
+	require.NoError(t, err)
+
+	proto := config.Consensus[protocol.ConsensusFuture]
+	tx := Transaction{
+		Type: protocol.AssetTransferTx,
+		Header: Header{
+			Sender:     addr,
+			Fee:        basics.MicroAlgos{Raw: proto.MinTxnFee},
+			FirstValid: basics.Round(1000),
+			LastValid:  basics.Round(1000 + proto.MaxTxnLife),
+		},
+		AssetTransferTxnFields: AssetTransferTxnFields{
+			XferAsset:      1,
+			AssetReceiver:  basics.Address{0x01},
+			AssetFundOptIn: true,
+		},
+	}
+	require.NoError(t, tx.WellFormed(spec, proto))
+
+	// Funded opt-in is not supported by the current protocol.
+	require.Error(t, tx.WellFormed(spec, config.Consensus[protocol.ConsensusCurrentVersion]))
+
+	// It cannot be combined with a clawback or close-to.
+	tx.AssetCloseTo = basics.Address{0x02}
+	require.Error(t, tx.WellFormed(spec, proto))
+	tx.AssetCloseTo = basics.Address{}
+	tx.AssetSender = basics.Address{0x02}
+	require.Error(t, tx.WellFormed(spec, proto))
+	tx.AssetSender = basics.Address{}
+
+	// The sender cannot fund its own opt-in.
+	tx.AssetReceiver = addr
+	require.Error(t, tx.WellFormed(spec, proto))
+}
+
+func TestCheckGroupFee(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusFuture]
+	min := basics.MicroAlgos{Raw: proto.MinTxnFee}
+
+	require.NoError(t, CheckGroupFee([]basics.MicroAlgos{{}, {Raw: 2 * min.Raw}}, proto))
+	require.NoError(t, CheckGroupFee([]basics.MicroAlgos{min, min}, proto))
+	require.Error(t, CheckGroupFee([]basics.MicroAlgos{{}, {Raw: 2*min.Raw - 1}}, proto))
+	require.Error(t, CheckGroupFee([]basics.MicroAlgos{{}}, proto))
+	require.NoError(t, CheckGroupFee([]basics.MicroAlgos{{Raw: math.MaxUint64}, {Raw: 1}}, proto))
+
+	// Without fee pooling, WellFormed checks each transaction's fee instead.
+	require.NoError(t, CheckGroupFee([]basics.MicroAlgos{{}}, config.Consensus[protocol.ConsensusCurrentVersion]))
+}
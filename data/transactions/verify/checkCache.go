@@ -0,0 +1,118 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package verify
+
+import (
+	"github.com/algorand/go-deadlock"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+	"github.com/vincentbdb/go-algorand/util/metrics"
+)
+
+var logicCheckCacheHits = metrics.MakeCounter(metrics.MetricName{Name: "algod_logic_check_cache_hits", Description: "Number of logic.Check results served from the program cache"})
+var logicCheckCacheMisses = metrics.MakeCounter(metrics.MetricName{Name: "algod_logic_check_cache_misses", Description: "Number of programs run through logic.Check because they were not in the program cache"})
+
+// defaultCheckCacheSize is the number of programs remembered by the
+// shared cache before its older half is dropped.
+const defaultCheckCacheSize = 10000
+
+// checkCacheKey identifies a program for the purpose of logic.Check.
+// Check depends only on the program and on the LogicSigVersion of
+// the consensus protocol, so that is all the key holds.
+type checkCacheKey struct {
+	program crypto.Digest
+	version uint64
+}
+
+// checkResult is the outcome of running logic.Check on a program
+type checkResult struct {
+	cost int
+	err  error
+}
+
+// checkCache remembers logic.Check results so that identical programs,
+// such as many escrows instantiated from the same template, are only
+// checked once.  It keeps two generations of results: when the current
+// one fills up it replaces the previous one, bounding the cache to twice
+// its size.
+type checkCache struct {
+	mu   deadlock.Mutex
+	cur  map[checkCacheKey]checkResult
+	prev map[checkCacheKey]checkResult
+	size int
+}
+
+func makeCheckCache(size int) *checkCache {
+	return &checkCache{
+		cur:  make(map[checkCacheKey]checkResult, size),
+		size: size,
+	}
+}
+
+func (cc *checkCache) get(key checkCacheKey) (result checkResult, found bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	result, found = cc.cur[key]
+	if found {
+		return
+	}
+	result, found = cc.prev[key]
+	return
+}
+
+func (cc *checkCache) put(key checkCacheKey, result checkResult) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.cur[key] = result
+	if len(cc.cur) >= cc.size {
+		cc.prev = cc.cur
+		cc.cur = make(map[checkCacheKey]checkResult, cc.size)
+	}
+}
+
+// check runs logic.Check on program, unless the result for the same
+// program under the same LogicSigVersion is already known.
+func (cc *checkCache) check(program []byte, proto *config.ConsensusParams) (cost int, err error) {
+	key := checkCacheKey{
+		program: crypto.HashObj(logic.Program(program)),
+		version: proto.LogicSigVersion,
+	}
+	result, found := cc.get(key)
+	if found {
+		logicCheckCacheHits.Inc(nil)
+		return result.cost, result.err
+	}
+	logicCheckCacheMisses.Inc(nil)
+
+	ep := logic.EvalParams{Proto: proto}
+	result.cost, result.err = logic.Check(program, ep)
+	cc.put(key, result)
+	return result.cost, result.err
+}
+
+// sharedCheckCache is used by both the transaction pool and the block evaluator
+var sharedCheckCache = makeCheckCache(defaultCheckCacheSize)
+
+// CheckProgram returns the cost of a LogicSig program as computed by
+// logic.Check, or the error Check found in it.  Results are cached by
+// program hash and LogicSigVersion, and the cache is shared by every
+// caller in the process.
+func CheckProgram(program []byte, proto *config.ConsensusParams) (cost int, err error) {
+	return sharedCheckCache.check(program, proto)
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package verify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+)
+
+func TestCheckCache(t *testing.T) {
+	proto := config.ConsensusParams{LogicSigVersion: 1, LogicSigMaxCost: 20000}
+	program, err := logic.AssembleString("byte 0x01\nsha256\nlen\nint 32\n==")
+	require.NoError(t, err)
+	expectedCost, err := logic.Check(program, logic.EvalParams{Proto: &proto})
+	require.NoError(t, err)
+
+	cc := makeCheckCache(4)
+	cost, err := cc.check(program, &proto)
+	require.NoError(t, err)
+	require.Equal(t, expectedCost, cost)
+
+	key := checkCacheKey{crypto.HashObj(logic.Program(program)), 1}
+	result, found := cc.get(key)
+	require.True(t, found)
+	require.Equal(t, expectedCost, result.cost)
+
+	// a different protocol version is a different entry
+	_, found = cc.get(checkCacheKey{key.program, 2})
+	require.False(t, found)
+
+	// errors are remembered too
+	bad := []byte{0x01, 0xff}
+	_, err = cc.check(bad, &proto)
+	require.Error(t, err)
+	result, found = cc.get(checkCacheKey{crypto.HashObj(logic.Program(bad)), 1})
+	require.True(t, found)
+	require.Error(t, result.err)
+}
+
+func TestCheckCacheBounded(t *testing.T) {
+	cc := makeCheckCache(4)
+	var keys []checkCacheKey
+	for i := 0; i < 10; i++ {
+		key := checkCacheKey{crypto.Hash([]byte{byte(i)}), 1}
+		keys = append(keys, key)
+		cc.put(key, checkResult{cost: i})
+		require.True(t, len(cc.cur) < 4)
+		require.True(t, len(cc.prev) <= 4)
+	}
+	// the most recent entries are still present, the oldest are gone
+	result, found := cc.get(keys[9])
+	require.True(t, found)
+	require.Equal(t, 9, result.cost)
+	_, found = cc.get(keys[0])
+	require.False(t, found)
+}
@@ -88,6 +88,10 @@ func stxnAsyncVerify(arg interface{}) interface{} {
 }
 
 func stxnVerifyCore(s *transactions.SignedTxn, proto *config.ConsensusParams) error {
+	if !proto.SupportRekeying && (s.AuthAddr != basics.Address{}) {
+		return errors.New("signedtxn has an auth address but protocol does not support rekeying")
+	}
+
 	numSigs := 0
 	hasSig := false
 	hasMsig := false
@@ -112,13 +116,16 @@ func stxnVerifyCore(s *transactions.SignedTxn, proto *config.ConsensusParams) er
 	}
 
 	if hasSig {
-		if crypto.SignatureVerifier(s.Txn.Src()).Verify(s.Txn, s.Sig) {
+		if crypto.SignatureVerifier(s.Authorizer()).Verify(s.Txn, s.Sig) {
 			return nil
 		}
 		return errors.New("signature validation failed")
 	}
 	if hasMsig {
-		if ok, _ := crypto.MultisigVerify(s.Txn, crypto.Digest(s.Txn.Src()), s.Msig); ok {
+		if !proto.SupportWeightedMultisig && s.Msig.Version == crypto.WeightedMultisigVersion {
+			return errors.New("signedtxn has a weighted multisig but protocol does not support weighted multisig")
+		}
+		if ok, _ := crypto.MultisigVerify(s.Txn, crypto.Digest(s.Authorizer()), s.Msig); ok {
 			return nil
 		}
 		return errors.New("multisig validation failed")
@@ -145,12 +152,14 @@ func LogicSig(lsig *transactions.LogicSig, proto *config.ConsensusParams, stxn *
 	if version > proto.LogicSigVersion {
 		return errors.New("LogicSig.Logic version too new")
 	}
+	if version < logic.RekeyToVersion && !stxn.Txn.RekeyTo.IsZero() {
+		return errors.New("LogicSig.Logic version cannot approve a rekey")
+	}
 	if uint64(lsig.Len()) > proto.LogicSigMaxSize {
 		return errors.New("LogicSig.Logic too long")
 	}
 
-	ep := logic.EvalParams{Txn: stxn, Proto: proto}
-	cost, err := logic.Check(lsig.Logic, ep)
+	cost, err := CheckProgram(lsig.Logic, proto)
 	if err != nil {
 		return err
 	}
@@ -170,10 +179,10 @@ func LogicSig(lsig *transactions.LogicSig, proto *config.ConsensusParams, stxn *
 		numSigs++
 	}
 	if numSigs == 0 {
-		// if the txn.Sender == hash(Logic) then this is a (potentially) valid operation on a contract-only account
+		// if the txn's authorizer == hash(Logic) then this is a (potentially) valid operation on a contract-only account
 		program := logic.Program(lsig.Logic)
 		lhash := crypto.HashObj(&program)
-		if crypto.Digest(stxn.Txn.Sender) == lhash {
+		if crypto.Digest(stxn.Authorizer()) == lhash {
 			return nil
 		}
 		return errors.New("LogicNot signed and not a Logic-only account")
@@ -184,14 +193,17 @@ func LogicSig(lsig *transactions.LogicSig, proto *config.ConsensusParams, stxn *
 
 	if hasSig {
 		program := logic.Program(lsig.Logic)
-		if crypto.SignatureVerifier(stxn.Txn.Src()).Verify(&program, lsig.Sig) {
+		if crypto.SignatureVerifier(stxn.Authorizer()).Verify(&program, lsig.Sig) {
 			return nil
 		}
 		return errors.New("logic signature validation failed")
 	}
 	if hasMsig {
+		if !proto.SupportWeightedMultisig && lsig.Msig.Version == crypto.WeightedMultisigVersion {
+			return errors.New("LogicSig has a weighted multisig but protocol does not support weighted multisig")
+		}
 		program := logic.Program(lsig.Logic)
-		if ok, _ := crypto.MultisigVerify(&program, crypto.Digest(stxn.Txn.Src()), lsig.Msig); ok {
+		if ok, _ := crypto.MultisigVerify(&program, crypto.Digest(stxn.Authorizer()), lsig.Msig); ok {
 			return nil
 		}
 		return errors.New("logic multisig validation failed")
@@ -26,6 +26,7 @@ import (
 	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
 	"github.com/vincentbdb/go-algorand/protocol"
 )
 
@@ -135,3 +136,83 @@ func TestDecodeNil(t *testing.T) {
 		Txn(&st, spec, config.Consensus[protocol.ConsensusCurrentVersion])
 	}
 }
+
+func TestRekeyedSignature(t *testing.T) {
+	payments, _, secrets, _ := generateTestObjects(1, 2)
+	payment := payments[0]
+	other := secrets[1]
+	if basics.Address(other.SignatureVerifier) == payment.Sender {
+		other = secrets[0]
+	}
+	otherAddr := basics.Address(other.SignatureVerifier)
+
+	future := config.Consensus[protocol.ConsensusFuture]
+	current := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	// Signed by another key, the transaction needs to name that key
+	// as its auth address.
+	stxn := payment.Sign(other)
+	require.Error(t, Txn(&stxn, spec, future))
+
+	stxn.AuthAddr = otherAddr
+	require.Equal(t, otherAddr, stxn.Authorizer())
+	require.NoError(t, Txn(&stxn, spec, future))
+	require.Error(t, Txn(&stxn, spec, current))
+
+	stxn.AuthAddr = payment.Sender
+	require.Error(t, Txn(&stxn, spec, future))
+}
+
+func TestWeightedMultisigSignature(t *testing.T) {
+	payments, _, secrets, _ := generateTestObjects(1, 3)
+	payment := payments[0]
+
+	pks := []crypto.PublicKey{secrets[0].SignatureVerifier, secrets[1].SignatureVerifier, secrets[2].SignatureVerifier}
+	weights := []uint8{2, 1, 1}
+	msigAddr, err := crypto.MultisigAddrGenWeighted(3, pks, weights)
+	require.NoError(t, err)
+	payment.Sender = basics.Address(msigAddr)
+	preimage := crypto.MultisigPreimageFromWeightedPKs(3, pks, weights)
+
+	future := config.Consensus[protocol.ConsensusFuture]
+	current := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	sig1, err := crypto.MultisigSignPreimage(payment, msigAddr, preimage, *secrets[1])
+	require.NoError(t, err)
+	sig2, err := crypto.MultisigSignPreimage(payment, msigAddr, preimage, *secrets[2])
+	require.NoError(t, err)
+	msig, err := crypto.MultisigMerge(sig1, sig2)
+	require.NoError(t, err)
+
+	// Two keys of weight 1 do not reach the threshold of 3
+	stxn := transactions.SignedTxn{Txn: payment, Msig: msig}
+	require.Error(t, Txn(&stxn, spec, future))
+
+	sig0, err := crypto.MultisigSignPreimage(payment, msigAddr, preimage, *secrets[0])
+	require.NoError(t, err)
+	stxn.Msig, err = crypto.MultisigMerge(sig0, sig1)
+	require.NoError(t, err)
+	require.NoError(t, Txn(&stxn, spec, future))
+	require.Error(t, Txn(&stxn, spec, current))
+}
+
+func TestLogicSigRekey(t *testing.T) {
+	payments, _, _, addresses := generateTestObjects(1, 1)
+	payment := payments[0]
+	payment.RekeyTo = addresses[0]
+
+	future := config.Consensus[protocol.ConsensusFuture]
+
+	for _, source := range []string{"int 1", "#pragma version 2\nint 1"} {
+		program, err := logic.AssembleString(source)
+		require.NoError(t, err)
+		payment.Sender = basics.Address(logic.HashProgram(program))
+		stxn := transactions.SignedTxn{Txn: payment, Lsig: transactions.LogicSig{Logic: program}}
+		if program[0] < logic.RekeyToVersion {
+			// the program cannot see the rekey, so it cannot approve it
+			require.Error(t, Txn(&stxn, spec, future))
+		} else {
+			require.NoError(t, Txn(&stxn, spec, future))
+		}
+	}
+}
@@ -45,9 +45,10 @@ var defaultIncentivePoolBalanceAtInception uint64 = 125e6 * 1e6
 const TotalMoney uint64 = 10 * 1e9 * 1e6
 
 type genesisAllocation struct {
-	Name   string
-	Stake  uint64
-	Online basics.Status
+	Name    string
+	Stake   uint64
+	Online  basics.Status
+	Vesting *VestingData
 }
 
 // GenerateGenesisFiles generates the genesis.json file and wallet files for a give genesis configuration.
@@ -62,12 +63,15 @@ func GenerateGenesisFiles(genesisData GenesisData, outDir string, verbose bool)
 
 	for i, wallet := range genesisData.Wallets {
 		acct := genesisAllocation{
-			Name:   wallet.Name,
-			Stake:  uint64(float64(TotalMoney/100)*wallet.Stake + .5),
-			Online: basics.Online,
+			Name:    wallet.Name,
+			Stake:   uint64(float64(TotalMoney/100)*wallet.Stake + .5),
+			Online:  basics.Online,
+			Vesting: wallet.Vesting,
 		}
 		if !wallet.Online {
 			acct.Online = basics.Offline
+		} else if wallet.Vesting != nil {
+			return fmt.Errorf("vesting wallet %s cannot be online", wallet.Name)
 		}
 		allocation[i] = acct
 		sum += acct.Stake
@@ -101,6 +105,7 @@ func generateGenesisFiles(outDir string, proto protocol.ConsensusVersion, netNam
 
 	genesisAddrs := make(map[string]basics.Address)
 	records := make(map[string]basics.AccountData)
+	vesting := make(map[string]*bookkeeping.VestingSchedule)
 
 	params, ok := config.Consensus[proto]
 	if !ok {
@@ -186,10 +191,23 @@ func generateGenesisFiles(outDir string, proto protocol.ConsensusVersion, netNam
 			data.VoteKeyDilution = part.KeyDilution
 		}
 
-		records[wallet.Name] = data
-
 		genesisAddrs[wallet.Name] = root.Address()
 
+		// A vesting wallet's stake is held by an escrow that pays out to its root key
+		if wallet.Vesting != nil {
+			var escrow basics.Address
+			var schedule bookkeeping.VestingSchedule
+			escrow, schedule, err = makeVestingSchedule(root.Address(), wallet.Stake, *wallet.Vesting, params)
+			if err != nil {
+				err = fmt.Errorf("wallet %s: %v", wallet.Name, err)
+				return
+			}
+			genesisAddrs[wallet.Name] = escrow
+			vesting[wallet.Name] = &schedule
+		}
+
+		records[wallet.Name] = data
+
 		rootDB.Close()
 		if wallet.Online == basics.Online {
 			partDB.Close()
@@ -237,6 +255,11 @@ func generateGenesisFiles(outDir string, proto protocol.ConsensusVersion, netNam
 		RewardsPool: rewardsPool.String(),
 		Comment:     comment,
 	}
+	// Only networks with consensus overrides pin their parameters, so that
+	// networks on stock protocols can follow releases that change them.
+	if config.ConsensusOverridden() {
+		g.ProtoParamsHash = bookkeeping.ProtoParamsHash(proto)
+	}
 
 	for _, wallet := range allocation {
 		walletData := records[wallet.Name]
@@ -245,6 +268,7 @@ func generateGenesisFiles(outDir string, proto protocol.ConsensusVersion, netNam
 			Address: genesisAddrs[wallet.Name].String(),
 			Comment: wallet.Name,
 			State:   walletData,
+			Vesting: vesting[wallet.Name],
 		})
 	}
 
@@ -0,0 +1,129 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package gen
+
+//go:generate ./bundle_vesting_template.sh
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+)
+
+// makeVestingSchedule compiles the vesting template for a wallet's stake and
+// returns the escrow address holding it.  The escrow pays the fee of every
+// withdrawal, and keeps MinBalance until it is closed, so enough for both is
+// held back.  The rest unlocks linearly from genesis to vesting.End: whatever
+// has vested by vesting.Cliff is released at the cliff, and the rest in equal
+// parts every vesting.Period rounds.  What is held back, less the fees paid,
+// and rounding leftovers are released when the escrow is closed after End.
+func makeVestingSchedule(beneficiary basics.Address, stake uint64, vesting VestingData, params config.ConsensusParams) (escrow basics.Address, schedule bookkeeping.VestingSchedule, err error) {
+	if vesting.Period == 0 {
+		err = fmt.Errorf("vesting period must be positive")
+		return
+	}
+	if vesting.Cliff == 0 || vesting.Cliff > vesting.End {
+		err = fmt.Errorf("vesting cliff %d must be positive and no later than the end %d", vesting.Cliff, vesting.End)
+		return
+	}
+
+	maxFee := vesting.MaxFee
+	if maxFee == 0 {
+		maxFee = 10 * params.MinTxnFee
+	}
+
+	// One withdrawal at the cliff, one per period, and the final close.
+	periods := vesting.End/vesting.Period - vesting.Cliff/vesting.Period
+	withdrawals := basics.AddSaturate(periods, 2)
+	reserve := basics.AddSaturate(params.MinBalance, basics.MulSaturate(maxFee, withdrawals))
+	if stake <= reserve {
+		err = fmt.Errorf("stake %d does not cover the escrow's minimum balance and the fees of %d withdrawals, %d", stake, withdrawals, reserve)
+		return
+	}
+	vested := stake - reserve
+
+	hi, lo := bits.Mul64(vested, vesting.Cliff)
+	cliffAmount, _ := bits.Div64(hi, lo, vesting.End)
+
+	var periodAmount uint64
+	if periods > 0 {
+		periodAmount = (vested - cliffAmount) / periods
+	}
+
+	duration := vesting.Period - 1
+	if duration > params.MaxTxnLife {
+		duration = params.MaxTxnLife
+	}
+
+	// The lease only needs to be fixed and nonzero, so that a single
+	// release can be withdrawn per window; the beneficiary's address is
+	// a convenient choice.
+	program, err := logic.AssembleString(fillVestingTemplate(map[string]string{
+		"TMPL_RCV":       beneficiary.String(),
+		"TMPL_CLIFF":     strconv.FormatUint(vesting.Cliff, 10),
+		"TMPL_CLIFF_AMT": strconv.FormatUint(cliffAmount, 10),
+		"TMPL_PERIOD":    strconv.FormatUint(vesting.Period, 10),
+		"TMPL_AMT":       strconv.FormatUint(periodAmount, 10),
+		"TMPL_DUR":       strconv.FormatUint(duration, 10),
+		"TMPL_END":       strconv.FormatUint(vesting.End, 10),
+		"TMPL_LEASE":     base64.StdEncoding.EncodeToString(beneficiary[:]),
+		"TMPL_FEE":       strconv.FormatUint(maxFee, 10),
+	}))
+	if err != nil {
+		err = fmt.Errorf("could not assemble vesting program: %v", err)
+		return
+	}
+
+	escrow = basics.Address(crypto.HashObj(logic.Program(program)))
+	schedule = bookkeeping.VestingSchedule{
+		Beneficiary:  beneficiary.String(),
+		Cliff:        basics.Round(vesting.Cliff),
+		CliffAmount:  cliffAmount,
+		Period:       vesting.Period,
+		PeriodAmount: periodAmount,
+		Duration:     duration,
+		End:          basics.Round(vesting.End),
+		Program:      program,
+	}
+	return
+}
+
+// fillVestingTemplate substitutes values into the vesting template, longer
+// names first so that no name clobbers another it prefixes.
+func fillVestingTemplate(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	progtext := vestingTemplate
+	for _, k := range names {
+		progtext = strings.ReplaceAll(progtext, k, values[k])
+	}
+	return progtext
+}
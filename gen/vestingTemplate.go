@@ -0,0 +1,98 @@
+// Code generated by bundle_vesting_template.sh from tools/teal/templates/vesting.teal.tmpl; DO NOT EDIT.
+
+package gen
+
+const vestingTemplate = `// Releases funds held in an escrow to a beneficiary on a vesting schedule.
+// This is a contract account.
+//
+// Nothing can be withdrawn before TMPL_CLIFF. At TMPL_CLIFF,
+// TMPL_RCV may withdraw TMPL_CLIFF_AMT. After that, TMPL_RCV
+// may withdraw TMPL_AMT in every multiple of TMPL_PERIOD,
+// within TMPL_DUR rounds of the start of the period.
+//
+// After TMPL_END, all remaining funds in the escrow are
+// available to TMPL_RCV, including any releases that were
+// not withdrawn in time.
+//
+// Parameters:
+//  - TMPL_RCV: address which receives the vested funds
+//  - TMPL_CLIFF: the round at which funds start to unlock
+//  - TMPL_CLIFF_AMT: the amount which unlocks at the cliff
+//  - TMPL_PERIOD: the time between releases after the cliff
+//  - TMPL_AMT: the amount released every period
+//  - TMPL_DUR: the duration of a withdrawal window
+//  - TMPL_END: the round after which all funds are unlocked
+//  - TMPL_LEASE: string to use for the transaction lease
+//  - TMPL_FEE: maximum fee used by the withdrawal transaction
+txn TypeEnum
+int 1
+==
+txn Fee
+int TMPL_FEE
+<=
+&&
+txn LastValid
+int TMPL_DUR
+txn FirstValid
++
+==
+&&
+txn Lease
+byte base64 TMPL_LEASE
+==
+&& // is Payment and ok Fee and correct duration and good lease
+txn FirstValid
+int TMPL_CLIFF
+==
+txn Receiver
+addr TMPL_RCV
+==
+&&
+txn Amount
+int TMPL_CLIFF_AMT
+==
+&&
+txn CloseRemainderTo
+global ZeroAddress
+==
+&& // at cliff and good Receiver and cliff amount and no close
+txn FirstValid
+int TMPL_CLIFF
+>
+txn FirstValid
+int TMPL_PERIOD
+%
+int 0
+==
+&&
+txn Receiver
+addr TMPL_RCV
+==
+&&
+txn Amount
+int TMPL_AMT
+==
+&&
+txn CloseRemainderTo
+global ZeroAddress
+==
+&& // after cliff and on period and good Receiver and good amount and no close
+|| // cliff release or periodic release
+txn FirstValid
+int TMPL_END
+>
+txn CloseRemainderTo
+addr TMPL_RCV
+==
+&&
+txn Receiver
+global ZeroAddress
+==
+&&
+txn Amount
+int 0
+==
+&& // after end and good close to and 0 Amount
+|| // release or close
+&& // preamble checks and (release or close)
+`
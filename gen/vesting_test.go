@@ -0,0 +1,200 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package gen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+func TestVestingTemplateBundled(t *testing.T) {
+	data, err := ioutil.ReadFile("../tools/teal/templates/vesting.teal.tmpl")
+	require.NoError(t, err)
+	require.Equal(t, string(data), vestingTemplate, "run go generate to update the bundled template")
+}
+
+func TestMakeVestingSchedule(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	var rcv basics.Address
+	rcv[0] = 1
+
+	escrow, schedule, err := makeVestingSchedule(rcv, 1e12, VestingData{Cliff: 1000, Period: 100, End: 10000}, proto)
+	require.NoError(t, err)
+	require.Equal(t, basics.Address(crypto.HashObj(logic.Program(schedule.Program))), escrow)
+	require.Equal(t, rcv.String(), schedule.Beneficiary)
+	require.Equal(t, uint64(99), schedule.Duration)
+
+	// MinBalance and the fees of the cliff release, 90 periodic releases
+	// and the close are held back.
+	vested := uint64(1e12) - proto.MinBalance - 92*10*proto.MinTxnFee
+	require.Equal(t, vested/10, schedule.CliffAmount)
+	require.Equal(t, (vested-vested/10)/90, schedule.PeriodAmount)
+
+	// Releases can only be withdrawn in their windows.
+	const held = 1e12
+	require.Equal(t, uint64(0), schedule.Unlocked(999, held))
+	require.Equal(t, schedule.CliffAmount, schedule.Unlocked(1000, held))
+	require.Equal(t, schedule.CliffAmount, schedule.Unlocked(1099, held))
+	require.Equal(t, schedule.PeriodAmount, schedule.Unlocked(1100, held))
+	require.Equal(t, schedule.PeriodAmount, schedule.Unlocked(10000, held))
+	require.Equal(t, uint64(held), schedule.Unlocked(10001, held))
+
+	// The escrow pays out the cliff release to the beneficiary
+	var st transactions.SignedTxn
+	st.Txn.Type = protocol.PaymentTx
+	st.Txn.Sender = escrow
+	st.Txn.Fee = basics.MicroAlgos{Raw: proto.MinTxnFee}
+	st.Txn.FirstValid = 1000
+	st.Txn.LastValid = 1099
+	st.Txn.Lease = rcv
+	st.Txn.Receiver = rcv
+	st.Txn.Amount = basics.MicroAlgos{Raw: schedule.CliffAmount}
+	st.Lsig.Logic = schedule.Program
+	pass, err := logic.Eval(schedule.Program, logic.EvalParams{Txn: &st, Proto: &proto})
+	require.NoError(t, err)
+	require.True(t, pass)
+
+	// A v1 escrow can never approve a release that rekeys it
+	st.Txn.RekeyTo = rcv
+	pass, _ = logic.Eval(schedule.Program, logic.EvalParams{Txn: &st, Proto: &proto})
+	require.False(t, pass)
+
+	st.Txn.RekeyTo = basics.Address{}
+	st.Txn.Amount.Raw++
+	pass, _ = logic.Eval(schedule.Program, logic.EvalParams{Txn: &st, Proto: &proto})
+	require.False(t, pass)
+
+	_, _, err = makeVestingSchedule(rcv, 1e12, VestingData{Cliff: 1000, End: 10000}, proto)
+	require.Error(t, err)
+	_, _, err = makeVestingSchedule(rcv, 1e12, VestingData{Cliff: 20000, Period: 100, End: 10000}, proto)
+	require.Error(t, err)
+
+	// A stake that cannot cover the minimum balance and fees is refused.
+	_, _, err = makeVestingSchedule(rcv, proto.MinBalance+92*10*proto.MinTxnFee, VestingData{Cliff: 1000, Period: 100, End: 10000}, proto)
+	require.Error(t, err)
+}
+
+func TestVestingWithdrawEveryPeriod(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	proto.MaxTxnLife = 50
+	var rcv basics.Address
+	rcv[0] = 1
+
+	const stake = 1e9
+	vesting := VestingData{Cliff: 1000, Period: 100, End: 10000}
+	escrow, schedule, err := makeVestingSchedule(rcv, stake, vesting, proto)
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), schedule.Duration)
+	maxFee := 10 * proto.MinTxnFee
+
+	balance := uint64(stake)
+	var received uint64
+	withdraw := func(first basics.Round, amount uint64, closeOut bool) {
+		var st transactions.SignedTxn
+		st.Txn.Type = protocol.PaymentTx
+		st.Txn.Sender = escrow
+		st.Txn.Fee = basics.MicroAlgos{Raw: maxFee}
+		st.Txn.FirstValid = first
+		st.Txn.LastValid = first + basics.Round(schedule.Duration)
+		st.Txn.Lease = rcv
+		st.Txn.Amount = basics.MicroAlgos{Raw: amount}
+		if closeOut {
+			st.Txn.CloseRemainderTo = rcv
+		} else {
+			st.Txn.Receiver = rcv
+		}
+		st.Lsig.Logic = schedule.Program
+		pass, err := logic.Eval(schedule.Program, logic.EvalParams{Txn: &st, Proto: &proto})
+		require.NoError(t, err)
+		require.True(t, pass, "withdrawal at round %d", first)
+
+		require.True(t, balance >= amount+maxFee, "withdrawal at round %d", first)
+		balance -= amount + maxFee
+		received += amount
+		if closeOut {
+			received += balance
+			balance = 0
+		} else {
+			require.True(t, balance >= proto.MinBalance, "withdrawal at round %d leaves %d", first, balance)
+		}
+	}
+
+	require.Equal(t, schedule.CliffAmount, schedule.Unlocked(schedule.Cliff, balance))
+	withdraw(schedule.Cliff, schedule.CliffAmount, false)
+	var releases int
+	for due := basics.Round(1100); due <= schedule.End; due += basics.Round(schedule.Period) {
+		require.Equal(t, schedule.PeriodAmount, schedule.Unlocked(due, balance))
+		withdraw(due, schedule.PeriodAmount, false)
+		releases++
+
+		// Between windows, nothing is unlocked.
+		if due < schedule.End {
+			require.Equal(t, uint64(0), schedule.Unlocked(due+basics.Round(schedule.Duration)+1, balance))
+		}
+	}
+	require.Equal(t, 90, releases)
+	require.Equal(t, balance, schedule.Unlocked(schedule.End+1, balance))
+	withdraw(schedule.End+1, 0, true)
+
+	require.Equal(t, uint64(stake), received+92*maxFee)
+}
+
+func TestGenerateVestingGenesis(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "vesting")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	outDir := filepath.Join(tempDir, "genesis")
+	data := DefaultGenesis
+	data.NetworkName = "vestingtest"
+	data.Wallets = []WalletData{
+		{Name: "Founder", Stake: 100, Vesting: &VestingData{Cliff: 1000, Period: 100, End: 10000}},
+	}
+	require.NoError(t, GenerateGenesisFiles(data, outDir, false))
+
+	genesis, err := bookkeeping.LoadGenesisFromFile(filepath.Join(outDir, config.GenesisJSONFile))
+	require.NoError(t, err)
+
+	var found bool
+	for _, alloc := range genesis.Allocation {
+		if alloc.Comment != "Founder" {
+			require.Nil(t, alloc.Vesting)
+			continue
+		}
+		found = true
+		require.NotNil(t, alloc.Vesting)
+		require.Equal(t, alloc.Address, basics.Address(crypto.HashObj(logic.Program(alloc.Vesting.Program))).String())
+		require.Equal(t, TotalMoney, alloc.State.MicroAlgos.Raw)
+		require.Equal(t, basics.Offline, alloc.State.Status)
+	}
+	require.True(t, found)
+
+	data.Wallets[0].Online = true
+	require.Error(t, GenerateGenesisFiles(data, filepath.Join(tempDir, "online"), false))
+}
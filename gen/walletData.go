@@ -33,9 +33,21 @@ var DefaultGenesis = GenesisData{
 
 // WalletData represents a wallet's name, percent stake, and initial online status for a genesis.json file
 type WalletData struct {
-	Name   string
-	Stake  float64
-	Online bool
+	Name    string
+	Stake   float64
+	Online  bool
+	Vesting *VestingData `json:",omitempty"`
+}
+
+// VestingData locks a wallet's stake in a vesting escrow, which releases it
+// to the wallet linearly from genesis until End, starting at round Cliff and
+// then every Period rounds.  MaxFee caps the fee of a withdrawal and defaults
+// to ten times the minimum transaction fee.
+type VestingData struct {
+	Cliff  uint64
+	Period uint64
+	End    uint64
+	MaxFee uint64 `json:",omitempty"`
 }
 
 // GenesisData represents the genesis data for creating a genesis.json and wallets
@@ -27,7 +27,7 @@ This package provides an implementation of the Algorand protocol.
 mkdir -p %{buildroot}/usr/bin
 # NOTE: keep in sync with scripts/build_deb.sh bin_files
 # NOTE: keep in sync with %files section below
-for f in algocfg algod algoh algokey carpenter catchupsrv ddconfig.sh diagcfg goal kmd msgpacktool node_exporter; do
+for f in algocfg algod algoh algokey algoreplay carpenter catchupsrv ddconfig.sh diagcfg goal kmd msgpacktool node_exporter; do
   install -m 755 ${GOPATH}/bin/${f} %{buildroot}/usr/bin/${f}
 done
 
@@ -72,6 +72,7 @@ fi
 /usr/bin/algod
 /usr/bin/algoh
 /usr/bin/algokey
+/usr/bin/algoreplay
 /usr/bin/carpenter
 /usr/bin/catchupsrv
 /usr/bin/ddconfig.sh
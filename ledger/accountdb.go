@@ -32,8 +32,10 @@ import (
 // the state of a single account.
 type accountsDbQueries struct {
 	listAssetsStmt         *sql.Stmt
+	listAssetHoldersStmt   *sql.Stmt
 	lookupStmt             *sql.Stmt
 	lookupAssetCreatorStmt *sql.Stmt
+	lookupHistoryStmt      *sql.Stmt
 }
 
 var accountsSchema = []string{
@@ -55,6 +57,24 @@ var accountsSchema = []string{
 	`CREATE TABLE IF NOT EXISTS assetcreators (
 		asset integer primary key,
 		creator blob)`,
+	`CREATE TABLE IF NOT EXISTS assetholdings (
+		asset integer,
+		address blob,
+		amount integer,
+		frozen boolean,
+		PRIMARY KEY (asset, address))`,
+}
+
+// accountHistorySchema is created only on archival nodes.  accounthist
+// holds the state of every account after every round in which it changed,
+// from the round recorded as 'acctbasehist' in acctrounds onwards.  A
+// deleted account is recorded with empty data.
+var accountHistorySchema = []string{
+	`CREATE TABLE IF NOT EXISTS accounthist (
+		address blob,
+		rnd integer,
+		data blob,
+		PRIMARY KEY (address, rnd))`,
 }
 
 var accountsResetExprs = []string{
@@ -62,6 +82,8 @@ var accountsResetExprs = []string{
 	`DROP TABLE IF EXISTS accounttotals`,
 	`DROP TABLE IF EXISTS accountbase`,
 	`DROP TABLE IF EXISTS assetcreators`,
+	`DROP TABLE IF EXISTS assetholdings`,
+	`DROP TABLE IF EXISTS accounthist`,
 }
 
 type accountDelta struct {
@@ -69,6 +91,15 @@ type accountDelta struct {
 	new basics.AccountData
 }
 
+// modifiedHolding is an asset holding that changed in an account delta.
+type modifiedHolding struct {
+	// deleted is set if the account no longer holds the asset
+	deleted bool
+
+	// holding is the new holding, if not deleted
+	holding basics.AssetHolding
+}
+
 // accountsInit fills the database using tx with initAccounts if the
 // database has not been initialized yet.
 //
@@ -114,9 +145,113 @@ func accountsInit(tx *sql.Tx, initAccounts map[basics.Address]basics.AccountData
 		}
 	}
 
+	return accountsInitAssetHoldings(tx)
+}
+
+// holdingAmountToDB converts an asset amount to the signed integer stored in
+// assetholdings.  SQLite integers are signed, and database/sql refuses
+// uint64 values with the high bit set, so amounts of 2^63 or more are
+// stored as negative numbers and converted back when read.
+func holdingAmountToDB(amount uint64) int64 {
+	return int64(amount)
+}
+
+// accountsInitAssetHoldings fills assetholdings from accountbase, unless it
+// has been filled already.  This also upgrades account DBs that predate the
+// assetholdings table.
+func accountsInitAssetHoldings(tx *sql.Tx) error {
+	var rnd basics.Round
+	err := tx.QueryRow("SELECT rnd FROM acctrounds WHERE id='assetholdings'").Scan(&rnd)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	err = accountsFillAssetHoldings(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO acctrounds (id, rnd) SELECT 'assetholdings', rnd FROM acctrounds WHERE id='acctbase'")
+	return err
+}
+
+// accountsFillAssetHoldings adds the asset holdings of every account in
+// accountbase to assetholdings.
+func accountsFillAssetHoldings(tx *sql.Tx) error {
+	bals, err := accountsAll(tx)
+	if err != nil {
+		return err
+	}
+
+	insertStmt, err := tx.Prepare("REPLACE INTO assetholdings (asset, address, amount, frozen) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertStmt.Close()
+
+	for addr, data := range bals {
+		for aidx, holding := range data.Assets {
+			_, err = insertStmt.Exec(aidx, addr[:], holdingAmountToDB(holding.Amount), holding.Frozen)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// accountsHistoryBase returns the first round of the account history,
+// if the account DB keeps one.
+func accountsHistoryBase(tx *sql.Tx) (histBase basics.Round, ok bool, err error) {
+	err = tx.QueryRow("SELECT rnd FROM acctrounds WHERE id='acctbasehist'").Scan(&histBase)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return histBase, err == nil, err
+}
+
+// accountsInitHistory creates the account history table if it does not
+// exist yet, seeding it with a snapshot of accountbase, and returns the
+// first round for which the history is complete.
+func accountsInitHistory(tx *sql.Tx) (histBase basics.Round, err error) {
+	histBase, ok, err := accountsHistoryBase(tx)
+	if err != nil || ok {
+		return
+	}
+
+	for _, tableCreate := range accountHistorySchema {
+		_, err = tx.Exec(tableCreate)
+		if err != nil {
+			return
+		}
+	}
+
+	histBase, err = accountsRound(tx)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec("INSERT INTO accounthist (address, rnd, data) SELECT address, ?, data FROM accountbase", histBase)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec("INSERT INTO acctrounds (id, rnd) VALUES ('acctbasehist', ?)", histBase)
+	return
+}
+
+// accountsDropHistory removes the account history, which would otherwise
+// have a gap if the node later becomes archival again.
+func accountsDropHistory(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS accounthist")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM acctrounds WHERE id='acctbasehist'")
+	return err
+}
+
 func accountsReset(tx *sql.Tx) error {
 	for _, stmt := range accountsResetExprs {
 		_, err := tx.Exec(stmt)
@@ -127,6 +262,48 @@ func accountsReset(tx *sql.Tx) error {
 	return nil
 }
 
+// accountsRestore replaces the contents of the account DB with the state
+// of round rnd, as found in a catchpoint.
+func accountsRestore(tx *sql.Tx, rnd basics.Round, accounts []catchpointAccount, assets []catchpointAsset, totals AccountTotals) error {
+	err := accountsReset(tx)
+	if err != nil {
+		return err
+	}
+
+	err = accountsInit(tx, nil, config.ConsensusParams{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE acctrounds SET rnd=? WHERE id='acctbase'", rnd)
+	if err != nil {
+		return err
+	}
+
+	for _, acct := range accounts {
+		_, err = tx.Exec("INSERT INTO accountbase (address, data) VALUES (?, ?)",
+			acct.Address[:], protocol.Encode(acct.Data))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, asset := range assets {
+		_, err = tx.Exec("INSERT INTO assetcreators (asset, creator) VALUES (?, ?)",
+			asset.Asset, asset.Creator[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = accountsFillAssetHoldings(tx)
+	if err != nil {
+		return err
+	}
+
+	return accountsPutTotals(tx, totals)
+}
+
 func accountsRound(tx *sql.Tx) (rnd basics.Round, err error) {
 	err = tx.QueryRow("SELECT rnd FROM acctrounds WHERE id='acctbase'").Scan(&rnd)
 	return
@@ -141,6 +318,11 @@ func accountsDbInit(q db.Queryable) (*accountsDbQueries, error) {
 		return nil, err
 	}
 
+	qs.listAssetHoldersStmt, err = q.Prepare("SELECT address, amount, frozen FROM assetholdings WHERE asset=? AND address>? ORDER BY address LIMIT ?")
+	if err != nil {
+		return nil, err
+	}
+
 	qs.lookupStmt, err = q.Prepare("SELECT data FROM accountbase WHERE address=?")
 	if err != nil {
 		return nil, err
@@ -151,6 +333,19 @@ func accountsDbInit(q db.Queryable) (*accountsDbQueries, error) {
 		return nil, err
 	}
 
+	// The history table exists only on archival nodes.
+	var histTables int
+	err = q.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name='accounthist'").Scan(&histTables)
+	if err != nil {
+		return nil, err
+	}
+	if histTables > 0 {
+		qs.lookupHistoryStmt, err = q.Prepare("SELECT data FROM accounthist WHERE address=? AND rnd<=? ORDER BY rnd DESC LIMIT 1")
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return qs, nil
 }
 
@@ -179,6 +374,34 @@ func (qs *accountsDbQueries) listAssets(maxAssetIdx basics.AssetIndex, maxResult
 	return
 }
 
+// listAssetHolders returns up to maxResults holders of assetIdx whose
+// addresses sort after the address after, in order of address.
+func (qs *accountsDbQueries) listAssetHolders(assetIdx basics.AssetIndex, after basics.Address, maxResults uint64) (results []basics.AssetHolder, err error) {
+	err = db.Retry(func() error {
+		results = nil
+		rows, err := qs.listAssetHoldersStmt.Query(assetIdx, after[:], maxResults)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var buf []byte
+		var amount int64
+		var holder basics.AssetHolder
+		for rows.Next() {
+			err := rows.Scan(&buf, &amount, &holder.Holding.Frozen)
+			if err != nil {
+				return err
+			}
+			copy(holder.Address[:], buf)
+			holder.Holding.Amount = uint64(amount)
+			results = append(results, holder)
+		}
+		return rows.Err()
+	})
+	return
+}
+
 func (qs *accountsDbQueries) lookupAssetCreator(assetIdx basics.AssetIndex) (addr basics.Address, err error) {
 	err = db.Retry(func() error {
 		var buf []byte
@@ -216,6 +439,36 @@ func (qs *accountsDbQueries) lookup(addr basics.Address) (data basics.AccountDat
 	return
 }
 
+// lookupHistory returns the state of addr at the end of round rnd, which
+// must not be before the start of the account history.
+func (qs *accountsDbQueries) lookupHistory(addr basics.Address, rnd basics.Round) (data basics.AccountData, err error) {
+	if qs.lookupHistoryStmt == nil {
+		err = fmt.Errorf("account history is not available")
+		return
+	}
+
+	err = db.Retry(func() error {
+		var buf []byte
+		err := qs.lookupHistoryStmt.QueryRow(addr[:], rnd).Scan(&buf)
+		if err == nil {
+			if len(buf) == 0 {
+				// Deleted account
+				return nil
+			}
+			return protocol.Decode(buf, &data)
+		}
+
+		if err == sql.ErrNoRows {
+			// Return the zero value of data
+			return nil
+		}
+
+		return err
+	})
+
+	return
+}
+
 func accountsAll(tx *sql.Tx) (bals map[basics.Address]basics.AccountData, err error) {
 	rows, err := tx.Query("SELECT address, data FROM accountbase")
 	if err != nil {
@@ -252,6 +505,81 @@ func accountsAll(tx *sql.Tx) (bals map[basics.Address]basics.AccountData, err er
 	return
 }
 
+// accountsCount returns the number of accounts in the account DB.
+func accountsCount(tx *sql.Tx) (count uint64, err error) {
+	err = tx.QueryRow("SELECT count(*) FROM accountbase").Scan(&count)
+	return
+}
+
+// accountsIterate calls fn on every account in the account DB, in address
+// order, without loading them all into memory.  It stops at the first
+// error that fn returns.
+func accountsIterate(tx *sql.Tx, fn func(addr basics.Address, data basics.AccountData) error) error {
+	rows, err := tx.Query("SELECT address, data FROM accountbase ORDER BY address")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var addrbuf []byte
+		var buf []byte
+		err = rows.Scan(&addrbuf, &buf)
+		if err != nil {
+			return err
+		}
+
+		var data basics.AccountData
+		err = protocol.Decode(buf, &data)
+		if err != nil {
+			return err
+		}
+
+		var addr basics.Address
+		if len(addrbuf) != len(addr) {
+			return fmt.Errorf("Account DB address length mismatch: %d != %d", len(addrbuf), len(addr))
+		}
+
+		copy(addr[:], addrbuf)
+		err = fn(addr, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func assetCreatorsAll(tx *sql.Tx) (creators map[basics.AssetIndex]basics.Address, err error) {
+	rows, err := tx.Query("SELECT asset, creator FROM assetcreators")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	creators = make(map[basics.AssetIndex]basics.Address)
+	for rows.Next() {
+		var aidx basics.AssetIndex
+		var buf []byte
+		err = rows.Scan(&aidx, &buf)
+		if err != nil {
+			return
+		}
+
+		var addr basics.Address
+		if len(buf) != len(addr) {
+			err = fmt.Errorf("Asset DB creator length mismatch: %d != %d", len(buf), len(addr))
+			return
+		}
+
+		copy(addr[:], buf)
+		creators[aidx] = addr
+	}
+
+	err = rows.Err()
+	return
+}
+
 func accountsTotals(tx *sql.Tx) (totals AccountTotals, err error) {
 	row := tx.QueryRow("SELECT online, onlinerewardunits, offline, offlinerewardunits, notparticipating, notparticipatingrewardunits, rewardslevel FROM accounttotals")
 	err = row.Scan(&totals.Online.Money.Raw, &totals.Online.RewardUnits,
@@ -303,7 +631,29 @@ func getChangedAssetIndices(creator basics.Address, delta accountDelta) map[basi
 	return assetMods
 }
 
-func accountsNewRound(tx *sql.Tx, rnd basics.Round, updates map[basics.Address]accountDelta, rewardsLevel uint64, proto config.ConsensusParams) (err error) {
+// getChangedAssetHoldings takes an accountDelta and returns the asset
+// holdings that were added, changed or removed
+func getChangedAssetHoldings(delta accountDelta) map[basics.AssetIndex]modifiedHolding {
+	holdingMods := make(map[basics.AssetIndex]modifiedHolding)
+
+	for idx, holding := range delta.new.Assets {
+		if old, ok := delta.old.Assets[idx]; !ok || old != holding {
+			holdingMods[idx] = modifiedHolding{holding: holding}
+		}
+	}
+
+	for idx := range delta.old.Assets {
+		if _, ok := delta.new.Assets[idx]; !ok {
+			holdingMods[idx] = modifiedHolding{deleted: true}
+		}
+	}
+
+	return holdingMods
+}
+
+// accountsNewRound applies updates for round rnd to the account DB.  If
+// history is set, it also records the updated accounts in accounthist.
+func accountsNewRound(tx *sql.Tx, rnd basics.Round, updates map[basics.Address]accountDelta, rewardsLevel uint64, proto config.ConsensusParams, history bool) (err error) {
 	var base basics.Round
 	err = tx.QueryRow("SELECT rnd FROM acctrounds WHERE id='acctbase'").Scan(&base)
 	if err != nil {
@@ -347,17 +697,47 @@ func accountsNewRound(tx *sql.Tx, rnd basics.Round, updates map[basics.Address]a
 	}
 	defer deleteAssetIdxStmt.Close()
 
+	replaceHoldingStmt, err := tx.Prepare("REPLACE INTO assetholdings (asset, address, amount, frozen) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return
+	}
+	defer replaceHoldingStmt.Close()
+
+	deleteHoldingStmt, err := tx.Prepare("DELETE FROM assetholdings WHERE asset=? AND address=?")
+	if err != nil {
+		return
+	}
+	defer deleteHoldingStmt.Close()
+
+	var insertHistStmt *sql.Stmt
+	if history {
+		insertHistStmt, err = tx.Prepare("INSERT INTO accounthist (address, rnd, data) VALUES (?, ?, ?)")
+		if err != nil {
+			return
+		}
+		defer insertHistStmt.Close()
+	}
+
 	for addr, data := range updates {
+		var encoded []byte
 		if data.new.IsZero() {
 			// prune empty accounts
 			_, err = deleteStmt.Exec(addr[:])
 		} else {
-			_, err = replaceStmt.Exec(addr[:], protocol.Encode(data.new))
+			encoded = protocol.Encode(data.new)
+			_, err = replaceStmt.Exec(addr[:], encoded)
 		}
 		if err != nil {
 			return
 		}
 
+		if history {
+			_, err = insertHistStmt.Exec(addr[:], rnd, encoded)
+			if err != nil {
+				return
+			}
+		}
+
 		totals.delAccount(proto, data.old, &ot)
 		totals.addAccount(proto, data.new, &ot)
 
@@ -372,6 +752,18 @@ func accountsNewRound(tx *sql.Tx, rnd basics.Round, updates map[basics.Address]a
 				return
 			}
 		}
+
+		hdeltas := getChangedAssetHoldings(data)
+		for aidx, delta := range hdeltas {
+			if delta.deleted {
+				_, err = deleteHoldingStmt.Exec(aidx, addr[:])
+			} else {
+				_, err = replaceHoldingStmt.Exec(aidx, addr[:], holdingAmountToDB(delta.holding.Amount), delta.holding.Frozen)
+			}
+			if err != nil {
+				return
+			}
+		}
 	}
 
 	if ot.Overflowed {
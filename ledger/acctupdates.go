@@ -17,12 +17,13 @@
 package ledger
 
 import (
-	"database/sql"
+	"bytes"
 	"fmt"
 	"sort"
 	"time"
 
 	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto/merkletrie"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 	"github.com/vincentbdb/go-algorand/logging"
@@ -56,14 +57,14 @@ type modifiedAsset struct {
 }
 
 type accountUpdates struct {
-	// Connection to the database.
-	dbs dbPair
+	// Storage for the accounts database.
+	store accountStore
 
-	// Prepared SQL statements for fast accounts DB lookups.
-	accountsq *accountsDbQueries
+	// Prepared queries for fast accounts DB lookups.
+	accountsq accountsQueries
 
 	// dbRound is always exactly accountsRound(),
-	// cached to avoid SQL queries.
+	// cached to avoid database queries.
 	dbRound basics.Round
 
 	// deltas stores updates for every round after dbRound.
@@ -88,6 +89,17 @@ type accountUpdates struct {
 	// i.e., totals is one longer than deltas.
 	roundTotals []AccountTotals
 
+	// stateTries stores the account state trie for stateTrieBase and
+	// every round after it, or is nil if no round since dbRound has
+	// needed the trie.  stateTrieBase is never before dbRound.
+	stateTries    []merkletrie.Trie
+	stateTrieBase basics.Round
+
+	// stateTrieBuilding is set while the state trie is being built in
+	// the background from the account DB, which must not advance until
+	// the trie is installed.
+	stateTrieBuilding bool
+
 	// initAccounts specifies initial account values for database.
 	initAccounts map[basics.Address]basics.AccountData
 
@@ -100,18 +112,32 @@ type accountUpdates struct {
 	// lastFlushTime is the time we last flushed updates to
 	// the accounts DB (bumping dbRound).
 	lastFlushTime time.Time
+
+	// history makes the account DB keep the history of every account,
+	// so that lookups can go back to any round since histBase.
+	history  bool
+	histBase basics.Round
+
+	// ledger is used to fetch the rewards state of historical rounds.
+	ledger ledgerForTracker
+
+	// catchpointInterval, if non-zero, makes committedUpTo stop
+	// at every multiple of catchpointInterval, so that the account
+	// DB passes through each catchpoint round.
+	catchpointInterval uint64
 }
 
 func (au *accountUpdates) loadFromDisk(l ledgerForTracker) error {
-	au.dbs = l.trackerDB()
+	au.store = l.trackerDB()
 	au.log = l.trackerLog()
+	au.ledger = l
 
 	if au.initAccounts == nil {
 		return fmt.Errorf("accountUpdates.loadFromDisk: initAccounts not set")
 	}
 
 	latest := l.Latest()
-	err := au.dbs.wdb.Atomic(func(tx *sql.Tx) error {
+	err := au.store.writeTx(func(tx accountsTx) error {
 		var err0 error
 		au.dbRound, err0 = au.accountsInitialize(tx)
 		if err0 != nil {
@@ -120,7 +146,7 @@ func (au *accountUpdates) loadFromDisk(l ledgerForTracker) error {
 		// Check for blocks DB and tracker DB un-sync
 		if au.dbRound > latest {
 			au.log.Warnf("resetting accounts DB (on round %v, but blocks DB's latest is %v)", au.dbRound, latest)
-			err0 = accountsReset(tx)
+			err0 = tx.accountsReset()
 			if err0 != nil {
 				return err0
 			}
@@ -130,19 +156,24 @@ func (au *accountUpdates) loadFromDisk(l ledgerForTracker) error {
 			}
 		}
 
-		totals, err0 := accountsTotals(tx)
+		totals, err0 := tx.accountsTotals()
 		if err0 != nil {
 			return err0
 		}
 
 		au.roundTotals = []AccountTotals{totals}
-		return nil
+
+		if au.history {
+			au.histBase, err0 = tx.accountsInitHistory()
+			return err0
+		}
+		return tx.accountsDropHistory()
 	})
 	if err != nil {
 		return err
 	}
 
-	au.accountsq, err = accountsDbInit(au.dbs.rdb.Handle)
+	au.accountsq, err = au.store.queries()
 	if err != nil {
 		return err
 	}
@@ -153,6 +184,8 @@ func (au *accountUpdates) loadFromDisk(l ledgerForTracker) error {
 	}
 	au.protos = []config.ConsensusParams{config.Consensus[hdr.CurrentProtocol]}
 
+	au.stateTries = nil
+
 	au.deltas = nil
 	au.assetDeltas = nil
 	au.accounts = make(map[basics.Address]modifiedAccount)
@@ -172,20 +205,32 @@ func (au *accountUpdates) loadFromDisk(l ledgerForTracker) error {
 		}
 
 		au.newBlock(blk, delta)
+		hdr = blk.BlockHeader
 		loaded = next
 	}
 
+	if wantStateTrie(hdr) {
+		trie, base, err := au.buildStateTrie()
+		if err != nil {
+			return err
+		}
+		err = au.installStateTrie(trie, base)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Initialize accounts DB if needed and return account round
-func (au *accountUpdates) accountsInitialize(tx *sql.Tx) (basics.Round, error) {
-	err := accountsInit(tx, au.initAccounts, au.initProto)
+func (au *accountUpdates) accountsInitialize(tx accountsTx) (basics.Round, error) {
+	err := tx.accountsInit(au.initAccounts, au.initProto)
 	if err != nil {
 		return 0, err
 	}
 
-	rnd, err := accountsRound(tx)
+	rnd, err := tx.accountsRound()
 	if err != nil {
 		return 0, err
 	}
@@ -211,6 +256,10 @@ func (au *accountUpdates) roundOffset(rnd basics.Round) (offset uint64, err erro
 }
 
 func (au *accountUpdates) lookup(rnd basics.Round, addr basics.Address, withRewards bool) (data basics.AccountData, err error) {
+	if rnd < au.dbRound && au.history {
+		return au.lookupHistory(rnd, addr, withRewards)
+	}
+
 	offset, err := au.roundOffset(rnd)
 	if err != nil {
 		return
@@ -253,15 +302,35 @@ func (au *accountUpdates) lookup(rnd basics.Round, addr basics.Address, withRewa
 	return au.accountsq.lookup(addr)
 }
 
+// lookupHistory looks up the state of addr at a round before dbRound, using
+// the account history.
+func (au *accountUpdates) lookupHistory(rnd basics.Round, addr basics.Address, withRewards bool) (data basics.AccountData, err error) {
+	if rnd < au.histBase {
+		err = fmt.Errorf("round %d before account history start %d", rnd, au.histBase)
+		return
+	}
+
+	data, err = au.accountsq.lookupHistory(addr, rnd)
+	if err != nil || !withRewards {
+		return
+	}
+
+	hdr, err := au.ledger.BlockHdr(rnd)
+	if err != nil {
+		return
+	}
+	return data.WithUpdatedRewards(config.Consensus[hdr.CurrentProtocol], hdr.RewardsLevel), nil
+}
+
 func (au *accountUpdates) allBalances(rnd basics.Round) (bals map[basics.Address]basics.AccountData, err error) {
 	offsetLimit, err := au.roundOffset(rnd)
 	if err != nil {
 		return
 	}
 
-	err = au.dbs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = au.store.readTx(func(tx accountsTx) error {
 		var err0 error
-		bals, err0 = accountsAll(tx)
+		bals, err0 = tx.accountsAll()
 		return err0
 	})
 	if err != nil {
@@ -342,6 +411,65 @@ func (au *accountUpdates) listAssets(maxAssetIdx basics.AssetIndex, maxResults u
 	return res, nil
 }
 
+// listAssetHolders returns up to maxResults holders of assetIdx as of the
+// latest round, in order of address, starting after the address after.
+func (au *accountUpdates) listAssetHolders(assetIdx basics.AssetIndex, after basics.Address, maxResults uint64) ([]basics.AssetHolder, error) {
+	// Accounts modified since dbRound override their rows in the database.
+	var unsyncedHolders []basics.AssetHolder
+	for addr, macct := range au.accounts {
+		if bytes.Compare(addr[:], after[:]) <= 0 {
+			continue
+		}
+		if holding, ok := macct.data.Assets[assetIdx]; ok {
+			unsyncedHolders = append(unsyncedHolders, basics.AssetHolder{Address: addr, Holding: holding})
+		}
+	}
+	sort.Slice(unsyncedHolders, func(i, j int) bool {
+		return bytes.Compare(unsyncedHolders[i].Address[:], unsyncedHolders[j].Address[:]) < 0
+	})
+
+	// Fetch enough extras from the database to make up for modified
+	// accounts that no longer hold the asset.
+	numToFetch := maxResults + uint64(len(au.accounts))
+	dbResults, err := au.accountsq.listAssetHolders(assetIdx, after, numToFetch)
+	if err != nil {
+		return nil, err
+	}
+
+	// The database returned everything it has after after, unless it
+	// returned a full page; in that case results past its last address
+	// are unknown, and must not come from memory either.
+	dbComplete := uint64(len(dbResults)) < numToFetch
+
+	var res []basics.AssetHolder
+	for len(dbResults) > 0 || len(unsyncedHolders) > 0 {
+		if uint64(len(res)) == maxResults {
+			break
+		}
+
+		if len(dbResults) > 0 {
+			if _, ok := au.accounts[dbResults[0].Address]; ok {
+				// Stale row for a modified account
+				dbResults = dbResults[1:]
+				continue
+			}
+		}
+
+		if len(unsyncedHolders) > 0 && (len(dbResults) == 0 || bytes.Compare(unsyncedHolders[0].Address[:], dbResults[0].Address[:]) < 0) {
+			if len(dbResults) == 0 && !dbComplete {
+				break
+			}
+			res = append(res, unsyncedHolders[0])
+			unsyncedHolders = unsyncedHolders[1:]
+		} else {
+			res = append(res, dbResults[0])
+			dbResults = dbResults[1:]
+		}
+	}
+
+	return res, nil
+}
+
 func (au *accountUpdates) getAssetCreatorForRound(rnd basics.Round, aidx basics.AssetIndex) (basics.Address, error) {
 	offset, err := au.roundOffset(rnd)
 	if err != nil {
@@ -377,6 +505,22 @@ func (au *accountUpdates) getAssetCreatorForRound(rnd basics.Round, aidx basics.
 }
 
 func (au *accountUpdates) committedUpTo(rnd basics.Round) basics.Round {
+	retain := au.flushUpTo(rnd)
+	if au.catchpointInterval != 0 {
+		// Keep the blocks that the next catchpoint file will include:
+		// MaxTxnLife rounds back from MaxBalLookback rounds after dbRound.
+		proto := au.protos[len(au.protos)-1]
+		catchpointFirst := (au.dbRound + basics.Round(proto.MaxBalLookback) + 1).SubSaturate(basics.Round(proto.MaxTxnLife))
+		if catchpointFirst < retain {
+			retain = catchpointFirst
+		}
+	}
+	return retain
+}
+
+// flushUpTo writes the deltas for rounds up to rnd-MaxBalLookback to the
+// account DB, and returns the new dbRound.
+func (au *accountUpdates) flushUpTo(rnd basics.Round) basics.Round {
 	lookback := basics.Round(au.protos[len(au.protos)-1].MaxBalLookback)
 	if rnd < lookback {
 		return 0
@@ -392,6 +536,22 @@ func (au *accountUpdates) committedUpTo(rnd basics.Round) basics.Round {
 		au.log.Panicf("committedUpTo: block %d too far in the future, lookback %d, dbRound %d, deltas %d", rnd, lookback, au.dbRound, len(au.deltas))
 	}
 
+	// Do not flush past the next catchpoint round, so that the
+	// account DB can be exported exactly as of that round.
+	if au.catchpointInterval != 0 {
+		interval := basics.Round(au.catchpointInterval)
+		nextCatchpoint := (au.dbRound/interval + 1) * interval
+		if newBase > nextCatchpoint {
+			newBase = nextCatchpoint
+		}
+	}
+
+	// Do not flush while the state trie is being built from the
+	// account DB.
+	if au.stateTrieBuilding {
+		return au.dbRound
+	}
+
 	// If we recently flushed, wait to aggregate some more blocks.
 	flushTime := time.Now()
 	if !flushTime.After(au.lastFlushTime.Add(5 * time.Second)) {
@@ -405,12 +565,12 @@ func (au *accountUpdates) committedUpTo(rnd basics.Round) basics.Round {
 	var assetFlushcount map[basics.AssetIndex]int
 
 	offset := uint64(newBase - au.dbRound)
-	err := au.dbs.wdb.Atomic(func(tx *sql.Tx) error {
+	err := au.store.writeTx(func(tx accountsTx) error {
 		flushcount = make(map[basics.Address]int)
 		assetFlushcount = make(map[basics.AssetIndex]int)
 		for i := uint64(0); i < offset; i++ {
 			rnd := au.dbRound + basics.Round(i) + 1
-			err := accountsNewRound(tx, rnd, au.deltas[i], au.roundTotals[i+1].RewardsLevel, au.protos[i+1])
+			err := tx.accountsNewRound(rnd, au.deltas[i], au.roundTotals[i+1].RewardsLevel, au.protos[i+1], au.history)
 			if err != nil {
 				return err
 			}
@@ -471,6 +631,10 @@ func (au *accountUpdates) committedUpTo(rnd basics.Round) basics.Round {
 	au.deltas = au.deltas[offset:]
 	au.protos = au.protos[offset:]
 	au.roundTotals = au.roundTotals[offset:]
+	if au.stateTries != nil && newBase > au.stateTrieBase {
+		au.stateTries = au.stateTries[newBase-au.stateTrieBase:]
+		au.stateTrieBase = newBase
+	}
 	au.assetDeltas = au.assetDeltas[offset:]
 	au.dbRound = newBase
 	au.lastFlushTime = flushTime
@@ -529,6 +693,10 @@ func (au *accountUpdates) newBlock(blk bookkeeping.Block, delta StateDelta) {
 		au.log.Panicf("accountUpdates: sum of money changed from %d to %d", allBefore.Raw, allAfter.Raw)
 	}
 	au.roundTotals = append(au.roundTotals, newTotals)
+
+	if au.stateTries != nil {
+		au.stateTries = append(au.stateTries, updateStateTrie(au.stateTries[len(au.stateTries)-1], delta.accts))
+	}
 }
 
 func (au *accountUpdates) latest() basics.Round {
@@ -80,8 +80,8 @@ func (ml *mockLedgerForTracker) blockAux(rnd basics.Round) (bookkeeping.Block, e
 	return ml.blocks[int(rnd)].block, ml.blocks[int(rnd)].aux, nil
 }
 
-func (ml *mockLedgerForTracker) trackerDB() dbPair {
-	return ml.dbs
+func (ml *mockLedgerForTracker) trackerDB() accountStore {
+	return sqlAccountStore{ml.dbs}
 }
 
 func (ml *mockLedgerForTracker) trackerLog() logging.Logger {
@@ -237,3 +237,80 @@ func TestAcctUpdates(t *testing.T) {
 		checkAcctUpdates(t, au, i, basics.Round(proto.MaxBalLookback+14), accts, rewardsLevels, proto)
 	}
 }
+
+func TestAcctUpdatesAssetHolders(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	ml := makeMockLedgerForTracker(t)
+	defer ml.close()
+	ml.blocks = randomInitChain(protocol.ConsensusCurrentVersion, 10)
+
+	accts := randomAccounts(30)
+	i := 0
+	for addr, data := range accts {
+		if i%2 == 0 {
+			data.Assets = map[basics.AssetIndex]basics.AssetHolding{1: {Amount: uint64(i)}}
+			accts[addr] = data
+		}
+		i++
+	}
+
+	au := &accountUpdates{initAccounts: accts, initProto: proto}
+	err := au.loadFromDisk(ml)
+	require.NoError(t, err)
+	defer au.close()
+
+	current := make(map[basics.Address]basics.AccountData)
+	for addr, data := range accts {
+		current[addr] = data
+	}
+	checkHolders := func() {
+		for _, pageSize := range []uint64{1, 4, 100} {
+			require.Equal(t, expectedAssetHolders(current, 1), pageAssetHolders(t, au.listAssetHolders, 1, pageSize))
+		}
+	}
+	checkHolders()
+
+	// Opt accounts in and out of the asset, so that the in-memory deltas
+	// both add holders and hide holders that are still in the database.
+	lastRound := basics.Round(proto.MaxBalLookback + 15)
+	for rnd := basics.Round(10); rnd <= lastRound; rnd++ {
+		updates := make(map[basics.Address]accountDelta)
+		for addr, old := range current {
+			if len(updates) == 2 {
+				break
+			}
+			if crypto.RandUint64()%4 != 0 {
+				continue
+			}
+			new := old
+			if _, ok := old.Assets[1]; ok {
+				new.Assets = nil
+			} else {
+				new.Assets = map[basics.AssetIndex]basics.AssetHolding{1: {Amount: uint64(rnd)}}
+			}
+			updates[addr] = accountDelta{old: old, new: new}
+		}
+		for addr, delta := range updates {
+			current[addr] = delta.new
+		}
+
+		blk := bookkeeping.Block{
+			BlockHeader: bookkeeping.BlockHeader{
+				Round: rnd,
+			},
+		}
+		blk.CurrentProtocol = protocol.ConsensusCurrentVersion
+		au.newBlock(blk, StateDelta{
+			accts: updates,
+			hdr:   &blk.BlockHeader,
+		})
+		checkHolders()
+	}
+
+	for rnd := basics.Round(10); rnd <= lastRound-basics.Round(proto.MaxBalLookback); rnd++ {
+		au.lastFlushTime = time.Time{}
+		au.committedUpTo(rnd + basics.Round(proto.MaxBalLookback))
+		checkHolders()
+	}
+}
@@ -18,17 +18,18 @@ package ledger
 
 import (
 	"crypto/rand"
-	"database/sql"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/crypto"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
@@ -72,7 +73,7 @@ func (wl *wrappedLedger) Latest() basics.Round {
 	return wl.l.Latest()
 }
 
-func (wl *wrappedLedger) trackerDB() dbPair {
+func (wl *wrappedLedger) trackerDB() accountStore {
 	return wl.l.trackerDB()
 }
 
@@ -177,11 +178,11 @@ func TestArchivalRestart(t *testing.T) {
 	l.WaitForCommit(blk.Round())
 
 	var latest, earliest basics.Round
-	err = l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
-		latest, err = blockLatest(tx)
+	err = l.blockDBs.readTx(func(tx blockTx) error {
+		latest, err = tx.blockLatest()
 		require.NoError(t, err)
 
-		earliest, err = blockEarliest(tx)
+		earliest, err = tx.blockEarliest()
 		require.NoError(t, err)
 		return err
 	})
@@ -196,11 +197,11 @@ func TestArchivalRestart(t *testing.T) {
 	require.NoError(t, err)
 	defer l.Close()
 
-	err = l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
-		latest, err = blockLatest(tx)
+	err = l.blockDBs.readTx(func(tx blockTx) error {
+		latest, err = tx.blockLatest()
 		require.NoError(t, err)
 
-		earliest, err = blockEarliest(tx)
+		earliest, err = tx.blockEarliest()
 		require.NoError(t, err)
 		return err
 	})
@@ -513,11 +514,11 @@ func TestArchivalFromNonArchival(t *testing.T) {
 	l.WaitForCommit(blk.Round())
 
 	var latest, earliest basics.Round
-	err = l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
-		latest, err = blockLatest(tx)
+	err = l.blockDBs.readTx(func(tx blockTx) error {
+		latest, err = tx.blockLatest()
 		require.NoError(t, err)
 
-		earliest, err = blockEarliest(tx)
+		earliest, err = tx.blockEarliest()
 		require.NoError(t, err)
 		return err
 	})
@@ -533,11 +534,11 @@ func TestArchivalFromNonArchival(t *testing.T) {
 	require.NoError(t, err)
 	defer l.Close()
 
-	err = l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
-		latest, err = blockLatest(tx)
+	err = l.blockDBs.readTx(func(tx blockTx) error {
+		latest, err = tx.blockLatest()
 		require.NoError(t, err)
 
-		earliest, err = blockEarliest(tx)
+		earliest, err = tx.blockEarliest()
 		require.NoError(t, err)
 		return err
 	})
@@ -561,6 +562,7 @@ func checkTrackers(t *testing.T, wl *wrappedLedger, rnd basics.Round) (basics.Ro
 		cleanTracker := reflect.New(trackerType).Interface().(ledgerTracker)
 		if trackerType.String() == "ledger.accountUpdates" {
 			cleanTracker.(*accountUpdates).initAccounts = wl.l.accts.initAccounts
+			cleanTracker.(*accountUpdates).history = wl.l.accts.history
 		}
 
 		wl.minQueriedBlock = rnd
@@ -577,3 +579,112 @@ func checkTrackers(t *testing.T, wl *wrappedLedger, rnd basics.Round) (basics.Ro
 
 	return minMinSave, nil
 }
+
+func TestArchivalAccountHistory(t *testing.T) {
+	// Start in archival mode with account history, add blocks that move money
+	// around, and ensure that accounts can be looked up at rounds long flushed
+	// to the account DB, also after a restart.
+
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	dbPrefix := filepath.Join(dbTempDir, dbName)
+	defer os.RemoveAll(dbTempDir)
+
+	genesisInitState := getInitState()
+	genesisInitState.GenesisHash = crypto.Digest{1}
+	genesisInitState.Block.BlockHeader.GenesisHash = crypto.Digest{1}
+	var src basics.Address
+	_, err = rand.Read(src[:])
+	require.NoError(t, err)
+	genesisInitState.Accounts[src] = basics.MakeAccountData(basics.Offline, basics.MicroAlgos{Raw: 1234567890})
+
+	const inMem = false // use persistent storage
+	const archival = true
+	l, err := OpenLedgerWithStorage(logging.Base(), dbPrefix, inMem, genesisInitState, archival, true, StorageSQLite)
+	require.NoError(t, err)
+	blk := genesisInitState.Block
+
+	const maxBlocks = 500
+	var receivers []basics.Address
+	expected := make(map[basics.Round]map[basics.Address]basics.AccountData)
+	for i := 0; i < maxBlocks; i++ {
+		blk.BlockHeader.Round++
+		blk.BlockHeader.TimeStamp += int64(crypto.RandUint64() % 100 * 1000)
+
+		var receiver basics.Address
+		_, err = rand.Read(receiver[:])
+		require.NoError(t, err)
+		receivers = append(receivers, receiver)
+
+		tx := transactions.Transaction{
+			Type: protocol.PaymentTx,
+			Header: transactions.Header{
+				Sender:      src,
+				Fee:         minFee,
+				FirstValid:  blk.Round(),
+				LastValid:   blk.Round(),
+				GenesisHash: genesisInitState.GenesisHash,
+			},
+			PaymentTxnFields: transactions.PaymentTxnFields{
+				Receiver: receiver,
+				Amount:   basics.MicroAlgos{Raw: 1000000 + uint64(i)},
+			},
+		}
+		stxnib, err := blk.EncodeSignedTxn(transactions.SignedTxn{Txn: tx}, transactions.ApplyData{})
+		require.NoError(t, err)
+		blk.Payset = transactions.Payset{stxnib}
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{}))
+
+		expected[blk.Round()] = make(map[basics.Address]basics.AccountData)
+		for _, addr := range []basics.Address{src, receiver, receivers[0]} {
+			data, err := l.Lookup(blk.Round(), addr)
+			require.NoError(t, err)
+			expected[blk.Round()][addr] = data
+		}
+	}
+	l.WaitForCommit(blk.Round())
+	l.trackerMu.Lock()
+	l.accts.lastFlushTime = time.Time{}
+	l.trackerMu.Unlock()
+	l.notifyCommit(blk.Round())
+	proto := config.Consensus[blk.CurrentProtocol]
+	require.Equal(t, basics.Round(maxBlocks-proto.MaxBalLookback), l.accts.dbRound)
+
+	checkHistory := func(l *Ledger) {
+		for rnd, accts := range expected {
+			for addr, data := range accts {
+				d, err := l.Lookup(rnd, addr)
+				require.NoError(t, err)
+				require.Equal(t, data, d)
+			}
+		}
+
+		// Receivers do not exist before they are paid.
+		d, err := l.Lookup(1, receivers[2])
+		require.NoError(t, err)
+		require.Equal(t, basics.AccountData{}, d)
+	}
+	checkHistory(l)
+
+	// close and reopen the same DB
+	l.Close()
+	l, err = OpenLedgerWithStorage(logging.Base(), dbPrefix, inMem, genesisInitState, archival, true, StorageSQLite)
+	require.NoError(t, err)
+	checkHistory(l)
+	l.Close()
+
+	// Without account history, even archival ledgers cannot look up
+	// flushed rounds, and the history is dropped.
+	l, err = OpenLedger(logging.Base(), dbPrefix, inMem, genesisInitState, archival)
+	require.NoError(t, err)
+	_, err = l.Lookup(1, src)
+	require.Error(t, err)
+	l.Close()
+
+	l, err = OpenLedgerWithStorage(logging.Base(), dbPrefix, inMem, genesisInitState, archival, true, StorageSQLite)
+	require.NoError(t, err)
+	defer l.Close()
+	_, err = l.Lookup(1, src)
+	require.Error(t, err)
+}
@@ -249,3 +249,19 @@ func blockForgetBefore(tx *sql.Tx, rnd basics.Round) error {
 	_, err = tx.Exec("DELETE FROM blocks WHERE rnd<?", rnd)
 	return err
 }
+
+// blockForgetAfter deletes the blocks after round rnd, which must be
+// present.
+func blockForgetAfter(tx *sql.Tx, rnd basics.Round) error {
+	earliest, err := blockEarliest(tx)
+	if err != nil {
+		return err
+	}
+
+	if rnd < earliest {
+		return fmt.Errorf("forgetting too much: rnd %d < earliest %d", rnd, earliest)
+	}
+
+	_, err = tx.Exec("DELETE FROM blocks WHERE rnd>?", rnd)
+	return err
+}
@@ -17,7 +17,6 @@
 package ledger
 
 import (
-	"database/sql"
 	"fmt"
 	"sync"
 
@@ -53,9 +52,9 @@ func bqInit(l *Ledger) (*blockQueue, error) {
 	bq.l = l
 	bq.running = true
 
-	err := bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err := bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		bq.lastCommitted, err0 = blockLatest(tx)
+		bq.lastCommitted, err0 = tx.blockLatest()
 		return err0
 	})
 	if err != nil {
@@ -90,9 +89,9 @@ func (bq *blockQueue) syncer() {
 		workQ := bq.q
 		bq.mu.Unlock()
 
-		err := bq.l.blockDBs.wdb.Atomic(func(tx *sql.Tx) error {
+		err := bq.l.blockDBs.writeTx(func(tx blockTx) error {
 			for _, e := range workQ {
-				err0 := blockPut(tx, e.block, e.cert, e.aux)
+				err0 := tx.blockPut(e.block, e.cert, e.aux)
 				if err0 != nil {
 					return err0
 				}
@@ -123,8 +122,8 @@ func (bq *blockQueue) syncer() {
 			bq.mu.Unlock()
 
 			minToSave := bq.l.notifyCommit(committed)
-			err = bq.l.blockDBs.wdb.Atomic(func(tx *sql.Tx) error {
-				return blockForgetBefore(tx, minToSave)
+			err = bq.l.blockDBs.writeTx(func(tx blockTx) error {
+				return tx.blockForgetBefore(minToSave)
 			})
 			if err != nil {
 				bq.l.log.Warnf("blockQueue.syncer: blockForgetBefore(%d): %v", minToSave, err)
@@ -235,9 +234,9 @@ func (bq *blockQueue) getBlock(r basics.Round) (blk bookkeeping.Block, err error
 		return
 	}
 
-	err = bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		blk, err0 = blockGet(tx, r)
+		blk, err0 = tx.blockGet(r)
 		return err0
 	})
 	err = updateErrNoEntry(err, lastCommitted, latest)
@@ -254,9 +253,9 @@ func (bq *blockQueue) getBlockHdr(r basics.Round) (hdr bookkeeping.BlockHeader,
 		return
 	}
 
-	err = bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		hdr, err0 = blockGetHdr(tx, r)
+		hdr, err0 = tx.blockGetHdr(r)
 		return err0
 	})
 	err = updateErrNoEntry(err, lastCommitted, latest)
@@ -277,9 +276,9 @@ func (bq *blockQueue) getEncodedBlockCert(r basics.Round) (blk []byte, cert []by
 		return
 	}
 
-	err = bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		blk, cert, err0 = blockGetEncodedCert(tx, r)
+		blk, cert, err0 = tx.blockGetEncodedCert(r)
 		return err0
 	})
 	err = updateErrNoEntry(err, lastCommitted, latest)
@@ -296,9 +295,9 @@ func (bq *blockQueue) getBlockCert(r basics.Round) (blk bookkeeping.Block, cert
 		return
 	}
 
-	err = bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		blk, cert, err0 = blockGetCert(tx, r)
+		blk, cert, err0 = tx.blockGetCert(r)
 		return err0
 	})
 	err = updateErrNoEntry(err, lastCommitted, latest)
@@ -315,9 +314,9 @@ func (bq *blockQueue) getBlockAux(r basics.Round) (blk bookkeeping.Block, aux ev
 		return
 	}
 
-	err = bq.l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
+	err = bq.l.blockDBs.readTx(func(tx blockTx) error {
 		var err0 error
-		blk, aux, err0 = blockGetAux(tx, r)
+		blk, aux, err0 = tx.blockGetAux(r)
 		return err0
 	})
 	err = updateErrNoEntry(err, lastCommitted, latest)
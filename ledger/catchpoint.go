@@ -0,0 +1,545 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// A catchpoint file captures the account DB (accountbase, accounttotals
+// and assetcreators) as of a catchpoint round, along with the blocks that
+// the trackers need to resume from that state: the MaxBalLookback blocks
+// following the catchpoint round, and enough blocks before it to cover
+// MaxTxnLife for duplicate detection.
+//
+// The file is a sequence of msgpack-encoded objects: a catchpointHeader,
+// NumAccounts catchpointAccount records, NumAssets catchpointAsset
+// records, NumBlocks blocks, the digest of everything that precedes it,
+// and finally the NumBlocks certificates of the blocks.  Accounts and
+// assets are sorted and certificates are left out of the digest, so that
+// every node computes the same digest for the same round, and the digest
+// can be published as part of a catchpoint label.  Certificates differ
+// from node to node, since each node may have seen different votes, but
+// a restored node needs them to serve its blocks to peers.
+
+// catchpointFileSuffix is appended to the round number to name catchpoint files.
+const catchpointFileSuffix = ".catchpoint"
+
+// catchpointFilesToKeep is the number of most recent catchpoint files
+// that the ledger keeps on disk.
+const catchpointFilesToKeep = 2
+
+type catchpointHeader struct {
+	Round       basics.Round  `codec:"rnd"`
+	Totals      AccountTotals `codec:"totals"`
+	NumAccounts uint64        `codec:"naccts"`
+	NumAssets   uint64        `codec:"nassets"`
+	NumBlocks   uint64        `codec:"nblocks"`
+}
+
+type catchpointAccount struct {
+	Address basics.Address     `codec:"addr"`
+	Data    basics.AccountData `codec:"data"`
+}
+
+type catchpointAsset struct {
+	Asset   basics.AssetIndex `codec:"asset"`
+	Creator basics.Address    `codec:"creator"`
+}
+
+// catchpointContents is a decoded catchpoint file.
+type catchpointContents struct {
+	header   catchpointHeader
+	accounts []catchpointAccount
+	assets   []catchpointAsset
+	blocks   []bookkeeping.Block
+	certs    []agreement.Certificate
+}
+
+// catchpointSnapshot is a catchpoint whose file is being written.  Its
+// accounts are read in a transaction that starts while the account DB is
+// at the catchpoint round, so the DB may move on while they are written.
+type catchpointSnapshot struct {
+	round basics.Round
+
+	// first and last are the range of blocks to include.
+	first basics.Round
+	last  basics.Round
+}
+
+// MakeCatchpointLabel returns the label that identifies the catchpoint
+// for round rnd whose file has the given digest.
+func MakeCatchpointLabel(rnd basics.Round, digest crypto.Digest) string {
+	return fmt.Sprintf("%d#%s", rnd, digest.String())
+}
+
+// ParseCatchpointLabel splits a catchpoint label into its round and digest.
+func ParseCatchpointLabel(label string) (rnd basics.Round, digest crypto.Digest, err error) {
+	parts := strings.Split(label, "#")
+	if len(parts) != 2 {
+		err = fmt.Errorf("catchpoint label %#v is not of the form <round>#<digest>", label)
+		return
+	}
+
+	r, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("catchpoint label %#v has a bad round: %v", label, err)
+		return
+	}
+
+	digest, err = crypto.DigestFromString(parts[1])
+	if err != nil {
+		err = fmt.Errorf("catchpoint label %#v has a bad digest: %v", label, err)
+		return
+	}
+
+	rnd = basics.Round(r)
+	return
+}
+
+func catchpointFilename(dir string, rnd basics.Round) string {
+	return filepath.Join(dir, strconv.FormatUint(uint64(rnd), 10)+catchpointFileSuffix)
+}
+
+// catchpointWriter writes the objects of a catchpoint file while
+// accumulating its digest.
+type catchpointWriter struct {
+	w   io.Writer
+	h   hash.Hash
+	err error
+}
+
+func (cw *catchpointWriter) put(obj interface{}) {
+	if cw.err != nil {
+		return
+	}
+	enc := protocol.Encode(obj)
+	cw.h.Write(enc)
+	_, cw.err = cw.w.Write(enc)
+}
+
+func (cw *catchpointWriter) digest() (d crypto.Digest) {
+	copy(d[:], cw.h.Sum(nil))
+	return
+}
+
+// snapshotCatchpoint picks the blocks to include in the catchpoint for
+// round rnd.
+func (l *Ledger) snapshotCatchpoint(rnd basics.Round) (snap *catchpointSnapshot, err error) {
+	snap = &catchpointSnapshot{round: rnd}
+	hdr, err := l.BlockHdr(rnd)
+	if err != nil {
+		return nil, err
+	}
+	snap.last = rnd + basics.Round(config.Consensus[hdr.CurrentProtocol].MaxBalLookback)
+	lastHdr, err := l.BlockHdr(snap.last)
+	if err != nil {
+		return nil, err
+	}
+	snap.first = (snap.last + 1).SubSaturate(basics.Round(config.Consensus[lastHdr.CurrentProtocol].MaxTxnLife))
+	if snap.first > rnd {
+		snap.first = rnd
+	}
+	return snap, nil
+}
+
+// writeCatchpoint writes a catchpoint file for snap into dir, and returns
+// the catchpoint label.  It streams the accounts from a read transaction
+// on the account DB, and closes started once that transaction has seen
+// the DB at the catchpoint round, or has failed to.  The caller must hold
+// trackerMu until then, but not afterwards.  The ledger must keep the
+// snapshot's blocks until writeCatchpoint returns.
+func (l *Ledger) writeCatchpoint(dir string, snap *catchpointSnapshot, started chan<- struct{}) (label string, err error) {
+	var startOnce sync.Once
+	start := func() {
+		startOnce.Do(func() { close(started) })
+	}
+	defer start()
+
+	tmpname := catchpointFilename(dir, snap.round) + ".tmp"
+	f, err := os.Create(tmpname)
+	if err != nil {
+		return
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(tmpname)
+		}
+	}()
+
+	bw := bufio.NewWriter(f)
+	cw := catchpointWriter{w: bw, h: crypto.NewHash()}
+	header := catchpointHeader{
+		Round:     snap.round,
+		NumBlocks: uint64(snap.last - snap.first + 1),
+	}
+	err = l.trackerDBs.readTx(func(tx accountsTx) error {
+		// The transaction may be retried after trackerMu is released,
+		// in which case the DB may have moved on.
+		dbRound, err0 := tx.accountsRound()
+		if err0 != nil {
+			return err0
+		}
+		if dbRound != snap.round {
+			return fmt.Errorf("account DB is at round %d, not catchpoint round %d", dbRound, snap.round)
+		}
+		start()
+
+		header.Totals, err0 = tx.accountsTotals()
+		if err0 != nil {
+			return err0
+		}
+		header.NumAccounts, err0 = tx.accountsCount()
+		if err0 != nil {
+			return err0
+		}
+		creators, err0 := tx.assetCreatorsAll()
+		if err0 != nil {
+			return err0
+		}
+		assets := make([]catchpointAsset, 0, len(creators))
+		for aidx, creator := range creators {
+			assets = append(assets, catchpointAsset{Asset: aidx, Creator: creator})
+		}
+		sort.Slice(assets, func(i, j int) bool {
+			return assets[i].Asset < assets[j].Asset
+		})
+		header.NumAssets = uint64(len(assets))
+
+		cw.put(header)
+		err0 = tx.accountsIterate(func(addr basics.Address, data basics.AccountData) error {
+			cw.put(catchpointAccount{Address: addr, Data: data})
+			return cw.err
+		})
+		if err0 != nil {
+			return err0
+		}
+		for _, asset := range assets {
+			cw.put(asset)
+		}
+		return cw.err
+	})
+	if err != nil {
+		return
+	}
+
+	certs := make([]agreement.Certificate, 0, header.NumBlocks)
+	for r := snap.first; r <= snap.last && cw.err == nil; r++ {
+		var blk bookkeeping.Block
+		var cert agreement.Certificate
+		blk, cert, err = l.BlockCert(r)
+		if err != nil {
+			return
+		}
+		cw.put(blk)
+		certs = append(certs, cert)
+	}
+	digest := cw.digest()
+	cw.put(digest)
+	for _, cert := range certs {
+		cw.put(cert)
+	}
+	if cw.err != nil {
+		err = cw.err
+		return
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return
+	}
+	err = f.Sync()
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmpname, catchpointFilename(dir, snap.round))
+	if err != nil {
+		return
+	}
+
+	label = MakeCatchpointLabel(snap.round, digest)
+	return
+}
+
+// readCatchpoint decodes a catchpoint file and checks it against label.
+func readCatchpoint(r io.Reader, label string) (*catchpointContents, error) {
+	rnd, expected, err := ParseCatchpointLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	cp, digest, err := decodeCatchpoint(r)
+	if err != nil {
+		return nil, err
+	}
+	if cp.header.Round != rnd {
+		return nil, fmt.Errorf("catchpoint file is for round %d, but label %s is for round %d", cp.header.Round, label, rnd)
+	}
+	if digest != expected {
+		return nil, fmt.Errorf("catchpoint file digest %v does not match label %s", digest, label)
+	}
+	return cp, nil
+}
+
+// decodeCatchpoint decodes a catchpoint file and returns it along with
+// its digest.  It checks that the file is consistent with the digest
+// stored in it, but not that the digest is a trusted one.
+func decodeCatchpoint(r io.Reader) (*catchpointContents, crypto.Digest, error) {
+	var cp catchpointContents
+	dec := protocol.NewDecoder(r)
+	h := crypto.NewHash()
+	get := func(obj interface{}) error {
+		err := dec.Decode(obj)
+		if err != nil {
+			return err
+		}
+		// Encoding is canonical, so re-encoding gives back the bytes
+		// that the writer hashed.
+		h.Write(protocol.Encode(obj))
+		return nil
+	}
+
+	err := get(&cp.header)
+	if err != nil {
+		return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint header: %v", err)
+	}
+	rnd := cp.header.Round
+
+	for i := uint64(0); i < cp.header.NumAccounts; i++ {
+		var acct catchpointAccount
+		err = get(&acct)
+		if err != nil {
+			return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint account %d: %v", i, err)
+		}
+		cp.accounts = append(cp.accounts, acct)
+	}
+
+	for i := uint64(0); i < cp.header.NumAssets; i++ {
+		var asset catchpointAsset
+		err = get(&asset)
+		if err != nil {
+			return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint asset %d: %v", i, err)
+		}
+		cp.assets = append(cp.assets, asset)
+	}
+
+	for i := uint64(0); i < cp.header.NumBlocks; i++ {
+		var blk bookkeeping.Block
+		err = get(&blk)
+		if err != nil {
+			return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint block %d: %v", i, err)
+		}
+		cp.blocks = append(cp.blocks, blk)
+	}
+
+	var digest, stored crypto.Digest
+	copy(digest[:], h.Sum(nil))
+	err = dec.Decode(&stored)
+	if err != nil {
+		return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint digest: %v", err)
+	}
+	if digest != stored {
+		return nil, crypto.Digest{}, fmt.Errorf("catchpoint file digest %v does not match its contents %v", stored, digest)
+	}
+
+	// The certificates are not covered by the digest, so check at
+	// least that each one claims to certify its block.  Peers that
+	// fetch the blocks from us authenticate the certificates in full.
+	for i, blk := range cp.blocks {
+		var cert agreement.Certificate
+		err = dec.Decode(&cert)
+		if err != nil {
+			return nil, crypto.Digest{}, fmt.Errorf("reading catchpoint certificate %d: %v", i, err)
+		}
+		if cert.Round != 0 && (cert.Round != blk.Round() || cert.Proposal.BlockDigest != blk.Digest()) {
+			return nil, crypto.Digest{}, fmt.Errorf("catchpoint certificate %d is not for block %d", i, blk.Round())
+		}
+		cp.certs = append(cp.certs, cert)
+	}
+
+	if len(cp.blocks) == 0 {
+		return nil, crypto.Digest{}, fmt.Errorf("catchpoint file has no blocks")
+	}
+	first := cp.blocks[0].Round()
+	for i, blk := range cp.blocks {
+		if blk.Round() != first+basics.Round(i) {
+			return nil, crypto.Digest{}, fmt.Errorf("catchpoint block %d is for round %d, expected %d", i, blk.Round(), first+basics.Round(i))
+		}
+	}
+	if first > rnd || cp.blocks[len(cp.blocks)-1].Round() < rnd {
+		return nil, crypto.Digest{}, fmt.Errorf("catchpoint blocks %d-%d do not include catchpoint round %d", first, cp.blocks[len(cp.blocks)-1].Round(), rnd)
+	}
+
+	return &cp, digest, nil
+}
+
+// restore replaces the contents of the tracker and block databases with
+// the catchpoint.
+func (cp *catchpointContents) restore(trackerStore accountStore, blocks blockStore) error {
+	err := trackerStore.writeTx(func(tx accountsTx) error {
+		return tx.accountsRestore(cp.header.Round, cp.accounts, cp.assets, cp.header.Totals)
+	})
+	if err != nil {
+		return err
+	}
+
+	return blocks.writeTx(func(tx blockTx) error {
+		err0 := tx.blockResetDB()
+		if err0 != nil {
+			return err0
+		}
+
+		err0 = tx.blockInit(nil)
+		if err0 != nil {
+			return err0
+		}
+
+		for i, blk := range cp.blocks {
+			err0 = tx.blockPut(blk, cp.certs[i], evalAux{})
+			if err0 != nil {
+				return err0
+			}
+		}
+		return nil
+	})
+}
+
+// RestoreCatchpoint replaces the ledger databases at dbPathPrefix with
+// the contents of a catchpoint file, after checking the file against a
+// trusted catchpoint label.  The ledger must not be open.  A subsequent
+// OpenLedger resumes from the catchpoint instead of from genesis.  The
+// databases are written with the given storage engine.
+func RestoreCatchpoint(dbPathPrefix string, catchpoint io.Reader, label string, engine StorageEngine) error {
+	cp, err := readCatchpoint(catchpoint, label)
+	if err != nil {
+		return err
+	}
+
+	trackerStore, blocks, err := openLedgerStores(dbPathPrefix, false, engine)
+	if err != nil {
+		return err
+	}
+	defer trackerStore.close()
+	defer blocks.close()
+
+	return cp.restore(trackerStore, blocks)
+}
+
+// EnableCatchpoints makes the ledger write a catchpoint file into dir
+// whenever its account DB reaches a multiple of interval rounds.  The
+// label of each catchpoint is logged when its file is written.
+func (l *Ledger) EnableCatchpoints(dir string, interval uint64) error {
+	if interval == 0 {
+		return fmt.Errorf("catchpoint interval must be positive")
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	l.trackerMu.Lock()
+	defer l.trackerMu.Unlock()
+	l.catchpointDir = dir
+	l.accts.catchpointInterval = interval
+	return nil
+}
+
+// OpenCatchpointFile opens the catchpoint file for round rnd, if the
+// ledger has written one and not yet removed it.
+func (l *Ledger) OpenCatchpointFile(rnd basics.Round) (*os.File, error) {
+	l.trackerMu.RLock()
+	dir := l.catchpointDir
+	l.trackerMu.RUnlock()
+
+	if dir == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(catchpointFilename(dir, rnd))
+}
+
+// LastCatchpoint returns the label of the most recent catchpoint written
+// by the ledger since it was opened, or the empty string if none.
+func (l *Ledger) LastCatchpoint() string {
+	l.trackerMu.RLock()
+	defer l.trackerMu.RUnlock()
+	return l.catchpointLabel
+}
+
+// catchpointCommitted is called by notifyCommit, with trackerMu held,
+// after the account DB has advanced from prevBase.  It writes the
+// catchpoint file in the background, and only waits until the writer has
+// started reading the account DB at the catchpoint round.
+func (l *Ledger) catchpointCommitted(prevBase basics.Round) {
+	interval := basics.Round(l.accts.catchpointInterval)
+	rnd := l.accts.dbRound
+	if l.catchpointDir == "" || rnd == prevBase || rnd%interval != 0 {
+		return
+	}
+	if l.catchpointWriting != nil {
+		l.log.Warnf("still writing catchpoint for round %d, skipping round %d", l.catchpointWriting.round, rnd)
+		return
+	}
+
+	snap, err := l.snapshotCatchpoint(rnd)
+	if err != nil {
+		l.log.Warnf("unable to write catchpoint for round %d: %v", rnd, err)
+		return
+	}
+
+	dir := l.catchpointDir
+	l.catchpointWriting = snap
+	l.catchpointWrites.Add(1)
+	started := make(chan struct{})
+	go func() {
+		defer l.catchpointWrites.Done()
+		label, err := l.writeCatchpoint(dir, snap, started)
+
+		l.trackerMu.Lock()
+		defer l.trackerMu.Unlock()
+		l.catchpointWriting = nil
+		if err != nil {
+			l.log.Warnf("unable to write catchpoint for round %d: %v", rnd, err)
+			return
+		}
+		l.log.Infof("wrote catchpoint %s", label)
+		l.catchpointLabel = label
+
+		old := rnd.SubSaturate(catchpointFilesToKeep * interval)
+		if old != 0 {
+			os.Remove(catchpointFilename(dir, old))
+		}
+	}()
+	<-started
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+)
+
+func TestCatchpointLabel(t *testing.T) {
+	digest := crypto.Hash([]byte("catchpoint"))
+	label := MakeCatchpointLabel(1234, digest)
+
+	rnd, d, err := ParseCatchpointLabel(label)
+	require.NoError(t, err)
+	require.Equal(t, basics.Round(1234), rnd)
+	require.Equal(t, digest, d)
+
+	for _, bad := range []string{"", "1234", "x#" + digest.String(), "1234#nope", label + "#1"} {
+		_, _, err = ParseCatchpointLabel(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestCatchpointWriteRestore(t *testing.T) {
+	for _, engine := range storageEngines {
+		t.Run(string(engine), func(t *testing.T) {
+			testCatchpointWriteRestore(t, engine)
+		})
+	}
+}
+
+func testCatchpointWriteRestore(t *testing.T, sourceEngine StorageEngine) {
+	name := strings.Replace(t.Name(), "/", "-", -1)
+	dbTempDir, err := ioutil.TempDir("", "testdir"+name)
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+	dbPrefix := filepath.Join(dbTempDir, fmt.Sprintf("%s.%d", name, crypto.RandUint64()))
+	catchpointDir := filepath.Join(dbTempDir, "catchpoints")
+
+	genesisInitState := getInitState()
+	l, err := OpenLedgerWithStorage(logging.Base(), dbPrefix, false, genesisInitState, false, false, sourceEngine)
+	require.NoError(t, err)
+	defer l.Close()
+
+	const interval = 100
+	require.NoError(t, l.EnableCatchpoints(catchpointDir, interval))
+
+	blk := genesisInitState.Block
+	const maxBlocks = 1000
+	for i := 0; i < maxBlocks; i++ {
+		blk.BlockHeader.Round++
+		blk.BlockHeader.TimeStamp += int64(crypto.RandUint64() % 100 * 1000)
+		var cert agreement.Certificate
+		cert.Round = blk.Round()
+		cert.Proposal.BlockDigest = blk.Digest()
+		require.NoError(t, l.AddBlock(blk, cert))
+	}
+	l.WaitForCommit(blk.Round())
+
+	// The account DB is flushed at most every few seconds; pretend
+	// enough time has passed to flush through every catchpoint.
+	proto := config.Consensus[blk.CurrentProtocol]
+	expected := basics.Round((maxBlocks - proto.MaxBalLookback) / interval * interval)
+	for i := 0; i < maxBlocks/interval && l.accts.dbRound < expected; i++ {
+		l.trackerMu.Lock()
+		l.accts.lastFlushTime = time.Time{}
+		l.trackerMu.Unlock()
+		l.notifyCommit(blk.Round())
+		// Catchpoint files are written in the background.
+		l.catchpointWrites.Wait()
+	}
+	require.Equal(t, expected, l.accts.dbRound)
+
+	label := l.LastCatchpoint()
+	rnd, _, err := ParseCatchpointLabel(label)
+	require.NoError(t, err)
+	require.Equal(t, expected, rnd)
+
+	// Only the most recent catchpoint files are kept.
+	_, err = os.Stat(catchpointFilename(catchpointDir, rnd-interval))
+	require.NoError(t, err)
+	_, err = os.Stat(catchpointFilename(catchpointDir, rnd-catchpointFilesToKeep*interval))
+	require.True(t, os.IsNotExist(err))
+
+	// A label for a different round or digest is rejected.
+	f, err := l.OpenCatchpointFile(rnd)
+	require.NoError(t, err)
+	_, err = readCatchpoint(f, MakeCatchpointLabel(rnd, crypto.Digest{}))
+	f.Close()
+	require.Error(t, err)
+
+	f, err = l.OpenCatchpointFile(rnd - interval)
+	require.NoError(t, err)
+	_, err = readCatchpoint(f, label)
+	f.Close()
+	require.Error(t, err)
+
+	// The catchpoint restores into every storage engine.
+	last := rnd + basics.Round(proto.MaxBalLookback)
+	for _, engine := range storageEngines {
+		f, err = l.OpenCatchpointFile(rnd)
+		require.NoError(t, err)
+		restored, err := OpenLedgerFromCatchpoint(logging.Base(), name+string(engine), true, genesisInitState, f, label, engine)
+		f.Close()
+		require.NoError(t, err)
+		defer restored.Close()
+
+		require.Equal(t, last, restored.Latest())
+		var earliest basics.Round
+		err = restored.blockDBs.readTx(func(tx blockTx) (err error) {
+			earliest, err = tx.blockEarliest()
+			return
+		})
+		require.NoError(t, err)
+		require.Equal(t, (last + 1).SubSaturate(basics.Round(proto.MaxTxnLife)), earliest)
+
+		// Blocks keep their certificates, to be served to peers.
+		for r := earliest; r <= last; r++ {
+			_, expectedCert, err := l.BlockCert(r)
+			require.NoError(t, err)
+			_, cert, err := restored.BlockCert(r)
+			require.NoError(t, err)
+			require.Equal(t, expectedCert, cert)
+			require.Equal(t, r, cert.Round)
+		}
+
+		for r := rnd; r <= last; r++ {
+			for addr := range genesisInitState.Accounts {
+				expectedData, err := l.Lookup(r, addr)
+				require.NoError(t, err)
+				data, err := restored.Lookup(r, addr)
+				require.NoError(t, err)
+				require.Equal(t, expectedData, data)
+			}
+
+			expectedTotals, err := l.Totals(r)
+			require.NoError(t, err)
+			totals, err := restored.Totals(r)
+			require.NoError(t, err)
+			require.Equal(t, expectedTotals, totals)
+		}
+
+		// The restored ledger carries on from the last catchpoint block.
+		next, err := l.Block(last + 1)
+		require.NoError(t, err)
+		require.NoError(t, restored.AddBlock(next, agreement.Certificate{}))
+	}
+}
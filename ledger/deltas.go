@@ -0,0 +1,373 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// deltaWindow is the number of recent rounds whose deltas are kept in
+// memory, so that consumers can catch up on them without an archive.
+const deltaWindow = 1000
+
+// RoundDelta is the encodable form of a round's StateDelta, for consumers
+// outside the node.  Its lists are sorted, so that a round's encoding does
+// not depend on map iteration order.
+type RoundDelta struct {
+	Round    basics.Round        `codec:"rnd"`
+	Accounts []AccountDelta      `codec:"accts"`
+	Assets   []AssetCreatorDelta `codec:"assets"`
+	Txleases []TxleaseDelta      `codec:"leases"`
+}
+
+// AccountDelta is an account record before and after a round, without
+// pending rewards.  A zero New record means the account was closed.
+type AccountDelta struct {
+	Address basics.Address     `codec:"addr"`
+	Old     basics.AccountData `codec:"old"`
+	New     basics.AccountData `codec:"new"`
+}
+
+// AssetCreatorDelta records an asset created or destroyed in a round.
+type AssetCreatorDelta struct {
+	Asset   basics.AssetIndex `codec:"asset"`
+	Creator basics.Address    `codec:"creator"`
+	Created bool              `codec:"created"`
+}
+
+// TxleaseDelta records a transaction lease taken out in a round.
+type TxleaseDelta struct {
+	Sender     basics.Address `codec:"snd"`
+	Lease      [32]byte       `codec:"lease"`
+	Expiration basics.Round   `codec:"exp"`
+}
+
+// MakeRoundDelta converts the StateDelta of round rnd to its encodable form.
+func MakeRoundDelta(rnd basics.Round, delta StateDelta) RoundDelta {
+	rd := RoundDelta{Round: rnd}
+
+	for addr, ad := range delta.accts {
+		rd.Accounts = append(rd.Accounts, AccountDelta{Address: addr, Old: ad.old, New: ad.new})
+	}
+	sort.Slice(rd.Accounts, func(i, j int) bool {
+		return bytes.Compare(rd.Accounts[i].Address[:], rd.Accounts[j].Address[:]) < 0
+	})
+
+	for aidx, ma := range delta.assets {
+		rd.Assets = append(rd.Assets, AssetCreatorDelta{Asset: aidx, Creator: ma.creator, Created: ma.created})
+	}
+	sort.Slice(rd.Assets, func(i, j int) bool {
+		return rd.Assets[i].Asset < rd.Assets[j].Asset
+	})
+
+	for txl, expires := range delta.txleases {
+		rd.Txleases = append(rd.Txleases, TxleaseDelta{Sender: txl.sender, Lease: txl.lease, Expiration: expires})
+	}
+	sort.Slice(rd.Txleases, func(i, j int) bool {
+		c := bytes.Compare(rd.Txleases[i].Sender[:], rd.Txleases[j].Sender[:])
+		if c == 0 {
+			c = bytes.Compare(rd.Txleases[i].Lease[:], rd.Txleases[j].Lease[:])
+		}
+		return c < 0
+	})
+
+	return rd
+}
+
+// deltaTracker keeps the deltas of recent rounds in memory, and, if an
+// archive is enabled, the deltas of every round since then on disk.  It is
+// only registered with the ledger once state deltas are enabled.
+type deltaTracker struct {
+	log logging.Logger
+
+	// recent holds the deltas of the rounds after recentBase.
+	recent     []RoundDelta
+	recentBase basics.Round
+
+	// archive holds the encoded RoundDelta of every archived round.  It
+	// is written by a separate goroutine, so that newBlock does not wait
+	// for the disk while holding the tracker lock.
+	archive *dbPair
+
+	// mu protects pending, the deltas not yet handed to the writer.
+	mu      sync.Mutex
+	pending []RoundDelta
+
+	// writeMu serializes updates to the archive.
+	writeMu sync.Mutex
+	wake    chan struct{}
+	writer  sync.WaitGroup
+}
+
+func makeDeltaTracker(archive *dbPair) *deltaTracker {
+	dt := &deltaTracker{archive: archive}
+	if archive != nil {
+		dt.wake = make(chan struct{}, 1)
+		dt.writer.Add(1)
+		go dt.writeArchive()
+	}
+	return dt
+}
+
+var deltaArchiveSchema = []string{
+	`CREATE TABLE IF NOT EXISTS deltas (
+		rnd integer primary key,
+		data blob)`,
+}
+
+// openDeltaArchive opens the delta archive in filename, creating it if
+// needed.
+func openDeltaArchive(filename string) (*dbPair, error) {
+	dbs, err := dbOpen(filename, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbs.wdb.Atomic(func(tx *sql.Tx) error {
+		for _, tableCreate := range deltaArchiveSchema {
+			_, err := tx.Exec(tableCreate)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		dbs.close()
+		return nil, err
+	}
+	return &dbs, nil
+}
+
+func (dt *deltaTracker) loadFromDisk(l ledgerForTracker) error {
+	dt.log = l.trackerLog()
+	dt.recent = nil
+	dt.recentBase = l.Latest()
+	return dt.trimArchive(dt.recentBase)
+}
+
+// trimArchive removes deltas for rounds after latest.  Those rounds were
+// lost from the block DB in a crash or rolled back, and may come back
+// different.
+func (dt *deltaTracker) trimArchive(latest basics.Round) error {
+	if dt.archive == nil {
+		return nil
+	}
+
+	dt.mu.Lock()
+	kept := dt.pending[:0]
+	for _, rd := range dt.pending {
+		if rd.Round <= latest {
+			kept = append(kept, rd)
+		}
+	}
+	dt.pending = kept
+	dt.mu.Unlock()
+
+	dt.writeMu.Lock()
+	defer dt.writeMu.Unlock()
+	return dt.archive.wdb.Atomic(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM deltas WHERE rnd > ?", latest)
+		return err
+	})
+}
+
+// writeArchive stores pending deltas in the archive until the tracker is
+// closed.
+func (dt *deltaTracker) writeArchive() {
+	defer dt.writer.Done()
+	for range dt.wake {
+		dt.flushArchive()
+	}
+	dt.flushArchive()
+}
+
+func (dt *deltaTracker) flushArchive() {
+	dt.writeMu.Lock()
+	defer dt.writeMu.Unlock()
+
+	dt.mu.Lock()
+	batch := dt.pending
+	dt.pending = nil
+	dt.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	err := dt.archive.wdb.Atomic(func(tx *sql.Tx) error {
+		for _, rd := range batch {
+			_, err := tx.Exec("INSERT OR REPLACE INTO deltas (rnd, data) VALUES (?, ?)", rd.Round, protocol.Encode(rd))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		dt.log.Warnf("deltaTracker: unable to archive deltas for rounds %d-%d: %v", batch[0].Round, batch[len(batch)-1].Round, err)
+	}
+}
+
+func (dt *deltaTracker) close() {
+	if dt.archive != nil {
+		close(dt.wake)
+		dt.writer.Wait()
+		dt.archive.close()
+	}
+}
+
+func (dt *deltaTracker) newBlock(blk bookkeeping.Block, delta StateDelta) {
+	rnd := blk.Round()
+	if rnd != dt.recentBase+basics.Round(len(dt.recent))+1 {
+		dt.log.Panicf("deltaTracker: newBlock %d, expected %d", rnd, dt.recentBase+basics.Round(len(dt.recent))+1)
+	}
+
+	rd := MakeRoundDelta(rnd, delta)
+	dt.recent = append(dt.recent, rd)
+	if len(dt.recent) > deltaWindow {
+		drop := len(dt.recent) - deltaWindow
+		dt.recent = dt.recent[drop:]
+		dt.recentBase += basics.Round(drop)
+	}
+
+	if dt.archive != nil {
+		dt.mu.Lock()
+		dt.pending = append(dt.pending, rd)
+		dt.mu.Unlock()
+
+		select {
+		case dt.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (dt *deltaTracker) committedUpTo(rnd basics.Round) basics.Round {
+	return rnd
+}
+
+// recentDelta returns the delta of rnd if it is still in memory.
+func (dt *deltaTracker) recentDelta(rnd basics.Round) (RoundDelta, bool) {
+	if rnd > dt.recentBase && rnd <= dt.recentBase+basics.Round(len(dt.recent)) {
+		return dt.recent[rnd-dt.recentBase-1], true
+	}
+	return RoundDelta{}, false
+}
+
+// archivedDelta returns the delta of rnd from the archive, including deltas
+// that the writer has not stored yet.  It does not need the tracker lock.
+func (dt *deltaTracker) archivedDelta(rnd basics.Round) (rd RoundDelta, err error) {
+	dt.mu.Lock()
+	for _, p := range dt.pending {
+		if p.Round == rnd {
+			dt.mu.Unlock()
+			return p, nil
+		}
+	}
+	dt.mu.Unlock()
+
+	err = dt.archive.rdb.Atomic(func(tx *sql.Tx) error {
+		var buf []byte
+		err := tx.QueryRow("SELECT data FROM deltas WHERE rnd = ?", rnd).Scan(&buf)
+		if err != nil {
+			return err
+		}
+		return protocol.Decode(buf, &rd)
+	})
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("delta for round %d is not available", rnd)
+	}
+	return
+}
+
+func (dt *deltaTracker) roundDelta(rnd basics.Round) (RoundDelta, error) {
+	if rd, ok := dt.recentDelta(rnd); ok {
+		return rd, nil
+	}
+	if dt.archive != nil {
+		return dt.archivedDelta(rnd)
+	}
+	return RoundDelta{}, fmt.Errorf("delta for round %d is not available", rnd)
+}
+
+// EnableStateDeltas makes the ledger keep the deltas of recent rounds in
+// memory, for RoundDelta.  If archiveFilename is not empty, the ledger also
+// stores the delta of every round from now on in that file, so that
+// RoundDelta can return it after the round leaves the in-memory window or
+// the node restarts.
+func (l *Ledger) EnableStateDeltas(archiveFilename string) error {
+	var archive *dbPair
+	if archiveFilename != "" {
+		var err error
+		archive, err = openDeltaArchive(archiveFilename)
+		if err != nil {
+			return err
+		}
+	}
+
+	dt := makeDeltaTracker(archive)
+
+	l.trackerMu.Lock()
+	defer l.trackerMu.Unlock()
+	if l.deltas != nil {
+		dt.close()
+		return fmt.Errorf("state deltas already enabled")
+	}
+
+	err := dt.loadFromDisk(l)
+	if err != nil {
+		dt.close()
+		return err
+	}
+
+	l.trackers.register(dt)
+	l.deltas = dt
+	return nil
+}
+
+// RoundDelta returns the changes that round rnd made to the ledger state.
+// It is available for recent rounds, and for rounds added since the delta
+// archive was enabled, once state deltas are enabled.
+func (l *Ledger) RoundDelta(rnd basics.Round) (RoundDelta, error) {
+	l.trackerMu.RLock()
+	dt := l.deltas
+	if dt == nil {
+		l.trackerMu.RUnlock()
+		return RoundDelta{}, fmt.Errorf("state deltas are not enabled")
+	}
+	rd, ok := dt.recentDelta(rnd)
+	l.trackerMu.RUnlock()
+	if ok {
+		return rd, nil
+	}
+
+	// The archive is read without the tracker lock, so that a slow disk
+	// does not hold up new blocks.
+	if dt.archive != nil {
+		return dt.archivedDelta(rnd)
+	}
+	return RoundDelta{}, fmt.Errorf("delta for round %d is not available", rnd)
+}
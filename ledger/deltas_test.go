@@ -0,0 +1,198 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+func TestMakeRoundDelta(t *testing.T) {
+	addrs := []basics.Address{randomAddress(), randomAddress(), randomAddress()}
+	delta := StateDelta{
+		accts:    make(map[basics.Address]accountDelta),
+		txleases: make(map[txlease]basics.Round),
+		assets:   make(map[basics.AssetIndex]modifiedAsset),
+	}
+	for i, addr := range addrs {
+		delta.accts[addr] = accountDelta{old: randomAccountData(0), new: randomAccountData(0)}
+		delta.txleases[txlease{sender: addr, lease: [32]byte{byte(i)}}] = basics.Round(i + 10)
+		delta.assets[basics.AssetIndex(3-i)] = modifiedAsset{created: i%2 == 0, creator: addr}
+	}
+
+	rd := MakeRoundDelta(7, delta)
+	require.Equal(t, basics.Round(7), rd.Round)
+	require.Len(t, rd.Accounts, len(addrs))
+	require.Len(t, rd.Txleases, len(addrs))
+	require.Len(t, rd.Assets, len(addrs))
+	for i, ad := range rd.Accounts {
+		require.Equal(t, delta.accts[ad.Address], accountDelta{old: ad.Old, new: ad.New})
+		if i > 0 {
+			require.True(t, bytes.Compare(rd.Accounts[i-1].Address[:], ad.Address[:]) < 0)
+		}
+	}
+	for i, ma := range rd.Assets {
+		require.Equal(t, basics.AssetIndex(i+1), ma.Asset)
+		require.Equal(t, delta.assets[ma.Asset], modifiedAsset{created: ma.Created, creator: ma.Creator})
+	}
+	for _, tl := range rd.Txleases {
+		require.Equal(t, delta.txleases[txlease{sender: tl.Sender, lease: tl.Lease}], tl.Expiration)
+	}
+
+	// The encoding round-trips and does not depend on map order.
+	var decoded RoundDelta
+	require.NoError(t, protocol.Decode(protocol.Encode(rd), &decoded))
+	require.Equal(t, rd, decoded)
+	require.Equal(t, protocol.Encode(rd), protocol.Encode(MakeRoundDelta(7, delta)))
+}
+
+func TestDeltaTrackerWindow(t *testing.T) {
+	var dt deltaTracker
+	dt.log = logging.Base()
+
+	const extra = 10
+	for rnd := basics.Round(1); rnd <= deltaWindow+extra; rnd++ {
+		var blk bookkeeping.Block
+		blk.BlockHeader.Round = rnd
+		dt.newBlock(blk, StateDelta{})
+	}
+
+	require.Len(t, dt.recent, deltaWindow)
+	for rnd := basics.Round(0); rnd <= extra; rnd++ {
+		_, err := dt.roundDelta(rnd)
+		require.Error(t, err)
+	}
+	for rnd := basics.Round(extra + 1); rnd <= deltaWindow+extra; rnd++ {
+		rd, err := dt.roundDelta(rnd)
+		require.NoError(t, err)
+		require.Equal(t, rnd, rd.Round)
+	}
+	_, err := dt.roundDelta(deltaWindow + extra + 1)
+	require.Error(t, err)
+}
+
+func TestDeltaArchive(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+
+	dbPrefix := filepath.Join(dbTempDir, "ledger")
+	archiveFilename := dbPrefix + ".deltas.sqlite"
+	l, err := OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, true)
+	require.NoError(t, err)
+	_, err = l.RoundDelta(0)
+	require.Error(t, err)
+	require.NoError(t, l.EnableStateDeltas(archiveFilename))
+	require.Error(t, l.EnableStateDeltas(archiveFilename))
+
+	blk := genesisInitState.Block
+	const maxBlocks = 50
+	payments := make(map[basics.Round]transactions.Transaction)
+	for i := 0; i < maxBlocks; i++ {
+		newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+		eval, err := l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+		require.NoError(t, err)
+
+		from := i % len(addrs)
+		to := (i + 1) % len(addrs)
+		txn := transactions.Transaction{
+			Type: protocol.PaymentTx,
+			Header: transactions.Header{
+				Sender:      addrs[from],
+				Fee:         minFee,
+				FirstValid:  newBlock.Round(),
+				LastValid:   newBlock.Round(),
+				GenesisHash: genesisInitState.GenesisHash,
+				Lease:       [32]byte{byte(i + 1)},
+			},
+			PaymentTxnFields: transactions.PaymentTxnFields{
+				Receiver: addrs[to],
+				Amount:   basics.MicroAlgos{Raw: 100 + uint64(i)},
+			},
+		}
+		require.NoError(t, eval.Transaction(txn.Sign(keys[from]), transactions.ApplyData{}))
+
+		vb, err := eval.GenerateBlock()
+		require.NoError(t, err)
+		blk = vb.Block()
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{Round: blk.Round()}))
+		payments[blk.Round()] = txn
+	}
+
+	checkDeltas := func(l *Ledger) {
+		for rnd, txn := range payments {
+			rd, err := l.RoundDelta(rnd)
+			require.NoError(t, err)
+			require.Equal(t, rnd, rd.Round)
+
+			modified := make(map[basics.Address]AccountDelta)
+			for _, ad := range rd.Accounts {
+				modified[ad.Address] = ad
+			}
+			require.Contains(t, modified, txn.Sender)
+			require.Contains(t, modified, txn.Receiver)
+			received := modified[txn.Receiver].New.MicroAlgos.Raw - modified[txn.Receiver].Old.MicroAlgos.Raw
+			require.Equal(t, txn.Amount.Raw, received)
+
+			require.Equal(t, []TxleaseDelta{{Sender: txn.Sender, Lease: txn.Lease, Expiration: txn.LastValid}}, rd.Txleases)
+		}
+	}
+	checkDeltas(l)
+	l.WaitForCommit(blk.Round())
+	l.Close()
+
+	// A delta archived for a round that the block DB no longer has is
+	// dropped when the archive is loaded.
+	archive, err := openDeltaArchive(archiveFilename)
+	require.NoError(t, err)
+	lost := blk.Round() + 1
+	require.NoError(t, archive.wdb.Atomic(func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO deltas (rnd, data) VALUES (?, ?)", lost, protocol.Encode(RoundDelta{Round: lost}))
+		return err
+	}))
+	archive.close()
+
+	// After a restart, the deltas are only available from the archive.
+	l, err = OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, true)
+	require.NoError(t, err)
+	_, err = l.RoundDelta(blk.Round())
+	require.Error(t, err)
+	require.NoError(t, l.EnableStateDeltas(archiveFilename))
+	checkDeltas(l)
+	_, err = l.RoundDelta(lost)
+	require.Error(t, err)
+	l.Close()
+}
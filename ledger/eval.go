@@ -194,6 +194,7 @@ type ledgerForEvaluator interface {
 	GetRoundTxIds(rnd basics.Round) (txMap map[transactions.Txid]bool)
 	LookupWithoutRewards(basics.Round, basics.Address) (basics.AccountData, error)
 	GetAssetCreatorForRound(basics.Round, basics.AssetIndex) (basics.Address, error)
+	accountStateRoot(basics.Round, map[basics.Address]accountDelta) (crypto.Digest, error)
 }
 
 // StartEvaluator creates a BlockEvaluator, given a ledger and a block header
@@ -353,6 +354,11 @@ func (eval *BlockEvaluator) Round() basics.Round {
 	return eval.block.Round()
 }
 
+// ConsensusVersion returns the consensus protocol of the block being evaluated by the BlockEvaluator.
+func (eval *BlockEvaluator) ConsensusVersion() protocol.ConsensusVersion {
+	return eval.block.CurrentProtocol
+}
+
 // ResetTxnBytes resets the number of bytes tracked by the BlockEvaluator to
 // zero.  This is a specialized operation used by the transaction pool to
 // simulate the effect of putting pending transactions in multiple blocks.
@@ -376,11 +382,13 @@ func (eval *BlockEvaluator) TestTransactionGroup(txgroup []transactions.SignedTx
 	cow := eval.state.child()
 
 	var group transactions.TxGroup
+	fees := make([]basics.MicroAlgos, 0, len(txgroup))
 	for gi, txn := range txgroup {
 		err := eval.testTransaction(txn, cow)
 		if err != nil {
 			return err
 		}
+		fees = append(fees, txn.Txn.Fee)
 
 		// Make sure all transactions in group have the same group value
 		if txn.Txn.Group != txgroup[0].Txn.Group {
@@ -407,7 +415,8 @@ func (eval *BlockEvaluator) TestTransactionGroup(txgroup []transactions.SignedTx
 		}
 	}
 
-	return nil
+	// Does the group pay enough fees between its transactions?
+	return transactions.CheckGroupFee(fees, eval.proto)
 }
 
 // testTransaction performs basic duplicate detection and well-formedness checks
@@ -493,6 +502,7 @@ func (eval *BlockEvaluator) transactionGroup(txgroup []transactions.SignedTxnWit
 	var txibs []transactions.SignedTxnInBlock
 	var group transactions.TxGroup
 	var groupTxBytes int
+	fees := make([]basics.MicroAlgos, 0, len(txgroup))
 
 	cow := eval.state.child()
 
@@ -505,6 +515,7 @@ func (eval *BlockEvaluator) transactionGroup(txgroup []transactions.SignedTxnWit
 		}
 
 		txibs = append(txibs, txib)
+		fees = append(fees, txad.SignedTxn.Txn.Fee)
 
 		if eval.validate {
 			groupTxBytes += len(protocol.Encode(txib))
@@ -538,6 +549,14 @@ func (eval *BlockEvaluator) transactionGroup(txgroup []transactions.SignedTxnWit
 		}
 	}
 
+	// Does the group pay enough fees between its transactions?
+	if eval.validate {
+		err := transactions.CheckGroupFee(fees, eval.proto)
+		if err != nil {
+			return err
+		}
+	}
+
 	if remember {
 		eval.block.Payset = append(eval.block.Payset, txibs...)
 		eval.blockTxBytes += groupTxBytes
@@ -655,8 +674,24 @@ func (eval *BlockEvaluator) transaction(txn transactions.SignedTxn, ad transacti
 			return fmt.Errorf("transaction groups not supported")
 		}
 
+		// A funded opt-in gives the receiver a holding it did not ask
+		// for, so the receiver must consent by sending a transaction
+		// of the same group.
+		if txn.Txn.Type == protocol.AssetTransferTx && txn.Txn.AssetFundOptIn && !groupHasSender(txgroup, txn.Txn.AssetReceiver) {
+			return fmt.Errorf("transaction %v: funded asset opt-in needs a transaction from receiver %v in the same group", txid, txn.Txn.AssetReceiver)
+		}
+
+		// Signed by the key the sender is currently rekeyed to?
+		err = eval.checkAuthorizer(txn, cow)
+		if err != nil {
+			return err
+		}
+
 		needCheckLsig := !txn.Lsig.Blank()
-		if needCheckLsig {
+		// A program that reads the round may pass in one block and fail in
+		// the next, so its result is never taken from or put in the cache.
+		cacheLsig := needCheckLsig && !logic.ReadsRound(txn.Lsig.Logic)
+		if cacheLsig {
 			found, txErr := eval.txcache.EvalOk(eval.block.CurrentProtocol, txid)
 			if found {
 				if txErr == nil {
@@ -671,7 +706,9 @@ func (eval *BlockEvaluator) transaction(txn transactions.SignedTxn, ad transacti
 			if err != nil {
 				return err
 			}
-			eval.txcache.EvalRemember(eval.block.CurrentProtocol, txid, nil)
+			if cacheLsig {
+				eval.txcache.EvalRemember(eval.block.CurrentProtocol, txid, nil)
+			}
 		}
 	}
 
@@ -739,6 +776,25 @@ func (eval *BlockEvaluator) transaction(txn transactions.SignedTxn, ad transacti
 	return nil
 }
 
+// checkAuthorizer checks that txn is authorized by the sender's current
+// AuthAddr, or by the sender itself if the sender has not been rekeyed.
+// Signatures were already checked against txn.Authorizer() by verify.
+func (eval *BlockEvaluator) checkAuthorizer(txn transactions.SignedTxn, cow *roundCowState) error {
+	record, err := cow.Get(txn.Txn.Sender, false)
+	if err != nil {
+		return err
+	}
+
+	expected := record.AuthAddr
+	if (expected == basics.Address{}) {
+		expected = txn.Txn.Sender
+	}
+	if txn.Authorizer() != expected {
+		return fmt.Errorf("transaction %v: should have been authorized by %v but was actually authorized by %v", txn.ID(), expected, txn.Authorizer())
+	}
+	return nil
+}
+
 func (eval *BlockEvaluator) checkLogicSig(txn transactions.SignedTxn, txgroup []transactions.SignedTxnWithAD, groupIndex int) (err error) {
 	if txn.Txn.FirstValid == 0 {
 		return errors.New("LogicSig does not work with FirstValid==0")
@@ -749,10 +805,12 @@ func (eval *BlockEvaluator) checkLogicSig(txn transactions.SignedTxn, txgroup []
 		return fmt.Errorf("could not fetch BlockHdr for FirstValid-1=%d (current=%d): %s", txn.Txn.FirstValid-1, eval.block.BlockHeader.Round, err)
 	}
 	ep := logic.EvalParams{
-		Txn:        &txn,
-		Proto:      &eval.proto,
-		TxnGroup:   txgroup,
-		GroupIndex: groupIndex,
+		Txn:             &txn,
+		Proto:           &eval.proto,
+		TxnGroup:        txgroup,
+		GroupIndex:      groupIndex,
+		Round:           uint64(eval.block.Round()),
+		LatestTimestamp: eval.prevHeader.TimeStamp,
 	}
 	if hdr.TimeStamp < 0 {
 		return fmt.Errorf("cannot evaluate LogicSig before 1970 at TimeStamp %d", hdr.TimeStamp)
@@ -771,6 +829,16 @@ func (eval *BlockEvaluator) checkLogicSig(txn transactions.SignedTxn, txgroup []
 	return nil
 }
 
+// groupHasSender reports whether addr sends one of the transactions of txgroup.
+func groupHasSender(txgroup []transactions.SignedTxnWithAD, addr basics.Address) bool {
+	for _, txad := range txgroup {
+		if txad.SignedTxn.Txn.Sender == addr {
+			return true
+		}
+	}
+	return false
+}
+
 // Call "endOfBlock" after all the block's rewards and transactions are processed. Applies any deferred balance updates.
 func (eval *BlockEvaluator) endOfBlock() error {
 	if eval.generate {
@@ -780,6 +848,11 @@ func (eval *BlockEvaluator) endOfBlock() error {
 		} else {
 			eval.block.TxnCounter = 0
 		}
+		root, err := eval.expectedAccountStateRoot()
+		if err != nil {
+			return err
+		}
+		eval.block.AccountStateRoot = root
 	}
 
 	return nil
@@ -794,11 +867,26 @@ func (eval *BlockEvaluator) endOfProxyBlock() error {
 		} else {
 			eval.block.TxnCounter = 0
 		}
+		root, err := eval.expectedAccountStateRoot()
+		if err != nil {
+			return err
+		}
+		eval.block.AccountStateRoot = root
 	}
 
 	return nil
 }
 
+// expectedAccountStateRoot returns the AccountStateRoot that the block
+// header must carry: the root of the account state trie after this block's
+// updates, or zero if the protocol does not commit to account state.
+func (eval *BlockEvaluator) expectedAccountStateRoot() (crypto.Digest, error) {
+	if !eval.proto.AccountStateRoot {
+		return crypto.Digest{}, nil
+	}
+	return eval.l.accountStateRoot(eval.prevHeader.Round, eval.state.mods.accts)
+}
+
 // FinalValidation does the validation that must happen after the block is built and all state updates are computed
 func (eval *BlockEvaluator) finalValidation() error {
 	if eval.validate {
@@ -815,6 +903,14 @@ func (eval *BlockEvaluator) finalValidation() error {
 		if eval.block.TxnCounter != expectedTxnCount {
 			return fmt.Errorf("txn count wrong: %d != %d", eval.block.TxnCounter, expectedTxnCount)
 		}
+
+		expectedRoot, err := eval.expectedAccountStateRoot()
+		if err != nil {
+			return err
+		}
+		if eval.block.AccountStateRoot != expectedRoot {
+			return fmt.Errorf("account state root wrong: %v != %v", eval.block.AccountStateRoot, expectedRoot)
+		}
 	}
 
 	return nil
@@ -836,6 +932,14 @@ func (eval *BlockEvaluator) finalProxyValidation() error {
 		if eval.block.TxnCounter != expectedTxnCount {
 			return fmt.Errorf("txn count wrong: %d != %d", eval.block.TxnCounter, expectedTxnCount)
 		}
+
+		expectedRoot, err := eval.expectedAccountStateRoot()
+		if err != nil {
+			return err
+		}
+		if eval.block.AccountStateRoot != expectedRoot {
+			return fmt.Errorf("account state root wrong: %v != %v", eval.block.AccountStateRoot, expectedRoot)
+		}
 	}
 
 	return nil
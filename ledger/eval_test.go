@@ -130,3 +130,394 @@ func TestBlockEvaluator(t *testing.T) {
 	require.Equal(t, bal1new.MicroAlgos.Raw, bal1.MicroAlgos.Raw+100)
 	require.Equal(t, bal2new.MicroAlgos.Raw, bal2.MicroAlgos.Raw-minFee.Raw)
 }
+
+func TestRekeying(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+	genesisInitState.Block.CurrentProtocol = protocol.ConsensusFuture
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	const inMem = true
+	const archival = true
+	l, err := OpenLedger(logging.Base(), dbName, inMem, genesisInitState, archival)
+	require.NoError(t, err)
+	defer l.Close()
+
+	blk := genesisInitState.Block
+	var eval *BlockEvaluator
+	startBlock := func() {
+		newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+		eval, err = l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+		require.NoError(t, err)
+	}
+	endBlock := func() {
+		vb, err := eval.GenerateBlock()
+		require.NoError(t, err)
+		require.NoError(t, l.AddValidatedBlock(*vb, agreement.Certificate{}))
+		blk = vb.Block()
+	}
+
+	var note uint64
+	makeTxn := func(sender basics.Address, rekeyTo basics.Address) transactions.Transaction {
+		note++
+		return transactions.Transaction{
+			Type: protocol.PaymentTx,
+			Header: transactions.Header{
+				Sender:      sender,
+				Fee:         minFee,
+				FirstValid:  blk.Round() + 1,
+				LastValid:   blk.Round() + 1,
+				GenesisHash: genesisInitState.GenesisHash,
+				Note:        protocol.Encode(note),
+				RekeyTo:     rekeyTo,
+			},
+			PaymentTxnFields: transactions.PaymentTxnFields{
+				Receiver: sender,
+			},
+		}
+	}
+	signWith := func(txn transactions.Transaction, i int) transactions.SignedTxn {
+		stxn := txn.Sign(keys[i])
+		if addrs[i] != txn.Sender {
+			stxn.AuthAddr = addrs[i]
+		}
+		return stxn
+	}
+
+	// Rekey addrs[0] to addrs[1]; within the same block, addrs[0]'s own
+	// key no longer works.
+	startBlock()
+	require.NoError(t, eval.Transaction(signWith(makeTxn(addrs[0], addrs[1]), 0), transactions.ApplyData{}))
+	require.Error(t, eval.Transaction(signWith(makeTxn(addrs[0], basics.Address{}), 0), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(signWith(makeTxn(addrs[0], basics.Address{}), 1), transactions.ApplyData{}))
+	endBlock()
+
+	data, err := l.Lookup(blk.Round(), addrs[0])
+	require.NoError(t, err)
+	require.Equal(t, addrs[1], data.AuthAddr)
+
+	// Another key cannot claim to be the auth address.
+	startBlock()
+	require.Error(t, eval.Transaction(signWith(makeTxn(addrs[0], basics.Address{}), 2), transactions.ApplyData{}))
+	bad := signWith(makeTxn(addrs[0], basics.Address{}), 2)
+	bad.AuthAddr = addrs[1]
+	require.Error(t, eval.Transaction(bad, transactions.ApplyData{}))
+
+	// Rekeying back to the sender restores its own key.
+	require.NoError(t, eval.Transaction(signWith(makeTxn(addrs[0], addrs[0]), 1), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(signWith(makeTxn(addrs[0], basics.Address{}), 0), transactions.ApplyData{}))
+	endBlock()
+
+	data, err = l.Lookup(blk.Round(), addrs[0])
+	require.NoError(t, err)
+	require.Equal(t, basics.Address{}, data.AuthAddr)
+}
+
+func TestFeePooling(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+	genesisInitState.Block.CurrentProtocol = protocol.ConsensusFuture
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	const inMem = true
+	const archival = true
+	l, err := OpenLedger(logging.Base(), dbName, inMem, genesisInitState, archival)
+	require.NoError(t, err)
+	defer l.Close()
+
+	newBlock := bookkeeping.MakeBlock(genesisInitState.Block.BlockHeader)
+	eval, err := l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+	require.NoError(t, err)
+
+	makeGroup := func(fees ...uint64) []transactions.SignedTxnWithAD {
+		var group transactions.TxGroup
+		txns := make([]transactions.Transaction, len(fees))
+		for i, fee := range fees {
+			txns[i] = transactions.Transaction{
+				Type: protocol.PaymentTx,
+				Header: transactions.Header{
+					Sender:      addrs[i],
+					Fee:         basics.MicroAlgos{Raw: fee},
+					FirstValid:  newBlock.Round(),
+					LastValid:   newBlock.Round(),
+					GenesisHash: genesisInitState.GenesisHash,
+					Note:        protocol.Encode(crypto.RandUint64()),
+				},
+				PaymentTxnFields: transactions.PaymentTxnFields{
+					Receiver: addrs[i],
+				},
+			}
+			group.TxGroupHashes = append(group.TxGroupHashes, crypto.HashObj(txns[i]))
+		}
+
+		txgroup := make([]transactions.SignedTxnWithAD, len(txns))
+		for i := range txns {
+			txns[i].Group = crypto.HashObj(group)
+			txgroup[i].SignedTxn = txns[i].Sign(keys[i])
+		}
+		return txgroup
+	}
+	signedTxns := func(txgroup []transactions.SignedTxnWithAD) []transactions.SignedTxn {
+		stxns := make([]transactions.SignedTxn, len(txgroup))
+		for i := range txgroup {
+			stxns[i] = txgroup[i].SignedTxn
+		}
+		return stxns
+	}
+
+	// One transaction may pay the fees of the whole group.
+	txgroup := makeGroup(0, 0, 3*minFee.Raw)
+	require.NoError(t, eval.TestTransactionGroup(signedTxns(txgroup)))
+	require.NoError(t, eval.TransactionGroup(txgroup))
+
+	// But the group must pay the minimum fee for each transaction.
+	txgroup = makeGroup(0, 0, 3*minFee.Raw-1)
+	require.Error(t, eval.TestTransactionGroup(signedTxns(txgroup)))
+	err = eval.TransactionGroup(txgroup)
+	require.Error(t, err)
+	require.IsType(t, transactions.MinFeeError(""), err)
+
+	// A lone transaction still pays its own fee.
+	single := makeGroup(0)[0].SignedTxn.Txn
+	single.Group = crypto.Digest{}
+	require.Error(t, eval.Transaction(single.Sign(keys[0]), transactions.ApplyData{}))
+}
+
+func TestFundedAssetOptIn(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+
+	testProto := protocol.ConsensusVersion("test-funded-asset-optin")
+	params := config.Consensus[protocol.ConsensusFuture]
+	params.MaxAssetsPerAccount = 2
+	config.Consensus[testProto] = params
+	defer delete(config.Consensus, testProto)
+	genesisInitState.Block.CurrentProtocol = testProto
+
+	minBalance := params.MinBalance
+
+	// addrs[1] can afford its base MinBalance but not an asset slot,
+	// addrs[2] can create an asset but then cannot pay for anyone's slot,
+	// and newAddr does not exist yet.
+	genesisInitState.Accounts[addrs[1]] = basics.AccountData{MicroAlgos: basics.MicroAlgos{Raw: minBalance + minFee.Raw}}
+	genesisInitState.Accounts[addrs[2]] = basics.AccountData{MicroAlgos: basics.MicroAlgos{Raw: 2*minBalance + 2*minFee.Raw}}
+	var seed crypto.Seed
+	crypto.RandBytes(seed[:])
+	newKey := crypto.GenerateSignatureSecrets(seed)
+	newAddr := basics.Address(newKey.SignatureVerifier)
+	keyOf := map[basics.Address]*crypto.SignatureSecrets{newAddr: newKey}
+	for i, addr := range addrs {
+		keyOf[addr] = keys[i]
+	}
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	const inMem = true
+	const archival = true
+	l, err := OpenLedger(logging.Base(), dbName, inMem, genesisInitState, archival)
+	require.NoError(t, err)
+	defer l.Close()
+
+	blk := genesisInitState.Block
+	var eval *BlockEvaluator
+	startBlock := func() {
+		newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+		eval, err = l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+		require.NoError(t, err)
+	}
+	endBlock := func() {
+		vb, err := eval.GenerateBlock()
+		require.NoError(t, err)
+		require.NoError(t, l.AddValidatedBlock(*vb, agreement.Certificate{}))
+		blk = vb.Block()
+	}
+
+	var note uint64
+	header := func(sender basics.Address) transactions.Header {
+		note++
+		return transactions.Header{
+			Sender:      sender,
+			Fee:         minFee,
+			FirstValid:  blk.Round() + 1,
+			LastValid:   blk.Round() + 1,
+			GenesisHash: genesisInitState.GenesisHash,
+			Note:        protocol.Encode(note),
+		}
+	}
+	create := func(creator basics.Address) transactions.Transaction {
+		return transactions.Transaction{
+			Type:   protocol.AssetConfigTx,
+			Header: header(creator),
+			AssetConfigTxnFields: transactions.AssetConfigTxnFields{
+				AssetParams: basics.AssetParams{Total: 1000, Manager: creator},
+			},
+		}
+	}
+	fundOptInTxn := func(manager basics.Address, aidx basics.AssetIndex, receiver basics.Address, amount uint64) transactions.Transaction {
+		return transactions.Transaction{
+			Type:   protocol.AssetTransferTx,
+			Header: header(manager),
+			AssetTransferTxnFields: transactions.AssetTransferTxnFields{
+				XferAsset:      aidx,
+				AssetAmount:    amount,
+				AssetReceiver:  receiver,
+				AssetFundOptIn: true,
+			},
+		}
+	}
+	makeGroup := func(txns ...transactions.Transaction) []transactions.SignedTxnWithAD {
+		var group transactions.TxGroup
+		for _, txn := range txns {
+			group.TxGroupHashes = append(group.TxGroupHashes, crypto.HashObj(txn))
+		}
+		txgroup := make([]transactions.SignedTxnWithAD, len(txns))
+		for i, txn := range txns {
+			txn.Group = crypto.HashObj(group)
+			txgroup[i].SignedTxn = txn.Sign(keyOf[txn.Sender])
+		}
+		return txgroup
+	}
+	// fundOptIn groups a funded opt-in with the receiver's consent: an
+	// empty payment to itself, whose fee the manager pays.
+	fundOptIn := func(manager basics.Address, aidx basics.AssetIndex, receiver basics.Address, amount uint64) []transactions.SignedTxnWithAD {
+		fund := fundOptInTxn(manager, aidx, receiver, amount)
+		fund.Fee.Raw = 2 * minFee.Raw
+		consent := transactions.Transaction{
+			Type:             protocol.PaymentTx,
+			Header:           header(receiver),
+			PaymentTxnFields: transactions.PaymentTxnFields{Receiver: receiver},
+		}
+		consent.Fee.Raw = 0
+		return makeGroup(fund, consent)
+	}
+	assetOf := func(creator basics.Address) basics.AssetIndex {
+		data, err := l.Lookup(blk.Round(), creator)
+		require.NoError(t, err)
+		require.Len(t, data.AssetParams, 1)
+		for aidx := range data.AssetParams {
+			return aidx
+		}
+		return 0
+	}
+
+	startBlock()
+	require.NoError(t, eval.Transaction(create(addrs[0]).Sign(keys[0]), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(create(addrs[2]).Sign(keys[2]), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(create(addrs[3]).Sign(keys[3]), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(create(addrs[4]).Sign(keys[4]), transactions.ApplyData{}))
+	endBlock()
+	asset0, asset2, asset3, asset4 := assetOf(addrs[0]), assetOf(addrs[2]), assetOf(addrs[3]), assetOf(addrs[4])
+
+	startBlock()
+
+	// addrs[1] cannot afford to opt in by itself.
+	selfOptIn := transactions.Transaction{
+		Type:   protocol.AssetTransferTx,
+		Header: header(addrs[1]),
+		AssetTransferTxnFields: transactions.AssetTransferTxnFields{
+			XferAsset:     asset0,
+			AssetReceiver: addrs[1],
+		},
+	}
+	err = eval.Transaction(selfOptIn.Sign(keys[1]), transactions.ApplyData{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "below min")
+
+	// Only the manager may fund an opt-in.
+	require.Error(t, eval.TransactionGroup(fundOptIn(addrs[4], asset0, addrs[1], 0)))
+
+	// The receiver must consent, so a manager cannot fill the asset slots
+	// of an account that did not ask for them.
+	err = eval.Transaction(fundOptInTxn(addrs[0], asset0, addrs[1], 0).Sign(keys[0]), transactions.ApplyData{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "needs a transaction from receiver")
+	other := transactions.Transaction{
+		Type:             protocol.PaymentTx,
+		Header:           header(addrs[3]),
+		PaymentTxnFields: transactions.PaymentTxnFields{Receiver: addrs[3]},
+	}
+	err = eval.TransactionGroup(makeGroup(fundOptInTxn(addrs[0], asset0, addrs[1], 0), other))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "needs a transaction from receiver")
+
+	// A manager that cannot keep its own MinBalance cannot pay for the slot.
+	err = eval.TransactionGroup(fundOptIn(addrs[2], asset2, addrs[1], 0))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("account %v balance", addrs[2]))
+
+	// The manager also pays a new account's base MinBalance.
+	require.NoError(t, eval.TransactionGroup(fundOptIn(addrs[0], asset0, newAddr, 10)))
+
+	// For an existing account, it only pays for the slot.
+	require.NoError(t, eval.TransactionGroup(fundOptIn(addrs[0], asset0, addrs[1], 10)))
+
+	// Funding the same slot twice fails.
+	require.Error(t, eval.TransactionGroup(fundOptIn(addrs[0], asset0, addrs[1], 0)))
+	endBlock()
+
+	data, err := l.Lookup(blk.Round(), addrs[1])
+	require.NoError(t, err)
+	require.Equal(t, 2*minBalance+minFee.Raw, data.MicroAlgos.Raw)
+	require.Equal(t, basics.AssetHolding{Amount: 10}, data.Assets[asset0])
+
+	data, err = l.Lookup(blk.Round(), newAddr)
+	require.NoError(t, err)
+	require.Equal(t, 2*minBalance, data.MicroAlgos.Raw)
+	require.Equal(t, basics.AssetHolding{Amount: 10}, data.Assets[asset0])
+
+	startBlock()
+
+	// addrs[1] now holds 1 asset; MaxAssetsPerAccount is 2.
+	require.NoError(t, eval.TransactionGroup(fundOptIn(addrs[3], asset3, addrs[1], 0)))
+	err = eval.TransactionGroup(fundOptIn(addrs[4], asset4, addrs[1], 0))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too many assets")
+	endBlock()
+
+	data, err = l.Lookup(blk.Round(), addrs[1])
+	require.NoError(t, err)
+	require.Len(t, data.Assets, 2)
+	require.Equal(t, 3*minBalance+minFee.Raw, data.MicroAlgos.Raw)
+
+	startBlock()
+
+	// The receiver can always close out a slot it was given, which frees
+	// it for another asset.
+	closeOut := func(holder basics.Address, aidx basics.AssetIndex, creator basics.Address) transactions.Transaction {
+		return transactions.Transaction{
+			Type:   protocol.AssetTransferTx,
+			Header: header(holder),
+			AssetTransferTxnFields: transactions.AssetTransferTxnFields{
+				XferAsset:     aidx,
+				AssetReceiver: creator,
+				AssetCloseTo:  creator,
+			},
+		}
+	}
+	require.NoError(t, eval.Transaction(closeOut(addrs[1], asset0, addrs[0]).Sign(keys[1]), transactions.ApplyData{}))
+	require.NoError(t, eval.Transaction(closeOut(addrs[1], asset3, addrs[3]).Sign(keys[1]), transactions.ApplyData{}))
+	require.NoError(t, eval.TransactionGroup(fundOptIn(addrs[4], asset4, addrs[1], 0)))
+
+	// A manager cannot fund a slot in an asset that is frozen by default,
+	// since the receiver might not be able to close it out.
+	frozen := create(addrs[5])
+	frozen.AssetParams.DefaultFrozen = true
+	require.NoError(t, eval.Transaction(frozen.Sign(keys[5]), transactions.ApplyData{}))
+	endBlock()
+	asset5 := assetOf(addrs[5])
+
+	data, err = l.Lookup(blk.Round(), addrs[1])
+	require.NoError(t, err)
+	require.Equal(t, map[basics.AssetIndex]basics.AssetHolding{asset4: {}}, data.Assets)
+
+	startBlock()
+	err = eval.TransactionGroup(fundOptIn(addrs[5], asset5, addrs[6], 0))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "frozen by default")
+	endBlock()
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/vincentbdb/go-algorand/data/basics"
+)
+
+// ReadEncodedBlockCerts calls fn with the encoded block and certificate of
+// every round from first to last, in order, as stored in the block
+// database at dbPathPrefix.  The database is opened read-only, so this
+// can run next to a node that is using it.  The blocks are read in a
+// single transaction, so they are consistent with one another.
+func ReadEncodedBlockCerts(dbPathPrefix string, engine StorageEngine, first basics.Round, last basics.Round, fn func(rnd basics.Round, blk []byte, cert []byte) error) error {
+	trackerStore, blocks, err := openLedgerStoresReadOnly(dbPathPrefix, engine)
+	if err != nil {
+		return err
+	}
+	defer trackerStore.close()
+	defer blocks.close()
+
+	return blocks.readTx(func(tx blockTx) error {
+		earliest, err := tx.blockEarliest()
+		if err != nil {
+			return err
+		}
+		latest, err := tx.blockLatest()
+		if err != nil {
+			return err
+		}
+		if first > last || first < earliest || last > latest {
+			return fmt.Errorf("cannot read rounds %d-%d: block database has rounds %d-%d", first, last, earliest, latest)
+		}
+
+		for rnd := first; rnd <= last; rnd++ {
+			blk, cert, err := tx.blockGetEncodedCert(rnd)
+			if err != nil {
+				return err
+			}
+			err = fn(rnd, blk, cert)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
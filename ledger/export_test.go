@@ -0,0 +1,67 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+func TestReadEncodedBlockCerts(t *testing.T) {
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+	dbPrefix := filepath.Join(dbTempDir, "ledger")
+
+	genesisInitState := getInitState()
+	l, err := OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, true)
+	require.NoError(t, err)
+	defer l.Close()
+
+	blk := genesisInitState.Block
+	for i := 0; i < 10; i++ {
+		blk.BlockHeader.Round++
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{Round: blk.Round()}))
+	}
+	l.WaitForCommit(blk.Round())
+
+	var rounds []basics.Round
+	err = ReadEncodedBlockCerts(dbPrefix, StorageSQLite, 3, 7, func(rnd basics.Round, encBlk []byte, encCert []byte) error {
+		expectedBlk, expectedCert, err := l.BlockCert(rnd)
+		require.NoError(t, err)
+		require.Equal(t, protocol.Encode(expectedBlk), encBlk)
+		require.Equal(t, protocol.Encode(expectedCert), encCert)
+		rounds = append(rounds, rnd)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []basics.Round{3, 4, 5, 6, 7}, rounds)
+
+	err = ReadEncodedBlockCerts(dbPrefix, StorageSQLite, 5, blk.Round()+1, func(basics.Round, []byte, []byte) error {
+		return nil
+	})
+	require.Error(t, err)
+}
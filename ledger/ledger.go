@@ -18,15 +18,17 @@ package ledger
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 
 	"github.com/algorand/go-deadlock"
 
 	"github.com/vincentbdb/go-algorand/agreement"
 	"github.com/vincentbdb/go-algorand/config"
 	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/crypto/merkletrie"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 	"github.com/vincentbdb/go-algorand/data/transactions"
@@ -38,8 +40,8 @@ type Ledger struct {
 	// Database connections to the DBs storing blocks and tracker state.
 	// We use potentially different databases to avoid SQLite contention
 	// during catchup.
-	trackerDBs dbPair
-	blockDBs   dbPair
+	trackerDBs accountStore
+	blockDBs   blockStore
 
 	// blockQ is the buffer of added blocks that will be flushed to
 	// persistent storage
@@ -61,11 +63,28 @@ type Ledger struct {
 	notifier blockNotifier
 	time     timeTracker
 	metrics  metricsTracker
+	deltas   *deltaTracker
 
 	trackers  trackerRegistry
 	trackerMu deadlock.RWMutex
 
 	headerCache heapLRUCache
+
+	// catchpointDir is where catchpoint files are written, if
+	// catchpoints are enabled.
+	catchpointDir string
+
+	// catchpointLabel is the label of the last catchpoint written.
+	catchpointLabel string
+
+	// catchpointWriting is the snapshot of the catchpoint being written
+	// in the background, if any.  Its blocks are kept until it is done.
+	catchpointWriting *catchpointSnapshot
+	catchpointWrites  sync.WaitGroup
+
+	// stateTrieBuilds tracks the background build of the account state
+	// trie, if any.
+	stateTrieBuilds sync.WaitGroup
 }
 
 // InitState structure defines blockchain init params
@@ -81,6 +100,38 @@ type InitState struct {
 // database wasn't initialized before.
 func OpenLedger(
 	log logging.Logger, dbPathPrefix string, dbMem bool, genesisInitState InitState, isArchival bool,
+) (*Ledger, error) {
+	return openLedger(log, dbPathPrefix, dbMem, genesisInitState, isArchival, false, StorageSQLite, nil)
+}
+
+// OpenLedgerWithStorage is like OpenLedger, but keeps blocks and accounts
+// in the given storage engine.  Databases created by one engine are not
+// visible to the other.  If accountHistory is set, the ledger also keeps
+// the state of every account at every round, so that Lookup can go back
+// to any round; otherwise it drops any history kept before.
+func OpenLedgerWithStorage(
+	log logging.Logger, dbPathPrefix string, dbMem bool, genesisInitState InitState, isArchival bool, accountHistory bool, engine StorageEngine,
+) (*Ledger, error) {
+	return openLedger(log, dbPathPrefix, dbMem, genesisInitState, isArchival, accountHistory, engine, nil)
+}
+
+// OpenLedgerFromCatchpoint is like OpenLedgerWithStorage, but first
+// replaces the contents of the ledger with a catchpoint file, after
+// checking the file against a trusted catchpoint label.  The resulting
+// ledger starts at the last block in the catchpoint rather than at
+// genesis, and is not archival.
+func OpenLedgerFromCatchpoint(
+	log logging.Logger, dbPathPrefix string, dbMem bool, genesisInitState InitState, catchpoint io.Reader, label string, engine StorageEngine,
+) (*Ledger, error) {
+	cp, err := readCatchpoint(catchpoint, label)
+	if err != nil {
+		return nil, err
+	}
+	return openLedger(log, dbPathPrefix, dbMem, genesisInitState, false, false, engine, cp)
+}
+
+func openLedger(
+	log logging.Logger, dbPathPrefix string, dbMem bool, genesisInitState InitState, isArchival bool, accountHistory bool, engine StorageEngine, cp *catchpointContents,
 ) (*Ledger, error) {
 	var err error
 	l := &Ledger{
@@ -97,12 +148,19 @@ func OpenLedger(
 		}
 	}()
 
-	l.trackerDBs, l.blockDBs, err = openLedgerDB(dbPathPrefix, dbMem)
+	l.trackerDBs, l.blockDBs, err = openLedgerStores(dbPathPrefix, dbMem, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	err = l.blockDBs.wdb.Atomic(func(tx *sql.Tx) error {
+	if cp != nil {
+		err = cp.restore(l.trackerDBs, l.blockDBs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = l.blockDBs.writeTx(func(tx blockTx) error {
 		return initBlocksDB(tx, l, []bookkeeping.Block{genesisInitState.Block}, isArchival)
 	})
 	if err != nil {
@@ -121,6 +179,7 @@ func OpenLedger(
 	}
 
 	l.accts.initProto = config.Consensus[genesisInitState.Block.CurrentProtocol]
+	l.accts.history = accountHistory
 	l.accts.initAccounts = initAccounts
 
 	l.trackers.register(&l.accts)
@@ -136,13 +195,13 @@ func OpenLedger(
 	}
 
 	// Check that the genesis hash, if present, matches.
-	err = l.blockDBs.rdb.Atomic(func(tx *sql.Tx) error {
-		latest, err := blockLatest(tx)
+	err = l.blockDBs.readTx(func(tx blockTx) error {
+		latest, err := tx.blockLatest()
 		if err != nil {
 			return err
 		}
 
-		hdr, err := blockGetHdr(tx, latest)
+		hdr, err := tx.blockGetHdr(latest)
 		if err != nil {
 			return err
 		}
@@ -165,11 +224,26 @@ func OpenLedger(
 }
 
 func openLedgerDB(dbPathPrefix string, dbMem bool) (trackerDBs dbPair, blockDBs dbPair, err error) {
+	trackerDBFilename, blockDBFilename, err := ledgerDBFilenames(dbPathPrefix, dbMem)
+	if err != nil {
+		return
+	}
+
+	trackerDBs, err = dbOpen(trackerDBFilename, dbMem)
+	if err != nil {
+		return
+	}
+
+	blockDBs, err = dbOpen(blockDBFilename, dbMem)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func ledgerDBFilenames(dbPathPrefix string, dbMem bool) (trackerDBFilename string, blockDBFilename string, err error) {
 	// Backwards compatibility: we used to store both blocks and tracker
 	// state in a single SQLite db file.
-	var trackerDBFilename string
-	var blockDBFilename string
-
 	commonDBFilename := dbPathPrefix + ".sqlite"
 	if !dbMem {
 		_, err = os.Stat(commonDBFilename)
@@ -179,23 +253,12 @@ func openLedgerDB(dbPathPrefix string, dbMem bool) (trackerDBs dbPair, blockDBs
 		// No common file, so use two separate files for blocks and tracker.
 		trackerDBFilename = dbPathPrefix + ".tracker.sqlite"
 		blockDBFilename = dbPathPrefix + ".block.sqlite"
+		err = nil
 	} else if err == nil {
 		// Legacy common file exists (or testing in-memory, where performance
 		// doesn't matter), use same database for everything.
 		trackerDBFilename = commonDBFilename
 		blockDBFilename = commonDBFilename
-	} else {
-		return
-	}
-
-	trackerDBs, err = dbOpen(trackerDBFilename, dbMem)
-	if err != nil {
-		return
-	}
-
-	blockDBs, err = dbOpen(blockDBFilename, dbMem)
-	if err != nil {
-		return
 	}
 	return
 }
@@ -204,15 +267,15 @@ func openLedgerDB(dbPathPrefix string, dbMem bool) (trackerDBs dbPair, blockDBs
 // - creates and populates it with genesis blocks
 // - ensures DB is in good shape for archival mode and resets it if not
 // - does nothing if everything looks good
-func initBlocksDB(tx *sql.Tx, l *Ledger, initBlocks []bookkeeping.Block, isArchival bool) (err error) {
-	err = blockInit(tx, initBlocks)
+func initBlocksDB(tx blockTx, l *Ledger, initBlocks []bookkeeping.Block, isArchival bool) (err error) {
+	err = tx.blockInit(initBlocks)
 	if err != nil {
 		return err
 	}
 
 	// in archival mode check if DB contains all blocks up to the latest
 	if isArchival {
-		earliest, err := blockEarliest(tx)
+		earliest, err := tx.blockEarliest()
 		if err != nil {
 			return err
 		}
@@ -221,11 +284,11 @@ func initBlocksDB(tx *sql.Tx, l *Ledger, initBlocks []bookkeeping.Block, isArchi
 		// So reset the DB and init it again
 		if earliest != basics.Round(0) {
 			l.log.Warnf("resetting blocks DB (earliest block is %v)", earliest)
-			err := blockResetDB(tx)
+			err := tx.blockResetDB()
 			if err != nil {
 				return err
 			}
-			err = blockInit(tx, initBlocks)
+			err = tx.blockInit(initBlocks)
 			if err != nil {
 				return err
 			}
@@ -237,8 +300,14 @@ func initBlocksDB(tx *sql.Tx, l *Ledger, initBlocks []bookkeeping.Block, isArchi
 // Close reclaims resources used by the ledger (namely, the database connection
 // and goroutines used by trackers).
 func (l *Ledger) Close() {
-	l.trackerDBs.close()
-	l.blockDBs.close()
+	l.catchpointWrites.Wait()
+	l.stateTrieBuilds.Wait()
+	if l.trackerDBs != nil {
+		l.trackerDBs.close()
+	}
+	if l.blockDBs != nil {
+		l.blockDBs.close()
+	}
 	l.trackers.close()
 	if l.blockQ != nil {
 		l.blockQ.close()
@@ -258,12 +327,17 @@ func (l *Ledger) RegisterBlockListeners(listeners []BlockListener) {
 func (l *Ledger) notifyCommit(r basics.Round) basics.Round {
 	l.trackerMu.Lock()
 	defer l.trackerMu.Unlock()
+	prevBase := l.accts.dbRound
 	minToSave := l.trackers.committedUpTo(r)
+	l.catchpointCommitted(prevBase)
 
 	if l.archival {
 		// Do not forget any blocks.
 		minToSave = 0
 	}
+	if l.catchpointWriting != nil && l.catchpointWriting.first < minToSave {
+		minToSave = l.catchpointWriting.first
+	}
 
 	return minToSave
 }
@@ -277,6 +351,24 @@ func (l *Ledger) GetAssetCreatorForRound(rnd basics.Round, assetIdx basics.Asset
 	return l.accts.getAssetCreatorForRound(rnd, assetIdx)
 }
 
+// AccountProof returns the data of addr at the end of round rnd, without
+// pending rewards, along with a Merkle proof of it against the account
+// state root of that round.  The returned root matches the block header's
+// AccountStateRoot when the round's protocol commits to account state.
+func (l *Ledger) AccountProof(rnd basics.Round, addr basics.Address) (basics.AccountData, merkletrie.Proof, crypto.Digest, error) {
+	l.trackerMu.RLock()
+	defer l.trackerMu.RUnlock()
+	return l.accts.accountProof(rnd, addr)
+}
+
+// accountStateRoot returns the account state root that results from
+// applying updates to the account state at the end of round rnd.
+func (l *Ledger) accountStateRoot(rnd basics.Round, updates map[basics.Address]accountDelta) (crypto.Digest, error) {
+	l.trackerMu.RLock()
+	defer l.trackerMu.RUnlock()
+	return l.accts.accountStateRoot(rnd, updates)
+}
+
 // GetAssetCreator is like GetAssetCreatorForRound, but for the latest round
 // and race free with respect to ledger.Latest()
 func (l *Ledger) GetAssetCreator(assetIdx basics.AssetIndex) (basics.Address, error) {
@@ -294,9 +386,21 @@ func (l *Ledger) ListAssets(maxAssetIdx basics.AssetIndex, maxResults uint64) (r
 	return l.accts.listAssets(maxAssetIdx, maxResults)
 }
 
+// ListAssetHolders returns up to maxResults accounts holding assetIdx as of
+// the latest round, with their holdings, in order of address.  Only
+// addresses after the address after are returned, so that callers can page
+// through the holders by passing the last address of the previous page.
+func (l *Ledger) ListAssetHolders(assetIdx basics.AssetIndex, after basics.Address, maxResults uint64) ([]basics.AssetHolder, error) {
+	l.trackerMu.RLock()
+	defer l.trackerMu.RUnlock()
+	return l.accts.listAssetHolders(assetIdx, after, maxResults)
+}
+
 // Lookup uses the accounts tracker to return the account state for a
 // given account in a particular round.  The account values reflect
-// the changes of all blocks up to and including rnd.
+// the changes of all blocks up to and including rnd.  Ledgers that keep
+// account history can look up any round; others only the most recent
+// MaxBalLookback or so.
 func (l *Ledger) Lookup(rnd basics.Round, addr basics.Address) (basics.AccountData, error) {
 	l.trackerMu.RLock()
 	defer l.trackerMu.RUnlock()
@@ -425,6 +529,7 @@ func (l *Ledger) AddValidatedBlock(vb ValidatedBlock, cert agreement.Certificate
 	}
 
 	l.trackers.newBlock(vb.blk, vb.delta)
+	l.stateTrieNewBlock(vb.blk.BlockHeader)
 	return nil
 }
 
@@ -466,7 +571,7 @@ func (l *Ledger) GenesisHash() crypto.Digest {
 }
 
 // ledgerForTracker methods
-func (l *Ledger) trackerDB() dbPair {
+func (l *Ledger) trackerDB() accountStore {
 	return l.trackerDBs
 }
 
@@ -133,6 +133,18 @@ func (l *Ledger) appendUnvalidated(blk bookkeeping.Block) error {
 	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
 	defer backlogPool.Shutdown()
 
+	// The account state root depends on the block's effects, so tests
+	// that build blocks by hand cannot fill it in themselves.
+	if config.Consensus[blk.CurrentProtocol].AccountStateRoot {
+		delta, _, err := l.eval(context.Background(), blk, nil, false, nil, backlogPool)
+		if err == nil {
+			blk.AccountStateRoot, err = l.accountStateRoot(blk.Round()-1, delta.accts)
+		}
+		if err != nil {
+			return fmt.Errorf("appendUnvalidated error computing account state root: %s", err.Error())
+		}
+	}
+
 	vb, err := l.Validate(context.Background(), blk, DummyVerifiedTxnCache{}, backlogPool)
 	if err != nil {
 		return fmt.Errorf("appendUnvalidated error in Validate: %s", err.Error())
@@ -0,0 +1,314 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+// replayProgressInterval is how often Replay logs its progress, in rounds.
+const replayProgressInterval = 1000
+
+// ReplayDivergence describes the first place where a replay of the block
+// database disagrees with the tracker database.
+type ReplayDivergence struct {
+	Round basics.Round
+
+	// Address is the diverging account, or the zero address if the
+	// account totals diverge.
+	Address basics.Address
+
+	// Field is the path to the first differing field, such as
+	// "MicroAlgos.Raw" or "Online.Money.Raw".
+	Field string
+
+	// Replayed and OnDisk are the values of the field in the replayed
+	// ledger and in the tracker database.
+	Replayed interface{}
+	OnDisk   interface{}
+}
+
+func (d ReplayDivergence) String() string {
+	what := "totals"
+	if !d.Address.IsZero() {
+		what = "account " + d.Address.String()
+	}
+	return fmt.Sprintf("round %d: %s: %s is %v after replay but %v on disk", d.Round, what, d.Field, d.Replayed, d.OnDisk)
+}
+
+// Replay re-evaluates every block in the ledger databases at dbPathPrefix,
+// from genesis, into a fresh in-memory ledger, and compares the result with
+// the tracker database.  The databases are opened read-only.
+//
+// The tracker database holds the full account state and totals only for its
+// own round, so those are compared when the replay reaches that round.  If the
+// tracker database keeps account history (on archival nodes), every account
+// modified by a block up to that round is also compared at the block's round.
+// Blocks past the tracker database's round are evaluated but not compared.
+//
+// Replay returns the first divergence it finds, or nil if there is none.  A
+// block that fails to evaluate is reported as an error.
+func Replay(log logging.Logger, dbPathPrefix string, engine StorageEngine, genesisInitState InitState, executionPool execpool.BacklogPool) (*ReplayDivergence, error) {
+	trackerStore, blocks, err := openLedgerStoresReadOnly(dbPathPrefix, engine)
+	if err != nil {
+		return nil, err
+	}
+	defer trackerStore.close()
+	defer blocks.close()
+
+	var earliest, latest basics.Round
+	err = blocks.readTx(func(tx blockTx) (err error) {
+		earliest, err = tx.blockEarliest()
+		if err != nil {
+			return
+		}
+		latest, err = tx.blockLatest()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if earliest != 0 {
+		return nil, fmt.Errorf("block database starts at round %d, not genesis; only archival ledgers can be replayed", earliest)
+	}
+
+	var dbRound, histBase basics.Round
+	var history bool
+	err = trackerStore.readTx(func(tx accountsTx) (err error) {
+		dbRound, err = tx.accountsRound()
+		if err != nil {
+			return
+		}
+		histBase, history, err = tx.accountsHistoryBase()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if dbRound > latest {
+		return nil, fmt.Errorf("tracker database is at round %d, past the latest block %d", dbRound, latest)
+	}
+
+	qs, err := trackerStore.queries()
+	if err != nil {
+		return nil, err
+	}
+
+	replay, err := OpenLedger(log, dbPathPrefix+".replay", true, genesisInitState, false)
+	if err != nil {
+		return nil, err
+	}
+	defer replay.Close()
+
+	for rnd := basics.Round(0); rnd <= latest; rnd++ {
+		var modified map[basics.Address]accountDelta
+		if rnd > 0 {
+			var blk bookkeeping.Block
+			var cert agreement.Certificate
+			err = blocks.readTx(func(tx blockTx) (err error) {
+				blk, cert, err = tx.blockGetCert(rnd)
+				return
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			vb, err := replay.Validate(context.Background(), blk, nil, executionPool)
+			if err != nil {
+				return nil, fmt.Errorf("round %d: %v", rnd, err)
+			}
+			err = replay.AddValidatedBlock(*vb, cert)
+			if err != nil {
+				return nil, err
+			}
+			modified = vb.delta.accts
+		}
+
+		if history && rnd >= histBase && rnd <= dbRound {
+			for _, addr := range sortedAddresses(modified) {
+				onDisk, err := qs.lookupHistory(addr, rnd)
+				if err != nil {
+					return nil, err
+				}
+				d := replayCompare(rnd, addr, modified[addr].new, onDisk)
+				if d != nil {
+					return d, nil
+				}
+			}
+		}
+
+		if rnd == dbRound {
+			d, err := replayCompareTracker(replay, trackerStore, rnd)
+			if d != nil || err != nil {
+				return d, err
+			}
+		}
+
+		if rnd%replayProgressInterval == 0 {
+			log.Infof("replay: verified round %d of %d", rnd, latest)
+		}
+	}
+
+	return nil, nil
+}
+
+// replayCompareTracker compares the replayed ledger with the full account
+// state and totals in the tracker database, which must be at round rnd.
+func replayCompareTracker(replay *Ledger, trackerStore accountStore, rnd basics.Round) (*ReplayDivergence, error) {
+	replayedTotals, err := replay.Totals(rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := replay.AllBalances(rnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var onDiskTotals AccountTotals
+	var onDisk map[basics.Address]basics.AccountData
+	err = trackerStore.readTx(func(tx accountsTx) (err error) {
+		onDiskTotals, err = tx.accountsTotals()
+		if err != nil {
+			return
+		}
+		onDisk, err = tx.accountsAll()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d := replayCompare(rnd, basics.Address{}, replayedTotals, onDiskTotals)
+	if d != nil {
+		return d, nil
+	}
+
+	addrs := make(map[basics.Address]bool)
+	for addr := range replayed {
+		addrs[addr] = true
+	}
+	for addr := range onDisk {
+		addrs[addr] = true
+	}
+	for _, addr := range sortedAddresses(addrs) {
+		d = replayCompare(rnd, addr, replayed[addr], onDisk[addr])
+		if d != nil {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// sortedAddresses returns the keys of an address-keyed map in order, so
+// that the first divergence reported does not depend on map iteration.
+func sortedAddresses(m interface{}) []basics.Address {
+	keys := reflect.ValueOf(m).MapKeys()
+	addrs := make([]basics.Address, len(keys))
+	for i, key := range keys {
+		addrs[i] = key.Interface().(basics.Address)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+func replayCompare(rnd basics.Round, addr basics.Address, replayed, onDisk interface{}) *ReplayDivergence {
+	field, a, b, ok := firstDifference("", reflect.ValueOf(replayed), reflect.ValueOf(onDisk))
+	if ok {
+		return nil
+	}
+	return &ReplayDivergence{
+		Round:    rnd,
+		Address:  addr,
+		Field:    field,
+		Replayed: a,
+		OnDisk:   b,
+	}
+}
+
+// firstDifference walks two values of the same type and returns the path
+// to the first field where they differ, with the differing values.  A
+// missing map entry compares equal to the zero value.
+func firstDifference(path string, a, b reflect.Value) (field string, av, bv interface{}, equal bool) {
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			if f.PkgPath != "" {
+				// unexported
+				continue
+			}
+			field, av, bv, equal = firstDifference(joinFieldPath(path, f.Name), a.Field(i), b.Field(i))
+			if !equal {
+				return
+			}
+		}
+		return "", nil, nil, true
+
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, key := range b.MapKeys() {
+			if !a.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		zero := reflect.Zero(a.Type().Elem())
+		for _, key := range keys {
+			ae, be := a.MapIndex(key), b.MapIndex(key)
+			if !ae.IsValid() {
+				ae = zero
+			}
+			if !be.IsValid() {
+				be = zero
+			}
+			field, av, bv, equal = firstDifference(fmt.Sprintf("%s[%v]", path, key.Interface()), ae, be)
+			if !equal {
+				return
+			}
+		}
+		return "", nil, nil, true
+
+	default:
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return "", nil, nil, true
+		}
+		return path, a.Interface(), b.Interface(), false
+	}
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
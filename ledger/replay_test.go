@@ -0,0 +1,181 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+func TestReplay(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+
+	for _, engine := range storageEngines {
+		dbPrefix := filepath.Join(dbTempDir, string(engine))
+		l, err := OpenLedgerWithStorage(logging.Base(), dbPrefix, false, genesisInitState, true, true, engine)
+		require.NoError(t, err)
+
+		blk := genesisInitState.Block
+		const maxBlocks = 400
+		for i := 0; i < maxBlocks; i++ {
+			newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+			eval, err := l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+			require.NoError(t, err)
+
+			from := i % len(addrs)
+			to := (i + 1) % len(addrs)
+			txn := transactions.Transaction{
+				Type: protocol.PaymentTx,
+				Header: transactions.Header{
+					Sender:      addrs[from],
+					Fee:         minFee,
+					FirstValid:  newBlock.Round(),
+					LastValid:   newBlock.Round(),
+					GenesisHash: genesisInitState.GenesisHash,
+				},
+				PaymentTxnFields: transactions.PaymentTxnFields{
+					Receiver: addrs[to],
+					Amount:   basics.MicroAlgos{Raw: 100 + uint64(i)},
+				},
+			}
+			require.NoError(t, eval.Transaction(txn.Sign(keys[from]), transactions.ApplyData{}))
+
+			vb, err := eval.GenerateBlock()
+			require.NoError(t, err)
+			blk = vb.Block()
+			require.NoError(t, l.AddBlock(blk, agreement.Certificate{Round: blk.Round()}))
+		}
+
+		l.WaitForCommit(blk.Round())
+		l.trackerMu.Lock()
+		l.accts.lastFlushTime = time.Time{}
+		l.trackerMu.Unlock()
+		l.notifyCommit(blk.Round())
+		proto := config.Consensus[blk.CurrentProtocol]
+		require.Equal(t, basics.Round(maxBlocks-proto.MaxBalLookback), l.accts.dbRound)
+		l.Close()
+
+		d, err := Replay(logging.Base(), dbPrefix, engine, genesisInitState, backlogPool)
+		require.NoError(t, err)
+		require.Nil(t, d, "%v", d)
+	}
+
+	// Corrupt the SQLite tracker DB, and check that the replay finds it.
+	dbPrefix := filepath.Join(dbTempDir, string(StorageSQLite))
+	dbs, err := dbOpen(dbPrefix+".tracker.sqlite", false)
+	require.NoError(t, err)
+	defer dbs.close()
+
+	// bumpBalance adds a microalgo to the account data in a row of the
+	// tracker DB, and returns a function that undoes the change.
+	bumpBalance := func(table string, where string, args ...interface{}) func() {
+		var buf []byte
+		err := dbs.wdb.Handle.QueryRow("SELECT data FROM "+table+" WHERE "+where, args...).Scan(&buf)
+		require.NoError(t, err)
+
+		var data basics.AccountData
+		require.NoError(t, protocol.Decode(buf, &data))
+		data.MicroAlgos.Raw++
+		_, err = dbs.wdb.Handle.Exec("UPDATE "+table+" SET data=? WHERE "+where, append([]interface{}{protocol.Encode(data)}, args...)...)
+		require.NoError(t, err)
+
+		return func() {
+			_, err := dbs.wdb.Handle.Exec("UPDATE "+table+" SET data=? WHERE "+where, append([]interface{}{buf}, args...)...)
+			require.NoError(t, err)
+		}
+	}
+
+	checkDivergence := func(rnd basics.Round, addr basics.Address, field string) {
+		d, err := Replay(logging.Base(), dbPrefix, StorageSQLite, genesisInitState, backlogPool)
+		require.NoError(t, err)
+		require.NotNil(t, d)
+		require.Equal(t, rnd, d.Round)
+		require.Equal(t, addr, d.Address)
+		require.Equal(t, field, d.Field)
+		require.Equal(t, d.Replayed.(uint64)+1, d.OnDisk)
+	}
+
+	// The sender of the payment in round 50 is addrs[49 % 10].
+	undo := bumpBalance("accounthist", "address=? AND rnd=?", addrs[9][:], 50)
+	checkDivergence(50, addrs[9], "MicroAlgos.Raw")
+	undo()
+
+	undo = bumpBalance("accountbase", "address=?", addrs[0][:])
+	checkDivergence(80, addrs[0], "MicroAlgos.Raw")
+	undo()
+
+	_, err = dbs.wdb.Handle.Exec("UPDATE accounttotals SET offline=offline+1")
+	require.NoError(t, err)
+	checkDivergence(80, basics.Address{}, "Offline.Money.Raw")
+}
+
+func TestReplayFirstDifference(t *testing.T) {
+	a := basics.AccountData{
+		Status: basics.Online,
+		Assets: map[basics.AssetIndex]basics.AssetHolding{
+			1: {Amount: 10},
+			2: {Amount: 20},
+		},
+	}
+	b := a
+	d := replayCompare(1, basics.Address{}, a, b)
+	require.Nil(t, d)
+
+	b.Assets = map[basics.AssetIndex]basics.AssetHolding{
+		1: {Amount: 10},
+		2: {Amount: 20, Frozen: true},
+	}
+	d = replayCompare(1, basics.Address{}, a, b)
+	require.NotNil(t, d)
+	require.Equal(t, "Assets[2].Frozen", d.Field)
+	require.Equal(t, false, d.Replayed)
+	require.Equal(t, true, d.OnDisk)
+
+	// A missing map entry is the same as a zero one.
+	b.Assets = map[basics.AssetIndex]basics.AssetHolding{
+		1: {Amount: 10},
+		2: {Amount: 20},
+		3: {},
+	}
+	require.Nil(t, replayCompare(1, basics.Address{}, a, b))
+
+	b.Status = basics.Offline
+	d = replayCompare(1, basics.Address{}, a, b)
+	require.NotNil(t, d)
+	require.Equal(t, "Status", d.Field)
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+)
+
+// RollbackLedger truncates the ledger databases at dbPathPrefix so that
+// round rnd becomes the latest round.  It is meant for development and
+// private networks, where discarding blocks that the rest of the network
+// has agreed on is acceptable.  The ledger must not be open.
+//
+// Blocks after rnd are deleted.  If the account DB is already past rnd,
+// it is reverted to the nearest snapshot at or before rnd: the most
+// recent catchpoint file in catchpointDir whose blocks are still in the
+// block DB, or else genesis.  A subsequent OpenLedger replays the blocks
+// from that snapshot up to rnd.  Catchpoint files for rounds after rnd
+// are removed.  RollbackLedger returns the round of the snapshot that
+// the account DB starts from.
+func RollbackLedger(log logging.Logger, dbPathPrefix string, engine StorageEngine, catchpointDir string, rnd basics.Round) (snapshot basics.Round, err error) {
+	trackerStore, blocks, err := openLedgerStores(dbPathPrefix, false, engine)
+	if err != nil {
+		return 0, err
+	}
+	defer trackerStore.close()
+	defer blocks.close()
+
+	var earliest, latest basics.Round
+	err = blocks.readTx(func(tx blockTx) (err error) {
+		earliest, err = tx.blockEarliest()
+		if err != nil {
+			return
+		}
+		latest, err = tx.blockLatest()
+		return
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rnd < earliest || rnd > latest {
+		return 0, fmt.Errorf("cannot roll back to round %d: block database has rounds %d-%d", rnd, earliest, latest)
+	}
+
+	err = trackerStore.readTx(func(tx accountsTx) (err error) {
+		snapshot, err = tx.accountsRound()
+		return
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if snapshot > rnd {
+		snapshot, err = rollbackAccounts(log, trackerStore, catchpointDir, earliest, rnd)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// The account DB is reverted before the blocks are truncated, so
+	// that an interrupted rollback leaves a ledger that still opens,
+	// and the rollback can be run again.
+	if rnd < latest {
+		log.Infof("RollbackLedger: deleting blocks %d-%d", rnd+1, latest)
+		err = blocks.writeTx(func(tx blockTx) error {
+			return tx.blockForgetAfter(rnd)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, cpRound := range catchpointRounds(catchpointDir) {
+		if cpRound > rnd {
+			os.Remove(catchpointFilename(catchpointDir, cpRound))
+		}
+	}
+	return snapshot, nil
+}
+
+// rollbackAccounts reverts the account DB to the most recent catchpoint
+// in catchpointDir between rounds earliest and rnd, or to genesis if there
+// is none.  It returns the round the account DB was reverted to.
+func rollbackAccounts(log logging.Logger, trackerStore accountStore, catchpointDir string, earliest basics.Round, rnd basics.Round) (basics.Round, error) {
+	rounds := catchpointRounds(catchpointDir)
+	for i := len(rounds) - 1; i >= 0; i-- {
+		cpRound := rounds[i]
+		if cpRound > rnd || cpRound < earliest {
+			continue
+		}
+
+		f, err := os.Open(catchpointFilename(catchpointDir, cpRound))
+		if err != nil {
+			log.Warnf("RollbackLedger: cannot open catchpoint for round %d: %v", cpRound, err)
+			continue
+		}
+		cp, _, err := decodeCatchpoint(f)
+		f.Close()
+		if err != nil {
+			log.Warnf("RollbackLedger: cannot read catchpoint for round %d: %v", cpRound, err)
+			continue
+		}
+
+		log.Infof("RollbackLedger: restoring account DB from catchpoint for round %d", cpRound)
+		err = trackerStore.writeTx(func(tx accountsTx) error {
+			return tx.accountsRestore(cp.header.Round, cp.accounts, cp.assets, cp.header.Totals)
+		})
+		return cpRound, err
+	}
+
+	if earliest != 0 {
+		return 0, fmt.Errorf("cannot roll back to round %d: no catchpoint between rounds %d and %d, and the block database does not start at genesis", rnd, earliest, rnd)
+	}
+
+	// OpenLedger initializes an empty account DB from genesis.
+	log.Infof("RollbackLedger: resetting account DB to genesis")
+	err := trackerStore.writeTx(func(tx accountsTx) error {
+		return tx.accountsReset()
+	})
+	return 0, err
+}
+
+// catchpointRounds returns the rounds of the catchpoint files in dir,
+// in increasing order.
+func catchpointRounds(dir string) []basics.Round {
+	if dir == "" {
+		return nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+catchpointFileSuffix))
+	if err != nil {
+		return nil
+	}
+
+	var rounds []basics.Round
+	for _, m := range matches {
+		r, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(m), catchpointFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		rounds = append(rounds, basics.Round(r))
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] < rounds[j] })
+	return rounds
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+)
+
+func TestRollbackLedger(t *testing.T) {
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+	dbPrefix := filepath.Join(dbTempDir, "ledger")
+	catchpointDir := filepath.Join(dbTempDir, "catchpoints")
+
+	genesisInitState := getInitState()
+	l, err := OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, true)
+	require.NoError(t, err)
+
+	const interval = 100
+	require.NoError(t, l.EnableCatchpoints(catchpointDir, interval))
+
+	type roundState struct {
+		totals   AccountTotals
+		accounts map[basics.Address]basics.AccountData
+	}
+	states := make(map[basics.Round]roundState)
+
+	blk := genesisInitState.Block
+	proto := config.Consensus[blk.CurrentProtocol]
+	const maxBlocks = 1000
+	expected := basics.Round((maxBlocks - proto.MaxBalLookback) / interval * interval)
+	targets := []basics.Round{expected - interval/2, expected - 3*interval/2}
+	for i := 0; i < maxBlocks; i++ {
+		blk.BlockHeader.Round++
+		blk.BlockHeader.TimeStamp += int64(crypto.RandUint64() % 100 * 1000)
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{}))
+
+		for _, target := range targets {
+			if blk.Round() != target {
+				continue
+			}
+			l.WaitForCommit(target)
+			totals, err := l.Totals(target)
+			require.NoError(t, err)
+			accounts := make(map[basics.Address]basics.AccountData)
+			for addr := range genesisInitState.Accounts {
+				accounts[addr], err = l.Lookup(target, addr)
+				require.NoError(t, err)
+			}
+			states[target] = roundState{totals, accounts}
+		}
+	}
+	l.WaitForCommit(blk.Round())
+
+	for i := 0; i < maxBlocks/interval && l.accts.dbRound < expected; i++ {
+		l.trackerMu.Lock()
+		l.accts.lastFlushTime = time.Time{}
+		l.trackerMu.Unlock()
+		l.notifyCommit(blk.Round())
+		l.catchpointWrites.Wait()
+	}
+	require.Equal(t, expected, l.accts.dbRound)
+	l.Close()
+
+	checkRollback := func(rnd basics.Round, expectedSnapshot basics.Round) {
+		snapshot, err := RollbackLedger(logging.Base(), dbPrefix, StorageSQLite, catchpointDir, rnd)
+		require.NoError(t, err)
+		require.Equal(t, expectedSnapshot, snapshot)
+
+		for _, cpRound := range catchpointRounds(catchpointDir) {
+			require.True(t, cpRound <= rnd)
+		}
+
+		rolled, err := OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, true)
+		require.NoError(t, err)
+		defer rolled.Close()
+
+		require.Equal(t, rnd, rolled.Latest())
+		totals, err := rolled.Totals(rnd)
+		require.NoError(t, err)
+		require.Equal(t, states[rnd].totals, totals)
+		for addr, expectedData := range states[rnd].accounts {
+			data, err := rolled.Lookup(rnd, addr)
+			require.NoError(t, err)
+			require.Equal(t, expectedData, data)
+		}
+		require.NoError(t, rolled.VerifyTotals(rnd))
+
+		// The ledger carries on from the round it was rolled back to.
+		next := blk
+		next.BlockHeader.Round = rnd + 1
+		require.NoError(t, rolled.AddBlock(next, agreement.Certificate{}))
+	}
+
+	// Only the two most recent catchpoints are kept, so a rollback to
+	// the middle of them restores the older one.
+	checkRollback(targets[0], expected-interval)
+
+	// Rolling back before the oldest remaining catchpoint starts over
+	// from genesis.
+	checkRollback(targets[1], 0)
+
+	_, err = RollbackLedger(logging.Base(), dbPrefix, StorageSQLite, catchpointDir, expected)
+	require.Error(t, err)
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/crypto/merkletrie"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// The account state trie maps every address in accountbase to the hash of
+// its msgpack-encoded AccountData, exactly as stored in the account DB
+// (i.e., without pending rewards applied).  Its root is the
+// AccountStateRoot committed to by block headers.
+//
+// The ledger only keeps the trie while the protocol commits to it, or an
+// upgrade to a protocol that does is pending.
+
+// accountStateValue is the AccountData of an account in the state trie.
+type accountStateValue basics.AccountData
+
+// ToBeHashed implements the crypto.Hashable interface.
+func (v accountStateValue) ToBeHashed() (protocol.HashID, []byte) {
+	return protocol.AccountStateValue, protocol.Encode(basics.AccountData(v))
+}
+
+// AccountValueHash returns the value stored in the account state trie for
+// an account with the given data.
+func AccountValueHash(data basics.AccountData) crypto.Digest {
+	return crypto.HashObj(accountStateValue(data))
+}
+
+// wantStateTrie reports whether the ledger needs the account state trie as
+// of the round of hdr.
+func wantStateTrie(hdr bookkeeping.BlockHeader) bool {
+	if config.Consensus[hdr.CurrentProtocol].AccountStateRoot {
+		return true
+	}
+	return hdr.NextProtocol != "" && config.Consensus[hdr.NextProtocol].AccountStateRoot
+}
+
+// AccountStateKey returns the key under which addr is stored in the
+// account state trie.
+func AccountStateKey(addr basics.Address) crypto.Digest {
+	return crypto.Digest(addr)
+}
+
+func makeStateTrie(bals map[basics.Address]basics.AccountData) (trie merkletrie.Trie) {
+	for addr, data := range bals {
+		trie = trie.Put(AccountStateKey(addr), AccountValueHash(data))
+	}
+	return
+}
+
+func updateStateTrie(trie merkletrie.Trie, updates map[basics.Address]accountDelta) merkletrie.Trie {
+	for addr, data := range updates {
+		if data.new.IsZero() {
+			trie = trie.Delete(AccountStateKey(addr))
+		} else {
+			trie = trie.Put(AccountStateKey(addr), AccountValueHash(data.new))
+		}
+	}
+	return trie
+}
+
+// buildStateTrie builds the account state trie from a read transaction
+// on the account DB, and returns it along with the DB round that it is
+// for.  It does not use the in-memory state, so it may run without
+// trackerMu.
+func (au *accountUpdates) buildStateTrie() (trie merkletrie.Trie, rnd basics.Round, err error) {
+	err = au.store.readTx(func(tx accountsTx) error {
+		var err0 error
+		rnd, err0 = tx.accountsRound()
+		if err0 != nil {
+			return err0
+		}
+
+		trie = merkletrie.Trie{}
+		return tx.accountsIterate(func(addr basics.Address, data basics.AccountData) error {
+			trie = trie.Put(AccountStateKey(addr), AccountValueHash(data))
+			return nil
+		})
+	})
+	return
+}
+
+// installStateTrie makes trie, built from the account DB at round base,
+// the state trie for dbRound, and applies the deltas of every later round
+// to it.
+func (au *accountUpdates) installStateTrie(trie merkletrie.Trie, base basics.Round) error {
+	if base != au.dbRound {
+		return fmt.Errorf("state trie built at round %d, but account DB is at round %d", base, au.dbRound)
+	}
+
+	tries := make([]merkletrie.Trie, 1, len(au.deltas)+1)
+	tries[0] = trie
+	for _, deltas := range au.deltas {
+		tries = append(tries, updateStateTrie(tries[len(tries)-1], deltas))
+	}
+	au.stateTries = tries
+	au.stateTrieBase = au.dbRound
+	return nil
+}
+
+// stateTrieNewBlock is called by AddValidatedBlock, with trackerMu held.
+// Once an upgrade to a protocol that commits to the state trie is pending,
+// it builds the trie in the background, streaming the accounts without
+// trackerMu.  If the build fails, it is retried at the next block.
+func (l *Ledger) stateTrieNewBlock(hdr bookkeeping.BlockHeader) {
+	if l.accts.stateTries != nil || l.accts.stateTrieBuilding || !wantStateTrie(hdr) {
+		return
+	}
+
+	l.accts.stateTrieBuilding = true
+	l.stateTrieBuilds.Add(1)
+	go func() {
+		defer l.stateTrieBuilds.Done()
+		trie, base, err := l.accts.buildStateTrie()
+
+		l.trackerMu.Lock()
+		defer l.trackerMu.Unlock()
+		l.accts.stateTrieBuilding = false
+		if err == nil {
+			err = l.accts.installStateTrie(trie, base)
+		}
+		if err != nil {
+			l.log.Warnf("unable to build account state trie, retrying at the next block: %v", err)
+		}
+	}()
+}
+
+// stateTrie returns the account state trie at the end of round rnd.
+func (au *accountUpdates) stateTrie(rnd basics.Round) (trie merkletrie.Trie, err error) {
+	_, err = au.roundOffset(rnd)
+	if err != nil {
+		return
+	}
+	if au.stateTries == nil || rnd < au.stateTrieBase {
+		err = fmt.Errorf("no account state trie for round %d", rnd)
+		return
+	}
+	return au.stateTries[rnd-au.stateTrieBase], nil
+}
+
+// accountStateRoot returns the account state root after applying updates
+// to the state at the end of round rnd.
+func (au *accountUpdates) accountStateRoot(rnd basics.Round, updates map[basics.Address]accountDelta) (crypto.Digest, error) {
+	trie, err := au.stateTrie(rnd)
+	if err != nil {
+		return crypto.Digest{}, err
+	}
+
+	return updateStateTrie(trie, updates).Root(), nil
+}
+
+// accountProof returns the data of addr at the end of round rnd, without
+// pending rewards, along with a proof of it against the account state root
+// of that round.
+func (au *accountUpdates) accountProof(rnd basics.Round, addr basics.Address) (data basics.AccountData, proof merkletrie.Proof, root crypto.Digest, err error) {
+	trie, err := au.stateTrie(rnd)
+	if err != nil {
+		return
+	}
+
+	data, err = au.lookup(rnd, addr, false)
+	if err != nil {
+		return
+	}
+
+	proof, ok := trie.Prove(AccountStateKey(addr))
+	if !ok {
+		err = fmt.Errorf("account %v has no record in round %d", addr, rnd)
+		return
+	}
+	return data, proof, trie.Root(), nil
+}
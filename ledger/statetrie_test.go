@@ -0,0 +1,148 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+func TestAccountStateRoot(t *testing.T) {
+	genesisInitState, addrs, keys := genesis(10)
+	genesisInitState.Block.CurrentProtocol = protocol.ConsensusFuture
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	const inMem = true
+	const archival = true
+	l, err := OpenLedger(logging.Base(), dbName, inMem, genesisInitState, archival)
+	require.NoError(t, err)
+	defer l.Close()
+
+	require.Equal(t, makeStateTrie(genesisInitState.Accounts).Root(), l.accts.stateTries[0].Root())
+
+	blk := genesisInitState.Block
+	for i := 0; i < 5; i++ {
+		newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+		eval, err := l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+		require.NoError(t, err)
+
+		txn := transactions.Transaction{
+			Type: protocol.PaymentTx,
+			Header: transactions.Header{
+				Sender:      addrs[i],
+				Fee:         minFee,
+				FirstValid:  newBlock.Round(),
+				LastValid:   newBlock.Round(),
+				GenesisHash: genesisInitState.GenesisHash,
+			},
+			PaymentTxnFields: transactions.PaymentTxnFields{
+				Receiver: addrs[i+1],
+				Amount:   basics.MicroAlgos{Raw: 100},
+			},
+		}
+		require.NoError(t, eval.Transaction(txn.Sign(keys[i]), transactions.ApplyData{}))
+
+		vb, err := eval.GenerateBlock()
+		require.NoError(t, err)
+		blk = vb.Block()
+		require.NotEqual(t, crypto.Digest{}, blk.AccountStateRoot)
+		require.NotEqual(t, l.accts.stateTries[len(l.accts.stateTries)-1].Root(), blk.AccountStateRoot)
+
+		// A block with the wrong root does not validate.
+		bad := blk
+		bad.AccountStateRoot[0] ^= 1
+		_, err = l.Validate(context.Background(), bad, nil, backlogPool)
+		require.Error(t, err)
+
+		_, err = l.Validate(context.Background(), blk, nil, backlogPool)
+		require.NoError(t, err)
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{}))
+
+		for _, addr := range []basics.Address{addrs[i], addrs[i+1], testPoolAddr} {
+			data, proof, root, err := l.AccountProof(blk.Round(), addr)
+			require.NoError(t, err)
+			require.Equal(t, blk.AccountStateRoot, root)
+			require.True(t, proof.Verify(blk.AccountStateRoot, AccountStateKey(addr), AccountValueHash(data)))
+
+			expected, err := l.LookupWithoutRewards(blk.Round(), addr)
+			require.NoError(t, err)
+			require.Equal(t, expected, data)
+		}
+	}
+
+	// Accounts that do not exist have no proof.
+	_, _, _, err = l.AccountProof(blk.Round(), basics.Address{1, 2, 3})
+	require.Error(t, err)
+}
+
+func TestStateTrieOnlyWhenEnabled(t *testing.T) {
+	genesisInitState, addrs, _ := genesis(10)
+	require.False(t, wantStateTrie(genesisInitState.Block.BlockHeader))
+
+	dbName := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	l, err := OpenLedger(logging.Base(), dbName, true, genesisInitState, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	require.Nil(t, l.accts.stateTries)
+	_, _, _, err = l.AccountProof(0, addrs[0])
+	require.Error(t, err)
+
+	// The trie is built in the background once an upgrade to a protocol
+	// that commits to it is pending, and catches up with the blocks added
+	// in the meantime.
+	blk := genesisInitState.Block
+	blk.BlockHeader.NextProtocol = protocol.ConsensusFuture
+	for i := 0; i < 3; i++ {
+		blk.BlockHeader.Round++
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{}))
+	}
+	l.stateTrieBuilds.Wait()
+	require.NotNil(t, l.accts.stateTries)
+	for rnd := basics.Round(1); rnd <= blk.Round(); rnd++ {
+		_, _, root, err := l.AccountProof(rnd, addrs[0])
+		require.NoError(t, err)
+		require.Equal(t, makeStateTrie(genesisInitState.Accounts).Root(), root)
+	}
+
+	// A trie built from an account DB that has since moved on is not
+	// installed.
+	trie, base, err := l.accts.buildStateTrie()
+	require.NoError(t, err)
+	require.Error(t, l.accts.installStateTrie(trie, base+1))
+}
+
+func TestAccountValueHashDomain(t *testing.T) {
+	var data basics.AccountData
+	data.MicroAlgos.Raw = 1
+	require.NotEqual(t, crypto.Hash(protocol.Encode(data)), AccountValueHash(data))
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/util/db"
+)
+
+// StorageEngine selects how the ledger stores blocks and account state.
+type StorageEngine string
+
+const (
+	// StorageSQLite keeps the ledger in SQLite databases.
+	StorageSQLite StorageEngine = "sqlite"
+
+	// StorageKV keeps the ledger in embedded key-value stores
+	// (see util/kvstore), which avoid SQLite's WAL checkpoints.
+	StorageKV StorageEngine = "kv"
+)
+
+// ParseStorageEngine returns the storage engine named by s, as found in
+// config.Local.LedgerStorageEngine.  The empty string means SQLite.
+func ParseStorageEngine(s string) (StorageEngine, error) {
+	switch StorageEngine(s) {
+	case "", StorageSQLite:
+		return StorageSQLite, nil
+	case StorageKV:
+		return StorageKV, nil
+	default:
+		return "", fmt.Errorf("unknown ledger storage engine %#v", s)
+	}
+}
+
+// blockStore holds the ledger's blocks, along with their certificates and
+// evaluation aux data.
+type blockStore interface {
+	// readTx runs fn against a consistent view of the store.
+	readTx(fn func(tx blockTx) error) error
+
+	// writeTx runs fn in a transaction, which is committed if fn
+	// returns nil.  fn may be retried.
+	writeTx(fn func(tx blockTx) error) error
+
+	close()
+}
+
+// blockTx is a transaction on a blockStore.
+type blockTx interface {
+	blockInit(initBlocks []bookkeeping.Block) error
+	blockResetDB() error
+	blockGet(rnd basics.Round) (bookkeeping.Block, error)
+	blockGetHdr(rnd basics.Round) (bookkeeping.BlockHeader, error)
+	blockGetEncodedCert(rnd basics.Round) (blk []byte, cert []byte, err error)
+	blockGetCert(rnd basics.Round) (bookkeeping.Block, agreement.Certificate, error)
+	blockGetAux(rnd basics.Round) (bookkeeping.Block, evalAux, error)
+	blockPut(blk bookkeeping.Block, cert agreement.Certificate, aux evalAux) error
+	blockNext() (basics.Round, error)
+	blockLatest() (basics.Round, error)
+	blockEarliest() (basics.Round, error)
+	blockForgetBefore(rnd basics.Round) error
+	blockForgetAfter(rnd basics.Round) error
+}
+
+// accountStore holds the account state as of the accountUpdates tracker's
+// dbRound.
+type accountStore interface {
+	// readTx runs fn against a consistent view of the store.
+	readTx(fn func(tx accountsTx) error) error
+
+	// writeTx runs fn in a transaction, which is committed if fn
+	// returns nil.  fn may be retried.
+	writeTx(fn func(tx accountsTx) error) error
+
+	// queries returns lookups that can run concurrently with
+	// transactions, without the overhead of starting one.
+	queries() (accountsQueries, error)
+
+	close()
+}
+
+// accountsTx is a transaction on an accountStore.
+type accountsTx interface {
+	accountsInit(initAccounts map[basics.Address]basics.AccountData, proto config.ConsensusParams) error
+	accountsInitHistory() (basics.Round, error)
+	accountsHistoryBase() (rnd basics.Round, ok bool, err error)
+	accountsDropHistory() error
+	accountsReset() error
+	accountsRestore(rnd basics.Round, accounts []catchpointAccount, assets []catchpointAsset, totals AccountTotals) error
+	accountsRound() (basics.Round, error)
+	accountsAll() (map[basics.Address]basics.AccountData, error)
+	accountsCount() (uint64, error)
+	accountsIterate(fn func(addr basics.Address, data basics.AccountData) error) error
+	assetCreatorsAll() (map[basics.AssetIndex]basics.Address, error)
+	accountsTotals() (AccountTotals, error)
+	accountsNewRound(rnd basics.Round, updates map[basics.Address]accountDelta, rewardsLevel uint64, proto config.ConsensusParams, history bool) error
+}
+
+// accountsQueries looks up individual accounts and assets.
+type accountsQueries interface {
+	lookup(addr basics.Address) (basics.AccountData, error)
+	lookupHistory(addr basics.Address, rnd basics.Round) (basics.AccountData, error)
+	lookupAssetCreator(assetIdx basics.AssetIndex) (basics.Address, error)
+	listAssets(maxAssetIdx basics.AssetIndex, maxResults uint64) ([]basics.AssetLocator, error)
+	listAssetHolders(assetIdx basics.AssetIndex, after basics.Address, maxResults uint64) ([]basics.AssetHolder, error)
+}
+
+// openLedgerStores opens the tracker and block stores at dbPathPrefix.
+func openLedgerStores(dbPathPrefix string, dbMem bool, engine StorageEngine) (trackerStore accountStore, blocks blockStore, err error) {
+	switch engine {
+	case StorageSQLite:
+		trackerDBs, blockDBs, err := openLedgerDB(dbPathPrefix, dbMem)
+		if err != nil {
+			trackerDBs.close()
+			blockDBs.close()
+			return nil, nil, err
+		}
+		return sqlAccountStore{trackerDBs}, sqlBlockStore{blockDBs}, nil
+
+	case StorageKV:
+		return openKVStores(dbPathPrefix, dbMem)
+
+	default:
+		return nil, nil, fmt.Errorf("unknown ledger storage engine %#v", engine)
+	}
+}
+
+// openLedgerStoresReadOnly opens the existing tracker and block stores at
+// dbPathPrefix, without modifying them.  Writes to the stores fail.
+func openLedgerStoresReadOnly(dbPathPrefix string, engine StorageEngine) (trackerStore accountStore, blocks blockStore, err error) {
+	switch engine {
+	case StorageSQLite:
+		trackerDBFilename, blockDBFilename, err := ledgerDBFilenames(dbPathPrefix, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, fn := range []string{trackerDBFilename, blockDBFilename} {
+			_, err = os.Stat(fn)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		trackerDB, err := db.MakeAccessor(trackerDBFilename, true, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		blockDB, err := db.MakeAccessor(blockDBFilename, true, false)
+		if err != nil {
+			trackerDB.Close()
+			return nil, nil, err
+		}
+
+		return sqlAccountStore{dbPair{rdb: trackerDB, wdb: trackerDB}}, sqlBlockStore{dbPair{rdb: blockDB, wdb: blockDB}}, nil
+
+	case StorageKV:
+		return openKVStoresReadOnly(dbPathPrefix)
+
+	default:
+		return nil, nil, fmt.Errorf("unknown ledger storage engine %#v", engine)
+	}
+}
@@ -0,0 +1,816 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/kvstore"
+)
+
+// The key-value stores mirror the SQLite schemas in blockdb.go and
+// accountdb.go.  Rounds and asset indexes in keys are big-endian, so
+// that keys sort in numeric order.
+var (
+	kvBlockPrefix = []byte("blk/")
+	kvHdrPrefix   = []byte("hdr/")
+	kvCertPrefix  = []byte("crt/")
+	kvAuxPrefix   = []byte("aux/")
+
+	kvAcctRoundKey     = []byte("rnd/acctbase")
+	kvAcctHistRoundKey = []byte("rnd/acctbasehist")
+	kvHoldingsRoundKey = []byte("rnd/assetholdings")
+	kvTotalsKey        = []byte("totals")
+	kvAccountPrefix    = []byte("acct/")
+	kvAssetPrefix      = []byte("asset/")
+	kvHoldingPrefix    = []byte("hold/")
+	kvHistoryPrefix    = []byte("hist/")
+)
+
+func kvKey(prefix []byte, parts ...[]byte) []byte {
+	key := append([]byte{}, prefix...)
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+	return key
+}
+
+func kvUint64(x uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], x)
+	return buf[:]
+}
+
+func kvRoundKey(prefix []byte, rnd basics.Round) []byte {
+	return kvKey(prefix, kvUint64(uint64(rnd)))
+}
+
+// kvDeletePrefix deletes every key starting with prefix.
+func kvDeletePrefix(tx *kvstore.Tx, prefix []byte) error {
+	var keys [][]byte
+	err := tx.Scan(prefix, kvstore.PrefixEnd(prefix), false, func(key []byte, value []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = tx.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func kvGetRound(tx *kvstore.Tx, key []byte) (rnd basics.Round, ok bool, err error) {
+	buf, ok, err := tx.Get(key)
+	if err != nil || !ok {
+		return
+	}
+	if len(buf) != 8 {
+		err = fmt.Errorf("bad round for %s: %v", key, buf)
+		return
+	}
+	return basics.Round(binary.BigEndian.Uint64(buf)), true, nil
+}
+
+func openKVStores(dbPathPrefix string, dbMem bool) (accountStore, blockStore, error) {
+	trackerDB, err := kvstore.Open(dbPathPrefix+".tracker.kv", dbMem)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockDB, err := kvstore.Open(dbPathPrefix+".block.kv", dbMem)
+	if err != nil {
+		trackerDB.Close()
+		return nil, nil, err
+	}
+
+	return kvAccountStore{trackerDB}, kvBlockStore{blockDB}, nil
+}
+
+func openKVStoresReadOnly(dbPathPrefix string) (accountStore, blockStore, error) {
+	trackerDB, err := kvstore.OpenReadOnly(dbPathPrefix + ".tracker.kv")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockDB, err := kvstore.OpenReadOnly(dbPathPrefix + ".block.kv")
+	if err != nil {
+		trackerDB.Close()
+		return nil, nil, err
+	}
+
+	return kvAccountStore{trackerDB}, kvBlockStore{blockDB}, nil
+}
+
+// kvBlockStore is a blockStore backed by a kvstore.DB.
+type kvBlockStore struct {
+	db *kvstore.DB
+}
+
+func (s kvBlockStore) readTx(fn func(tx blockTx) error) error {
+	return s.db.View(func(tx *kvstore.Tx) error {
+		return fn(kvBlockTx{tx})
+	})
+}
+
+func (s kvBlockStore) writeTx(fn func(tx blockTx) error) error {
+	return s.db.Update(func(tx *kvstore.Tx) error {
+		return fn(kvBlockTx{tx})
+	})
+}
+
+func (s kvBlockStore) close() {
+	s.db.Close()
+}
+
+type kvBlockTx struct {
+	tx *kvstore.Tx
+}
+
+func (t kvBlockTx) blockInit(initBlocks []bookkeeping.Block) error {
+	next, err := t.blockNext()
+	if err != nil {
+		return err
+	}
+
+	if next == 0 {
+		for _, blk := range initBlocks {
+			err = t.blockPut(blk, agreement.Certificate{}, evalAux{})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t kvBlockTx) blockResetDB() error {
+	for _, prefix := range [][]byte{kvBlockPrefix, kvHdrPrefix, kvCertPrefix, kvAuxPrefix} {
+		err := kvDeletePrefix(t.tx, prefix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t kvBlockTx) get(prefix []byte, rnd basics.Round, obj interface{}) error {
+	buf, ok, err := t.tx.Get(kvRoundKey(prefix, rnd))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoEntry{Round: rnd}
+	}
+	return protocol.Decode(buf, obj)
+}
+
+func (t kvBlockTx) blockGet(rnd basics.Round) (blk bookkeeping.Block, err error) {
+	err = t.get(kvBlockPrefix, rnd, &blk)
+	return
+}
+
+func (t kvBlockTx) blockGetHdr(rnd basics.Round) (hdr bookkeeping.BlockHeader, err error) {
+	err = t.get(kvHdrPrefix, rnd, &hdr)
+	return
+}
+
+func (t kvBlockTx) blockGetEncodedCert(rnd basics.Round) (blk []byte, cert []byte, err error) {
+	blk, ok, err := t.tx.Get(kvRoundKey(kvBlockPrefix, rnd))
+	if err == nil && !ok {
+		err = ErrNoEntry{Round: rnd}
+	}
+	if err != nil {
+		return
+	}
+
+	cert, _, err = t.tx.Get(kvRoundKey(kvCertPrefix, rnd))
+	return
+}
+
+func (t kvBlockTx) blockGetCert(rnd basics.Round) (blk bookkeeping.Block, cert agreement.Certificate, err error) {
+	err = t.get(kvBlockPrefix, rnd, &blk)
+	if err != nil {
+		return
+	}
+
+	err = t.get(kvCertPrefix, rnd, &cert)
+	return
+}
+
+func (t kvBlockTx) blockGetAux(rnd basics.Round) (blk bookkeeping.Block, aux evalAux, err error) {
+	err = t.get(kvBlockPrefix, rnd, &blk)
+	if err != nil {
+		return
+	}
+
+	err = t.get(kvAuxPrefix, rnd, &aux)
+	return
+}
+
+func (t kvBlockTx) blockPut(blk bookkeeping.Block, cert agreement.Certificate, aux evalAux) error {
+	next, err := t.blockNext()
+	if err != nil {
+		return err
+	}
+
+	if blk.Round() != next {
+		return fmt.Errorf("inserting block %d but expected %d", blk.Round(), next)
+	}
+
+	puts := []struct {
+		prefix []byte
+		obj    interface{}
+	}{
+		{kvHdrPrefix, blk.BlockHeader},
+		{kvBlockPrefix, blk},
+		{kvCertPrefix, cert},
+		{kvAuxPrefix, aux},
+	}
+	for _, put := range puts {
+		err = t.tx.Put(kvRoundKey(put.prefix, blk.Round()), protocol.Encode(put.obj))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockEdge returns the first or last round in the store.
+func (t kvBlockTx) blockEdge(last bool) (rnd basics.Round, ok bool, err error) {
+	err = t.tx.Scan(kvBlockPrefix, kvstore.PrefixEnd(kvBlockPrefix), last, func(key []byte, value []byte) bool {
+		rnd = basics.Round(binary.BigEndian.Uint64(key[len(kvBlockPrefix):]))
+		ok = true
+		return false
+	})
+	return
+}
+
+func (t kvBlockTx) blockNext() (basics.Round, error) {
+	latest, ok, err := t.blockEdge(true)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return latest + 1, nil
+}
+
+func (t kvBlockTx) blockLatest() (basics.Round, error) {
+	latest, ok, err := t.blockEdge(true)
+	if err == nil && !ok {
+		err = fmt.Errorf("no blocks present")
+	}
+	return latest, err
+}
+
+func (t kvBlockTx) blockEarliest() (basics.Round, error) {
+	earliest, ok, err := t.blockEdge(false)
+	if err == nil && !ok {
+		err = fmt.Errorf("no blocks present")
+	}
+	return earliest, err
+}
+
+func (t kvBlockTx) blockForgetBefore(rnd basics.Round) error {
+	next, err := t.blockNext()
+	if err != nil {
+		return err
+	}
+
+	if rnd >= next {
+		return fmt.Errorf("forgetting too much: rnd %d >= next %d", rnd, next)
+	}
+
+	return t.blockDeleteRange(kvRoundKey(kvBlockPrefix, 0), kvRoundKey(kvBlockPrefix, rnd))
+}
+
+func (t kvBlockTx) blockForgetAfter(rnd basics.Round) error {
+	earliest, err := t.blockEarliest()
+	if err != nil {
+		return err
+	}
+
+	if rnd < earliest {
+		return fmt.Errorf("forgetting too much: rnd %d < earliest %d", rnd, earliest)
+	}
+
+	return t.blockDeleteRange(kvRoundKey(kvBlockPrefix, rnd+1), kvstore.PrefixEnd(kvBlockPrefix))
+}
+
+// blockDeleteRange deletes the blocks whose keys are in [start, end),
+// along with their headers, certificates and aux data.
+func (t kvBlockTx) blockDeleteRange(start []byte, end []byte) error {
+	var rounds []basics.Round
+	err := t.tx.Scan(start, end, false, func(key []byte, value []byte) bool {
+		rounds = append(rounds, basics.Round(binary.BigEndian.Uint64(key[len(kvBlockPrefix):])))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rounds {
+		for _, prefix := range [][]byte{kvBlockPrefix, kvHdrPrefix, kvCertPrefix, kvAuxPrefix} {
+			err = t.tx.Delete(kvRoundKey(prefix, r))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// kvAccountStore is an accountStore backed by a kvstore.DB.
+type kvAccountStore struct {
+	db *kvstore.DB
+}
+
+func (s kvAccountStore) readTx(fn func(tx accountsTx) error) error {
+	return s.db.View(func(tx *kvstore.Tx) error {
+		return fn(kvAccountsTx{tx})
+	})
+}
+
+func (s kvAccountStore) writeTx(fn func(tx accountsTx) error) error {
+	return s.db.Update(func(tx *kvstore.Tx) error {
+		return fn(kvAccountsTx{tx})
+	})
+}
+
+func (s kvAccountStore) queries() (accountsQueries, error) {
+	return kvAccountsQueries{s.db}, nil
+}
+
+func (s kvAccountStore) close() {
+	s.db.Close()
+}
+
+type kvAccountsTx struct {
+	tx *kvstore.Tx
+}
+
+func (t kvAccountsTx) putAccount(addr basics.Address, data basics.AccountData) error {
+	if data.IsZero() {
+		// prune empty accounts
+		return t.tx.Delete(kvKey(kvAccountPrefix, addr[:]))
+	}
+	return t.tx.Put(kvKey(kvAccountPrefix, addr[:]), protocol.Encode(data))
+}
+
+func (t kvAccountsTx) putHoldings(addr basics.Address, holdings map[basics.AssetIndex]modifiedHolding) error {
+	for aidx, delta := range holdings {
+		key := kvKey(kvHoldingPrefix, kvUint64(uint64(aidx)), addr[:])
+		var err error
+		if delta.deleted {
+			err = t.tx.Delete(key)
+		} else {
+			err = t.tx.Put(key, protocol.Encode(delta.holding))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t kvAccountsTx) accountsInit(initAccounts map[basics.Address]basics.AccountData, proto config.ConsensusParams) error {
+	_, ok, err := kvGetRound(t.tx, kvAcctRoundKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		// Already initialized.
+		return t.accountsInitAssetHoldings()
+	}
+
+	err = t.tx.Put(kvAcctRoundKey, kvUint64(0))
+	if err != nil {
+		return err
+	}
+
+	var ot basics.OverflowTracker
+	var totals AccountTotals
+	for addr, data := range initAccounts {
+		err = t.tx.Put(kvKey(kvAccountPrefix, addr[:]), protocol.Encode(data))
+		if err != nil {
+			return err
+		}
+
+		totals.addAccount(proto, data, &ot)
+	}
+
+	if ot.Overflowed {
+		return fmt.Errorf("overflow computing totals")
+	}
+
+	err = t.tx.Put(kvTotalsKey, protocol.Encode(totals))
+	if err != nil {
+		return err
+	}
+
+	return t.accountsInitAssetHoldings()
+}
+
+// accountsInitAssetHoldings fills the holdings from the accounts, unless
+// they have been filled already, like the SQLite version.
+func (t kvAccountsTx) accountsInitAssetHoldings() error {
+	_, ok, err := kvGetRound(t.tx, kvHoldingsRoundKey)
+	if err != nil || ok {
+		return err
+	}
+
+	err = t.accountsFillAssetHoldings()
+	if err != nil {
+		return err
+	}
+
+	rnd, err := t.accountsRound()
+	if err != nil {
+		return err
+	}
+	return t.tx.Put(kvHoldingsRoundKey, kvUint64(uint64(rnd)))
+}
+
+func (t kvAccountsTx) accountsFillAssetHoldings() error {
+	bals, err := t.accountsAll()
+	if err != nil {
+		return err
+	}
+
+	for addr, data := range bals {
+		err = t.putHoldings(addr, getChangedAssetHoldings(accountDelta{new: data}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t kvAccountsTx) accountsInitHistory() (basics.Round, error) {
+	histBase, ok, err := kvGetRound(t.tx, kvAcctHistRoundKey)
+	if err != nil || ok {
+		return histBase, err
+	}
+
+	histBase, err = t.accountsRound()
+	if err != nil {
+		return 0, err
+	}
+
+	var accts [][2][]byte
+	err = t.tx.Scan(kvAccountPrefix, kvstore.PrefixEnd(kvAccountPrefix), false, func(key []byte, value []byte) bool {
+		accts = append(accts, [2][]byte{key[len(kvAccountPrefix):], value})
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, acct := range accts {
+		err = t.tx.Put(kvKey(kvHistoryPrefix, acct[0], kvUint64(uint64(histBase))), acct[1])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return histBase, t.tx.Put(kvAcctHistRoundKey, kvUint64(uint64(histBase)))
+}
+
+func (t kvAccountsTx) accountsHistoryBase() (basics.Round, bool, error) {
+	return kvGetRound(t.tx, kvAcctHistRoundKey)
+}
+
+func (t kvAccountsTx) accountsDropHistory() error {
+	err := kvDeletePrefix(t.tx, kvHistoryPrefix)
+	if err != nil {
+		return err
+	}
+	return t.tx.Delete(kvAcctHistRoundKey)
+}
+
+func (t kvAccountsTx) accountsReset() error {
+	for _, prefix := range [][]byte{kvAccountPrefix, kvAssetPrefix, kvHoldingPrefix, kvHistoryPrefix} {
+		err := kvDeletePrefix(t.tx, prefix)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range [][]byte{kvAcctRoundKey, kvAcctHistRoundKey, kvHoldingsRoundKey, kvTotalsKey} {
+		err := t.tx.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t kvAccountsTx) accountsRestore(rnd basics.Round, accounts []catchpointAccount, assets []catchpointAsset, totals AccountTotals) error {
+	err := t.accountsReset()
+	if err != nil {
+		return err
+	}
+
+	err = t.tx.Put(kvAcctRoundKey, kvUint64(uint64(rnd)))
+	if err != nil {
+		return err
+	}
+
+	for _, acct := range accounts {
+		err = t.putAccount(acct.Address, acct.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, asset := range assets {
+		err = t.tx.Put(kvKey(kvAssetPrefix, kvUint64(uint64(asset.Asset))), asset.Creator[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = t.accountsFillAssetHoldings()
+	if err != nil {
+		return err
+	}
+
+	err = t.tx.Put(kvHoldingsRoundKey, kvUint64(uint64(rnd)))
+	if err != nil {
+		return err
+	}
+
+	return t.tx.Put(kvTotalsKey, protocol.Encode(totals))
+}
+
+func (t kvAccountsTx) accountsRound() (basics.Round, error) {
+	rnd, ok, err := kvGetRound(t.tx, kvAcctRoundKey)
+	if err == nil && !ok {
+		err = fmt.Errorf("account DB not initialized")
+	}
+	return rnd, err
+}
+
+func (t kvAccountsTx) accountsAll() (bals map[basics.Address]basics.AccountData, err error) {
+	bals = make(map[basics.Address]basics.AccountData)
+	err = t.accountsIterate(func(addr basics.Address, data basics.AccountData) error {
+		bals[addr] = data
+		return nil
+	})
+	return
+}
+
+func (t kvAccountsTx) accountsCount() (count uint64, err error) {
+	err = t.tx.Scan(kvAccountPrefix, kvstore.PrefixEnd(kvAccountPrefix), false, func(key []byte, value []byte) bool {
+		count++
+		return true
+	})
+	return
+}
+
+func (t kvAccountsTx) accountsIterate(fn func(addr basics.Address, data basics.AccountData) error) error {
+	var iterErr error
+	err := t.tx.Scan(kvAccountPrefix, kvstore.PrefixEnd(kvAccountPrefix), false, func(key []byte, value []byte) bool {
+		var addr basics.Address
+		if len(key) != len(kvAccountPrefix)+len(addr) {
+			iterErr = fmt.Errorf("Account DB address length mismatch: %d != %d", len(key)-len(kvAccountPrefix), len(addr))
+			return false
+		}
+		copy(addr[:], key[len(kvAccountPrefix):])
+
+		var data basics.AccountData
+		iterErr = protocol.Decode(value, &data)
+		if iterErr == nil {
+			iterErr = fn(addr, data)
+		}
+		return iterErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return iterErr
+}
+
+func (t kvAccountsTx) assetCreatorsAll() (creators map[basics.AssetIndex]basics.Address, err error) {
+	creators = make(map[basics.AssetIndex]basics.Address)
+	err = t.tx.Scan(kvAssetPrefix, kvstore.PrefixEnd(kvAssetPrefix), false, func(key []byte, value []byte) bool {
+		var creator basics.Address
+		copy(creator[:], value)
+		creators[basics.AssetIndex(binary.BigEndian.Uint64(key[len(kvAssetPrefix):]))] = creator
+		return true
+	})
+	return
+}
+
+func (t kvAccountsTx) accountsTotals() (totals AccountTotals, err error) {
+	buf, ok, err := t.tx.Get(kvTotalsKey)
+	if err == nil && !ok {
+		err = fmt.Errorf("account DB not initialized")
+	}
+	if err != nil {
+		return
+	}
+
+	err = protocol.Decode(buf, &totals)
+	return
+}
+
+func (t kvAccountsTx) accountsNewRound(rnd basics.Round, updates map[basics.Address]accountDelta, rewardsLevel uint64, proto config.ConsensusParams, history bool) error {
+	base, err := t.accountsRound()
+	if err != nil {
+		return err
+	}
+
+	if rnd != base+1 {
+		return fmt.Errorf("newRound %d is not immediately after base %d", rnd, base)
+	}
+
+	var ot basics.OverflowTracker
+	totals, err := t.accountsTotals()
+	if err != nil {
+		return err
+	}
+
+	totals.applyRewards(rewardsLevel, &ot)
+
+	for addr, data := range updates {
+		err = t.putAccount(addr, data.new)
+		if err != nil {
+			return err
+		}
+
+		if history {
+			var encoded []byte
+			if !data.new.IsZero() {
+				encoded = protocol.Encode(data.new)
+			}
+			err = t.tx.Put(kvKey(kvHistoryPrefix, addr[:], kvUint64(uint64(rnd))), encoded)
+			if err != nil {
+				return err
+			}
+		}
+
+		totals.delAccount(proto, data.old, &ot)
+		totals.addAccount(proto, data.new, &ot)
+
+		adeltas := getChangedAssetIndices(addr, data)
+		for aidx, delta := range adeltas {
+			key := kvKey(kvAssetPrefix, kvUint64(uint64(aidx)))
+			if delta.created {
+				err = t.tx.Put(key, addr[:])
+			} else {
+				err = t.tx.Delete(key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		err = t.putHoldings(addr, getChangedAssetHoldings(data))
+		if err != nil {
+			return err
+		}
+	}
+
+	if ot.Overflowed {
+		return fmt.Errorf("overflow computing totals")
+	}
+
+	err = t.tx.Put(kvAcctRoundKey, kvUint64(uint64(rnd)))
+	if err != nil {
+		return err
+	}
+
+	return t.tx.Put(kvTotalsKey, protocol.Encode(totals))
+}
+
+// kvAccountsQueries implements accountsQueries with a read-only
+// transaction per lookup, which is cheap for a kvstore.DB.
+type kvAccountsQueries struct {
+	db *kvstore.DB
+}
+
+func (qs kvAccountsQueries) lookup(addr basics.Address) (data basics.AccountData, err error) {
+	err = qs.db.View(func(tx *kvstore.Tx) error {
+		buf, ok, err := tx.Get(kvKey(kvAccountPrefix, addr[:]))
+		if err != nil || !ok {
+			// Return the zero value of data
+			return err
+		}
+		return protocol.Decode(buf, &data)
+	})
+	return
+}
+
+func (qs kvAccountsQueries) lookupHistory(addr basics.Address, rnd basics.Round) (data basics.AccountData, err error) {
+	err = qs.db.View(func(tx *kvstore.Tx) error {
+		_, ok, err := kvGetRound(tx, kvAcctHistRoundKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("account history is not available")
+		}
+
+		prefix := kvKey(kvHistoryPrefix, addr[:])
+		var buf []byte
+		err = tx.Scan(prefix, kvKey(prefix, kvUint64(uint64(rnd)+1)), true, func(key []byte, value []byte) bool {
+			buf = value
+			return false
+		})
+		if err != nil || len(buf) == 0 {
+			// Missing or deleted account
+			return err
+		}
+		return protocol.Decode(buf, &data)
+	})
+	return
+}
+
+func (qs kvAccountsQueries) lookupAssetCreator(assetIdx basics.AssetIndex) (addr basics.Address, err error) {
+	err = qs.db.View(func(tx *kvstore.Tx) error {
+		buf, ok, err := tx.Get(kvKey(kvAssetPrefix, kvUint64(uint64(assetIdx))))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("asset %d does not exist or has been deleted", assetIdx)
+		}
+		copy(addr[:], buf)
+		return nil
+	})
+	return
+}
+
+func (qs kvAccountsQueries) listAssets(maxAssetIdx basics.AssetIndex, maxResults uint64) (results []basics.AssetLocator, err error) {
+	err = qs.db.View(func(tx *kvstore.Tx) error {
+		to := kvstore.PrefixEnd(kvAssetPrefix)
+		if uint64(maxAssetIdx) < ^uint64(0) {
+			to = kvKey(kvAssetPrefix, kvUint64(uint64(maxAssetIdx)+1))
+		}
+		return tx.Scan(kvAssetPrefix, to, true, func(key []byte, value []byte) bool {
+			if uint64(len(results)) >= maxResults {
+				return false
+			}
+			var al basics.AssetLocator
+			al.Index = basics.AssetIndex(binary.BigEndian.Uint64(key[len(kvAssetPrefix):]))
+			copy(al.Creator[:], value)
+			results = append(results, al)
+			return true
+		})
+	})
+	return
+}
+
+func (qs kvAccountsQueries) listAssetHolders(assetIdx basics.AssetIndex, after basics.Address, maxResults uint64) (results []basics.AssetHolder, err error) {
+	err = qs.db.View(func(tx *kvstore.Tx) error {
+		results = nil
+		prefix := kvKey(kvHoldingPrefix, kvUint64(uint64(assetIdx)))
+		var decodeErr error
+		err := tx.Scan(kvKey(prefix, after[:]), kvstore.PrefixEnd(prefix), false, func(key []byte, value []byte) bool {
+			if uint64(len(results)) >= maxResults {
+				return false
+			}
+			var holder basics.AssetHolder
+			copy(holder.Address[:], key[len(prefix):])
+			if holder.Address == after {
+				return true
+			}
+			decodeErr = protocol.Decode(value, &holder.Holding)
+			if decodeErr != nil {
+				return false
+			}
+			results = append(results, holder)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return decodeErr
+	})
+	return
+}
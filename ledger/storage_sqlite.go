@@ -0,0 +1,188 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"database/sql"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+)
+
+// sqlBlockStore is a blockStore backed by the SQLite code in blockdb.go.
+type sqlBlockStore struct {
+	dbs dbPair
+}
+
+func (s sqlBlockStore) readTx(fn func(tx blockTx) error) error {
+	return s.dbs.rdb.Atomic(func(tx *sql.Tx) error {
+		return fn(sqlBlockTx{tx})
+	})
+}
+
+func (s sqlBlockStore) writeTx(fn func(tx blockTx) error) error {
+	return s.dbs.wdb.Atomic(func(tx *sql.Tx) error {
+		return fn(sqlBlockTx{tx})
+	})
+}
+
+func (s sqlBlockStore) close() {
+	s.dbs.close()
+}
+
+type sqlBlockTx struct {
+	tx *sql.Tx
+}
+
+func (t sqlBlockTx) blockInit(initBlocks []bookkeeping.Block) error {
+	return blockInit(t.tx, initBlocks)
+}
+
+func (t sqlBlockTx) blockResetDB() error {
+	return blockResetDB(t.tx)
+}
+
+func (t sqlBlockTx) blockGet(rnd basics.Round) (bookkeeping.Block, error) {
+	return blockGet(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockGetHdr(rnd basics.Round) (bookkeeping.BlockHeader, error) {
+	return blockGetHdr(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockGetEncodedCert(rnd basics.Round) ([]byte, []byte, error) {
+	return blockGetEncodedCert(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockGetCert(rnd basics.Round) (bookkeeping.Block, agreement.Certificate, error) {
+	return blockGetCert(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockGetAux(rnd basics.Round) (bookkeeping.Block, evalAux, error) {
+	return blockGetAux(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockPut(blk bookkeeping.Block, cert agreement.Certificate, aux evalAux) error {
+	return blockPut(t.tx, blk, cert, aux)
+}
+
+func (t sqlBlockTx) blockNext() (basics.Round, error) {
+	return blockNext(t.tx)
+}
+
+func (t sqlBlockTx) blockLatest() (basics.Round, error) {
+	return blockLatest(t.tx)
+}
+
+func (t sqlBlockTx) blockEarliest() (basics.Round, error) {
+	return blockEarliest(t.tx)
+}
+
+func (t sqlBlockTx) blockForgetBefore(rnd basics.Round) error {
+	return blockForgetBefore(t.tx, rnd)
+}
+
+func (t sqlBlockTx) blockForgetAfter(rnd basics.Round) error {
+	return blockForgetAfter(t.tx, rnd)
+}
+
+// sqlAccountStore is an accountStore backed by the SQLite code in accountdb.go.
+type sqlAccountStore struct {
+	dbs dbPair
+}
+
+func (s sqlAccountStore) readTx(fn func(tx accountsTx) error) error {
+	return s.dbs.rdb.Atomic(func(tx *sql.Tx) error {
+		return fn(sqlAccountsTx{tx})
+	})
+}
+
+func (s sqlAccountStore) writeTx(fn func(tx accountsTx) error) error {
+	return s.dbs.wdb.Atomic(func(tx *sql.Tx) error {
+		return fn(sqlAccountsTx{tx})
+	})
+}
+
+func (s sqlAccountStore) queries() (accountsQueries, error) {
+	qs, err := accountsDbInit(s.dbs.rdb.Handle)
+	if err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+func (s sqlAccountStore) close() {
+	s.dbs.close()
+}
+
+type sqlAccountsTx struct {
+	tx *sql.Tx
+}
+
+func (t sqlAccountsTx) accountsInit(initAccounts map[basics.Address]basics.AccountData, proto config.ConsensusParams) error {
+	return accountsInit(t.tx, initAccounts, proto)
+}
+
+func (t sqlAccountsTx) accountsInitHistory() (basics.Round, error) {
+	return accountsInitHistory(t.tx)
+}
+
+func (t sqlAccountsTx) accountsHistoryBase() (basics.Round, bool, error) {
+	return accountsHistoryBase(t.tx)
+}
+
+func (t sqlAccountsTx) accountsDropHistory() error {
+	return accountsDropHistory(t.tx)
+}
+
+func (t sqlAccountsTx) accountsReset() error {
+	return accountsReset(t.tx)
+}
+
+func (t sqlAccountsTx) accountsRestore(rnd basics.Round, accounts []catchpointAccount, assets []catchpointAsset, totals AccountTotals) error {
+	return accountsRestore(t.tx, rnd, accounts, assets, totals)
+}
+
+func (t sqlAccountsTx) accountsRound() (basics.Round, error) {
+	return accountsRound(t.tx)
+}
+
+func (t sqlAccountsTx) accountsAll() (map[basics.Address]basics.AccountData, error) {
+	return accountsAll(t.tx)
+}
+
+func (t sqlAccountsTx) accountsCount() (uint64, error) {
+	return accountsCount(t.tx)
+}
+
+func (t sqlAccountsTx) accountsIterate(fn func(addr basics.Address, data basics.AccountData) error) error {
+	return accountsIterate(t.tx, fn)
+}
+
+func (t sqlAccountsTx) assetCreatorsAll() (map[basics.AssetIndex]basics.Address, error) {
+	return assetCreatorsAll(t.tx)
+}
+
+func (t sqlAccountsTx) accountsTotals() (AccountTotals, error) {
+	return accountsTotals(t.tx)
+}
+
+func (t sqlAccountsTx) accountsNewRound(rnd basics.Round, updates map[basics.Address]accountDelta, rewardsLevel uint64, proto config.ConsensusParams, history bool) error {
+	return accountsNewRound(t.tx, rnd, updates, rewardsLevel, proto, history)
+}
@@ -0,0 +1,693 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+var storageEngines = []StorageEngine{StorageSQLite, StorageKV}
+
+// storageResult is what a conformance scenario observed; every engine
+// must observe the same thing.
+type storageResult struct {
+	accounts []map[basics.Address]basics.AccountData
+	totals   []AccountTotals
+	assets   []map[basics.AssetIndex]basics.Address
+}
+
+func openTestStores(t *testing.T, engine StorageEngine) (accountStore, blockStore) {
+	fn := fmt.Sprintf("%s.%d", t.Name(), crypto.RandUint64())
+	trackerStore, blocks, err := openLedgerStores(fn, true, engine)
+	require.NoError(t, err)
+	return trackerStore, blocks
+}
+
+func TestParseStorageEngine(t *testing.T) {
+	engine, err := ParseStorageEngine("")
+	require.NoError(t, err)
+	require.Equal(t, StorageSQLite, engine)
+
+	for _, e := range storageEngines {
+		engine, err = ParseStorageEngine(string(e))
+		require.NoError(t, err)
+		require.Equal(t, e, engine)
+	}
+
+	_, err = ParseStorageEngine("leveldb")
+	require.Error(t, err)
+}
+
+func TestStorageBlocks(t *testing.T) {
+	initBlocks := randomInitChain(protocol.ConsensusCurrentVersion, 10)
+
+	for _, engine := range storageEngines {
+		t.Run(string(engine), func(t *testing.T) {
+			_, blocks := openTestStores(t, engine)
+			defer blocks.close()
+
+			chain := append([]blockEntry{}, initBlocks...)
+			err := blocks.writeTx(func(tx blockTx) error {
+				err := tx.blockInit(blockChainBlocks(chain))
+				require.NoError(t, err)
+
+				// blockInit does nothing on an initialized store.
+				err = tx.blockInit(blockChainBlocks(chain))
+				require.NoError(t, err)
+
+				for i := 0; i < 10; i++ {
+					blkent := randomBlock(basics.Round(len(chain)))
+					err = tx.blockPut(blkent.block, blkent.cert, blkent.aux)
+					require.NoError(t, err)
+					chain = append(chain, blkent)
+				}
+
+				blkent := randomBlock(basics.Round(len(chain) + 1))
+				require.Error(t, tx.blockPut(blkent.block, blkent.cert, blkent.aux))
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.readTx(func(tx blockTx) error {
+				next, err := tx.blockNext()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(len(chain)), next)
+
+				latest, err := tx.blockLatest()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(len(chain)-1), latest)
+
+				earliest, err := tx.blockEarliest()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(0), earliest)
+
+				for rnd := basics.Round(0); rnd < basics.Round(len(chain)); rnd++ {
+					blk, err := tx.blockGet(rnd)
+					require.NoError(t, err)
+					require.Equal(t, chain[rnd].block, blk)
+
+					hdr, err := tx.blockGetHdr(rnd)
+					require.NoError(t, err)
+					require.Equal(t, chain[rnd].block.BlockHeader, hdr)
+
+					blk, cert, err := tx.blockGetCert(rnd)
+					require.NoError(t, err)
+					require.Equal(t, chain[rnd].block, blk)
+					require.Equal(t, chain[rnd].cert, cert)
+
+					blk, aux, err := tx.blockGetAux(rnd)
+					require.NoError(t, err)
+					require.Equal(t, chain[rnd].block, blk)
+					require.Equal(t, chain[rnd].aux, aux)
+
+					encBlk, encCert, err := tx.blockGetEncodedCert(rnd)
+					require.NoError(t, err)
+					require.Equal(t, protocol.Encode(chain[rnd].block), encBlk)
+					require.Equal(t, protocol.Encode(chain[rnd].cert), encCert)
+				}
+
+				_, err = tx.blockGet(next)
+				require.Equal(t, ErrNoEntry{Round: next}, err)
+				_, err = tx.blockGetHdr(next)
+				require.Equal(t, ErrNoEntry{Round: next}, err)
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.writeTx(func(tx blockTx) error {
+				require.NoError(t, tx.blockForgetBefore(5))
+				require.Error(t, tx.blockForgetBefore(basics.Round(len(chain))))
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.readTx(func(tx blockTx) error {
+				earliest, err := tx.blockEarliest()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(5), earliest)
+
+				_, err = tx.blockGet(4)
+				require.Equal(t, ErrNoEntry{Round: 4}, err)
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.writeTx(func(tx blockTx) error {
+				require.NoError(t, tx.blockForgetAfter(7))
+				require.Error(t, tx.blockForgetAfter(4))
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.readTx(func(tx blockTx) error {
+				latest, err := tx.blockLatest()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(7), latest)
+
+				_, err = tx.blockGetHdr(8)
+				require.Equal(t, ErrNoEntry{Round: 8}, err)
+				_, _, err = tx.blockGetAux(8)
+				require.Equal(t, ErrNoEntry{Round: 8}, err)
+				return nil
+			})
+			require.NoError(t, err)
+
+			err = blocks.writeTx(func(tx blockTx) error {
+				require.NoError(t, tx.blockResetDB())
+				require.NoError(t, tx.blockInit(nil))
+
+				next, err := tx.blockNext()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(0), next)
+
+				_, err = tx.blockLatest()
+				require.Error(t, err)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+// runAccountsScenario applies the same rounds of updates to an account
+// store and records what it observes after each round.
+func runAccountsScenario(t *testing.T, trackerStore accountStore, accts map[basics.Address]basics.AccountData, rounds []map[basics.Address]accountDelta) (res storageResult) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	observe := func(rnd basics.Round) {
+		err := trackerStore.readTx(func(tx accountsTx) error {
+			dbRound, err := tx.accountsRound()
+			require.NoError(t, err)
+			require.Equal(t, rnd, dbRound)
+
+			all, err := tx.accountsAll()
+			require.NoError(t, err)
+			res.accounts = append(res.accounts, all)
+
+			// accountsIterate visits the same accounts, in address order.
+			count, err := tx.accountsCount()
+			require.NoError(t, err)
+			require.Equal(t, uint64(len(all)), count)
+			iterated := make(map[basics.Address]basics.AccountData)
+			var prev basics.Address
+			err = tx.accountsIterate(func(addr basics.Address, data basics.AccountData) error {
+				require.True(t, len(iterated) == 0 || bytes.Compare(prev[:], addr[:]) < 0)
+				iterated[addr] = data
+				prev = addr
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, all, iterated)
+
+			totals, err := tx.accountsTotals()
+			require.NoError(t, err)
+			res.totals = append(res.totals, totals)
+
+			creators, err := tx.assetCreatorsAll()
+			require.NoError(t, err)
+			res.assets = append(res.assets, creators)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	err := trackerStore.writeTx(func(tx accountsTx) error {
+		require.NoError(t, tx.accountsInit(accts, proto))
+		histBase, err := tx.accountsInitHistory()
+		require.NoError(t, err)
+		require.Equal(t, basics.Round(0), histBase)
+		return nil
+	})
+	require.NoError(t, err)
+	observe(0)
+
+	for i, updates := range rounds {
+		rnd := basics.Round(i + 1)
+		err = trackerStore.writeTx(func(tx accountsTx) error {
+			return tx.accountsNewRound(rnd, updates, 0, proto, true)
+		})
+		require.NoError(t, err)
+		observe(rnd)
+	}
+
+	// Rounds must be applied in order.
+	err = trackerStore.writeTx(func(tx accountsTx) error {
+		return tx.accountsNewRound(basics.Round(len(rounds)+2), nil, 0, proto, true)
+	})
+	require.Error(t, err)
+
+	qs, err := trackerStore.queries()
+	require.NoError(t, err)
+	last := res.accounts[len(res.accounts)-1]
+	for addr, data := range last {
+		d, err := qs.lookup(addr)
+		require.NoError(t, err)
+		require.Equal(t, data, d)
+	}
+	d, err := qs.lookup(randomAddress())
+	require.NoError(t, err)
+	require.Equal(t, basics.AccountData{}, d)
+
+	for rnd, all := range res.accounts {
+		for addr, data := range all {
+			d, err := qs.lookupHistory(addr, basics.Round(rnd))
+			require.NoError(t, err)
+			require.Equal(t, data, d)
+		}
+	}
+
+	lastAssets := res.assets[len(res.assets)-1]
+	for aidx, creator := range lastAssets {
+		c, err := qs.lookupAssetCreator(aidx)
+		require.NoError(t, err)
+		require.Equal(t, creator, c)
+	}
+	_, err = qs.lookupAssetCreator(basics.AssetIndex(len(rounds) + 100))
+	require.Error(t, err)
+
+	// listAssets returns the highest-numbered assets first.
+	var expectedList []basics.AssetLocator
+	for aidx := basics.AssetIndex(len(rounds)); aidx > 0 && len(expectedList) < 3; aidx-- {
+		if creator, ok := lastAssets[aidx]; ok {
+			expectedList = append(expectedList, basics.AssetLocator{Index: aidx, Creator: creator})
+		}
+	}
+	list, err := qs.listAssets(basics.AssetIndex(len(rounds)), 3)
+	require.NoError(t, err)
+	require.Equal(t, expectedList, list)
+
+	err = trackerStore.writeTx(func(tx accountsTx) error {
+		return tx.accountsDropHistory()
+	})
+	require.NoError(t, err)
+	qs, err = trackerStore.queries()
+	require.NoError(t, err)
+	for addr := range last {
+		_, err = qs.lookupHistory(addr, 0)
+		require.Error(t, err)
+		break
+	}
+
+	return
+}
+
+func TestStorageAccounts(t *testing.T) {
+	accts := randomAccounts(20)
+
+	// Each round updates some accounts, and creates an asset in a fresh
+	// account, so that every round also changes the asset table.
+	var rounds []map[basics.Address]accountDelta
+	base := accts
+	for i := 1; i < 10; i++ {
+		updates, newaccts, _ := randomDeltas(10, base, 0)
+
+		creator := randomAddress()
+		data := randomAccountData(0)
+		data.AssetParams = map[basics.AssetIndex]basics.AssetParams{
+			basics.AssetIndex(i): {Total: uint64(i)},
+		}
+		updates[creator] = accountDelta{new: data}
+		newaccts[creator] = data
+
+		rounds = append(rounds, updates)
+		base = newaccts
+	}
+
+	// Delete one account.
+	for addr, data := range base {
+		if len(data.AssetParams) == 0 {
+			rounds = append(rounds, map[basics.Address]accountDelta{addr: {old: data}})
+			break
+		}
+	}
+
+	var results []storageResult
+	for _, engine := range storageEngines {
+		t.Run(string(engine), func(t *testing.T) {
+			trackerStore, _ := openTestStores(t, engine)
+			defer trackerStore.close()
+
+			results = append(results, runAccountsScenario(t, trackerStore, accts, rounds))
+		})
+	}
+
+	require.Len(t, results, len(storageEngines))
+	for _, res := range results[1:] {
+		require.Equal(t, results[0], res)
+	}
+	require.Equal(t, accts, results[0].accounts[0])
+	require.Equal(t, base, results[0].accounts[len(rounds)-1])
+}
+
+func TestStorageAccountsRestore(t *testing.T) {
+	accts := randomAccounts(20)
+	var accounts []catchpointAccount
+	var totals AccountTotals
+	var ot basics.OverflowTracker
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	for addr, data := range accts {
+		accounts = append(accounts, catchpointAccount{Address: addr, Data: data})
+		totals.addAccount(proto, data, &ot)
+	}
+	assets := []catchpointAsset{
+		{Asset: 1, Creator: randomAddress()},
+		{Asset: 5, Creator: randomAddress()},
+	}
+
+	for _, engine := range storageEngines {
+		t.Run(string(engine), func(t *testing.T) {
+			trackerStore, _ := openTestStores(t, engine)
+			defer trackerStore.close()
+
+			err := trackerStore.writeTx(func(tx accountsTx) error {
+				require.NoError(t, tx.accountsInit(randomAccounts(5), proto))
+				return tx.accountsRestore(42, accounts, assets, totals)
+			})
+			require.NoError(t, err)
+
+			err = trackerStore.readTx(func(tx accountsTx) error {
+				rnd, err := tx.accountsRound()
+				require.NoError(t, err)
+				require.Equal(t, basics.Round(42), rnd)
+
+				all, err := tx.accountsAll()
+				require.NoError(t, err)
+				require.Equal(t, accts, all)
+
+				creators, err := tx.assetCreatorsAll()
+				require.NoError(t, err)
+				require.Equal(t, map[basics.AssetIndex]basics.Address{
+					1: assets[0].Creator,
+					5: assets[1].Creator,
+				}, creators)
+
+				restoredTotals, err := tx.accountsTotals()
+				require.NoError(t, err)
+				require.Equal(t, totals, restoredTotals)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+// expectedAssetHolders returns the holders of aidx among accts, in the order
+// listAssetHolders returns them.
+func expectedAssetHolders(accts map[basics.Address]basics.AccountData, aidx basics.AssetIndex) (holders []basics.AssetHolder) {
+	for addr, data := range accts {
+		if holding, ok := data.Assets[aidx]; ok {
+			holders = append(holders, basics.AssetHolder{Address: addr, Holding: holding})
+		}
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		return bytes.Compare(holders[i].Address[:], holders[j].Address[:]) < 0
+	})
+	return
+}
+
+// pageAssetHolders lists every holder of aidx, pageSize at a time.
+func pageAssetHolders(t *testing.T, list func(basics.AssetIndex, basics.Address, uint64) ([]basics.AssetHolder, error), aidx basics.AssetIndex, pageSize uint64) (holders []basics.AssetHolder) {
+	var after basics.Address
+	for {
+		page, err := list(aidx, after, pageSize)
+		require.NoError(t, err)
+		require.True(t, uint64(len(page)) <= pageSize)
+		holders = append(holders, page...)
+		if uint64(len(page)) < pageSize {
+			return
+		}
+		after = page[len(page)-1].Address
+	}
+}
+
+func TestStorageAssetHolders(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	accts := randomAccounts(20)
+	i := 0
+	for addr, data := range accts {
+		data.Assets = map[basics.AssetIndex]basics.AssetHolding{
+			basics.AssetIndex(1 + i%2): {Amount: uint64(i), Frozen: i%3 == 0},
+		}
+		if i == 0 {
+			// Asset totals can use the full uint64 range.
+			data.Assets[1] = basics.AssetHolding{Amount: math.MaxUint64}
+		}
+		accts[addr] = data
+		i++
+	}
+
+	for _, engine := range storageEngines {
+		t.Run(string(engine), func(t *testing.T) {
+			trackerStore, _ := openTestStores(t, engine)
+			defer trackerStore.close()
+
+			err := trackerStore.writeTx(func(tx accountsTx) error {
+				return tx.accountsInit(accts, proto)
+			})
+			require.NoError(t, err)
+
+			current := make(map[basics.Address]basics.AccountData)
+			for addr, data := range accts {
+				current[addr] = data
+			}
+
+			for rnd := basics.Round(1); rnd <= 5; rnd++ {
+				// Change, add and remove some holdings, and close an account.
+				updates := make(map[basics.Address]accountDelta)
+				n := 0
+				for addr, old := range current {
+					if n == 4 {
+						break
+					}
+					new := old
+					new.Assets = make(map[basics.AssetIndex]basics.AssetHolding)
+					for aidx, holding := range old.Assets {
+						new.Assets[aidx] = holding
+					}
+					switch n {
+					case 0:
+						new.Assets[1] = basics.AssetHolding{Amount: math.MaxUint64 - uint64(rnd)}
+					case 1:
+						delete(new.Assets, 1)
+						delete(new.Assets, 2)
+					case 2:
+						new.Assets[2] = basics.AssetHolding{Amount: uint64(rnd), Frozen: true}
+					case 3:
+						new = basics.AccountData{}
+					}
+					updates[addr] = accountDelta{old: old, new: new}
+					n++
+				}
+				for addr, delta := range updates {
+					if delta.new.IsZero() {
+						delete(current, addr)
+					} else {
+						current[addr] = delta.new
+					}
+				}
+
+				err = trackerStore.writeTx(func(tx accountsTx) error {
+					return tx.accountsNewRound(rnd, updates, 0, proto, false)
+				})
+				require.NoError(t, err)
+
+				qs, err := trackerStore.queries()
+				require.NoError(t, err)
+				for _, aidx := range []basics.AssetIndex{1, 2, 3} {
+					require.Equal(t, expectedAssetHolders(current, aidx), pageAssetHolders(t, qs.listAssetHolders, aidx, 3))
+				}
+			}
+
+			// A catchpoint restore rebuilds the holdings from the accounts.
+			var accounts []catchpointAccount
+			for addr, data := range accts {
+				accounts = append(accounts, catchpointAccount{Address: addr, Data: data})
+			}
+			err = trackerStore.writeTx(func(tx accountsTx) error {
+				return tx.accountsRestore(42, accounts, nil, AccountTotals{})
+			})
+			require.NoError(t, err)
+			qs, err := trackerStore.queries()
+			require.NoError(t, err)
+			for _, aidx := range []basics.AssetIndex{1, 2} {
+				require.Equal(t, expectedAssetHolders(accts, aidx), pageAssetHolders(t, qs.listAssetHolders, aidx, 100))
+			}
+		})
+	}
+}
+
+// TestStorageAssetHoldersUpgrade checks that opening an account DB from
+// before the assetholdings table fills it in.
+func TestStorageAssetHoldersUpgrade(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+
+	accts := randomAccounts(10)
+	for addr, data := range accts {
+		data.Assets = map[basics.AssetIndex]basics.AssetHolding{7: {Amount: data.MicroAlgos.Raw}}
+		accts[addr] = data
+	}
+
+	dbs := dbOpenTest(t)
+	defer dbs.close()
+
+	tx, err := dbs.wdb.Handle.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	require.NoError(t, accountsInit(tx, accts, proto))
+	_, err = tx.Exec("DROP TABLE assetholdings")
+	require.NoError(t, err)
+	_, err = tx.Exec("DELETE FROM acctrounds WHERE id='assetholdings'")
+	require.NoError(t, err)
+
+	require.NoError(t, accountsInit(tx, nil, proto))
+	qs, err := accountsDbInit(tx)
+	require.NoError(t, err)
+	require.Equal(t, expectedAssetHolders(accts, 7), pageAssetHolders(t, qs.listAssetHolders, 7, 4))
+}
+
+// TestStorageLedger runs a ledger on the key-value engine through a
+// restart, and checks that it agrees with a ledger on SQLite.
+func TestStorageLedger(t *testing.T) {
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+
+	genesisInitState := getInitState()
+	ledgers := make(map[StorageEngine]*Ledger)
+	for _, engine := range storageEngines {
+		dbPrefix := filepath.Join(dbTempDir, string(engine))
+		l, err := OpenLedgerWithStorage(logging.Base(), dbPrefix, false, genesisInitState, true, true, engine)
+		require.NoError(t, err)
+		ledgers[engine] = l
+	}
+
+	blk := genesisInitState.Block
+	const maxBlocks = 500
+	for i := 0; i < maxBlocks; i++ {
+		blk.BlockHeader.Round++
+		blk.BlockHeader.TimeStamp += int64(crypto.RandUint64() % 100 * 1000)
+		for _, l := range ledgers {
+			require.NoError(t, l.AddBlock(blk, agreement.Certificate{}))
+		}
+	}
+
+	for _, l := range ledgers {
+		l.WaitForCommit(blk.Round())
+		l.trackerMu.Lock()
+		l.accts.lastFlushTime = time.Time{}
+		l.trackerMu.Unlock()
+		l.notifyCommit(blk.Round())
+		require.NotEqual(t, basics.Round(0), l.accts.dbRound)
+	}
+
+	// Reopen the key-value ledger from disk.
+	ledgers[StorageKV].Close()
+	ledgers[StorageKV], err = OpenLedgerWithStorage(logging.Base(), filepath.Join(dbTempDir, string(StorageKV)), false, genesisInitState, true, true, StorageKV)
+	require.NoError(t, err)
+	for _, l := range ledgers {
+		defer l.Close()
+	}
+
+	expected := ledgers[StorageSQLite]
+	kv := ledgers[StorageKV]
+	require.Equal(t, expected.Latest(), kv.Latest())
+	for r := basics.Round(0); r <= blk.Round(); r += 25 {
+		expectedBlk, err := expected.Block(r)
+		require.NoError(t, err)
+		kvBlk, err := kv.Block(r)
+		require.NoError(t, err)
+		require.Equal(t, expectedBlk, kvBlk)
+
+		for addr := range genesisInitState.Accounts {
+			expectedData, err := expected.Lookup(r, addr)
+			require.NoError(t, err)
+			data, err := kv.Lookup(r, addr)
+			require.NoError(t, err)
+			require.Equal(t, expectedData, data)
+		}
+	}
+}
+
+// BenchmarkStorageNewRound measures how fast each engine commits rounds
+// of account updates to disk, as the tracker does when it flushes.
+func BenchmarkStorageNewRound(b *testing.B) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	for _, engine := range storageEngines {
+		b.Run(string(engine), func(b *testing.B) {
+			dbTempDir, err := ioutil.TempDir("", "testdir"+b.Name()[len("BenchmarkStorageNewRound/"):])
+			require.NoError(b, err)
+			defer os.RemoveAll(dbTempDir)
+
+			trackerStore, blocks, err := openLedgerStores(filepath.Join(dbTempDir, "ledger"), false, engine)
+			require.NoError(b, err)
+			defer blocks.close()
+			defer trackerStore.close()
+
+			accts := randomAccounts(10000)
+			err = trackerStore.writeTx(func(tx accountsTx) error {
+				return tx.accountsInit(accts, proto)
+			})
+			require.NoError(b, err)
+
+			// Each round touches 100 accounts, without moving money.
+			addrs := make([]basics.Address, 0, len(accts))
+			for addr := range accts {
+				addrs = append(addrs, addr)
+			}
+			rounds := make([]map[basics.Address]accountDelta, b.N)
+			for i := range rounds {
+				rounds[i] = make(map[basics.Address]accountDelta)
+				for j := 0; j < 100; j++ {
+					addr := addrs[(i*100+j)%len(addrs)]
+					old := accts[addr]
+					new := old
+					new.VoteLastValid++
+					rounds[i][addr] = accountDelta{old: old, new: new}
+					accts[addr] = new
+				}
+			}
+
+			b.ResetTimer()
+			for i, updates := range rounds {
+				rnd := basics.Round(i + 1)
+				err = trackerStore.writeTx(func(tx accountsTx) error {
+					return tx.accountsNewRound(rnd, updates, 0, proto, false)
+				})
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -79,7 +79,7 @@ type ledgerTracker interface {
 // ledgerForTracker defines the part of the ledger that a tracker can
 // access.  This is particularly useful for testing trackers in isolation.
 type ledgerForTracker interface {
-	trackerDB() dbPair
+	trackerDB() accountStore
 	trackerLog() logging.Logger
 	trackerEvalVerified(bookkeeping.Block, evalAux) (StateDelta, error)
 
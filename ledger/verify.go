@@ -0,0 +1,207 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+)
+
+// TotalsMismatch is returned by VerifyTotals when the account totals, or
+// the fee sink and rewards pool, disagree with the account table or the
+// block headers.
+type TotalsMismatch struct {
+	Round    basics.Round
+	Problems []string
+}
+
+func (m *TotalsMismatch) Error() string {
+	return fmt.Sprintf("ledger totals do not verify at round %d: %s", m.Round, strings.Join(m.Problems, "; "))
+}
+
+func (m *TotalsMismatch) addf(format string, args ...interface{}) {
+	m.Problems = append(m.Problems, fmt.Sprintf(format, args...))
+}
+
+// sumTotals recomputes the totals of bals at the given rewards level.
+func sumTotals(bals map[basics.Address]basics.AccountData, proto config.ConsensusParams, rewardsLevel uint64) (totals AccountTotals, err error) {
+	var ot basics.OverflowTracker
+	totals.RewardsLevel = rewardsLevel
+	for _, data := range bals {
+		totals.addAccount(proto, data, &ot)
+	}
+	if ot.Overflowed {
+		err = fmt.Errorf("overflow computing totals")
+	}
+	return
+}
+
+// compareTotals records every field of got that differs from expected.
+func (m *TotalsMismatch) compareTotals(what string, got AccountTotals, expected AccountTotals) {
+	counts := []struct {
+		name          string
+		got, expected AlgoCount
+	}{
+		{"online", got.Online, expected.Online},
+		{"offline", got.Offline, expected.Offline},
+		{"not participating", got.NotParticipating, expected.NotParticipating},
+	}
+	for _, c := range counts {
+		if c.got.Money != c.expected.Money {
+			m.addf("%s %s balance %d, accounts sum to %d", what, c.name, c.got.Money.Raw, c.expected.Money.Raw)
+		}
+		if c.got.RewardUnits != c.expected.RewardUnits {
+			m.addf("%s %s reward units %d, accounts sum to %d", what, c.name, c.got.RewardUnits, c.expected.RewardUnits)
+		}
+	}
+}
+
+// VerifyTotals cross-checks the incrementally maintained account totals
+// against the account table.  It sums the balances and reward units of
+// every account, and compares them with the totals stored in the account
+// DB, and with the totals the ledger holds for round rnd, which must not be
+// older than the account DB.  It also checks that the fee sink and rewards
+// pool of round rnd agree with its block header and with the rewards state
+// of the next block, if there is one.
+//
+// VerifyTotals returns a *TotalsMismatch if the ledger is inconsistent.
+// It reads every account, so it is slow on large ledgers, but it only
+// holds the tracker lock to copy the in-memory deltas and totals.
+func (l *Ledger) VerifyTotals(rnd basics.Round) error {
+	// The in-memory state: the deltas from the account DB round up to
+	// rnd, and the totals for rnd.  Delta maps are never modified once
+	// added, so the slice can be shared.
+	l.trackerMu.RLock()
+	au := &l.accts
+	base := au.dbRound
+	if rnd < base || rnd > au.latest() {
+		l.trackerMu.RUnlock()
+		return fmt.Errorf("round %d is not between account DB round %d and latest round %d", rnd, base, au.latest())
+	}
+	deltas := append([]map[basics.Address]accountDelta(nil), au.deltas[:rnd-base]...)
+	totals, err := au.totals(rnd)
+	l.trackerMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	mismatch := &TotalsMismatch{Round: rnd}
+
+	// The totals in the account DB, against the accounts in the same
+	// snapshot.  The DB may have moved on since the deltas were copied;
+	// the deltas up to its new round are then already in it, and if it
+	// has moved past rnd, rnd can no longer be checked.
+	var dbRound basics.Round
+	var bals map[basics.Address]basics.AccountData
+	err = l.trackerDB().readTx(func(tx accountsTx) error {
+		var err error
+		dbRound, err = tx.accountsRound()
+		if err != nil {
+			return err
+		}
+
+		bals, err = tx.accountsAll()
+		if err != nil {
+			return err
+		}
+
+		stored, err := tx.accountsTotals()
+		if err != nil {
+			return err
+		}
+
+		hdr, err := l.BlockHdr(dbRound)
+		if err != nil {
+			return err
+		}
+
+		summed, err := sumTotals(bals, config.Consensus[hdr.CurrentProtocol], stored.RewardsLevel)
+		if err != nil {
+			return err
+		}
+
+		if stored.RewardsLevel != hdr.RewardsLevel {
+			mismatch.addf("account DB rewards level %d at round %d, header has %d", stored.RewardsLevel, dbRound, hdr.RewardsLevel)
+		}
+		mismatch.compareTotals(fmt.Sprintf("account DB at round %d:", dbRound), stored, summed)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if dbRound < base || dbRound > rnd {
+		return fmt.Errorf("account DB moved from round %d to %d while verifying round %d", base, dbRound, rnd)
+	}
+
+	// The totals for rnd, against the accounts at rnd.
+	for _, delta := range deltas[dbRound-base:] {
+		for addr, d := range delta {
+			bals[addr] = d.new
+		}
+	}
+
+	hdr, err := l.BlockHdr(rnd)
+	if err != nil {
+		return err
+	}
+	proto := config.Consensus[hdr.CurrentProtocol]
+
+	summed, err := sumTotals(bals, proto, totals.RewardsLevel)
+	if err != nil {
+		return err
+	}
+
+	if totals.RewardsLevel != hdr.RewardsLevel {
+		mismatch.addf("rewards level %d, header has %d", totals.RewardsLevel, hdr.RewardsLevel)
+	}
+	mismatch.compareTotals("ledger", totals, summed)
+
+	// The fee sink can neither be closed nor register participation keys.
+	sink, ok := bals[hdr.FeeSink]
+	if !ok {
+		mismatch.addf("fee sink %v has no account", hdr.FeeSink)
+	} else if sink.Status == basics.Online {
+		mismatch.addf("fee sink %v is online", hdr.FeeSink)
+	}
+
+	// The rewards pool keeps MinBalance after paying out rewards, and its
+	// balance determines the rewards rate of the next block.
+	pool := bals[hdr.RewardsPool]
+	if pool.MicroAlgos.Raw < proto.MinBalance {
+		mismatch.addf("rewards pool %v balance %d is below the minimum balance %d", hdr.RewardsPool, pool.MicroAlgos.Raw, proto.MinBalance)
+	}
+
+	if rnd < l.blockQ.latest() {
+		next, err := l.BlockHdr(rnd + 1)
+		if err != nil {
+			return err
+		}
+
+		expected := hdr.NextRewardsState(rnd+1, config.Consensus[next.CurrentProtocol], pool.MicroAlgos, summed.RewardUnits())
+		if next.RewardsState != expected {
+			mismatch.addf("rewards state of round %d is %+v, but the rewards pool balance %d and reward units %d give %+v", rnd+1, next.RewardsState, pool.MicroAlgos.Raw, summed.RewardUnits(), expected)
+		}
+	}
+
+	if len(mismatch.Problems) > 0 {
+		return mismatch
+	}
+	return nil
+}
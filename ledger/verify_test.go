@@ -0,0 +1,105 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/util/execpool"
+)
+
+func TestVerifyTotals(t *testing.T) {
+	genesisInitState, _, _ := genesis(10)
+
+	backlogPool := execpool.MakeBacklog(nil, 0, execpool.LowPriority, nil)
+	defer backlogPool.Shutdown()
+
+	dbTempDir, err := ioutil.TempDir("", "testdir"+t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dbTempDir)
+
+	dbPrefix := filepath.Join(dbTempDir, "ledger")
+	l, err := OpenLedger(logging.Base(), dbPrefix, false, genesisInitState, false)
+	require.NoError(t, err)
+	defer l.Close()
+
+	require.NoError(t, l.VerifyTotals(0))
+
+	blk := genesisInitState.Block
+	proto := config.Consensus[blk.CurrentProtocol]
+	maxBlocks := proto.MaxBalLookback + 20
+	for i := uint64(0); i < maxBlocks; i++ {
+		newBlock := bookkeeping.MakeBlock(blk.BlockHeader)
+		eval, err := l.StartEvaluator(newBlock.BlockHeader, nil, backlogPool)
+		require.NoError(t, err)
+		vb, err := eval.GenerateBlock()
+		require.NoError(t, err)
+		blk = vb.Block()
+		require.NoError(t, l.AddBlock(blk, agreement.Certificate{Round: blk.Round()}))
+	}
+
+	l.WaitForCommit(blk.Round())
+	l.trackerMu.Lock()
+	l.accts.lastFlushTime = time.Time{}
+	l.trackerMu.Unlock()
+	l.notifyCommit(blk.Round())
+	dbRound := l.accts.dbRound
+	require.NotEqual(t, basics.Round(0), dbRound)
+
+	for rnd := dbRound; rnd <= blk.Round(); rnd++ {
+		require.NoError(t, l.VerifyTotals(rnd))
+	}
+	require.Error(t, l.VerifyTotals(dbRound-1))
+	require.Error(t, l.VerifyTotals(blk.Round()+1))
+
+	// Corrupt the totals the ledger holds in memory.
+	l.trackerMu.Lock()
+	offset := len(l.accts.roundTotals) - 1
+	l.accts.roundTotals[offset].Online.Money.Raw++
+	l.trackerMu.Unlock()
+	err = l.VerifyTotals(blk.Round())
+	require.IsType(t, &TotalsMismatch{}, err)
+	require.Len(t, err.(*TotalsMismatch).Problems, 1)
+	require.NoError(t, l.VerifyTotals(blk.Round()-1))
+	l.trackerMu.Lock()
+	l.accts.roundTotals[offset].Online.Money.Raw--
+	l.trackerMu.Unlock()
+
+	// Corrupt the totals in the account DB.
+	err = l.trackerDBs.writeTx(func(tx accountsTx) error {
+		totals, err := tx.accountsTotals()
+		require.NoError(t, err)
+		totals.NotParticipating.Money.Raw += 5
+		return accountsPutTotals(tx.(sqlAccountsTx).tx, totals)
+	})
+	require.NoError(t, err)
+	err = l.VerifyTotals(blk.Round())
+	require.IsType(t, &TotalsMismatch{}, err)
+	require.Len(t, err.(*TotalsMismatch).Problems, 1)
+	require.Contains(t, err.Error(), "account DB")
+}
@@ -368,6 +368,16 @@ func (c *Client) GenerateAddress(walletHandle []byte) (string, error) {
 // CreateMultisigAccount takes a wallet handle, a list of (nonmultisig) addresses, and a threshold and creates (and returns) a multisig adress
 // TODO: Should these be raw public keys instead of addresses so users can't shoot themselves in the foot by passing in a multisig addr? Probably will become irrelevant after CSID changes.
 func (c *Client) CreateMultisigAccount(walletHandle []byte, threshold uint8, addrs []string) (string, error) {
+	return c.importMultisigAccount(walletHandle, 1, threshold, addrs, nil)
+}
+
+// CreateWeightedMultisigAccount creates a weighted multisig account, in which
+// addrs[i] contributes weights[i] towards the threshold.
+func (c *Client) CreateWeightedMultisigAccount(walletHandle []byte, threshold uint8, addrs []string, weights []uint8) (string, error) {
+	return c.importMultisigAccount(walletHandle, crypto.WeightedMultisigVersion, threshold, addrs, weights)
+}
+
+func (c *Client) importMultisigAccount(walletHandle []byte, version, threshold uint8, addrs []string, weights []uint8) (string, error) {
 	// convert the addresses into public keys
 	pks := make([]crypto.PublicKey, len(addrs))
 	for i, addrStr := range addrs {
@@ -381,7 +391,7 @@ func (c *Client) CreateMultisigAccount(walletHandle []byte, threshold uint8, add
 	if err != nil {
 		return "", err
 	}
-	resp, err := kmd.ImportMultisigAddr(walletHandle, 1, threshold, pks)
+	resp, err := kmd.ImportMultisigAddr(walletHandle, version, threshold, pks, weights)
 	if err != nil {
 		return "", err
 	}
@@ -400,8 +410,8 @@ func (c *Client) DeleteMultisigAccount(walletHandle []byte, walletPassword []byt
 	return err
 }
 
-// LookupMultisigAccount returns the threshold and public keys for a
-// multisig address.
+// LookupMultisigAccount returns the threshold, public keys and, for weighted
+// multisig accounts, key weights for a multisig address.
 func (c *Client) LookupMultisigAccount(walletHandle []byte, multisigAddr string) (info MultisigInfo, err error) {
 	kmd, err := c.ensureKmdClient()
 	if err != nil {
@@ -422,6 +432,7 @@ func (c *Client) LookupMultisigAccount(walletHandle []byte, multisigAddr string)
 	info.Version = resp.Version
 	info.Threshold = resp.Threshold
 	info.PKs = pks
+	info.Weights = resp.Weights
 	return
 }
 
@@ -430,6 +441,7 @@ type MultisigInfo struct {
 	Version   uint8
 	Threshold uint8
 	PKs       []string
+	Weights   []uint8
 }
 
 // SendPaymentFromWallet signs a transaction using the given wallet and returns the resulted transaction id
@@ -454,7 +466,7 @@ func (c *Client) signAndBroadcastTransactionWithWallet(walletHandle, pw []byte,
 	if err != nil {
 		return transactions.Transaction{}, err
 	}
-	resp0, err := kmd.SignTransaction(walletHandle, pw, tx)
+	resp0, err := kmd.SignTransaction(walletHandle, pw, crypto.PublicKey{}, tx)
 	if err != nil {
 		return transactions.Transaction{}, err
 	}
@@ -539,6 +551,12 @@ func computeValidityRounds(firstValid, lastValid, validRounds, lastRound, maxTxn
 // if the lastValid is 0, firstValid + maxTxnLifetime will be used
 // if the firstValid is 0, lastRound + 1 will be used
 func (c *Client) ConstructPayment(from, to string, fee, amount uint64, note []byte, closeTo string, lease [32]byte, firstValid, lastValid basics.Round) (transactions.Transaction, error) {
+	return c.ConstructPaymentWithRekey(from, to, fee, amount, note, closeTo, lease, firstValid, lastValid, "")
+}
+
+// ConstructPaymentWithRekey is like ConstructPayment, but also rekeys the sender to rekeyTo, so that
+// its transactions must afterwards be authorized by rekeyTo.  If rekeyTo is empty, the sender is not rekeyed.
+func (c *Client) ConstructPaymentWithRekey(from, to string, fee, amount uint64, note []byte, closeTo string, lease [32]byte, firstValid, lastValid basics.Round, rekeyTo string) (transactions.Transaction, error) {
 	fromAddr, err := basics.UnmarshalChecksumAddress(from)
 	if err != nil {
 		return transactions.Transaction{}, err
@@ -592,6 +610,15 @@ func (c *Client) ConstructPayment(from, to string, fee, amount uint64, note []by
 		tx.PaymentTxnFields.CloseRemainderTo = closeToAddr
 	}
 
+	if rekeyTo != "" {
+		rekeyToAddr, err := basics.UnmarshalChecksumAddress(rekeyTo)
+		if err != nil {
+			return transactions.Transaction{}, err
+		}
+
+		tx.Header.RekeyTo = rekeyToAddr
+	}
+
 	tx.Header.GenesisID = params.GenesisID
 
 	// Check if the protocol supports genesis hash
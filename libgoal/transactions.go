@@ -31,13 +31,30 @@ import (
 
 // SignTransactionWithWallet signs the passed transaction with keys from the wallet associated with the passed walletHandle
 func (c *Client) SignTransactionWithWallet(walletHandle, pw []byte, utx transactions.Transaction) (stx transactions.SignedTxn, err error) {
+	return c.SignTransactionWithWalletAndSigner(walletHandle, pw, "", utx)
+}
+
+// SignTransactionWithWalletAndSigner signs the passed transaction with the key for signerAddr from the wallet
+// associated with the passed walletHandle. signerAddr is the address the sender has been rekeyed to; if it is
+// empty, the transaction is signed with the sender's own key
+func (c *Client) SignTransactionWithWalletAndSigner(walletHandle, pw []byte, signerAddr string, utx transactions.Transaction) (stx transactions.SignedTxn, err error) {
+	var pk crypto.PublicKey
+	if signerAddr != "" {
+		var addr basics.Address
+		addr, err = basics.UnmarshalChecksumAddress(signerAddr)
+		if err != nil {
+			return
+		}
+		pk = crypto.PublicKey(addr)
+	}
+
 	kmd, err := c.ensureKmdClient()
 	if err != nil {
 		return
 	}
 
 	// Sign the transaction
-	resp, err := kmd.SignTransaction(walletHandle, pw, utx)
+	resp, err := kmd.SignTransaction(walletHandle, pw, pk, utx)
 	if err != nil {
 		return
 	}
@@ -67,16 +84,31 @@ func (c *Client) SignProgramWithWallet(walletHandle, pw []byte, addr string, pro
 // MultisigSignTransactionWithWallet creates a multisig (or adds to an existing partial multisig, if one is provided), signing with the key corresponding to the given address and using the specified wallet
 // TODO instead of returning MultisigSigs, accept and return blobs
 func (c *Client) MultisigSignTransactionWithWallet(walletHandle, pw []byte, utx transactions.Transaction, signerAddr string, partial crypto.MultisigSig) (msig crypto.MultisigSig, err error) {
+	return c.MultisigSignTransactionWithWalletAndSigner(walletHandle, pw, utx, signerAddr, partial, "")
+}
+
+// MultisigSignTransactionWithWalletAndSigner is like MultisigSignTransactionWithWallet, but signs on behalf of
+// msigAddr, the multisig address the sender has been rekeyed to. If msigAddr is empty, the sender is the multisig
+func (c *Client) MultisigSignTransactionWithWalletAndSigner(walletHandle, pw []byte, utx transactions.Transaction, signerAddr string, partial crypto.MultisigSig, msigAddr string) (msig crypto.MultisigSig, err error) {
 	txBytes := protocol.Encode(utx)
 	addr, err := basics.UnmarshalChecksumAddress(signerAddr)
 	if err != nil {
 		return
 	}
+	var signer crypto.Digest
+	if msigAddr != "" {
+		var msigAuth basics.Address
+		msigAuth, err = basics.UnmarshalChecksumAddress(msigAddr)
+		if err != nil {
+			return
+		}
+		signer = crypto.Digest(msigAuth)
+	}
 	kmd, err := c.ensureKmdClient()
 	if err != nil {
 		return
 	}
-	resp, err := kmd.MultisigSignTransaction(walletHandle, pw, txBytes, crypto.PublicKey(addr), partial)
+	resp, err := kmd.MultisigSignTransaction(walletHandle, pw, txBytes, crypto.PublicKey(addr), partial, signer)
 	if err != nil {
 		return
 	}
@@ -389,7 +421,7 @@ func (c *Client) FillUnsignedTxTemplate(sender string, firstValid, lastValid, fe
 //
 // Call FillUnsignedTxTemplate afterwards to fill out common fields in
 // the resulting transaction template.
-func (c *Client) MakeUnsignedAssetCreateTx(total uint64, defaultFrozen bool, manager string, reserve string, freeze string, clawback string, unitName string, assetName string, url string, metadataHash []byte) (transactions.Transaction, error) {
+func (c *Client) MakeUnsignedAssetCreateTx(total uint64, defaultFrozen bool, manager string, reserve string, freeze string, clawback string, unitName string, assetName string, url string, metadataHash []byte, decimals uint32) (transactions.Transaction, error) {
 	var tx transactions.Transaction
 	var err error
 
@@ -458,6 +490,11 @@ func (c *Client) MakeUnsignedAssetCreateTx(total uint64, defaultFrozen bool, man
 	}
 	tx.AssetParams.AssetName = assetName
 
+	if decimals > cparams.MaxAssetDecimals {
+		return tx, fmt.Errorf("asset decimals %d too large (max %d)", decimals, cparams.MaxAssetDecimals)
+	}
+	tx.AssetParams.Decimals = decimals
+
 	return tx, nil
 }
 
@@ -592,6 +629,23 @@ func (c *Client) MakeUnsignedAssetSendTx(index uint64, amount uint64, recipient
 	return tx, nil
 }
 
+// MakeUnsignedAssetFundedOptInTx creates a tx template with which an asset's
+// manager opts recipient in to the asset, paying the MinBalance for the new
+// holding, and sends it amount units of the asset.  The transaction is only
+// valid in a group that also holds a transaction sent by recipient.
+//
+// Call FillUnsignedTxTemplate afterwards, with the manager as the sender,
+// to fill out common fields in the resulting transaction template.
+func (c *Client) MakeUnsignedAssetFundedOptInTx(index uint64, amount uint64, recipient string) (transactions.Transaction, error) {
+	tx, err := c.MakeUnsignedAssetSendTx(index, amount, recipient, "", "")
+	if err != nil {
+		return tx, err
+	}
+
+	tx.AssetFundOptIn = true
+	return tx, nil
+}
+
 // MakeUnsignedAssetFreezeTx creates a tx template for freezing assets.
 //
 // Call FillUnsignedTxTemplate afterwards to fill out common fields in
@@ -0,0 +1,277 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package lightclient follows the chain of blocks agreed on by the network
+// without a full Ledger.  Starting from trusted block headers, it accepts
+// each following header only if the header extends the chain and comes
+// with a certificate that authenticates it.  Certificates are checked with
+// agreement's own vote and credential verification, so the client needs the
+// online-account balances that sortition weighs votes against, which are
+// supplied by a Balances.
+package lightclient
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/committee"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// Balances supplies the online-account state that sortition needs to
+// verify the votes in a certificate.  The votes of round r are weighed
+// against balances as of the end of round agreement.BalanceRound(r).
+type Balances interface {
+	// BalanceRecord returns the record of addr as of the end of round
+	// rnd, with pending rewards applied.
+	BalanceRecord(rnd basics.Round, addr basics.Address) (basics.BalanceRecord, error)
+
+	// Circulation returns the online stake as of the end of round rnd.
+	Circulation(rnd basics.Round) (basics.MicroAlgos, error)
+}
+
+// Snapshot is a Balances that holds the balances of a fixed set of rounds
+// in memory.
+type Snapshot map[basics.Round]SnapshotRound
+
+// SnapshotRound holds the online-account balances of a single round.
+type SnapshotRound struct {
+	Circulation basics.MicroAlgos
+	Records     map[basics.Address]basics.AccountData
+}
+
+// BalanceRecord implements Balances.BalanceRecord.  Accounts missing from
+// a round that the snapshot holds are treated as empty.
+func (s Snapshot) BalanceRecord(rnd basics.Round, addr basics.Address) (basics.BalanceRecord, error) {
+	sr, ok := s[rnd]
+	if !ok {
+		return basics.BalanceRecord{}, fmt.Errorf("no balances for round %d", rnd)
+	}
+	return basics.BalanceRecord{Addr: addr, AccountData: sr.Records[addr]}, nil
+}
+
+// Circulation implements Balances.Circulation.
+func (s Snapshot) Circulation(rnd basics.Round) (basics.MicroAlgos, error) {
+	sr, ok := s[rnd]
+	if !ok {
+		return basics.MicroAlgos{}, fmt.Errorf("no balances for round %d", rnd)
+	}
+	return sr.Circulation, nil
+}
+
+// Client holds a contiguous chain of authenticated block headers and
+// extends it one round at a time.  It implements agreement.LedgerReader
+// on top of those headers, so that certificates can be checked exactly as
+// a node checks them.
+type Client struct {
+	balances Balances
+	verifier *agreement.AsyncVoteVerifier
+
+	mu      sync.Mutex
+	headers map[basics.Round]bookkeeping.BlockHeader
+	latest  basics.Round
+	waiters map[basics.Round]chan struct{}
+}
+
+// MakeClient creates a Client that trusts the given headers, which must
+// be for consecutive rounds, in order.  Verifying a round uses the seed
+// and consensus parameters of earlier rounds, so callers should supply at
+// least the last two headers up to the trusted one.
+func MakeClient(trusted []bookkeeping.BlockHeader, balances Balances) (*Client, error) {
+	if len(trusted) == 0 {
+		return nil, fmt.Errorf("no trusted headers")
+	}
+
+	c := &Client{
+		balances: balances,
+		headers:  make(map[basics.Round]bookkeeping.BlockHeader),
+		waiters:  make(map[basics.Round]chan struct{}),
+	}
+	for i, hdr := range trusted {
+		if i > 0 {
+			prev := trusted[i-1]
+			if hdr.Round != prev.Round+1 {
+				return nil, fmt.Errorf("trusted header for round %d does not follow round %d", hdr.Round, prev.Round)
+			}
+			if hdr.Branch != prev.Hash() {
+				return nil, fmt.Errorf("trusted header for round %d does not extend round %d: branch %v != %v", hdr.Round, prev.Round, hdr.Branch, prev.Hash())
+			}
+		}
+		c.headers[hdr.Round] = hdr
+	}
+	c.latest = trusted[len(trusted)-1].Round
+	c.verifier = agreement.MakeAsyncVoteVerifier(nil)
+	return c, nil
+}
+
+// Close stops the client's vote verification workers.
+func (c *Client) Close() {
+	c.verifier.Quit()
+}
+
+// Latest returns the latest authenticated header.
+func (c *Client) Latest() bookkeeping.BlockHeader {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.headers[c.latest]
+}
+
+// Header returns the authenticated header of round rnd, if the client
+// still holds it.
+func (c *Client) Header(rnd basics.Round) (bookkeeping.BlockHeader, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hdr, ok := c.headers[rnd]
+	return hdr, ok
+}
+
+// Verify authenticates hdr, which must be the header of the round after
+// the latest one, against cert.  If hdr extends the chain and cert
+// certifies it, hdr becomes the latest header.
+func (c *Client) Verify(hdr bookkeeping.BlockHeader, cert agreement.Certificate) error {
+	prev := c.Latest()
+	if hdr.Round != prev.Round+1 {
+		return fmt.Errorf("header for round %d does not follow latest round %d", hdr.Round, prev.Round)
+	}
+	if hdr.GenesisHash != prev.GenesisHash {
+		return fmt.Errorf("header for round %d has genesis hash %v != %v", hdr.Round, hdr.GenesisHash, prev.GenesisHash)
+	}
+	if hdr.Branch != prev.Hash() {
+		return fmt.Errorf("header for round %d does not extend round %d: branch %v != %v", hdr.Round, prev.Round, hdr.Branch, prev.Hash())
+	}
+
+	// The certificate only depends on the block through its round and
+	// digest, which the header determines.
+	err := cert.Authenticate(bookkeeping.Block{BlockHeader: hdr}, c, c.verifier)
+	if err != nil {
+		return fmt.Errorf("round %d: %v", hdr.Round, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latest != prev.Round {
+		return fmt.Errorf("round %d was verified concurrently", hdr.Round)
+	}
+	c.headers[hdr.Round] = hdr
+	c.latest = hdr.Round
+	if ch, ok := c.waiters[hdr.Round]; ok {
+		close(ch)
+		delete(c.waiters, hdr.Round)
+	}
+
+	// Keep the headers that verifying the next round may need: its seed
+	// and parameter lookback rounds, and its balance round, which callers
+	// may want to check balances against.
+	proto, ok := config.Consensus[hdr.CurrentProtocol]
+	if ok {
+		keep := agreement.BalanceRound(hdr.Round+1, proto)
+		if params := agreement.ParamsRound(hdr.Round + 1); params < keep {
+			keep = params
+		}
+		for rnd := range c.headers {
+			if rnd < keep {
+				delete(c.headers, rnd)
+			}
+		}
+	}
+	return nil
+}
+
+// header returns the header of round rnd, or an error if the client does
+// not hold it.
+func (c *Client) header(rnd basics.Round) (bookkeeping.BlockHeader, error) {
+	hdr, ok := c.Header(rnd)
+	if !ok {
+		return bookkeeping.BlockHeader{}, fmt.Errorf("no header for round %d", rnd)
+	}
+	return hdr, nil
+}
+
+// NextRound implements agreement.LedgerReader.NextRound.
+func (c *Client) NextRound() basics.Round {
+	return c.Latest().Round + 1
+}
+
+// Wait implements agreement.LedgerReader.Wait.
+func (c *Client) Wait(rnd basics.Round) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.waiters[rnd]
+	if !ok {
+		ch = make(chan struct{})
+		if rnd <= c.latest {
+			close(ch)
+			return ch
+		}
+		c.waiters[rnd] = ch
+	}
+	return ch
+}
+
+// Seed implements agreement.LedgerReader.Seed.
+func (c *Client) Seed(rnd basics.Round) (committee.Seed, error) {
+	hdr, err := c.header(rnd)
+	if err != nil {
+		return committee.Seed{}, err
+	}
+	return hdr.Seed, nil
+}
+
+// LookupDigest implements agreement.LedgerReader.LookupDigest.
+func (c *Client) LookupDigest(rnd basics.Round) (crypto.Digest, error) {
+	hdr, err := c.header(rnd)
+	if err != nil {
+		return crypto.Digest{}, err
+	}
+	return crypto.Digest(hdr.Hash()), nil
+}
+
+// ConsensusParams implements agreement.LedgerReader.ConsensusParams.
+func (c *Client) ConsensusParams(rnd basics.Round) (config.ConsensusParams, error) {
+	version, err := c.ConsensusVersion(rnd)
+	if err != nil {
+		return config.ConsensusParams{}, err
+	}
+	proto, ok := config.Consensus[version]
+	if !ok {
+		return config.ConsensusParams{}, fmt.Errorf("round %d: unsupported protocol %v", rnd, version)
+	}
+	return proto, nil
+}
+
+// ConsensusVersion implements agreement.LedgerReader.ConsensusVersion.
+func (c *Client) ConsensusVersion(rnd basics.Round) (protocol.ConsensusVersion, error) {
+	hdr, err := c.header(rnd)
+	if err != nil {
+		return "", err
+	}
+	return hdr.CurrentProtocol, nil
+}
+
+// BalanceRecord implements agreement.LedgerReader.BalanceRecord.
+func (c *Client) BalanceRecord(rnd basics.Round, addr basics.Address) (basics.BalanceRecord, error) {
+	return c.balances.BalanceRecord(rnd, addr)
+}
+
+// Circulation implements agreement.LedgerReader.Circulation.
+func (c *Client) Circulation(rnd basics.Round) (basics.MicroAlgos, error) {
+	return c.balances.Circulation(rnd)
+}
@@ -0,0 +1,293 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package lightclient
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/agreement/agreementtest"
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/account"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/data/committee"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/protocol"
+	"github.com/vincentbdb/go-algorand/util/db"
+)
+
+var testProto = protocol.ConsensusCurrentVersion
+
+// testLedger is a minimal agreement.Ledger whose blocks are only headers
+// chained to one another, with fixed balances.
+type testLedger struct {
+	mu       sync.Mutex
+	entries  map[basics.Round]bookkeeping.Block
+	certs    map[basics.Round]agreement.Certificate
+	balances SnapshotRound
+	waiters  map[basics.Round]chan struct{}
+}
+
+func (l *testLedger) NextRound() basics.Round {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return basics.Round(len(l.entries))
+}
+
+func (l *testLedger) Wait(r basics.Round) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.waiters[r]
+	if !ok {
+		ch = make(chan struct{})
+		l.waiters[r] = ch
+		if _, ok := l.entries[r]; ok {
+			close(ch)
+		}
+	}
+	return ch
+}
+
+func (l *testLedger) block(r basics.Round) bookkeeping.Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.entries[r]
+}
+
+func (l *testLedger) Seed(r basics.Round) (committee.Seed, error) {
+	return l.block(r).BlockHeader.Seed, nil
+}
+
+func (l *testLedger) LookupDigest(r basics.Round) (crypto.Digest, error) {
+	return l.block(r).Digest(), nil
+}
+
+func (l *testLedger) BalanceRecord(r basics.Round, addr basics.Address) (basics.BalanceRecord, error) {
+	return basics.BalanceRecord{Addr: addr, AccountData: l.balances.Records[addr]}, nil
+}
+
+func (l *testLedger) Circulation(r basics.Round) (basics.MicroAlgos, error) {
+	return l.balances.Circulation, nil
+}
+
+func (l *testLedger) ConsensusParams(r basics.Round) (config.ConsensusParams, error) {
+	return config.Consensus[testProto], nil
+}
+
+func (l *testLedger) ConsensusVersion(r basics.Round) (protocol.ConsensusVersion, error) {
+	return testProto, nil
+}
+
+func (l *testLedger) EnsureValidatedBlock(vb agreement.ValidatedBlock, c agreement.Certificate) {
+	l.EnsureBlock(vb.Block(), c)
+}
+
+func (l *testLedger) EnsureBlock(e bookkeeping.Block, c agreement.Certificate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.entries[e.Round()]; ok {
+		return
+	}
+	l.entries[e.Round()] = e
+	l.certs[e.Round()] = c
+	if ch, ok := l.waiters[e.Round()]; ok {
+		close(ch)
+	} else {
+		ch = make(chan struct{})
+		close(ch)
+		l.waiters[e.Round()] = ch
+	}
+}
+
+func (l *testLedger) EnsureDigest(c agreement.Certificate, quit chan struct{}, verifier *agreement.AsyncVoteVerifier) {
+	select {
+	case <-quit:
+	case <-l.Wait(c.Round):
+	}
+}
+
+type testValidatedBlock struct {
+	Inside bookkeeping.Block
+}
+
+func (b testValidatedBlock) Block() bookkeeping.Block {
+	return b.Inside
+}
+
+func (b testValidatedBlock) WithSeed(s committee.Seed) agreement.ValidatedBlock {
+	b.Inside.BlockHeader.Seed = s
+	return b
+}
+
+type testBlockFactory struct {
+	l *testLedger
+}
+
+func (f testBlockFactory) AssembleBlock(r basics.Round, deadline time.Time) (agreement.ValidatedBlock, error) {
+	prev := f.l.block(r - 1)
+	hdr := bookkeeping.BlockHeader{
+		Round:       r,
+		Branch:      prev.Hash(),
+		GenesisID:   prev.BlockHeader.GenesisID,
+		GenesisHash: prev.BlockHeader.GenesisHash,
+		TimeStamp:   prev.TimeStamp + 1,
+		UpgradeState: bookkeeping.UpgradeState{
+			CurrentProtocol: testProto,
+		},
+	}
+	return testValidatedBlock{Inside: bookkeeping.Block{BlockHeader: hdr}}, nil
+}
+
+type testBlockValidator struct{}
+
+func (v testBlockValidator) Validate(ctx context.Context, e bookkeeping.Block) (agreement.ValidatedBlock, error) {
+	return testValidatedBlock{Inside: e}, nil
+}
+
+// runAgreement runs agreement among numAccounts online accounts for the
+// given number of rounds, returning the resulting ledger.
+func runAgreement(t *testing.T, dir string, numAccounts int, rounds basics.Round) *testLedger {
+	genesis := bookkeeping.Block{BlockHeader: bookkeeping.BlockHeader{
+		GenesisID:   "lightclient-test",
+		GenesisHash: crypto.Hash([]byte("lightclient-test")),
+		UpgradeState: bookkeeping.UpgradeState{
+			CurrentProtocol: testProto,
+		},
+	}}
+
+	l := &testLedger{
+		entries: map[basics.Round]bookkeeping.Block{0: genesis},
+		certs:   make(map[basics.Round]agreement.Certificate),
+		waiters: make(map[basics.Round]chan struct{}),
+		balances: SnapshotRound{
+			Records: make(map[basics.Address]basics.AccountData),
+		},
+	}
+
+	var parts []account.Participation
+	for i := 0; i < numAccounts; i++ {
+		rootAccess, err := db.MakeAccessor(filepath.Join(dir, "root"+strconv.Itoa(i)), false, true)
+		require.NoError(t, err)
+		defer rootAccess.Close()
+		root, err := account.GenerateRoot(rootAccess)
+		require.NoError(t, err)
+
+		partAccess, err := db.MakeAccessor(filepath.Join(dir, "part"+strconv.Itoa(i)), false, true)
+		require.NoError(t, err)
+		defer partAccess.Close()
+		part, err := account.FillDBWithParticipationKeys(partAccess, root.Address(), 0, rounds, config.Consensus[testProto].DefaultKeyDilution)
+		require.NoError(t, err)
+		parts = append(parts, part)
+
+		stake := basics.MicroAlgos{Raw: 100000}
+		l.balances.Records[root.Address()] = basics.AccountData{
+			Status:      basics.Online,
+			MicroAlgos:  stake,
+			SelectionID: part.VRFSecrets().PK,
+			VoteID:      part.VotingSecrets().OneTimeSignatureVerifier,
+		}
+		l.balances.Circulation.Raw += stake.Raw
+	}
+
+	err := agreementtest.Simulate(filepath.Join(dir, t.Name()), rounds, time.Second, l, agreementtest.SimpleKeyManager(parts), testBlockFactory{l: l}, testBlockValidator{}, logging.Base())
+	require.NoError(t, err)
+	return l
+}
+
+func TestLightClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lightclient")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rounds := basics.Round(5)
+	l := runAgreement(t, dir, 10, rounds)
+	balances := Snapshot{0: l.balances}
+
+	c, err := MakeClient([]bookkeeping.BlockHeader{l.entries[0].BlockHeader}, balances)
+	require.NoError(t, err)
+	defer c.Close()
+
+	// A header must extend the latest one.
+	require.Error(t, c.Verify(l.entries[2].BlockHeader, l.certs[2]))
+
+	// A header must match its certificate.
+	tampered := l.entries[1].BlockHeader
+	tampered.TimeStamp++
+	require.Error(t, c.Verify(tampered, l.certs[1]))
+	require.Error(t, c.Verify(l.entries[1].BlockHeader, l.certs[2]))
+	require.Error(t, c.Verify(l.entries[1].BlockHeader, agreement.Certificate{}))
+
+	// Votes only count with the stake they were cast with.
+	poorer := make(map[basics.Address]basics.AccountData)
+	for addr, data := range l.balances.Records {
+		data.SelectionID = crypto.VRFVerifier{}
+		poorer[addr] = data
+	}
+	other, err := MakeClient([]bookkeeping.BlockHeader{l.entries[0].BlockHeader}, Snapshot{0: {Circulation: l.balances.Circulation, Records: poorer}})
+	require.NoError(t, err)
+	defer other.Close()
+	require.Error(t, other.Verify(l.entries[1].BlockHeader, l.certs[1]))
+
+	wait := c.Wait(rounds)
+	for rnd := basics.Round(1); rnd <= rounds; rnd++ {
+		require.NoError(t, c.Verify(l.entries[rnd].BlockHeader, l.certs[rnd]))
+		require.Equal(t, l.entries[rnd].BlockHeader, c.Latest())
+		require.Equal(t, rnd+1, c.NextRound())
+	}
+	<-wait
+
+	hdr, ok := c.Header(0)
+	require.True(t, ok)
+	require.Equal(t, l.entries[0].BlockHeader, hdr)
+}
+
+func TestMakeClient(t *testing.T) {
+	var hdrs []bookkeeping.BlockHeader
+	for rnd := basics.Round(5); rnd < 10; rnd++ {
+		var hdr bookkeeping.BlockHeader
+		hdr.Round = rnd
+		if len(hdrs) > 0 {
+			hdr.Branch = hdrs[len(hdrs)-1].Hash()
+		}
+		hdrs = append(hdrs, hdr)
+	}
+
+	c, err := MakeClient(hdrs, Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, hdrs[len(hdrs)-1], c.Latest())
+	c.Close()
+
+	_, err = MakeClient(nil, Snapshot{})
+	require.Error(t, err)
+	_, err = MakeClient(append([]bookkeeping.BlockHeader{}, hdrs[0], hdrs[2]), Snapshot{})
+	require.Error(t, err)
+	broken := append([]bookkeeping.BlockHeader{}, hdrs...)
+	broken[2].TimeStamp++
+	_, err = MakeClient(broken, Snapshot{})
+	require.Error(t, err)
+}
@@ -49,6 +49,10 @@ type nodeConfig struct {
 type NetworkTemplate struct {
 	Genesis gen.GenesisData
 	Nodes   []nodeConfig
+
+	// Consensus, if set, is written to every node as its consensus.json,
+	// so that the network can use consensus protocols of its own.
+	Consensus json.RawMessage `json:",omitempty"`
 }
 
 var defaultNetworkTemplate = NetworkTemplate{
@@ -56,6 +60,17 @@ var defaultNetworkTemplate = NetworkTemplate{
 }
 
 func (t NetworkTemplate) generateGenesisAndWallets(targetFolder, networkName, binDir string) error {
+	if len(t.Consensus) > 0 {
+		err := config.ApplyConsensusOverrides(t.Consensus)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(targetFolder, config.ConsensusFilename), t.Consensus, 0666)
+		if err != nil {
+			return err
+		}
+	}
+
 	genesisData := t.Genesis
 	genesisData.NetworkName = networkName
 	return gen.GenerateGenesisFiles(genesisData, targetFolder, true)
@@ -91,6 +106,14 @@ func (t NetworkTemplate) createNodeDirectories(targetFolder string, binDir strin
 			return
 		}
 
+		consensusFile := filepath.Join(targetFolder, config.ConsensusFilename)
+		if util.FileExists(consensusFile) {
+			_, err = util.CopyFile(consensusFile, filepath.Join(nodeDir, config.ConsensusFilename))
+			if err != nil {
+				return
+			}
+		}
+
 		if cfg.IsRelay {
 			_, err = filepath.Abs(nodeDir)
 			if err != nil {
@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -64,3 +65,35 @@ func TestGenerateGenesis(t *testing.T) {
 	fileExists := err == nil
 	a.True(fileExists)
 }
+
+func TestGenerateGenesisCustomConsensus(t *testing.T) {
+	a := require.New(t)
+
+	templateDir, _ := filepath.Abs("../test/testdata/nettemplates")
+	template, err := loadTemplate(filepath.Join(templateDir, "TwoNodes50EachCustomConsensus.json"))
+	a.NoError(err)
+
+	targetFolder, err := ioutil.TempDir("", "netroot")
+	a.NoError(err)
+	defer os.RemoveAll(targetFolder)
+	binDir := os.ExpandEnv("${GOPATH}/bin")
+
+	smallLambda, bigLambda := config.Protocol.SmallLambda, config.Protocol.BigLambda
+	defer func() {
+		delete(config.Consensus, template.Genesis.ConsensusProtocol)
+		config.Protocol.SmallLambda, config.Protocol.BigLambda = smallLambda, bigLambda
+	}()
+
+	err = template.generateGenesisAndWallets(targetFolder, "testGenCustom", binDir)
+	a.NoError(err)
+
+	consensus, err := ioutil.ReadFile(filepath.Join(targetFolder, config.ConsensusFilename))
+	a.NoError(err)
+	a.JSONEq(string(template.Consensus), string(consensus))
+
+	genesis, err := bookkeeping.LoadGenesisFromFile(filepath.Join(targetFolder, config.GenesisJSONFile))
+	a.NoError(err)
+	a.Equal(template.Genesis.ConsensusProtocol, genesis.Proto)
+	a.Equal(uint64(100), config.Consensus[genesis.Proto].MaxTxnLife)
+	a.NoError(genesis.CheckProtoParams())
+}
@@ -75,14 +75,19 @@ type blockFactoryImpl struct {
 	tp               *pools.TransactionPool
 	logStats         bool
 	verificationPool execpool.BacklogPool
+
+	// upgrade, if set, is a protocol version that proposed blocks vote
+	// for regardless of the current protocol's ApprovedUpgrades
+	upgrade protocol.ConsensusVersion
 }
 
-func makeBlockFactory(l *data.Ledger, tp *pools.TransactionPool, logStats bool, executionPool execpool.BacklogPool) *blockFactoryImpl {
+func makeBlockFactory(l *data.Ledger, tp *pools.TransactionPool, logStats bool, executionPool execpool.BacklogPool, upgrade protocol.ConsensusVersion) *blockFactoryImpl {
 	bf := &blockFactoryImpl{
 		l:                l,
 		tp:               tp,
 		logStats:         logStats,
 		verificationPool: executionPool,
+		upgrade:          upgrade,
 	}
 	return bf
 }
@@ -95,7 +100,7 @@ func (i *blockFactoryImpl) AssembleBlock(round basics.Round, deadline time.Time)
 		return nil, fmt.Errorf("could not make proposals at round %d: could not read block from ledger: %v", round, err)
 	}
 
-	newEmptyBlk := bookkeeping.MakeBlock(prev)
+	newEmptyBlk := bookkeeping.MakeBlockWithUpgrade(prev, i.upgrade)
 
 	eval, err := i.l.StartEvaluator(newEmptyBlk.BlockHeader, i.tp, i.verificationPool)
 	if err != nil {
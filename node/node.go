@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/vincentbdb/go-algorand/node/appinterface"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -64,6 +65,12 @@ type StatusReport struct {
 	SynchronizingTime     time.Duration
 	CatchupTime           time.Duration
 	HasSyncedSinceStartup bool
+	UpgradeProposal       protocol.ConsensusVersion
+	UpgradeApprovals      uint64
+	UpgradeThreshold      uint64
+	UpgradeVoteRounds     uint64
+	UpgradeVoteBefore     basics.Round
+	UpgradeSwitchRound    basics.Round
 }
 
 // TimeSinceLastRound returns the time since the last block was approved (locally), or 0 if no blocks seen
@@ -118,6 +125,10 @@ type AlgorandFullNode struct {
 
 	oldKeyDeletionNotify chan struct{}
 
+	// partKeyFiles maps the participation keys loaded from disk to their
+	// filenames, so that superseded keys can be deleted.
+	partKeyFiles map[account.ParticipationInterval]string
+
 	application appinterface.Application
 }
 
@@ -177,7 +188,7 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 	// create initial ledger, if it doesn't exist
 	os.Mkdir(genesisDir, 0700)
 	var genalloc data.GenesisBalances
-	genalloc, err = bootstrapData(genesis, log)
+	genalloc, err = data.LoadGenesisBalances(genesis)
 	if err != nil {
 		log.Errorf("Cannot load genesis allocation: %v", err)
 		return nil, err
@@ -186,12 +197,45 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 	node.cryptoPool = execpool.MakePool(node)
 	node.lowPriorityCryptoVerificationPool = execpool.MakeBacklog(node.cryptoPool, 2*node.cryptoPool.GetParallelism(), execpool.LowPriority, node)
 	node.highPriorityCryptoVerificationPool = execpool.MakeBacklog(node.cryptoPool, 2*node.cryptoPool.GetParallelism(), execpool.HighPriority, node)
-	node.ledger, err = data.LoadLedger(node.log, ledgerPathnamePrefix, false, genesis.Proto, genalloc, node.genesisID, node.genesisHash, []ledger.BlockListener{}, cfg.Archival)
+	storage, err := ledger.ParseStorageEngine(cfg.LedgerStorageEngine)
+	if err != nil {
+		log.Errorf("Cannot initialize ledger (%s): %v", ledgerPathnamePrefix, err)
+		return nil, err
+	}
+	node.ledger, err = data.LoadLedger(node.log, ledgerPathnamePrefix, false, genesis.Proto, genalloc, node.genesisID, node.genesisHash, []ledger.BlockListener{}, cfg.Archival, cfg.Archival && cfg.EnableAccountHistory, storage)
 	if err != nil {
 		log.Errorf("Cannot initialize ledger (%s): %v", ledgerPathnamePrefix, err)
 		return nil, err
 	}
 
+	if cfg.CatchpointInterval > 0 {
+		err = node.ledger.EnableCatchpoints(filepath.Join(genesisDir, config.CatchpointDirName), cfg.CatchpointInterval)
+		if err != nil {
+			log.Errorf("Cannot enable ledger catchpoints: %v", err)
+			return nil, err
+		}
+	}
+
+	if cfg.VerifyLedgerTotalsOnStartup {
+		err = node.ledger.VerifyTotals(node.ledger.Latest())
+		if err != nil {
+			log.Errorf("Cannot verify ledger totals: %v", err)
+			return nil, err
+		}
+	}
+
+	if cfg.EnableStateDeltas || cfg.ArchiveStateDeltas {
+		archiveFilename := ""
+		if cfg.ArchiveStateDeltas {
+			archiveFilename = ledgerPathnamePrefix + ".deltas.sqlite"
+		}
+		err = node.ledger.EnableStateDeltas(archiveFilename)
+		if err != nil {
+			log.Errorf("Cannot enable state deltas: %v", err)
+			return nil, err
+		}
+	}
+
 	node.transactionPool = pools.MakeTransactionPool(node.ledger.Ledger, cfg)
 
 	blockListeners := []ledger.BlockListener{
@@ -221,6 +265,7 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 
 	node.ledgerService = rpcs.RegisterLedgerService(cfg, node.ledger, p2pNode, node.genesisID)
 	node.wsFetcherService = rpcs.RegisterWsFetcherService(node.log, p2pNode)
+	rpcs.RegisterCatchpointService(node.ledger, p2pNode, node.genesisID)
 	rpcs.RegisterTxService(node.transactionPool, p2pNode, node.genesisID, cfg.TxPoolSize, cfg.TxSyncServeResponseSize)
 
 	crashPathname := filepath.Join(genesisDir, config.CrashFilename)
@@ -230,7 +275,22 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 		return nil, err
 	}
 
-	blockFactory := makeBlockFactory(node.ledger, node.transactionPool, node.config.EnableProcessBlockStats, node.highPriorityCryptoVerificationPool)
+	upgrade := protocol.ConsensusVersion(cfg.VoteForUpgrade)
+	if upgrade != "" {
+		if config.IsPublicNetwork(genesis.Network) {
+			err = fmt.Errorf("VoteForUpgrade is not allowed on public network %s", genesis.Network)
+			log.Error(err)
+			return nil, err
+		}
+		if _, ok := config.Consensus[upgrade]; !ok {
+			err = fmt.Errorf("VoteForUpgrade names unsupported protocol %s", upgrade)
+			log.Error(err)
+			return nil, err
+		}
+		log.Warnf("Voting for an upgrade to protocol %s", upgrade)
+	}
+
+	blockFactory := makeBlockFactory(node.ledger, node.transactionPool, node.config.EnableProcessBlockStats, node.highPriorityCryptoVerificationPool, upgrade)
 	blockValidator := blockValidatorImpl{l: node.ledger, tp: node.transactionPool, verificationPool: node.highPriorityCryptoVerificationPool}
 	agreementLedger := agreementLedger{Ledger: node.ledger, ff: rpcs.MakeNetworkFetcherFactory(node.net, blockQueryPeerLimit, node.wsFetcherService), n: node.net}
 
@@ -252,6 +312,7 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 	node.syncer = catchup.MakeService(node.log, node.config, p2pNode, node.ledger, node.wsFetcherService, blockAuthenticatorImpl{Ledger: node.ledger, AsyncVoteVerifier: agreement.MakeAsyncVoteVerifier(node.lowPriorityCryptoVerificationPool)})
 	node.txPoolSyncer = rpcs.MakeTxSyncer(node.transactionPool, node.net, node.txHandler.SolicitedTxHandler(), time.Duration(cfg.TxSyncIntervalSeconds)*time.Second, time.Duration(cfg.TxSyncTimeoutSeconds)*time.Second, cfg.TxSyncServeResponseSize)
 
+	node.partKeyFiles = make(map[account.ParticipationInterval]string)
 	err = node.loadParticipationKeys()
 	if err != nil {
 		log.Errorf("Cannot load participation keys: %v", err)
@@ -262,40 +323,6 @@ func MakeFull(log logging.Logger, rootDir string, cfg config.Local, phonebookDir
 	return node, err
 }
 
-func bootstrapData(genesis bookkeeping.Genesis, log logging.Logger) (data.GenesisBalances, error) {
-	genalloc := make(map[basics.Address]basics.AccountData)
-	for _, entry := range genesis.Allocation {
-		addr, err := basics.UnmarshalChecksumAddress(entry.Address)
-		if err != nil {
-			log.Errorf("Cannot parse genesis addr %s: %v", entry.Address, err)
-			return data.GenesisBalances{}, err
-		}
-
-		_, present := genalloc[addr]
-		if present {
-			err = fmt.Errorf("repeated allocation to %s", entry.Address)
-			log.Error(err)
-			return data.GenesisBalances{}, err
-		}
-
-		genalloc[addr] = entry.State
-	}
-
-	feeSink, err := basics.UnmarshalChecksumAddress(genesis.FeeSink)
-	if err != nil {
-		log.Errorf("Cannot parse fee sink addr %s: %v", genesis.FeeSink, err)
-		return data.GenesisBalances{}, err
-	}
-
-	rewardsPool, err := basics.UnmarshalChecksumAddress(genesis.RewardsPool)
-	if err != nil {
-		log.Errorf("Cannot parse rewards pool addr %s: %v", genesis.RewardsPool, err)
-		return data.GenesisBalances{}, err
-	}
-
-	return data.MakeTimestampedGenesisBalances(genalloc, feeSink, rewardsPool, genesis.Timestamp), nil
-}
-
 // Config returns a copy of the node's Local configuration
 func (node *AlgorandFullNode) Config() config.Local {
 	return node.config
@@ -391,6 +418,62 @@ func (node *AlgorandFullNode) Ledger() *data.Ledger {
 	return node.ledger
 }
 
+// ImportBlocks adds the blocks in a block archive, as written by goal
+// ledger export, to the node's ledger.  Blocks that the ledger already
+// has are skipped; every other block must follow the ledger's latest
+// round and be authenticated by its certificate, as during catchup.  It
+// returns the number of blocks added.  The node must not be started.
+func (node *AlgorandFullNode) ImportBlocks(archive io.Reader) (uint64, error) {
+	ar, err := rpcs.MakeBlockArchiveReader(archive)
+	if err != nil {
+		return 0, err
+	}
+
+	header := ar.Header()
+	if header.GenesisID != node.genesisID || header.GenesisHash != node.genesisHash {
+		return 0, fmt.Errorf("block archive is for genesis %s, not %s", header.GenesisID, node.genesisID)
+	}
+
+	auth := blockAuthenticatorImpl{Ledger: node.ledger, AsyncVoteVerifier: agreement.MakeAsyncVoteVerifier(node.lowPriorityCryptoVerificationPool)}
+	defer auth.Quit()
+
+	var imported uint64
+	for {
+		entry, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		rnd := entry.Block.Round()
+		latest := node.ledger.Latest()
+		if rnd <= latest {
+			continue
+		}
+		if rnd != latest+1 {
+			return imported, fmt.Errorf("block archive starts at round %d, but the ledger ends at round %d", rnd, latest)
+		}
+
+		if !entry.Block.ContentsMatchHeader() {
+			return imported, fmt.Errorf("block %d contents do not match its header", rnd)
+		}
+		err = auth.Authenticate(&entry.Block, &entry.Certificate)
+		if err != nil {
+			return imported, fmt.Errorf("block %d is not authenticated by its certificate: %v", rnd, err)
+		}
+		err = node.ledger.AddBlock(entry.Block, entry.Certificate)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+
+	node.ledger.WaitForCommit(node.ledger.Latest())
+	return imported, nil
+}
+
 // BroadcastSignedTxGroup broadcasts a transaction group that has already been signed.
 func (node *AlgorandFullNode) BroadcastSignedTxGroup(txgroup []transactions.SignedTxn) error {
 	lastRound := node.ledger.Latest()
@@ -579,6 +662,14 @@ func (node *AlgorandFullNode) Status() (s StatusReport, err error) {
 	s.LastRoundTimestamp = node.lastRoundTimestamp
 	s.CatchupTime = node.syncer.SynchronizingTime()
 	s.HasSyncedSinceStartup = node.hasSyncedSinceStartup
+
+	proto := config.Consensus[b.CurrentProtocol]
+	s.UpgradeProposal = b.NextProtocol
+	s.UpgradeApprovals = b.NextProtocolApprovals
+	s.UpgradeThreshold = proto.UpgradeThreshold
+	s.UpgradeVoteRounds = proto.UpgradeVoteRounds
+	s.UpgradeVoteBefore = b.NextProtocolVoteBefore
+	s.UpgradeSwitchRound = b.NextProtocolSwitchOn
 	return
 }
 
@@ -691,6 +782,11 @@ func (node *AlgorandFullNode) loadParticipationKeys() error {
 			added := node.accountManager.AddParticipation(part)
 			if added {
 				node.log.Infof("Loaded participation keys from storage: %s %s", part.Address(), info.Name())
+				first, last := part.ValidInterval()
+				node.mu.Lock()
+				node.partKeyFiles[account.ParticipationInterval{Address: part.Address(), FirstValid: first, LastValid: last}] = filename
+				node.mu.Unlock()
+				node.logOverlappingKeys(part)
 			} else {
 				part.Close()
 			}
@@ -700,6 +796,22 @@ func (node *AlgorandFullNode) loadParticipationKeys() error {
 	return nil
 }
 
+// logOverlappingKeys reports other keys of the same account whose validity
+// overlaps the given participation key. Overlapping keys are expected while a
+// key is rolled over; agreement votes with whichever one is registered.
+func (node *AlgorandFullNode) logOverlappingKeys(part account.Participation) {
+	first, last := part.ValidInterval()
+	for _, other := range node.accountManager.Keys() {
+		otherFirst, otherLast := other.ValidInterval()
+		if other.Address() != part.Address() || (otherFirst == first && otherLast == last) {
+			continue
+		}
+		if other.OverlapsInterval(first, last) {
+			node.log.Infof("Participation key for %s (%d-%d) overlaps key (%d-%d); switching over once the newer key is registered", part.Address(), first, last, otherFirst, otherLast)
+		}
+	}
+}
+
 func (node *AlgorandFullNode) txPoolGaugeThread() {
 	txPoolGuage := metrics.MakeGauge(metrics.MetricName{Name: "algod_tx_pool_count", Description: "current number of available transactions in pool"})
 	ticker := time.NewTicker(10 * time.Second)
@@ -764,12 +876,56 @@ func (node *AlgorandFullNode) oldKeyDeletionThread() {
 			proto := config.Consensus[hdr.CurrentProtocol]
 
 			node.mu.Lock()
-			node.accountManager.DeleteOldKeys(r+1, proto)
+			node.deleteOldKeys(r+1, proto)
+			node.deleteSupersededKeys(r+1, proto)
 			node.mu.Unlock()
 		}
 	}
 }
 
+// deleteOldKeys deletes the ephemeral keys that agreement no longer needs
+// by the time it votes in round rnd, along with the participation keys,
+// and their files, that have expired by then.  It must be called with
+// node.mu held.
+func (node *AlgorandFullNode) deleteOldKeys(rnd basics.Round, proto config.ConsensusParams) {
+	expired := node.accountManager.DeleteOldKeys(rnd, proto)
+	for _, interval := range expired {
+		node.log.Infof("Deleted participation key for %s (%d-%d), which has expired", interval.Address, interval.FirstValid, interval.LastValid)
+		node.removePartKeyFile(interval)
+	}
+}
+
+// deleteSupersededKeys deletes the participation keys, and their files,
+// that accounts have rolled over from by the time agreement votes in
+// round rnd.  It must be called with node.mu held.
+func (node *AlgorandFullNode) deleteSupersededKeys(rnd basics.Round, proto config.ConsensusParams) {
+	balanceRound := agreement.BalanceRound(rnd, proto)
+	deleted := node.accountManager.DeleteSupersededKeys(proto, func(addr basics.Address) (crypto.OneTimeSignatureVerifier, error) {
+		data, err := node.ledger.Lookup(balanceRound, addr)
+		return data.VoteID, err
+	})
+
+	for _, interval := range deleted {
+		node.log.Infof("Deleted participation key for %s (%d-%d), which a newer key has replaced", interval.Address, interval.FirstValid, interval.LastValid)
+		node.removePartKeyFile(interval)
+	}
+}
+
+// removePartKeyFile removes the file of a participation key that is no
+// longer managed, if the key was loaded from one.  It must be called with
+// node.mu held.
+func (node *AlgorandFullNode) removePartKeyFile(interval account.ParticipationInterval) {
+	filename, ok := node.partKeyFiles[interval]
+	if !ok {
+		return
+	}
+	delete(node.partKeyFiles, interval)
+	err := os.Remove(filepath.Join(node.rootDir, node.genesisID, filename))
+	if err != nil {
+		node.log.Warnf("Cannot remove participation key file %s: %v", filename, err)
+	}
+}
+
 // Uint64 implements the randomness by calling the crypto library.
 func (node *AlgorandFullNode) Uint64() uint64 {
 	return crypto.RandUint64()
@@ -35,6 +35,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data/account"
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/protocol"
 	"github.com/vincentbdb/go-algorand/util"
@@ -147,7 +148,7 @@ func setupFullNodes(t *testing.T, proto protocol.ConsensusVersion, verificationP
 		nodeID := fmt.Sprintf("Node%d", i)
 		const inMem = false
 		const archival = true
-		_, err := data.LoadLedger(logging.Base().With("name", nodeID), ledgerFilenamePrefix, inMem, g.Proto, bootstrap, "", crypto.Digest{}, nil, archival)
+		_, err := data.LoadLedger(logging.Base().With("name", nodeID), ledgerFilenamePrefix, inMem, g.Proto, bootstrap, "", crypto.Digest{}, nil, archival, false, ledger.StorageSQLite)
 		require.NoError(t, err)
 	}
 
@@ -413,3 +414,59 @@ func TestStatusReport_TimeSinceLastRound(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteExpiredPartKeyFiles(t *testing.T) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	rootDir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	const genesisID = "test"
+	genesisDir := filepath.Join(rootDir, genesisID)
+	require.NoError(t, os.Mkdir(genesisDir, 0700))
+
+	var addr basics.Address
+	addr[0] = 1
+	makeKeyFile := func(first, last basics.Round) string {
+		filename := config.PartKeyFilename("acct", uint64(first), uint64(last))
+		store, err := db.MakeAccessor(filepath.Join(genesisDir, filename), false, false)
+		require.NoError(t, err)
+		part, err := account.FillDBWithParticipationKeys(store, addr, first, last, proto.DefaultKeyDilution)
+		require.NoError(t, err)
+		part.Close()
+		return filename
+	}
+	expiring := makeKeyFile(0, 100)
+	live := makeKeyFile(50, 2000)
+
+	node := &AlgorandFullNode{
+		rootDir:        rootDir,
+		genesisID:      genesisID,
+		log:            logging.Base(),
+		accountManager: data.MakeAccountManager(logging.Base()),
+		partKeyFiles:   make(map[account.ParticipationInterval]string),
+	}
+	require.NoError(t, node.loadParticipationKeys())
+	require.Len(t, node.partKeyFiles, 2)
+
+	node.mu.Lock()
+	node.deleteOldKeys(100, proto)
+	node.mu.Unlock()
+	require.Len(t, node.partKeyFiles, 2)
+
+	// Once a key has expired, it is forgotten and its file is removed.
+	node.mu.Lock()
+	node.deleteOldKeys(101, proto)
+	node.mu.Unlock()
+	require.Equal(t, map[account.ParticipationInterval]string{
+		{Address: addr, FirstValid: 50, LastValid: 2000}: live,
+	}, node.partKeyFiles)
+	_, err = os.Stat(filepath.Join(genesisDir, expiring))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(genesisDir, live))
+	require.NoError(t, err)
+
+	for _, part := range node.accountManager.Keys() {
+		part.Close()
+	}
+}
@@ -29,10 +29,14 @@ const (
 	AuctionSettlement HashID = "aS"
 
 	AgreementSelector HashID = "AS"
+	AccountStateValue HashID = "AV"
 	BlockHeader       HashID = "BH"
 	BalanceRecord     HashID = "BR"
+	ConsensusParams   HashID = "CP"
 	Credential        HashID = "CR"
 	Genesis           HashID = "GE"
+	MerkleTrieLeaf    HashID = "ML"
+	MerkleTrieNode    HashID = "MN"
 	Message           HashID = "MX"
 	NetPrioResponse   HashID = "NPR"
 	OneTimeSigKey1    HashID = "OT1"
@@ -0,0 +1,235 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpcs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+// A block archive holds a contiguous range of blocks along with their
+// certificates, so that they can be carried to a node without going
+// through the block service.  It starts with blockArchiveMagic, followed
+// by a BlockArchiveHeader frame and one EncodedBlockCert frame for each
+// round from FirstRound to LastRound.  Each frame is the length of an
+// msgpack-encoded object as a 4-byte big-endian integer, the object, and
+// the digest of the object, so that a damaged archive is detected before
+// its contents are used.
+
+// BlockArchiveVersion is the version of the block archive format written
+// by BlockArchiveWriter.
+const BlockArchiveVersion = 1
+
+const blockArchiveMagic = "algorand-block-archive\n"
+
+// blockArchiveMaxFrame bounds the frame size accepted by
+// BlockArchiveReader, so that a corrupt length does not make it allocate
+// an arbitrary amount of memory.
+const blockArchiveMaxFrame = 1 << 26
+
+// BlockArchiveHeader describes the blocks in a block archive.
+type BlockArchiveHeader struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Version     uint64        `codec:"v"`
+	GenesisID   string        `codec:"gen"`
+	GenesisHash crypto.Digest `codec:"gh"`
+	FirstRound  basics.Round  `codec:"first"`
+	LastRound   basics.Round  `codec:"last"`
+}
+
+// BlockArchiveWriter writes a block archive.
+type BlockArchiveWriter struct {
+	w      *bufio.Writer
+	header BlockArchiveHeader
+	next   basics.Round
+}
+
+// MakeBlockArchiveWriter starts a block archive for the rounds in header,
+// which must then be written in order with Write.  The Version in header
+// is filled in.
+func MakeBlockArchiveWriter(w io.Writer, header BlockArchiveHeader) (*BlockArchiveWriter, error) {
+	if header.FirstRound > header.LastRound {
+		return nil, fmt.Errorf("block archive rounds %d-%d are out of order", header.FirstRound, header.LastRound)
+	}
+
+	header.Version = BlockArchiveVersion
+	aw := &BlockArchiveWriter{
+		w:      bufio.NewWriter(w),
+		header: header,
+		next:   header.FirstRound,
+	}
+
+	_, err := aw.w.WriteString(blockArchiveMagic)
+	if err != nil {
+		return nil, err
+	}
+
+	err = aw.writeFrame(protocol.Encode(header))
+	if err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+func (aw *BlockArchiveWriter) writeFrame(data []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	digest := crypto.Hash(data)
+
+	for _, b := range [][]byte{length[:], data, digest[:]} {
+		_, err := aw.w.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write adds the next round's block and certificate, encoded as returned
+// by Ledger.EncodedBlockCert, to the archive.
+func (aw *BlockArchiveWriter) Write(blk []byte, cert []byte) error {
+	if aw.next > aw.header.LastRound {
+		return fmt.Errorf("block archive already holds rounds %d-%d", aw.header.FirstRound, aw.header.LastRound)
+	}
+
+	err := aw.writeFrame(protocol.Encode(PreEncodedBlockCert{
+		Block:       blk,
+		Certificate: cert,
+	}))
+	if err != nil {
+		return err
+	}
+	aw.next++
+	return nil
+}
+
+// Close finishes the archive, after checking that every round in its
+// header has been written.  It does not close the underlying writer.
+func (aw *BlockArchiveWriter) Close() error {
+	if aw.next <= aw.header.LastRound {
+		return fmt.Errorf("block archive is missing rounds %d-%d", aw.next, aw.header.LastRound)
+	}
+	return aw.w.Flush()
+}
+
+// BlockArchiveReader reads a block archive.
+type BlockArchiveReader struct {
+	r      *bufio.Reader
+	header BlockArchiveHeader
+	next   basics.Round
+}
+
+// MakeBlockArchiveReader reads the header of a block archive.
+func MakeBlockArchiveReader(r io.Reader) (*BlockArchiveReader, error) {
+	ar := &BlockArchiveReader{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(blockArchiveMagic))
+	_, err := io.ReadFull(ar.r, magic)
+	if err != nil || string(magic) != blockArchiveMagic {
+		return nil, fmt.Errorf("not a block archive")
+	}
+
+	data, err := ar.readFrame()
+	if err != nil {
+		return nil, fmt.Errorf("reading block archive header: %v", err)
+	}
+	err = protocol.Decode(data, &ar.header)
+	if err != nil {
+		return nil, fmt.Errorf("decoding block archive header: %v", err)
+	}
+	if ar.header.Version != BlockArchiveVersion {
+		return nil, fmt.Errorf("unsupported block archive version %d", ar.header.Version)
+	}
+	if ar.header.FirstRound > ar.header.LastRound {
+		return nil, fmt.Errorf("block archive rounds %d-%d are out of order", ar.header.FirstRound, ar.header.LastRound)
+	}
+
+	ar.next = ar.header.FirstRound
+	return ar, nil
+}
+
+func (ar *BlockArchiveReader) readFrame() ([]byte, error) {
+	var length [4]byte
+	_, err := io.ReadFull(ar.r, length[:])
+	if err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(length[:])
+	if n > blockArchiveMaxFrame {
+		return nil, fmt.Errorf("frame of %d bytes is too large", n)
+	}
+
+	data := make([]byte, n)
+	_, err = io.ReadFull(ar.r, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var digest crypto.Digest
+	_, err = io.ReadFull(ar.r, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	if digest != crypto.Hash(data) {
+		return nil, fmt.Errorf("frame digest mismatch")
+	}
+	return data, nil
+}
+
+// Header returns the header of the archive.
+func (ar *BlockArchiveReader) Header() BlockArchiveHeader {
+	return ar.header
+}
+
+// Next returns the block and certificate for the next round in the
+// archive, or io.EOF once every round has been read.
+func (ar *BlockArchiveReader) Next() (EncodedBlockCert, error) {
+	if ar.next > ar.header.LastRound {
+		return EncodedBlockCert{}, io.EOF
+	}
+
+	data, err := ar.readFrame()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return EncodedBlockCert{}, fmt.Errorf("reading block %d from archive: %v", ar.next, err)
+	}
+
+	var entry EncodedBlockCert
+	err = protocol.Decode(data, &entry)
+	if err != nil {
+		return EncodedBlockCert{}, fmt.Errorf("decoding block %d from archive: %v", ar.next, err)
+	}
+	if entry.Block.Round() != ar.next || entry.Certificate.Round != ar.next {
+		return EncodedBlockCert{}, fmt.Errorf("block archive entry for round %d holds block %d and certificate %d", ar.next, entry.Block.Round(), entry.Certificate.Round)
+	}
+	if entry.Block.GenesisID() != ar.header.GenesisID || entry.Block.GenesisHash() != ar.header.GenesisHash {
+		return EncodedBlockCert{}, fmt.Errorf("block %d in archive is for genesis %s, not %s", ar.next, entry.Block.GenesisID(), ar.header.GenesisID)
+	}
+
+	ar.next++
+	return entry, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpcs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/agreement"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+func TestBlockArchive(t *testing.T) {
+	header := BlockArchiveHeader{
+		GenesisID:   "test-v1",
+		GenesisHash: crypto.Hash([]byte("test-v1")),
+		FirstRound:  5,
+		LastRound:   9,
+	}
+
+	var entries []EncodedBlockCert
+	for rnd := header.FirstRound; rnd <= header.LastRound; rnd++ {
+		var entry EncodedBlockCert
+		entry.Block.BlockHeader.Round = rnd
+		entry.Block.BlockHeader.GenesisID = header.GenesisID
+		entry.Block.BlockHeader.GenesisHash = header.GenesisHash
+		entry.Block.BlockHeader.TimeStamp = int64(rnd) * 1000
+		entry.Certificate = agreement.Certificate{Round: rnd}
+		entries = append(entries, entry)
+	}
+
+	write := func(entries []EncodedBlockCert) ([]byte, error) {
+		var buf bytes.Buffer
+		aw, err := MakeBlockArchiveWriter(&buf, header)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			err = aw.Write(protocol.Encode(entry.Block), protocol.Encode(entry.Certificate))
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = aw.Close()
+		return buf.Bytes(), err
+	}
+
+	archive, err := write(entries)
+	require.NoError(t, err)
+
+	ar, err := MakeBlockArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	expectedHeader := header
+	expectedHeader.Version = BlockArchiveVersion
+	require.Equal(t, expectedHeader, ar.Header())
+	for _, expected := range entries {
+		entry, err := ar.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, entry)
+	}
+	_, err = ar.Next()
+	require.Equal(t, io.EOF, err)
+
+	// The writer insists on exactly the rounds in the header.
+	_, err = write(entries[1:])
+	require.Error(t, err)
+	_, err = write(append(entries, entries[0]))
+	require.Error(t, err)
+
+	// A truncated archive is detected.
+	ar, err = MakeBlockArchiveReader(bytes.NewReader(archive[:len(archive)-10]))
+	require.NoError(t, err)
+	for i := 0; i < len(entries)-1; i++ {
+		_, err = ar.Next()
+		require.NoError(t, err)
+	}
+	_, err = ar.Next()
+	require.Error(t, err)
+	require.NotEqual(t, io.EOF, err)
+
+	// So is a damaged one.
+	damaged := append([]byte{}, archive...)
+	damaged[len(damaged)-40] ^= 1
+	ar, err = MakeBlockArchiveReader(bytes.NewReader(damaged))
+	require.NoError(t, err)
+	for i := 0; i < len(entries)-1; i++ {
+		_, err = ar.Next()
+		require.NoError(t, err)
+	}
+	_, err = ar.Next()
+	require.Error(t, err)
+
+	_, err = MakeBlockArchiveReader(bytes.NewReader([]byte("not an archive")))
+	require.Error(t, err)
+
+	// Blocks must be for the rounds and genesis in the header.
+	swapped := append([]EncodedBlockCert{entries[1], entries[0]}, entries[2:]...)
+	archive, err = write(swapped)
+	require.NoError(t, err)
+	ar, err = MakeBlockArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	_, err = ar.Next()
+	require.Error(t, err)
+
+	other := append([]EncodedBlockCert{}, entries...)
+	other[0].Block.BlockHeader.GenesisID = "other-v1"
+	archive, err = write(other)
+	require.NoError(t, err)
+	ar, err = MakeBlockArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	_, err = ar.Next()
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/vincentbdb/go-algorand/data"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/logging"
+	"github.com/vincentbdb/go-algorand/network"
+)
+
+const catchpointResponseContentType = "application/x-algorand-catchpoint-v1"
+
+// CatchpointServicePath is the path to register CatchpointService as a handler for when using gorilla/mux
+// e.g. .Handle(CatchpointServicePath, &cs)
+const CatchpointServicePath = "/v{version:[0-9.]+}/{genesisID}/catchpoint/{round:[0-9a-z]+}"
+
+// CatchpointService serves the catchpoint files written by the ledger
+type CatchpointService struct {
+	ledger    *data.Ledger
+	genesisID string
+}
+
+// RegisterCatchpointService creates a CatchpointService around the provider Ledger and registers it for RPC with the provided Registrar
+func RegisterCatchpointService(ledger *data.Ledger, registrar Registrar, genesisID string) *CatchpointService {
+	service := &CatchpointService{ledger: ledger, genesisID: genesisID}
+	registrar.RegisterHTTPHandler(CatchpointServicePath, service)
+	return service
+}
+
+// ServeHTTP returns the catchpoint file for /v1/{genesisID}/catchpoint/{round}, with the round in base 36
+// like the block service.
+func (cs *CatchpointService) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	pathVars := mux.Vars(request)
+	if pathVars["version"] != "1" {
+		logging.Base().Debug("http catchpoint bad version", pathVars["version"])
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if pathVars["genesisID"] != cs.genesisID {
+		logging.Base().Debugf("http catchpoint bad genesisID mine=%#v theirs=%#v", cs.genesisID, pathVars["genesisID"])
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	round, err := strconv.ParseUint(pathVars["round"], 36, 64)
+	if err != nil {
+		logging.Base().Debug("http catchpoint round parse fail", pathVars["round"], err)
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f, err := cs.ledger.OpenCatchpointFile(basics.Round(round))
+	if err != nil {
+		if os.IsNotExist(err) {
+			response.Header().Set("Cache-Control", ledgerResponseMissingBlockCacheControl)
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		logging.Base().Warnf("CatchpointService.ServeHTTP : failed to open catchpoint %d %v", round, err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		logging.Base().Warnf("CatchpointService.ServeHTTP : failed to stat catchpoint %d %v", round, err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response.Header().Set("Content-Type", catchpointResponseContentType)
+	response.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	response.Header().Set("Cache-Control", ledgerResponseHasBlockCacheControl)
+	response.WriteHeader(http.StatusOK)
+	_, err = io.Copy(response, f)
+	if err != nil {
+		logging.Base().Warn("http catchpoint write failed ", err)
+	}
+}
+
+// FetchCatchpoint requests the catchpoint file for round rnd from the node at peerAddress.
+// The caller must close the returned reader, and should check its contents against a
+// trusted catchpoint label before using them.
+func FetchCatchpoint(ctx context.Context, client *http.Client, peerAddress string, genesisID string, rnd basics.Round) (io.ReadCloser, error) {
+	parsedURL, err := network.ParseHostOrURL(peerAddress)
+	if err != nil {
+		return nil, err
+	}
+	parsedURL.Path = path.Join(parsedURL.Path, "v1", genesisID, "catchpoint", strconv.FormatUint(uint64(rnd), 36))
+	request, err := http.NewRequest("GET", parsedURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	network.SetUserAgentHeader(request.Header)
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, fmt.Errorf("peer %s has no catchpoint for round %d", peerAddress, rnd)
+	default:
+		response.Body.Close()
+		return nil, fmt.Errorf("FetchCatchpoint error response status code %d", response.StatusCode)
+	}
+
+	if response.Header.Get("Content-Type") != catchpointResponseContentType {
+		response.Body.Close()
+		return nil, fmt.Errorf("catchpoint response has an invalid content type '%s'", response.Header.Get("Content-Type"))
+	}
+	return response.Body, nil
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/vincentbdb/go-algorand/data/basics"
 	"github.com/vincentbdb/go-algorand/data/bookkeeping"
 	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/ledger"
 	"github.com/vincentbdb/go-algorand/logging"
 	"github.com/vincentbdb/go-algorand/network"
 	"github.com/vincentbdb/go-algorand/protocol"
@@ -307,7 +308,7 @@ func TestGetBlockMocked(t *testing.T) {
 	ledgerA, err := data.LoadLedger(
 		log.With("name", "A"), t.Name(), inMem,
 		protocol.ConsensusCurrentVersion, genBal, "", crypto.Digest{},
-		nil, archival,
+		nil, archival, false, ledger.StorageSQLite,
 	)
 	if err != nil {
 		t.Errorf("Couldn't make ledger: %v", err)
@@ -393,7 +394,7 @@ func TestGetFutureBlock(t *testing.T) {
 	ledgerA, err := data.LoadLedger(
 		log.With("name", "A"), t.Name(), inMem,
 		protocol.ConsensusCurrentVersion, gen, "", crypto.Digest{},
-		nil, archival,
+		nil, archival, false, ledger.StorageSQLite,
 	)
 	if err != nil {
 		t.Errorf("Couldn't make ledger: %v", err)
@@ -411,7 +412,7 @@ func TestGetFutureBlock(t *testing.T) {
 	require.Nil(t, client)
 }
 
-func buildTestLedger(t *testing.T) (ledger *data.Ledger, next basics.Round, b bookkeeping.Block, err error) {
+func buildTestLedger(t *testing.T) (l *data.Ledger, next basics.Round, b bookkeeping.Block, err error) {
 	var user basics.Address
 	user[0] = 123
 
@@ -435,15 +436,15 @@ func buildTestLedger(t *testing.T) (ledger *data.Ledger, next basics.Round, b bo
 	genHash := crypto.Digest{0x42}
 	const inMem = true
 	const archival = true
-	ledger, err = data.LoadLedger(
+	l, err = data.LoadLedger(
 		log, t.Name(), inMem, protocol.ConsensusCurrentVersion, genBal, "", genHash,
-		nil, archival,
+		nil, archival, false, ledger.StorageSQLite,
 	)
 	if err != nil {
 		t.Fatal("couldn't build ledger", err)
 		return
 	}
-	next = ledger.NextRound()
+	next = l.NextRound()
 	tx := transactions.Transaction{
 		Type: protocol.PaymentTx,
 		Header: transactions.Header{
@@ -462,7 +463,7 @@ func buildTestLedger(t *testing.T) (ledger *data.Ledger, next basics.Round, b bo
 		Txn: tx,
 	}
 
-	prev, err := ledger.Block(ledger.LastRound())
+	prev, err := l.Block(l.LastRound())
 	require.NoError(t, err)
 	b.RewardsLevel = prev.RewardsLevel
 	b.BlockHeader.Round = next
@@ -474,6 +475,6 @@ func buildTestLedger(t *testing.T) (ledger *data.Ledger, next basics.Round, b bo
 		txib,
 	}
 
-	require.NoError(t, ledger.AddBlock(b, agreement.Certificate{Round: next}))
+	require.NoError(t, l.AddBlock(b, agreement.Certificate{Round: next}))
 	return
 }
@@ -167,7 +167,7 @@ func TestMultisigSign(t *testing.T) {
 	pk3 := crypto.PublicKey{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} // some public key we haven't imported
 
 	// Create a 2-of-3 multisig account from the three public keys
-	resp1, err := f.Client.ImportMultisigAddr([]byte(walletHandleToken), 1, 2, []crypto.PublicKey{pk1, pk2, pk3})
+	resp1, err := f.Client.ImportMultisigAddr([]byte(walletHandleToken), 1, 2, []crypto.PublicKey{pk1, pk2, pk3}, nil)
 
 	require.NoError(t, err)
 	msigAddr := addrToPK(t, resp1.Address)
@@ -239,7 +239,7 @@ func TestMultisigSignProgram(t *testing.T) {
 	pk3 := crypto.PublicKey{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} // some public key we haven't imported
 
 	// Create a 2-of-3 multisig account from the three public keys
-	resp1, err := f.Client.ImportMultisigAddr([]byte(walletHandleToken), 1, 2, []crypto.PublicKey{pk1, pk2, pk3})
+	resp1, err := f.Client.ImportMultisigAddr([]byte(walletHandleToken), 1, 2, []crypto.PublicKey{pk1, pk2, pk3}, nil)
 
 	require.NoError(t, err)
 	msigAddr := addrToPK(t, resp1.Address)
@@ -283,3 +283,69 @@ func TestMultisigSignProgram(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ok)
 }
+
+func TestWeightedMultisigSign(t *testing.T) {
+	t.Parallel()
+	var f fixtures.KMDFixture
+	walletHandleToken := f.SetupWithWallet(t)
+	defer f.Shutdown()
+
+	resp, err := f.Client.GenerateKey([]byte(walletHandleToken))
+	require.NoError(t, err)
+	pk1 := addrToPK(t, resp.Address)
+	resp, err = f.Client.GenerateKey([]byte(walletHandleToken))
+	require.NoError(t, err)
+	pk2 := addrToPK(t, resp.Address)
+	pk3 := crypto.PublicKey{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} // some public key we haven't imported
+
+	// Version 1 addresses must not carry weights
+	_, err = f.Client.ImportMultisigAddr([]byte(walletHandleToken), 1, 2, []crypto.PublicKey{pk1, pk2, pk3}, []uint8{2, 1, 1})
+	require.Error(t, err)
+
+	// Create a weighted multisig account in which pk1 alone can spend
+	pks := []crypto.PublicKey{pk1, pk2, pk3}
+	weights := []uint8{2, 1, 1}
+	resp1, err := f.Client.ImportMultisigAddr([]byte(walletHandleToken), crypto.WeightedMultisigVersion, 2, pks, weights)
+	require.NoError(t, err)
+	msigAddr := addrToPK(t, resp1.Address)
+
+	expected, err := crypto.MultisigAddrGenWeighted(2, pks, weights)
+	require.NoError(t, err)
+	require.Equal(t, basics.Address(expected).String(), resp1.Address)
+
+	// The weights are exported along with the preimage
+	resp2, err := f.Client.ExportMultisigAddr([]byte(walletHandleToken), resp1.Address)
+	require.NoError(t, err)
+	require.Equal(t, uint8(crypto.WeightedMultisigVersion), resp2.Version)
+	require.Equal(t, weights, resp2.Weights)
+
+	tx := transactions.Transaction{
+		Type: protocol.PaymentTx,
+		Header: transactions.Header{
+			Sender:     basics.Address(msigAddr),
+			Fee:        basics.MicroAlgos{Raw: config.Consensus[protocol.ConsensusCurrentVersion].MinTxnFee},
+			FirstValid: basics.Round(1),
+			LastValid:  basics.Round(1),
+		},
+	}
+
+	req3 := kmdapi.APIV1POSTMultisigTransactionSignRequest{
+		WalletHandleToken: walletHandleToken,
+		Transaction:       protocol.Encode(tx),
+		PublicKey:         pk1,
+		PartialMsig:       crypto.MultisigSig{},
+		WalletPassword:    f.WalletPassword,
+	}
+	resp3 := kmdapi.APIV1POSTMultisigTransactionSignResponse{}
+	err = f.Client.DoV1Request(req3, &resp3)
+	require.NoError(t, err)
+
+	var msig crypto.MultisigSig
+	err = protocol.Decode(resp3.Multisig, &msig)
+	require.NoError(t, err)
+	require.Equal(t, weights, msig.Weights())
+
+	ok, err := crypto.MultisigVerify(tx, crypto.Digest(msigAddr), msig)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
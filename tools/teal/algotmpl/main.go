@@ -19,6 +19,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -31,6 +32,7 @@ import (
 const tmplSuffix = ".teal.tmpl"
 
 var templateDir string
+var manifestFile string
 var dummyHelp bool
 
 func reportErrorf(format string, args ...interface{}) {
@@ -54,6 +56,7 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVarP(&templateDir, "directory", "d", "", "directory of templates")
 	rootCmd.MarkPersistentFlagRequired("directory")
+	rootCmd.PersistentFlags().StringVarP(&manifestFile, "manifest", "m", "", "write a JSON manifest of the filled-out template to this file")
 }
 
 var rootCmd = &cobra.Command{
@@ -128,6 +131,7 @@ func initCommandsFromDir(dirname string) error {
 	helptext := make([]string, len(fnames))
 	shorthelp := make([]string, len(fnames))
 	params := make([]paramSet, len(fnames))
+	schemas := make([]templateSchema, len(fnames))
 	filedata := make([]string, len(fnames))
 
 	for i, fname := range fnames {
@@ -137,6 +141,14 @@ func initCommandsFromDir(dirname string) error {
 		if err != nil {
 			return err
 		}
+		schemas[i], err = loadSchema(filepath.Join(dirname, fname+schemaSuffix))
+		if err != nil {
+			return err
+		}
+		err = schemas[i].checkParams(params[i])
+		if err != nil {
+			return fmt.Errorf("template %s: %v", fname, err)
+		}
 		data, err := ioutil.ReadFile(fullpath)
 		if err != nil {
 			return err
@@ -145,8 +157,8 @@ func initCommandsFromDir(dirname string) error {
 	}
 
 	for i := range fnames {
+		i := i
 		vars := make(map[string]*string)
-		progtext := filedata[i]
 
 		subCmd := &cobra.Command{
 			Use:   fnames[i],
@@ -154,12 +166,29 @@ func initCommandsFromDir(dirname string) error {
 			Long:  helptext[i],
 			Args:  cobra.NoArgs,
 			Run: func(cmd *cobra.Command, args []string) {
+				values := make(map[string]string, len(vars))
 				for k, v := range vars {
-					progtext = strings.ReplaceAll(progtext, k, *v)
+					values[k] = *v
+				}
+				progtext, err := fillTemplate(filedata[i], schemas[i], values)
+				if err != nil {
+					reportErrorf("template %s: %v", fnames[i], err)
+				}
+				manifest, err := buildManifest(fnames[i], progtext, values)
+				if err != nil {
+					reportErrorf("template %s: cannot assemble program: %v", fnames[i], err)
 				}
-				if strings.Contains(progtext, "TMPL_") {
-					reportErrorf("template %s fails to document all parameters", fnames[i])
+				if manifestFile != "" {
+					data, err := json.MarshalIndent(manifest, "", "  ")
+					if err != nil {
+						reportErrorf("cannot encode manifest: %v", err)
+					}
+					err = ioutil.WriteFile(manifestFile, append(data, '\n'), 0644)
+					if err != nil {
+						reportErrorf("cannot write manifest %s: %v", manifestFile, err)
+					}
 				}
+				fmt.Fprintf(os.Stderr, "%s: %s\n", fnames[i], manifest.Address)
 				fmt.Println(progtext)
 			},
 		}
@@ -0,0 +1,228 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+)
+
+const schemaSuffix = ".schema.json"
+
+// paramType is the type of a template parameter, as declared in the
+// template's schema file.
+type paramType string
+
+const (
+	// paramAddress is a checksummed Algorand address
+	paramAddress paramType = "address"
+	// paramUint64 is a decimal unsigned 64-bit integer
+	paramUint64 paramType = "uint64"
+	// paramRound is a decimal round number
+	paramRound paramType = "round"
+	// paramBytes is a base64-encoded byte string
+	paramBytes paramType = "bytes"
+	// paramString is a literal token substituted as-is; it must be one of Values
+	paramString paramType = "string"
+)
+
+// paramSchema describes the type and constraints of a single template parameter.
+type paramSchema struct {
+	Name string    `json:"name"`
+	Type paramType `json:"type"`
+
+	// Min and Max bound uint64 and round parameters (inclusive).
+	Min *uint64 `json:"min,omitempty"`
+	Max *uint64 `json:"max,omitempty"`
+
+	// Length is the exact decoded length of a bytes parameter, if set.
+	Length *int `json:"length,omitempty"`
+
+	// Values enumerates the accepted values of a string parameter.
+	Values []string `json:"values,omitempty"`
+}
+
+// templateSchema is the machine-readable description of a template's parameters.
+type templateSchema struct {
+	Params []paramSchema `json:"params"`
+}
+
+// templateManifest records how a template was instantiated.
+type templateManifest struct {
+	Template string            `json:"template"`
+	Params   map[string]string `json:"params"`
+	Address  string            `json:"address"`
+	Program  []byte            `json:"program"`
+}
+
+// loadSchema reads the schema for a template.  Every template must have
+// one, so that its parameters are validated.
+func loadSchema(filename string) (schema templateSchema, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("missing template schema %s", filename)
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		err = fmt.Errorf("%s: %v", filename, err)
+		return
+	}
+	for _, p := range schema.Params {
+		switch p.Type {
+		case paramAddress, paramUint64, paramRound, paramBytes:
+		case paramString:
+			if len(p.Values) == 0 {
+				err = fmt.Errorf("%s: string parameter %s has no values", filename, p.Name)
+				return
+			}
+		default:
+			err = fmt.Errorf("%s: parameter %s has unknown type '%s'", filename, p.Name, p.Type)
+			return
+		}
+	}
+	return
+}
+
+// lookup returns the schema entry for the named parameter.
+func (ts templateSchema) lookup(name string) (paramSchema, bool) {
+	for _, p := range ts.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return paramSchema{}, false
+}
+
+// checkParams ensures that the schema describes exactly the documented parameters.
+func (ts templateSchema) checkParams(params paramSet) error {
+	if len(ts.Params) == 0 {
+		return nil
+	}
+	for _, p := range params.params {
+		if _, ok := ts.lookup(p.name); !ok {
+			return fmt.Errorf("parameter %s is missing from the schema", p.name)
+		}
+	}
+	for _, p := range ts.Params {
+		found := false
+		for _, documented := range params.params {
+			if documented.name == p.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("schema parameter %s is not documented", p.Name)
+		}
+	}
+	return nil
+}
+
+// validate checks that value is well-formed for the parameter and satisfies its constraints.
+func (ps paramSchema) validate(value string) error {
+	switch ps.Type {
+	case paramAddress:
+		_, err := basics.UnmarshalChecksumAddress(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid address '%s': %v", ps.Name, value, err)
+		}
+	case paramUint64, paramRound:
+		x, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid %s '%s'", ps.Name, ps.Type, value)
+		}
+		if ps.Min != nil && x < *ps.Min {
+			return fmt.Errorf("%s: %d is less than the minimum %d", ps.Name, x, *ps.Min)
+		}
+		if ps.Max != nil && x > *ps.Max {
+			return fmt.Errorf("%s: %d is greater than the maximum %d", ps.Name, x, *ps.Max)
+		}
+	case paramBytes:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid base64 '%s': %v", ps.Name, value, err)
+		}
+		if ps.Length != nil && len(b) != *ps.Length {
+			return fmt.Errorf("%s: expected %d bytes but got %d", ps.Name, *ps.Length, len(b))
+		}
+	case paramString:
+		for _, v := range ps.Values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: '%s' is not one of %s", ps.Name, value, strings.Join(ps.Values, ", "))
+	}
+	return nil
+}
+
+// fillTemplate validates values against the schema and substitutes them into progtext.
+func fillTemplate(progtext string, schema templateSchema, values map[string]string) (string, error) {
+	for _, p := range schema.Params {
+		v, ok := values[p.Name]
+		if !ok {
+			return "", fmt.Errorf("missing value for parameter %s", p.Name)
+		}
+		err := p.validate(v)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// substitute longer names first so that no name clobbers another it prefixes
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	for _, k := range names {
+		progtext = strings.ReplaceAll(progtext, k, values[k])
+	}
+	if strings.Contains(progtext, "TMPL_") {
+		return "", fmt.Errorf("template fails to document all parameters")
+	}
+	return progtext, nil
+}
+
+// buildManifest assembles a filled-out template and derives its escrow address.
+func buildManifest(name string, progtext string, values map[string]string) (manifest templateManifest, err error) {
+	program, err := logic.AssembleString(progtext)
+	if err != nil {
+		return
+	}
+	manifest.Template = name
+	manifest.Params = values
+	manifest.Program = program
+	manifest.Address = basics.Address(crypto.HashObj(logic.Program(program))).String()
+	return
+}
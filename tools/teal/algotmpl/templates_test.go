@@ -0,0 +1,514 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/vincentbdb/go-algorand/config"
+	"github.com/vincentbdb/go-algorand/crypto"
+	"github.com/vincentbdb/go-algorand/data/basics"
+	"github.com/vincentbdb/go-algorand/data/transactions"
+	"github.com/vincentbdb/go-algorand/data/transactions/logic"
+	"github.com/vincentbdb/go-algorand/protocol"
+)
+
+const testTemplateDir = "../templates"
+
+var testLease = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+func testAddress(seed byte) basics.Address {
+	var addr basics.Address
+	for i := range addr {
+		addr[i] = seed
+	}
+	return addr
+}
+
+// instantiate fills out a bundled template and assembles it.
+func instantiate(t *testing.T, name string, values map[string]string) []byte {
+	fullpath := filepath.Join(testTemplateDir, name+tmplSuffix)
+	_, _, params, err := extractHelpFromFile(fullpath)
+	require.NoError(t, err)
+	schema, err := loadSchema(filepath.Join(testTemplateDir, name+schemaSuffix))
+	require.NoError(t, err)
+	require.NoError(t, schema.checkParams(params))
+
+	data, err := ioutil.ReadFile(fullpath)
+	require.NoError(t, err)
+	progtext, err := fillTemplate(string(data), schema, values)
+	require.NoError(t, err)
+	manifest, err := buildManifest(name, progtext, values)
+	require.NoError(t, err)
+	return manifest.Program
+}
+
+// dryrun evaluates the transaction at index idx of group, as `goal clerk dryrun` would.
+func dryrun(program []byte, group []transactions.SignedTxn, idx int, args ...[]byte) (bool, error) {
+	proto := config.Consensus[protocol.ConsensusCurrentVersion]
+	txgroup := make([]transactions.SignedTxnWithAD, len(group))
+	for i, st := range group {
+		txgroup[i].SignedTxn = st
+	}
+	txgroup[idx].Lsig.Logic = program
+	txgroup[idx].Lsig.Args = args
+
+	ep := logic.EvalParams{Txn: &txgroup[idx].SignedTxn, Proto: &proto}
+	_, err := logic.Check(program, ep)
+	if err != nil {
+		return false, err
+	}
+	sb := strings.Builder{}
+	ep = logic.EvalParams{
+		Txn:        &txgroup[idx].SignedTxn,
+		Proto:      &proto,
+		Trace:      &sb,
+		TxnGroup:   txgroup,
+		GroupIndex: idx,
+	}
+	return logic.Eval(program, ep)
+}
+
+func payment(sender, receiver basics.Address, amount uint64) transactions.SignedTxn {
+	var st transactions.SignedTxn
+	st.Txn.Type = protocol.PaymentTx
+	st.Txn.Sender = sender
+	st.Txn.Fee = basics.MicroAlgos{Raw: 1000}
+	st.Txn.FirstValid = 100
+	st.Txn.LastValid = 1100
+	st.Txn.Receiver = receiver
+	st.Txn.Amount = basics.MicroAlgos{Raw: amount}
+	return st
+}
+
+func assetTransfer(sender, receiver basics.Address, asset uint64, amount uint64) transactions.SignedTxn {
+	var st transactions.SignedTxn
+	st.Txn.Type = protocol.AssetTransferTx
+	st.Txn.Sender = sender
+	st.Txn.Fee = basics.MicroAlgos{Raw: 1000}
+	st.Txn.FirstValid = 100
+	st.Txn.LastValid = 1100
+	st.Txn.XferAsset = basics.AssetIndex(asset)
+	st.Txn.AssetReceiver = receiver
+	st.Txn.AssetAmount = amount
+	return st
+}
+
+func requirePass(t *testing.T, program []byte, group []transactions.SignedTxn, idx int, args ...[]byte) {
+	pass, err := dryrun(program, group, idx, args...)
+	require.NoError(t, err)
+	require.True(t, pass)
+}
+
+func requireReject(t *testing.T, program []byte, group []transactions.SignedTxn, idx int, args ...[]byte) {
+	pass, _ := dryrun(program, group, idx, args...)
+	require.False(t, pass)
+}
+
+func TestAllTemplatesHaveSchemas(t *testing.T) {
+	files, err := ioutil.ReadDir(testTemplateDir)
+	require.NoError(t, err)
+	count := 0
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), tmplSuffix) {
+			continue
+		}
+		count++
+		name := strings.TrimSuffix(f.Name(), tmplSuffix)
+		_, _, params, err := extractHelpFromFile(filepath.Join(testTemplateDir, f.Name()))
+		require.NoError(t, err)
+		schema, err := loadSchema(filepath.Join(testTemplateDir, name+schemaSuffix))
+		require.NoError(t, err)
+		require.NotEmpty(t, schema.Params, "template %s has no schema", name)
+		require.NoError(t, schema.checkParams(params), "template %s", name)
+	}
+	require.NotZero(t, count)
+}
+
+func TestMissingSchema(t *testing.T) {
+	_, err := loadSchema(filepath.Join(testTemplateDir, "no-such-template"+schemaSuffix))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing template schema")
+}
+
+func TestSchemaValidation(t *testing.T) {
+	one := uint64(1)
+	length := 32
+
+	addr := paramSchema{Name: "TMPL_OWN", Type: paramAddress}
+	require.NoError(t, addr.validate(testAddress(1).String()))
+	require.Error(t, addr.validate("WO3QIJ6T4DZHBX5PWJH26JLHFSRT7W7M2DJOULPXDTUS6TUX7ZRIO4KDFZ"))
+	require.Error(t, addr.validate(""))
+
+	round := paramSchema{Name: "TMPL_TIMEOUT", Type: paramRound, Min: &one}
+	require.NoError(t, round.validate("1000"))
+	require.Error(t, round.validate("-5"))
+	require.Error(t, round.validate("0"))
+	require.Error(t, round.validate("18446744073709551616"))
+
+	u := paramSchema{Name: "TMPL_FEE", Type: paramUint64}
+	require.NoError(t, u.validate("0"))
+	require.Error(t, u.validate("1.5"))
+
+	lease := paramSchema{Name: "TMPL_LEASE", Type: paramBytes, Length: &length}
+	require.NoError(t, lease.validate(testLease))
+	require.Error(t, lease.validate("aGVsbG8="))
+	require.Error(t, lease.validate("not base64!"))
+
+	fn := paramSchema{Name: "TMPL_HASHFN", Type: paramString, Values: []string{"sha256", "keccak256"}}
+	require.NoError(t, fn.validate("keccak256"))
+	require.Error(t, fn.validate("sha512_256"))
+
+	schema := templateSchema{Params: []paramSchema{addr, round}}
+	_, err := fillTemplate("addr TMPL_OWN\nint TMPL_TIMEOUT\n", schema, map[string]string{"TMPL_OWN": testAddress(1).String()})
+	require.Error(t, err)
+	_, err = fillTemplate("addr TMPL_OWN\nint TMPL_TIMEOUT\n", schema, map[string]string{"TMPL_OWN": testAddress(1).String(), "TMPL_TIMEOUT": "-1"})
+	require.Error(t, err)
+	text, err := fillTemplate("addr TMPL_OWN\nint TMPL_TIMEOUT\n", schema, map[string]string{"TMPL_OWN": testAddress(1).String(), "TMPL_TIMEOUT": "7"})
+	require.NoError(t, err)
+	require.Equal(t, "addr "+testAddress(1).String()+"\nint 7\n", text)
+}
+
+func TestManifestAddress(t *testing.T) {
+	values := map[string]string{
+		"TMPL_RCV":     testAddress(1).String(),
+		"TMPL_PERIOD":  "100",
+		"TMPL_DUR":     "95",
+		"TMPL_AMT":     "500000",
+		"TMPL_LEASE":   testLease,
+		"TMPL_TIMEOUT": "44000",
+		"TMPL_FEE":     "100000",
+	}
+	program := instantiate(t, "periodic-payment-escrow", values)
+	data, err := ioutil.ReadFile(filepath.Join(testTemplateDir, "periodic-payment-escrow"+tmplSuffix))
+	require.NoError(t, err)
+	schema, err := loadSchema(filepath.Join(testTemplateDir, "periodic-payment-escrow"+schemaSuffix))
+	require.NoError(t, err)
+	progtext, err := fillTemplate(string(data), schema, values)
+	require.NoError(t, err)
+	manifest, err := buildManifest("periodic-payment-escrow", progtext, values)
+	require.NoError(t, err)
+	require.Equal(t, program, manifest.Program)
+	require.Equal(t, basics.Address(crypto.HashObj(logic.Program(program))).String(), manifest.Address)
+}
+
+func TestAtomicSwapTemplate(t *testing.T) {
+	escrow, rcv, own := testAddress(1), testAddress(2), testAddress(3)
+	preimage := []byte("secret")
+	image := sha256.Sum256(preimage)
+	program := instantiate(t, "atomic-swap", map[string]string{
+		"TMPL_RCV":     rcv.String(),
+		"TMPL_HASHFN":  "sha256",
+		"TMPL_HASHIMG": base64.StdEncoding.EncodeToString(image[:]),
+		"TMPL_TIMEOUT": "3000",
+		"TMPL_OWN":     own.String(),
+		"TMPL_FEE":     "2000",
+	})
+
+	claim := payment(escrow, basics.Address{}, 0)
+	claim.Txn.CloseRemainderTo = rcv
+	requirePass(t, program, []transactions.SignedTxn{claim}, 0, preimage)
+	requireReject(t, program, []transactions.SignedTxn{claim}, 0, []byte("wrong"))
+
+	refund := payment(escrow, basics.Address{}, 0)
+	refund.Txn.CloseRemainderTo = own
+	requireReject(t, program, []transactions.SignedTxn{refund}, 0, preimage)
+	refund.Txn.FirstValid = 3001
+	requirePass(t, program, []transactions.SignedTxn{refund}, 0, preimage)
+}
+
+func TestDelegateKeyRegistrationTemplate(t *testing.T) {
+	var seed crypto.Seed
+	seed[0] = 1
+	secrets := crypto.GenerateSignatureSecrets(seed)
+	program := instantiate(t, "delegate-key-registration", map[string]string{
+		"TMPL_AUTH":   basics.Address(secrets.SignatureVerifier).String(),
+		"TMPL_EXPIRE": "10000",
+		"TMPL_PERIOD": "100",
+		"TMPL_DUR":    "95",
+		"TMPL_LEASE":  testLease,
+		"TMPL_FEE":    "100000",
+	})
+
+	var keyreg transactions.SignedTxn
+	keyreg.Txn.Type = protocol.KeyRegistrationTx
+	keyreg.Txn.Sender = testAddress(1)
+	keyreg.Txn.Fee = basics.MicroAlgos{Raw: 1000}
+	keyreg.Txn.FirstValid = 200
+	keyreg.Txn.LastValid = 295
+	txid := keyreg.Txn.ID()
+	sig := secrets.Sign(logic.Msg{ProgramHash: crypto.HashObj(logic.Program(program)), Data: txid[:]})
+	requirePass(t, program, []transactions.SignedTxn{keyreg}, 0, sig[:])
+
+	var badSig crypto.Signature
+	requireReject(t, program, []transactions.SignedTxn{keyreg}, 0, badSig[:])
+
+	keyreg.Txn.FirstValid = 201
+	keyreg.Txn.LastValid = 296
+	txid = keyreg.Txn.ID()
+	sig = secrets.Sign(logic.Msg{ProgramHash: crypto.HashObj(logic.Program(program)), Data: txid[:]})
+	requireReject(t, program, []transactions.SignedTxn{keyreg}, 0, sig[:])
+}
+
+func dynamicFeeValues(to basics.Address, pool string) map[string]string {
+	return map[string]string{
+		"TMPL_TO":    to.String(),
+		"TMPL_CLS":   basics.Address{}.String(),
+		"TMPL_AMT":   "100000",
+		"TMPL_FV":    "100",
+		"TMPL_LV":    "1100",
+		"TMPL_LEASE": testLease,
+		"TMPL_POOL":  pool,
+	}
+}
+
+func TestDynamicFeeTemplate(t *testing.T) {
+	owner, funder, to := testAddress(1), testAddress(2), testAddress(3)
+	program := instantiate(t, "dynamic-fee", dynamicFeeValues(to, "0"))
+
+	pay := payment(owner, to, 100000)
+	fund := payment(funder, owner, pay.Txn.Fee.Raw)
+	requirePass(t, program, []transactions.SignedTxn{fund, pay}, 1)
+
+	fund.Txn.Amount.Raw = pay.Txn.Fee.Raw - 1
+	requireReject(t, program, []transactions.SignedTxn{fund, pay}, 1)
+	requireReject(t, program, []transactions.SignedTxn{pay}, 0)
+
+	// a zero-fee payment in the first slot is only allowed in pooled mode
+	pay.Txn.Fee = basics.MicroAlgos{}
+	fund = payment(funder, funder, 0)
+	fund.Txn.Fee = basics.MicroAlgos{Raw: 2000}
+	requireReject(t, program, []transactions.SignedTxn{pay, fund}, 0)
+}
+
+func TestDynamicFeeTemplatePooled(t *testing.T) {
+	owner, funder, to := testAddress(1), testAddress(2), testAddress(3)
+	program := instantiate(t, "dynamic-fee", dynamicFeeValues(to, "1"))
+
+	pay := payment(owner, to, 100000)
+	pay.Txn.Fee = basics.MicroAlgos{}
+	fund := payment(funder, funder, 0)
+	fund.Txn.Fee = basics.MicroAlgos{Raw: 2000}
+	requirePass(t, program, []transactions.SignedTxn{pay, fund}, 0)
+
+	requireReject(t, program, []transactions.SignedTxn{fund, pay}, 1)
+	requireReject(t, program, []transactions.SignedTxn{pay}, 0)
+	pay.Txn.Fee = basics.MicroAlgos{Raw: 1}
+	requireReject(t, program, []transactions.SignedTxn{pay, fund}, 0)
+
+	// the reimbursement shape of the unpooled mode does not pass either
+	pay = payment(owner, to, 100000)
+	fund = payment(funder, owner, pay.Txn.Fee.Raw)
+	requireReject(t, program, []transactions.SignedTxn{fund, pay}, 1)
+}
+
+func TestDynamicFeeTemplateMode(t *testing.T) {
+	schema, err := loadSchema(filepath.Join(testTemplateDir, "dynamic-fee"+schemaSuffix))
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(testTemplateDir, "dynamic-fee"+tmplSuffix))
+	require.NoError(t, err)
+	_, err = fillTemplate(string(data), schema, dynamicFeeValues(testAddress(3), "2"))
+	require.Error(t, err)
+}
+
+func limitOrderValues(own basics.Address) map[string]string {
+	return map[string]string{
+		"TMPL_ASSET":   "39",
+		"TMPL_SWAPN":   "3",
+		"TMPL_SWAPD":   "2",
+		"TMPL_TIMEOUT": "150000",
+		"TMPL_OWN":     own.String(),
+		"TMPL_FEE":     "100000",
+		"TMPL_MINTRD":  "1000",
+	}
+}
+
+func TestLimitOrderTemplate(t *testing.T) {
+	escrow, own, taker := testAddress(1), testAddress(2), testAddress(3)
+	program := instantiate(t, "limit-order", limitOrderValues(own))
+
+	algos := payment(escrow, taker, 2000)
+	algos.Txn.CloseRemainderTo = own
+	asset := assetTransfer(taker, own, 39, 3001)
+	requirePass(t, program, []transactions.SignedTxn{algos, asset}, 0)
+
+	asset.Txn.AssetAmount = 3000
+	requireReject(t, program, []transactions.SignedTxn{algos, asset}, 0)
+}
+
+func TestLimitOrderATemplate(t *testing.T) {
+	escrow, own, taker := testAddress(1), testAddress(2), testAddress(3)
+	program := instantiate(t, "limit-order-a", limitOrderValues(own))
+
+	algos := payment(escrow, taker, 2000)
+	asset := assetTransfer(taker, own, 39, 3000)
+	requirePass(t, program, []transactions.SignedTxn{algos, asset}, 0)
+
+	asset.Txn.AssetAmount = 2999
+	requireReject(t, program, []transactions.SignedTxn{algos, asset}, 0)
+
+	closeOut := payment(escrow, basics.Address{}, 0)
+	closeOut.Txn.CloseRemainderTo = own
+	requireReject(t, program, []transactions.SignedTxn{closeOut}, 0)
+	closeOut.Txn.FirstValid = 150001
+	requirePass(t, program, []transactions.SignedTxn{closeOut}, 0)
+}
+
+func TestLimitOrderBTemplate(t *testing.T) {
+	escrow, own, taker := testAddress(1), testAddress(2), testAddress(3)
+	program := instantiate(t, "limit-order-b", limitOrderValues(own))
+
+	algos := payment(taker, own, 3000)
+	asset := assetTransfer(escrow, taker, 39, 2000)
+	requirePass(t, program, []transactions.SignedTxn{algos, asset}, 1)
+
+	asset.Txn.XferAsset = 40
+	requireReject(t, program, []transactions.SignedTxn{algos, asset}, 1)
+
+	setup := assetTransfer(escrow, escrow, 39, 0)
+	requirePass(t, program, []transactions.SignedTxn{setup}, 0)
+	setup.Txn.LastValid = 150000
+	requireReject(t, program, []transactions.SignedTxn{setup}, 0)
+}
+
+func TestPeriodicPaymentEscrowTemplate(t *testing.T) {
+	escrow, rcv := testAddress(1), testAddress(2)
+	program := instantiate(t, "periodic-payment-escrow", map[string]string{
+		"TMPL_RCV":     rcv.String(),
+		"TMPL_PERIOD":  "100",
+		"TMPL_DUR":     "95",
+		"TMPL_AMT":     "500000",
+		"TMPL_LEASE":   testLease,
+		"TMPL_TIMEOUT": "44000",
+		"TMPL_FEE":     "100000",
+	})
+
+	withdraw := payment(escrow, rcv, 500000)
+	withdraw.Txn.FirstValid = 1000
+	withdraw.Txn.LastValid = 1095
+	requirePass(t, program, []transactions.SignedTxn{withdraw}, 0)
+
+	withdraw.Txn.RekeyTo = rcv
+	requireReject(t, program, []transactions.SignedTxn{withdraw}, 0)
+
+	withdraw.Txn.RekeyTo = basics.Address{}
+	withdraw.Txn.Amount.Raw = 500001
+	requireReject(t, program, []transactions.SignedTxn{withdraw}, 0)
+
+	withdraw.Txn.Amount.Raw = 500000
+	withdraw.Txn.FirstValid = 1001
+	withdraw.Txn.LastValid = 1096
+	requireReject(t, program, []transactions.SignedTxn{withdraw}, 0)
+}
+
+func TestSplitTemplate(t *testing.T) {
+	escrow, rcv1, rcv2, own := testAddress(1), testAddress(2), testAddress(3), testAddress(4)
+	program := instantiate(t, "split", map[string]string{
+		"TMPL_RCV1":    rcv1.String(),
+		"TMPL_RCV2":    rcv2.String(),
+		"TMPL_RAT1":    "30",
+		"TMPL_RAT2":    "70",
+		"TMPL_MINPAY":  "100000",
+		"TMPL_TIMEOUT": "60000",
+		"TMPL_OWN":     own.String(),
+		"TMPL_FEE":     "20000",
+	})
+
+	pay1 := payment(escrow, rcv1, 300000)
+	pay2 := payment(escrow, rcv2, 700000)
+	group := []transactions.SignedTxn{pay1, pay2}
+	requirePass(t, program, group, 0)
+	requirePass(t, program, group, 1)
+
+	pay1.Txn.RekeyTo = own
+	group = []transactions.SignedTxn{pay1, pay2}
+	requireReject(t, program, group, 0)
+
+	pay1.Txn.RekeyTo = basics.Address{}
+	pay2.Txn.Amount.Raw = 700001
+	group = []transactions.SignedTxn{pay1, pay2}
+	requireReject(t, program, group, 0)
+
+	pay1.Txn.Amount.Raw = 30000
+	pay2.Txn.Amount.Raw = 70000
+	group = []transactions.SignedTxn{pay1, pay2}
+	requireReject(t, program, group, 0)
+}
+
+func TestVestingTemplate(t *testing.T) {
+	escrow, rcv := testAddress(1), testAddress(2)
+	program := instantiate(t, "vesting", map[string]string{
+		"TMPL_RCV":       rcv.String(),
+		"TMPL_CLIFF":     "1000",
+		"TMPL_CLIFF_AMT": "2000000",
+		"TMPL_PERIOD":    "100",
+		"TMPL_AMT":       "500000",
+		"TMPL_DUR":       "99",
+		"TMPL_END":       "5000",
+		"TMPL_LEASE":     testLease,
+		"TMPL_FEE":       "100000",
+	})
+
+	cliff := payment(escrow, rcv, 2000000)
+	cliff.Txn.FirstValid = 1000
+	cliff.Txn.LastValid = 1099
+	requirePass(t, program, []transactions.SignedTxn{cliff}, 0)
+
+	cliff.Txn.FirstValid = 900
+	cliff.Txn.LastValid = 999
+	requireReject(t, program, []transactions.SignedTxn{cliff}, 0)
+
+	release := payment(escrow, rcv, 500000)
+	release.Txn.FirstValid = 1100
+	release.Txn.LastValid = 1199
+	requirePass(t, program, []transactions.SignedTxn{release}, 0)
+
+	// a release that rekeys the escrow is rejected
+	release.Txn.RekeyTo = rcv
+	requireReject(t, program, []transactions.SignedTxn{release}, 0)
+	release.Txn.RekeyTo = basics.Address{}
+
+	// a periodic release before the cliff, or off the period, is locked
+	release.Txn.FirstValid = 900
+	release.Txn.LastValid = 999
+	requireReject(t, program, []transactions.SignedTxn{release}, 0)
+	release.Txn.FirstValid = 1101
+	release.Txn.LastValid = 1200
+	requireReject(t, program, []transactions.SignedTxn{release}, 0)
+
+	release.Txn.FirstValid = 1100
+	release.Txn.LastValid = 1199
+	release.Txn.Amount.Raw = 500001
+	requireReject(t, program, []transactions.SignedTxn{release}, 0)
+
+	closeOut := payment(escrow, basics.Address{}, 0)
+	closeOut.Txn.CloseRemainderTo = rcv
+	closeOut.Txn.FirstValid = 5001
+	closeOut.Txn.LastValid = 5100
+	requirePass(t, program, []transactions.SignedTxn{closeOut}, 0)
+
+	closeOut.Txn.FirstValid = 4900
+	closeOut.Txn.LastValid = 4999
+	requireReject(t, program, []transactions.SignedTxn{closeOut}, 0)
+}
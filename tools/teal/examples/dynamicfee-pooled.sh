@@ -0,0 +1,26 @@
+#!/usr/bin/env bash
+
+# produce TEAL assembly for a delegated logic signature on a payment whose fee is pooled with another transaction, and compile it (note the required lease value)
+algotmpl -d `git rev-parse --show-toplevel`/tools/teal/templates dynamic-fee --cls AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ --to NBH2CNQCPV7S2ZOSWVQ7JLFO7W5JERULPSGYUP4ZAEOLSVMTYODHVY37VQ --fv 170500 --lv 171500 --amt 100000 --lease uFVDhjBpkpKQ8sZaau0qsDsf0eW3oXFEn1Ar5o39vkk= --pool 1 > dynamic-pooled.teal
+goal clerk compile -a EVADN3MAXLTUAZFLJNIN7RD7WCNEPTB37GJMD5OACMVBKTGFXYYFMS5IT4 -s -o dynamic-pooled.lsig dynamic-pooled.teal -d .
+
+# make the main, unsigned transaction which executes the intended transfer
+goal clerk send -o main.tx -f EVADN3MAXLTUAZFLJNIN7RD7WCNEPTB37GJMD5OACMVBKTGFXYYFMS5IT4 -a 100000 -t NBH2CNQCPV7S2ZOSWVQ7JLFO7W5JERULPSGYUP4ZAEOLSVMTYODHVY37VQ --firstvalid 170500 --lastvalid 171500 -x uFVDhjBpkpKQ8sZaau0qsDsf0eW3oXFEn1Ar5o39vkk= -d .
+
+# make the auxiliary transaction, a zero-amount payment from the fee payer to itself
+goal clerk send -o aux.tx -f WO3QIJ6T4DZHBX5PWJH26JLHFSRT7W7M2DJOULPXDTUS6TUX7ZRIO4KDFY -a 0 -t WO3QIJ6T4DZHBX5PWJH26JLHFSRT7W7M2DJOULPXDTUS6TUX7ZRIO4KDFY -d .
+
+# group the transactions, moving the fee of the main transaction onto the auxiliary one, and re-split them in preparation for signing
+cat main.tx aux.tx > testcmd.tx
+goal clerk group --fee-payer 1 -i testcmd.tx -o testgrp.tx
+goal clerk split -i testgrp.tx -o testraw.tx
+# > Wrote transaction 0 to testraw-0.tx
+# > Wrote transaction 1 to testraw-1.tx
+
+# sign the first transaction with the delegated logic signature (and the second transaction with the standard signature)
+goal clerk sign -L dynamic-pooled.lsig -i testraw-0.tx -o testraw-0.stx -d .
+goal clerk sign -i testraw-1.tx -o testraw-1.stx -d .
+cat testraw-0.stx testraw-1.stx > testraw.stx
+
+# send the group transaction to the network
+goal clerk rawsend -f testraw.stx -d .
@@ -0,0 +1,221 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package kvstore
+
+// keyNode is a node of a key tree: an immutable sorted map from keys to
+// the location of their values, kept as a treap.  A node's priority is a
+// hash of its key, so the shape of a tree depends only on its keys.
+//
+// Changing a tree copies the path to the changed key and shares the rest,
+// so a transaction can keep reading the tree it started with while later
+// commits build new ones.  A nil *keyNode is the empty tree.
+type keyNode struct {
+	key   string
+	ref   valueRef
+	prio  uint64
+	left  *keyNode
+	right *keyNode
+}
+
+// keyPriority hashes key with FNV-1a, and mixes the result so that
+// similar keys get unrelated priorities.
+func keyPriority(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
+
+// get returns the location of key's value, and whether key is in the tree.
+func (n *keyNode) get(key string) (valueRef, bool) {
+	for n != nil {
+		switch {
+		case key < n.key:
+			n = n.left
+		case key > n.key:
+			n = n.right
+		default:
+			return n.ref, true
+		}
+	}
+	return valueRef{}, false
+}
+
+// put returns a tree in which key's value is at ref.
+func (n *keyNode) put(key string, ref valueRef) *keyNode {
+	if n == nil {
+		return &keyNode{key: key, ref: ref, prio: keyPriority(key)}
+	}
+
+	c := *n
+	switch {
+	case key < n.key:
+		c.left = n.left.put(key, ref)
+		if c.left.prio > c.prio {
+			// c.left is a fresh copy, so it can be rotated in place.
+			l := c.left
+			c.left = l.right
+			l.right = &c
+			return l
+		}
+	case key > n.key:
+		c.right = n.right.put(key, ref)
+		if c.right.prio > c.prio {
+			r := c.right
+			c.right = r.left
+			r.left = &c
+			return r
+		}
+	default:
+		c.ref = ref
+	}
+	return &c
+}
+
+// remove returns a tree without key.
+func (n *keyNode) remove(key string) *keyNode {
+	if n == nil {
+		return nil
+	}
+
+	switch {
+	case key < n.key:
+		l := n.left.remove(key)
+		if l == n.left {
+			return n
+		}
+		c := *n
+		c.left = l
+		return &c
+	case key > n.key:
+		r := n.right.remove(key)
+		if r == n.right {
+			return n
+		}
+		c := *n
+		c.right = r
+		return &c
+	default:
+		return mergeKeyTrees(n.left, n.right)
+	}
+}
+
+// mergeKeyTrees joins two trees, all of whose keys in a are smaller
+// than those in b.
+func mergeKeyTrees(a *keyNode, b *keyNode) *keyNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.prio > b.prio {
+		c := *a
+		c.right = mergeKeyTrees(a.right, b)
+		return &c
+	}
+	c := *b
+	c.left = mergeKeyTrees(a, b.left)
+	return &c
+}
+
+// keyTreeBuilder builds a tree from keys added in ascending order, in
+// linear time.
+type keyTreeBuilder struct {
+	// spine is the right spine of the tree built so far, from the root down.
+	spine []*keyNode
+}
+
+func (b *keyTreeBuilder) add(key string, ref valueRef) {
+	n := &keyNode{key: key, ref: ref, prio: keyPriority(key)}
+	var last *keyNode
+	for len(b.spine) > 0 && b.spine[len(b.spine)-1].prio < n.prio {
+		last = b.spine[len(b.spine)-1]
+		b.spine = b.spine[:len(b.spine)-1]
+	}
+	n.left = last
+	if len(b.spine) > 0 {
+		b.spine[len(b.spine)-1].right = n
+	}
+	b.spine = append(b.spine, n)
+}
+
+func (b *keyTreeBuilder) root() *keyNode {
+	if len(b.spine) == 0 {
+		return nil
+	}
+	return b.spine[0]
+}
+
+// keyIter walks a tree in ascending or descending order of keys.
+type keyIter struct {
+	stack   []*keyNode
+	reverse bool
+}
+
+// ascend returns an iterator over the keys of n at or after from.
+func (n *keyNode) ascend(from string) *keyIter {
+	it := &keyIter{}
+	for n != nil {
+		if n.key >= from {
+			it.stack = append(it.stack, n)
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return it
+}
+
+// descend returns an iterator over the keys of n before to, in descending
+// order.  A nil to means no upper bound.
+func (n *keyNode) descend(to []byte) *keyIter {
+	it := &keyIter{reverse: true}
+	for n != nil {
+		if to == nil || n.key < string(to) {
+			it.stack = append(it.stack, n)
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return it
+}
+
+// next returns the next node, or nil when there are no more.
+func (it *keyIter) next() *keyNode {
+	if len(it.stack) == 0 {
+		return nil
+	}
+	n := it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	if it.reverse {
+		for x := n.left; x != nil; x = x.right {
+			it.stack = append(it.stack, x)
+		}
+	} else {
+		for x := n.right; x != nil; x = x.left {
+			it.stack = append(it.stack, x)
+		}
+	}
+	return n
+}
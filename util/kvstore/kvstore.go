@@ -0,0 +1,867 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package kvstore implements a simple embedded key-value store.
+//
+// Every key, along with the location of its value, is kept in memory;
+// values live in an append-only log file.  Each committed transaction is
+// appended to the log as a single checksummed record and fsynced, so a
+// crash loses at most the transaction being written.  When most of the log
+// is garbage, it is compacted by rewriting the live values into a new file.
+//
+// There is no write-ahead log to checkpoint and no page cache to manage,
+// which makes the store cheap to write to, at the cost of keeping the whole
+// key set in memory and replaying the log on startup.
+//
+// Read-only transactions see the store as of when they started, and never
+// block commits, however long they run.
+package kvstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+
+	"github.com/algorand/go-deadlock"
+
+	"github.com/vincentbdb/go-algorand/logging"
+)
+
+// ErrReadOnly is returned when modifying the store in a read-only
+// transaction, or in a store opened with OpenReadOnly.
+var ErrReadOnly = errors.New("kvstore: transaction is read-only")
+
+const (
+	opPut    = 1
+	opDelete = 2
+
+	// The log starts with fileMagic and a random salt.
+	fileMagic      = "algokv01"
+	fileHeaderSize = len(fileMagic) + 8
+
+	// A record header holds the payload length, the payload checksum,
+	// and a checksum of the salt and those two, so that a damaged length
+	// is caught before it is used.  The salt keeps values that happen to
+	// contain records, perhaps on purpose, from passing for records.
+	recordHeaderSize = 12
+
+	// maxRecordSize bounds the records written during compaction.
+	// Ordinary transactions are written as one record, however large.
+	maxRecordSize = 1 << 20
+
+	// compactMinSize is the log size below which we never compact.
+	compactMinSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// storageFile is the log: an *os.File, or a memFile for in-memory stores.
+type storageFile interface {
+	io.ReaderAt
+	io.WriterAt
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// memFile keeps the log in memory.  Its own lock lets commit append to it
+// while View transactions read it, as they can with an *os.File.
+type memFile struct {
+	mu  deadlock.RWMutex
+	buf []byte
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if off >= int64(len(f.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	end := off + int64(len(p))
+	if end > int64(len(f.buf)) {
+		f.buf = append(f.buf, make([]byte, end-int64(len(f.buf)))...)
+	}
+	return copy(f.buf[off:], p), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buf = f.buf[:size]
+	return nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+func (f *memFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buf = nil
+	return nil
+}
+
+// valueRef locates a value in the log.
+type valueRef struct {
+	off int64
+	len int
+}
+
+// logFile is a log shared by the store and the transactions reading it.
+// It is closed once all of them have released it, so compaction can
+// replace the store's log while transactions still read the old one.
+type logFile struct {
+	file storageFile
+	refs int32
+}
+
+func (l *logFile) acquire() {
+	atomic.AddInt32(&l.refs, 1)
+}
+
+func (l *logFile) release() error {
+	if atomic.AddInt32(&l.refs, -1) == 0 {
+		return l.file.Close()
+	}
+	return nil
+}
+
+// snapshot is a committed state of the store.
+type snapshot struct {
+	root *keyNode
+	log  *logFile
+}
+
+func (s *snapshot) read(ref valueRef) ([]byte, error) {
+	value := make([]byte, ref.len)
+	if ref.len == 0 {
+		return value, nil
+	}
+	_, err := s.log.file.ReadAt(value, ref.off)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// DB is a key-value store.
+type DB struct {
+	filename string
+	readOnly bool
+
+	// salt is the checksum of the log's salt, which record header
+	// checksums continue from.
+	salt uint32
+
+	// wmu serializes read-write transactions, and protects size and
+	// live.  Only holders of wmu replace cur.
+	wmu  deadlock.Mutex
+	size int64
+	live int64
+
+	// mu protects cur, which is nil once the store is closed.
+	mu  deadlock.RWMutex
+	cur *snapshot
+}
+
+// Open opens the store in filename, creating it if it does not exist.  If
+// memory is set, the store is kept in memory and filename is ignored.
+func Open(filename string, memory bool) (*DB, error) {
+	db := &DB{filename: filename}
+
+	if memory {
+		f := &memFile{}
+		err := db.initLog(f)
+		if err != nil {
+			return nil, err
+		}
+		db.cur = &snapshot{log: &logFile{file: f, refs: 1}}
+		return db, nil
+	}
+
+	// A leftover from an interrupted compaction; the log is intact.
+	os.Remove(filename + ".compact")
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.load(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenReadOnly opens an existing store in filename without modifying it.
+// Read-write transactions on the store fail with ErrReadOnly.
+func OpenReadOnly(filename string) (*DB, error) {
+	db := &DB{filename: filename, readOnly: true}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.load(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// initLog starts a new, empty log in f with a fresh salt.
+func (db *DB) initLog(f storageFile) error {
+	var header [fileHeaderSize]byte
+	copy(header[:], fileMagic)
+	_, err := rand.Read(header[len(fileMagic):])
+	if err != nil {
+		return err
+	}
+	err = db.writeLogHeader(f, header[:])
+	if err != nil {
+		return err
+	}
+	db.salt = crc32.Checksum(header[len(fileMagic):], crcTable)
+	db.size = int64(fileHeaderSize)
+	return nil
+}
+
+func (db *DB) writeLogHeader(f storageFile, header []byte) error {
+	_, err := f.WriteAt(header, 0)
+	if err == nil {
+		err = f.Sync()
+	}
+	return err
+}
+
+func (db *DB) recordHeaderValid(header []byte) bool {
+	return crc32.Update(db.salt, crcTable, header[0:8]) == binary.BigEndian.Uint32(header[8:12])
+}
+
+// load replays the log, truncating any partially-written record at its end.
+// A damaged record anywhere else is an error: dropping it would silently
+// drop every record after it too.
+func (db *DB) load(f *os.File) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := stat.Size()
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	var fileHeader [fileHeaderSize]byte
+	switch {
+	case fileSize >= int64(fileHeaderSize):
+		_, err = io.ReadFull(r, fileHeader[:])
+		if err != nil {
+			return fmt.Errorf("kvstore: %s: %v", db.filename, err)
+		}
+		if !bytes.Equal(fileHeader[:len(fileMagic)], []byte(fileMagic)) {
+			return fmt.Errorf("kvstore: %s is not a key-value store", db.filename)
+		}
+		db.salt = crc32.Checksum(fileHeader[len(fileMagic):], crcTable)
+		db.size = int64(fileHeaderSize)
+	case db.readOnly:
+		// Empty, or torn while being created.
+		fileSize = 0
+	default:
+		err = f.Truncate(0)
+		if err == nil {
+			err = db.initLog(f)
+		}
+		if err != nil {
+			return err
+		}
+		fileSize = db.size
+	}
+
+	index := make(map[string]valueRef)
+	var header [recordHeaderSize]byte
+	for {
+		if db.size+recordHeaderSize > fileSize {
+			// Nothing, or a torn header, is left.
+			break
+		}
+		_, err := io.ReadFull(r, header[:])
+		if err != nil {
+			return fmt.Errorf("kvstore: %s: record at %d: %v", db.filename, db.size, err)
+		}
+
+		// A crash while writing the last record can leave any part of
+		// it unwritten, including its header.  A damaged header is that
+		// torn record, unless whole records follow it.
+		if !db.recordHeaderValid(header[:]) {
+			follow, err := db.recordsFollow(f, db.size+1, fileSize)
+			if err != nil {
+				return fmt.Errorf("kvstore: %s: record at %d: %v", db.filename, db.size, err)
+			}
+			if follow {
+				return fmt.Errorf("kvstore: %s: record at %d has a bad header", db.filename, db.size)
+			}
+			break
+		}
+
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		end := db.size + recordHeaderSize + length
+		if end > fileSize {
+			// The header is intact, so the record was cut short.
+			break
+		}
+
+		payload := make([]byte, length)
+		_, err = io.ReadFull(r, payload)
+		if err != nil {
+			return fmt.Errorf("kvstore: %s: record at %d: %v", db.filename, db.size, err)
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			if end == fileSize {
+				// The last record was torn by a crash while being written.
+				break
+			}
+			return fmt.Errorf("kvstore: %s: record at %d has a bad checksum", db.filename, db.size)
+		}
+
+		err = db.replay(index, payload, db.size+recordHeaderSize)
+		if err != nil {
+			return fmt.Errorf("kvstore: %s: record at %d: %v", db.filename, db.size, err)
+		}
+		db.size = end
+	}
+
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b keyTreeBuilder
+	for _, key := range keys {
+		b.add(key, index[key])
+	}
+	db.cur = &snapshot{root: b.root(), log: &logFile{file: f, refs: 1}}
+
+	if db.readOnly {
+		return nil
+	}
+	return f.Truncate(db.size)
+}
+
+// recordsFollow reports whether a run of whole, well-formed records starts
+// anywhere from offset from and ends exactly at the end of the file.  It is
+// only used to tell a damaged record from a torn one.  Candidate starts are
+// those with a valid header checksum, and no offset is followed twice, so
+// it reads the rest of the file about once.
+func (db *DB) recordsFollow(f io.ReaderAt, from int64, fileSize int64) (bool, error) {
+	// dead holds the offsets from which no run reaches the end.
+	dead := make(map[int64]bool)
+	follow := func(off int64) (bool, error) {
+		var header [recordHeaderSize]byte
+		for off != fileSize {
+			if dead[off] || off+recordHeaderSize > fileSize {
+				return false, nil
+			}
+			dead[off] = true
+			_, err := f.ReadAt(header[:], off)
+			if err != nil {
+				return false, err
+			}
+			// The store never writes empty records, so runs of zeros,
+			// which could look like them, do not count.
+			length := int64(binary.BigEndian.Uint32(header[0:4]))
+			if !db.recordHeaderValid(header[:]) || length == 0 || off+recordHeaderSize+length > fileSize {
+				return false, nil
+			}
+			payload := make([]byte, length)
+			_, err = f.ReadAt(payload, off+recordHeaderSize)
+			if err != nil {
+				return false, err
+			}
+			if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+				return false, nil
+			}
+			off += recordHeaderSize + length
+		}
+		return true, nil
+	}
+
+	const window = 1 << 20
+	buf := make([]byte, window+recordHeaderSize-1)
+	for base := from; base+recordHeaderSize <= fileSize; base += window {
+		n := int64(len(buf))
+		if base+n > fileSize {
+			n = fileSize - base
+		}
+		_, err := f.ReadAt(buf[:n], base)
+		if err != nil {
+			return false, err
+		}
+		for i := int64(0); i+recordHeaderSize <= n && i < window; i++ {
+			if !db.recordHeaderValid(buf[i : i+recordHeaderSize]) {
+				continue
+			}
+			ok, err := follow(base + i)
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// replay applies the operations in a record whose payload starts at offset
+// base to index, and keeps track of the live data size.
+func (db *DB) replay(index map[string]valueRef, payload []byte, base int64) error {
+	pos := 0
+	readBytes := func() ([]byte, int, error) {
+		n, l := binary.Uvarint(payload[pos:])
+		if l <= 0 || uint64(len(payload)-pos-l) < n {
+			return nil, 0, fmt.Errorf("malformed record")
+		}
+		start := pos + l
+		pos = start + int(n)
+		return payload[start:pos], start, nil
+	}
+
+	for pos < len(payload) {
+		op := payload[pos]
+		pos++
+		key, _, err := readBytes()
+		if err != nil {
+			return err
+		}
+
+		if old, ok := index[string(key)]; ok {
+			db.live -= int64(len(key) + old.len)
+			delete(index, string(key))
+		}
+
+		switch op {
+		case opPut:
+			value, start, err := readBytes()
+			if err != nil {
+				return err
+			}
+			index[string(key)] = valueRef{off: base + int64(start), len: len(value)}
+			db.live += int64(len(key) + len(value))
+		case opDelete:
+		default:
+			return fmt.Errorf("unknown operation %d", op)
+		}
+	}
+	return nil
+}
+
+// setCurrent makes snap the store's current state.  The caller must hold
+// db.wmu.
+func (db *DB) setCurrent(snap *snapshot) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.cur = snap
+}
+
+// Close closes the store.  Its log is closed once the transactions still
+// reading it are done.
+func (db *DB) Close() error {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	cur := db.cur
+	if cur == nil {
+		return nil
+	}
+	db.setCurrent(nil)
+	return cur.log.release()
+}
+
+// View runs fn in a read-only transaction.  fn sees a consistent snapshot
+// of the store as of when View was called.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	db.mu.RLock()
+	snap := db.cur
+	if snap != nil {
+		snap.log.acquire()
+	}
+	db.mu.RUnlock()
+	if snap == nil {
+		return fmt.Errorf("kvstore: %s is closed", db.filename)
+	}
+	defer snap.log.release()
+	return fn(&Tx{snap: snap})
+}
+
+// Update runs fn in a read-write transaction, and commits its changes if fn
+// returns nil.  Update transactions are serialized, but may run concurrently
+// with View transactions, which do not see uncommitted changes.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	if db.cur == nil {
+		return fmt.Errorf("kvstore: %s is closed", db.filename)
+	}
+	if db.readOnly {
+		return ErrReadOnly
+	}
+
+	tx := &Tx{snap: db.cur, pending: make(map[string][]byte)}
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+	return db.commit(tx.pending)
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	var lenbuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenbuf[:], uint64(len(b)))
+	buf = append(buf, lenbuf[:n]...)
+	return append(buf, b...)
+}
+
+func (db *DB) frame(payload []byte) []byte {
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	binary.BigEndian.PutUint32(record[8:12], crc32.Update(db.salt, crcTable, record[0:8]))
+	return append(record, payload...)
+}
+
+// commit writes pending changes to the log and makes them current.
+// The caller must hold db.wmu.
+func (db *DB) commit(pending map[string][]byte) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var payload []byte
+	refs := make([]valueRef, len(keys))
+	for i, key := range keys {
+		value := pending[key]
+		if value == nil {
+			payload = append(payload, opDelete)
+			payload = appendBytes(payload, []byte(key))
+			refs[i] = valueRef{len: -1}
+			continue
+		}
+
+		payload = append(payload, opPut)
+		payload = appendBytes(payload, []byte(key))
+		payload = appendBytes(payload, value)
+		refs[i] = valueRef{off: db.size + recordHeaderSize + int64(len(payload)-len(value)), len: len(value)}
+	}
+
+	cur := db.cur
+	record := db.frame(payload)
+	_, err := cur.log.file.WriteAt(record, db.size)
+	if err == nil {
+		err = cur.log.file.Sync()
+	}
+	if err != nil {
+		// Drop whatever was written, so the log stays well-formed.
+		cur.log.file.Truncate(db.size)
+		return err
+	}
+
+	root := cur.root
+	for i, key := range keys {
+		if old, ok := root.get(key); ok {
+			db.live -= int64(len(key) + old.len)
+		}
+		if refs[i].len >= 0 {
+			root = root.put(key, refs[i])
+			db.live += int64(len(key) + refs[i].len)
+		} else {
+			root = root.remove(key)
+		}
+	}
+	db.size += int64(len(record))
+	db.setCurrent(&snapshot{root: root, log: cur.log})
+
+	if db.size > compactMinSize && db.size-db.live > db.size/2 {
+		// The transaction is already committed; a failed compaction
+		// only leaves the old log in place, to be compacted later.
+		err = db.compact()
+		if err != nil {
+			logging.Base().Warnf("kvstore: %s: unable to compact: %v", db.filename, err)
+		}
+	}
+	return nil
+}
+
+// compact rewrites the live values into a new log.  The caller must hold
+// db.wmu.  Transactions reading the old log keep it open until they are
+// done.
+func (db *DB) compact() error {
+	cur := db.cur
+	var newFile storageFile
+	tmpname := db.filename + ".compact"
+	if _, ok := cur.log.file.(*memFile); ok {
+		newFile = &memFile{}
+	} else {
+		f, err := os.OpenFile(tmpname, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		newFile = f
+	}
+
+	// Keep the salt, so the new log's records are framed the same way.
+	var header [fileHeaderSize]byte
+	_, err := cur.log.file.ReadAt(header[:], 0)
+	if err == nil {
+		err = db.writeLogHeader(newFile, header[:])
+	}
+	if err != nil {
+		newFile.Close()
+		os.Remove(tmpname)
+		return err
+	}
+
+	var b keyTreeBuilder
+	size := int64(fileHeaderSize)
+	var payload []byte
+	var pendingKeys []string
+	var pendingRefs []valueRef
+	flush := func() error {
+		if len(payload) == 0 {
+			return nil
+		}
+		record := db.frame(payload)
+		_, err := newFile.WriteAt(record, size)
+		if err != nil {
+			return err
+		}
+		for i, key := range pendingKeys {
+			ref := pendingRefs[i]
+			ref.off += size + recordHeaderSize
+			b.add(key, ref)
+		}
+		size += int64(len(record))
+		payload, pendingKeys, pendingRefs = nil, nil, nil
+		return nil
+	}
+
+	it := cur.root.ascend("")
+	for n := it.next(); n != nil; n = it.next() {
+		var value []byte
+		value, err = cur.read(n.ref)
+		if err != nil {
+			break
+		}
+		payload = append(payload, opPut)
+		payload = appendBytes(payload, []byte(n.key))
+		payload = appendBytes(payload, value)
+		pendingKeys = append(pendingKeys, n.key)
+		pendingRefs = append(pendingRefs, valueRef{off: int64(len(payload) - len(value)), len: len(value)})
+		if len(payload) >= maxRecordSize {
+			err = flush()
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		err = newFile.Sync()
+	}
+	if err == nil {
+		if _, ok := newFile.(*memFile); !ok {
+			err = os.Rename(tmpname, db.filename)
+		}
+	}
+	if err != nil {
+		newFile.Close()
+		os.Remove(tmpname)
+		return err
+	}
+
+	db.setCurrent(&snapshot{root: b.root(), log: &logFile{file: newFile, refs: 1}})
+	db.size = size
+	return cur.log.release()
+}
+
+// Tx is a transaction on the store.
+type Tx struct {
+	snap *snapshot
+
+	// pending holds the uncommitted changes of a read-write
+	// transaction, with nil values for deleted keys.  It is nil
+	// for read-only transactions.
+	pending map[string][]byte
+}
+
+// Get returns the value of key, and whether it exists.
+func (tx *Tx) Get(key []byte) (value []byte, ok bool, err error) {
+	if value, pending := tx.pending[string(key)]; pending {
+		return value, value != nil, nil
+	}
+
+	ref, ok := tx.snap.root.get(string(key))
+	if !ok {
+		return nil, false, nil
+	}
+	value, err = tx.snap.read(ref)
+	return value, err == nil, err
+}
+
+// Put sets the value of key.
+func (tx *Tx) Put(key []byte, value []byte) error {
+	if tx.pending == nil {
+		return ErrReadOnly
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	tx.pending[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete removes key, if it exists.
+func (tx *Tx) Delete(key []byte) error {
+	if tx.pending == nil {
+		return ErrReadOnly
+	}
+	tx.pending[string(key)] = nil
+	return nil
+}
+
+// PrefixEnd returns the smallest key greater than every key starting with
+// prefix, or nil if there is none.
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] != 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// Scan calls fn on every key in [from, to), and its value, in ascending
+// order of keys, or descending if reverse is set, until fn returns false.
+// A nil to means no upper bound.
+func (tx *Tx) Scan(from []byte, to []byte, reverse bool, fn func(key []byte, value []byte) bool) error {
+	if tx.pending == nil {
+		return tx.scan(from, to, reverse, func(n *keyNode) (bool, error) {
+			value, err := tx.snap.read(n.ref)
+			if err != nil {
+				return false, err
+			}
+			return fn([]byte(n.key), value), nil
+		})
+	}
+
+	// Merge in the pending keys, and look up every value through Get.
+	var pendingKeys []string
+	for key := range tx.pending {
+		if key >= string(from) && (to == nil || key < string(to)) {
+			pendingKeys = append(pendingKeys, key)
+		}
+	}
+	sort.Strings(pendingKeys)
+	if reverse {
+		for i, j := 0, len(pendingKeys)-1; i < j; i, j = i+1, j-1 {
+			pendingKeys[i], pendingKeys[j] = pendingKeys[j], pendingKeys[i]
+		}
+	}
+
+	emit := func(key string) (bool, error) {
+		value, ok, err := tx.Get([]byte(key))
+		if err != nil || !ok {
+			return true, err
+		}
+		return fn([]byte(key), value), nil
+	}
+
+	more := true
+	err := tx.scan(from, to, reverse, func(n *keyNode) (bool, error) {
+		var err error
+		for more && len(pendingKeys) > 0 && (pendingKeys[0] < n.key) != reverse && pendingKeys[0] != n.key {
+			more, err = emit(pendingKeys[0])
+			pendingKeys = pendingKeys[1:]
+			if err != nil {
+				return false, err
+			}
+		}
+		if !more {
+			return false, nil
+		}
+		if len(pendingKeys) > 0 && pendingKeys[0] == n.key {
+			pendingKeys = pendingKeys[1:]
+		}
+		more, err = emit(n.key)
+		return more, err
+	})
+	for more && err == nil && len(pendingKeys) > 0 {
+		more, err = emit(pendingKeys[0])
+		pendingKeys = pendingKeys[1:]
+	}
+	return err
+}
+
+// scan walks the committed keys in [from, to), calling fn with each until
+// it returns false or an error.
+func (tx *Tx) scan(from []byte, to []byte, reverse bool, fn func(n *keyNode) (bool, error)) error {
+	var it *keyIter
+	if reverse {
+		it = tx.snap.root.descend(to)
+	} else {
+		it = tx.snap.root.ascend(string(from))
+	}
+
+	for n := it.next(); n != nil; n = it.next() {
+		if reverse && n.key < string(from) {
+			return nil
+		}
+		if !reverse && to != nil && n.key >= string(to) {
+			return nil
+		}
+		more, err := fn(n)
+		if !more || err != nil {
+			return err
+		}
+	}
+	return nil
+}
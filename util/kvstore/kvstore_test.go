@@ -0,0 +1,460 @@
+// Copyright (C) 2019 Algorand, Inc.
+// This file is part of go-algorand
+//
+// go-algorand is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// go-algorand is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with go-algorand.  If not, see <https://www.gnu.org/licenses/>.
+
+package kvstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scanAll(t *testing.T, tx *Tx, from []byte, to []byte, reverse bool) (keys []string) {
+	err := tx.Scan(from, to, reverse, func(key []byte, value []byte) bool {
+		require.Equal(t, "v"+string(key), string(value))
+		keys = append(keys, string(key))
+		return true
+	})
+	require.NoError(t, err)
+	return
+}
+
+func TestKVStoreBasic(t *testing.T) {
+	db, err := Open("", true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Update(func(tx *Tx) error {
+		for _, k := range []string{"b", "a", "c", "ab"} {
+			require.NoError(t, tx.Put([]byte(k), []byte("v"+k)))
+		}
+		// Uncommitted changes are visible within the transaction.
+		require.Equal(t, []string{"a", "ab", "b", "c"}, scanAll(t, tx, nil, nil, false))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View(func(tx *Tx) error {
+		v, ok, err := tx.Get([]byte("ab"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "vab", string(v))
+
+		_, ok, err = tx.Get([]byte("x"))
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		require.Equal(t, []string{"a", "ab"}, scanAll(t, tx, []byte("a"), PrefixEnd([]byte("a")), false))
+		require.Equal(t, []string{"c", "b", "ab", "a"}, scanAll(t, tx, nil, nil, true))
+		require.Equal(t, []string{"b", "ab"}, scanAll(t, tx, []byte("ab"), []byte("c"), true))
+
+		require.Equal(t, ErrReadOnly, tx.Put([]byte("x"), nil))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A failed transaction leaves no trace.
+	err = db.Update(func(tx *Tx) error {
+		require.NoError(t, tx.Delete([]byte("a")))
+		return fmt.Errorf("abort")
+	})
+	require.Error(t, err)
+
+	err = db.Update(func(tx *Tx) error {
+		require.NoError(t, tx.Delete([]byte("b")))
+		require.NoError(t, tx.Put([]byte("bb"), []byte("vbb")))
+		require.Equal(t, []string{"c", "bb", "ab", "a"}, scanAll(t, tx, nil, nil, true))
+		require.Equal(t, []string{"bb", "ab"}, scanAll(t, tx, []byte("ab"), []byte("bc"), true))
+		require.Equal(t, []string{"ab", "bb"}, scanAll(t, tx, []byte("aa"), []byte("bc"), false))
+
+		var first string
+		err := tx.Scan([]byte("b"), nil, false, func(key []byte, value []byte) bool {
+			first = string(key)
+			return false
+		})
+		require.NoError(t, err)
+		require.Equal(t, "bb", first)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View(func(tx *Tx) error {
+		require.Equal(t, []string{"a", "ab", "bb", "c"}, scanAll(t, tx, nil, nil, false))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestKVStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.kv")
+
+	db, err := Open(filename, false)
+	require.NoError(t, err)
+
+	expected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		err = db.Update(func(tx *Tx) error {
+			for j := 0; j < 10; j++ {
+				k := fmt.Sprintf("%03d", (i*7+j*13)%300)
+				if j%3 == 0 {
+					delete(expected, k)
+					require.NoError(t, tx.Delete([]byte(k)))
+				} else {
+					expected[k] = "v" + k
+					require.NoError(t, tx.Put([]byte(k), []byte("v"+k)))
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	check := func(db *DB) {
+		err := db.View(func(tx *Tx) error {
+			keys := scanAll(t, tx, nil, nil, false)
+			require.Equal(t, len(expected), len(keys))
+			for _, k := range keys {
+				require.Contains(t, expected, k)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	check(db)
+
+	// A transaction reading the old log is unaffected by compaction.
+	err = db.View(func(tx *Tx) error {
+		db.wmu.Lock()
+		size := db.size
+		require.NoError(t, db.compact())
+		require.True(t, db.size < size)
+		db.wmu.Unlock()
+
+		require.Equal(t, len(expected), len(scanAll(t, tx, nil, nil, false)))
+		return nil
+	})
+	require.NoError(t, err)
+	check(db)
+	require.NoError(t, db.Close())
+
+	db, err = Open(filename, false)
+	require.NoError(t, err)
+	check(db)
+
+	// A partially-written record at the end of the log is dropped.
+	size := db.size
+	require.NoError(t, db.Close())
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.Write(db.frame([]byte{opPut, 1, 'z', 1, 'z'})[:10])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// A read-only store ignores the torn record but leaves it in place.
+	ro, err := OpenReadOnly(filename)
+	require.NoError(t, err)
+	require.Equal(t, size, ro.size)
+	check(ro)
+	require.Equal(t, ErrReadOnly, ro.Update(func(tx *Tx) error { return nil }))
+	require.NoError(t, ro.Close())
+	fi, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, size+10, fi.Size())
+
+	_, err = OpenReadOnly(filename + ".missing")
+	require.Error(t, err)
+
+	db, err = Open(filename, false)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, size, db.size)
+	check(db)
+}
+
+func TestKVStoreCorruptRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.kv")
+
+	db, err := Open(filename, false)
+	require.NoError(t, err)
+	var offsets []int64
+	for _, k := range []string{"a", "b", "c"} {
+		offsets = append(offsets, db.size)
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			return tx.Put([]byte(k), []byte("v"+k))
+		}))
+	}
+	size := db.size
+	require.NoError(t, db.Close())
+
+	flip := func(off int64) {
+		f, err := os.OpenFile(filename, os.O_RDWR, 0600)
+		require.NoError(t, err)
+		var b [1]byte
+		_, err = f.ReadAt(b[:], off)
+		require.NoError(t, err)
+		b[0] ^= 0xff
+		_, err = f.WriteAt(b[:], off)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	// A damaged record followed by others is an error, not a truncation.
+	flip(offsets[1] + recordHeaderSize)
+	_, err = Open(filename, false)
+	require.Error(t, err)
+	fi, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, size, fi.Size())
+	flip(offsets[1] + recordHeaderSize)
+
+	// So is a damaged length that falls short of the file's end.
+	flip(offsets[1] + 3)
+	_, err = Open(filename, false)
+	require.Error(t, err)
+	fi, err = os.Stat(filename)
+	require.NoError(t, err)
+	require.Equal(t, size, fi.Size())
+	flip(offsets[1] + 3)
+
+	// A damaged last record was torn by a crash, and is dropped.
+	flip(offsets[2] + recordHeaderSize)
+	db, err = Open(filename, false)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, offsets[2], db.size)
+	err = db.View(func(tx *Tx) error {
+		require.Equal(t, []string{"a", "b"}, scanAll(t, tx, nil, nil, false))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestKVStoreTornTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.kv")
+
+	db, err := Open(filename, false)
+	require.NoError(t, err)
+	var last int64
+	for _, k := range []string{"a", "b"} {
+		last = db.size
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			return tx.Put([]byte(k), []byte("v"+k))
+		}))
+	}
+	size := db.size
+	require.NoError(t, db.Close())
+
+	// The last record cut short anywhere, even in its header, is dropped.
+	for end := last; end < size; end++ {
+		require.NoError(t, os.Truncate(filename, end))
+		db, err = Open(filename, false)
+		require.NoError(t, err)
+		err = db.View(func(tx *Tx) error {
+			require.Equal(t, []string{"a"}, scanAll(t, tx, nil, nil, false))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			return tx.Put([]byte("b"), []byte("vb"))
+		}))
+		require.Equal(t, size, db.size)
+		require.NoError(t, db.Close())
+	}
+}
+
+func TestKVStoreConcurrentView(t *testing.T) {
+	db, err := Open(t.Name(), true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				err := db.View(func(tx *Tx) error {
+					scanAll(t, tx, nil, nil, false)
+					return nil
+				})
+				require.NoError(t, err)
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("%04d", i)
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			return tx.Put([]byte(k), []byte("v"+k))
+		}))
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestKVStoreViewSnapshot(t *testing.T) {
+	db, err := Open(t.Name(), true)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Update(func(tx *Tx) error {
+		return tx.Put([]byte("a"), []byte("va"))
+	}))
+
+	// Commits do not wait for a View, which keeps seeing the store as
+	// of when it started.
+	err = db.View(func(tx *Tx) error {
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			require.NoError(t, tx.Delete([]byte("a")))
+			return tx.Put([]byte("b"), []byte("vb"))
+		}))
+		require.Equal(t, []string{"a"}, scanAll(t, tx, nil, nil, false))
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View(func(tx *Tx) error {
+		require.Equal(t, []string{"b"}, scanAll(t, tx, nil, nil, false))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestKVStoreDamagedHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.kv")
+
+	// Values that look like records must not be taken for ones.
+	other, err := Open(t.Name(), true)
+	require.NoError(t, err)
+	defer other.Close()
+	db, err := Open(filename, false)
+	require.NoError(t, err)
+	var offsets []int64
+	for i := 0; i < 50; i++ {
+		offsets = append(offsets, db.size)
+		k := fmt.Sprintf("%02d", i)
+		require.NoError(t, db.Update(func(tx *Tx) error {
+			return tx.Put([]byte(k), other.frame([]byte{opPut, 1, 'z', 1, 'z'}))
+		}))
+	}
+	require.NoError(t, db.Close())
+
+	f, err := os.OpenFile(filename, os.O_RDWR, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff, 0xff, 0xff}, offsets[10])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = Open(filename, false)
+	require.Error(t, err)
+
+	require.NoError(t, os.Truncate(filename, offsets[11]))
+	db, err = Open(filename, false)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, offsets[10], db.size)
+}
+
+func TestKeyTree(t *testing.T) {
+	var root *keyNode
+	expected := make(map[string]int)
+	var versions []*keyNode
+	var versionKeys [][]string
+	sortedKeys := func() []string {
+		var keys []string
+		for k := range expected {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	for i := 0; i < 5000; i++ {
+		k := fmt.Sprintf("%04d", rand.Intn(1000))
+		if rand.Intn(3) == 0 {
+			root = root.remove(k)
+			delete(expected, k)
+		} else {
+			root = root.put(k, valueRef{len: i})
+			expected[k] = i
+		}
+		if i%1000 == 0 {
+			versions = append(versions, root)
+			versionKeys = append(versionKeys, sortedKeys())
+		}
+	}
+
+	walk := func(it *keyIter) (keys []string) {
+		for n := it.next(); n != nil; n = it.next() {
+			keys = append(keys, n.key)
+		}
+		return
+	}
+
+	keys := sortedKeys()
+	require.Equal(t, keys, walk(root.ascend("")))
+	backward := walk(root.descend(nil))
+	for i, j := 0, len(backward)-1; i < j; i, j = i+1, j-1 {
+		backward[i], backward[j] = backward[j], backward[i]
+	}
+	require.Equal(t, keys, backward)
+	for k, v := range expected {
+		ref, ok := root.get(k)
+		require.True(t, ok)
+		require.Equal(t, v, ref.len)
+	}
+
+	i := sort.SearchStrings(keys, "0500")
+	require.Equal(t, keys[i:], walk(root.ascend("0500")))
+	require.Equal(t, keys[i-1], root.descend([]byte("0500")).next().key)
+	require.Nil(t, root.ascend("9999").next())
+
+	// Older versions are untouched by later changes.
+	for v, old := range versions {
+		require.Equal(t, versionKeys[v], walk(old.ascend("")))
+	}
+
+	// A tree built from sorted keys is the same treap.
+	var b keyTreeBuilder
+	for _, k := range keys {
+		b.add(k, valueRef{len: expected[k]})
+	}
+	require.Equal(t, root, b.root())
+}